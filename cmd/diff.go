@@ -0,0 +1,184 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/backend"
+	"github.com/pulumi/pulumi/pkg/backend/display"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/resource/stack"
+	"github.com/pulumi/pulumi/pkg/util/cmdutil"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/result"
+)
+
+func newDiffCmd() *cobra.Command {
+	var checkpoint string
+	var debug bool
+	var diffs diffFlags
+	var jsonDisplay bool
+	var showSames bool
+	var stackName string
+
+	var cmd = &cobra.Command{
+		Use:   "diff",
+		Short: "Show the differences between a stack's resources and their live or saved state",
+		Long: "Show the differences between a stack's resources and their live or saved state.\n" +
+			"\n" +
+			"By default, this command reads the current state of the stack's resources from their\n" +
+			"providers and displays the differences between the stack's state and the live state as a\n" +
+			"rich diff. The program is not run, no steps are planned, and the stack is not locked.\n" +
+			"\n" +
+			"If `--checkpoint` is given, nothing is read from the providers. Instead, the stack's current\n" +
+			"state is compared against a saved checkpoint, such as one written by `pulumi stack export`.\n" +
+			"\n" +
+			"The command exits with a non-zero exit code if any differences are found.",
+		Args: cmdutil.NoArgs,
+		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			diffOpts, _, err := diffFlagsToOptions(&diffs, jsonDisplay)
+			if err != nil {
+				return result.FromError(err)
+			}
+
+			opts := display.Options{
+				Color:             cmdutil.GetGlobalColorization(),
+				ShowSameResources: showSames,
+				IsInteractive:     cmdutil.Interactive(),
+				Type:              display.DisplayDiff,
+				JSONDisplay:       jsonDisplay,
+				Debug:             debug,
+				DiffOptions:       diffOpts,
+			}
+
+			s, err := requireStack(stackName, false, opts, true /*setCurrent*/)
+			if err != nil {
+				return result.FromError(err)
+			}
+
+			if opts.IgnoreDiffPaths, err = getIgnoreDiffPaths(s, diffs.ignoreDiffPaths); err != nil {
+				return result.FromError(err)
+			}
+
+			var changed bool
+			if checkpoint != "" {
+				changed, err = diffAgainstCheckpoint(s, checkpoint, opts)
+			} else {
+				changed, err = diffAgainstLiveState(s, opts)
+			}
+			if err != nil {
+				return result.FromError(err)
+			}
+
+			// The differences have already been displayed, so we just need to exit with a non-zero code.
+			if changed {
+				return result.Bail()
+			}
+			return nil
+		}),
+	}
+
+	cmd.PersistentFlags().StringVar(
+		&checkpoint, "checkpoint", "",
+		"Compare the stack's current state against the saved checkpoint in the given file rather than "+
+			"against the live state of its resources")
+	cmd.PersistentFlags().BoolVarP(
+		&debug, "debug", "d", false,
+		"Print detailed debugging output")
+	cmd.PersistentFlags().BoolVarP(
+		&jsonDisplay, "json", "j", false,
+		"Serialize the differences as JSON")
+	cmd.PersistentFlags().BoolVar(
+		&showSames, "show-sames", false,
+		"Show resources that haven't changed, alongside those that have")
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "",
+		"The name of the stack to operate on. Defaults to the current stack")
+
+	addDiffFlags(cmd, &diffs)
+
+	return cmd
+}
+
+// diffAgainstCheckpoint displays the differences between the stack's current state and the saved checkpoint in the
+// given file. It returns true if any differences were found.
+func diffAgainstCheckpoint(s backend.Stack, checkpoint string, opts display.Options) (bool, error) {
+	olds, err := readCheckpointFile(checkpoint)
+	if err != nil {
+		return false, err
+	}
+
+	news, err := s.Snapshot(commandContext())
+	if err != nil {
+		return false, err
+	}
+
+	return display.ShowSnapshotDiff(os.Stdout, olds, news, opts)
+}
+
+// readCheckpointFile reads the deployment in the given file, as written by `pulumi stack export`.
+func readCheckpointFile(path string) (*deploy.Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open checkpoint")
+	}
+	defer contract.IgnoreClose(f)
+
+	var deployment apitype.UntypedDeployment
+	if err = json.NewDecoder(f).Decode(&deployment); err != nil {
+		return nil, errors.Wrap(err, "could not read checkpoint")
+	}
+
+	snapshot, err := stack.DeserializeUntypedDeployment(&deployment)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not deserialize checkpoint")
+	}
+	return snapshot, nil
+}
+
+// diffAgainstLiveState reads the current state of the stack's resources from their providers and displays the
+// differences between the stack's saved state and the live state, without running the program or planning any steps.
+// It returns true if any differences were found.
+func diffAgainstLiveState(s backend.Stack, opts display.Options) (bool, error) {
+	olds, err := s.Snapshot(commandContext())
+	if err != nil {
+		return false, err
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return false, err
+	}
+	ctx, err := plugin.NewContext(cmdutil.Diag(), cmdutil.Diag(), nil, nil, pwd, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	defer contract.IgnoreClose(ctx)
+
+	news, err := engine.ReadSnapshot(ctx.Host, olds)
+	if err != nil {
+		return false, errors.Wrap(err, "reading live state")
+	}
+
+	return display.ShowSnapshotDiff(os.Stdout, olds, news, opts)
+}
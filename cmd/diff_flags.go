@@ -0,0 +1,248 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/backend/display"
+)
+
+// diffFlags holds the values of the flags that control how the diffs of resources are rendered.
+type diffFlags struct {
+	arrayKeys                 []string
+	diffAlignArrays           bool
+	diffArrayWindow           int
+	diffContext               int
+	diffDependencyOrder       bool
+	diffFlattenDepth          int
+	diffIndentWidth           int
+	diffMatchKeyCasing        bool
+	diffMoves                 bool
+	diffNormalize             []string
+	diffPathsOnly             bool
+	diffSummarizeReplacements bool
+	diffTreeStyle             string
+	diffWidth                 int
+	ignoreDiffPaths           []string
+	jsonStringPaths           []string
+	plainDiff                 bool
+	showReplacementReasons    bool
+	tupleArrayPaths           []string
+	unorderedArrayPaths       []string
+
+	// Flags registered by addUpdateDiffFlags.
+	diffBudget             int
+	diffFormat             string
+	diffLegend             bool
+	diffLogPath            string
+	diffLogValues          bool
+	diffSummaryThreshold   int
+	globalDiffBudget       bool
+	groupChangesByKind     bool
+	groupReplacements      bool
+	maxStringDisplayLength int
+	showSecretChanges      bool
+}
+
+// addDiffFlags registers the flags that control how the diffs of resources are rendered on the given command.
+func addDiffFlags(cmd *cobra.Command, flags *diffFlags) {
+	cmd.PersistentFlags().StringArrayVar(
+		&flags.arrayKeys, "array-key", []string{},
+		"Diff the arrays of objects at properties matching the given path pattern by the given key property, "+
+			"written PATTERN=KEY (e.g. **.rules=name), rather than by position")
+	cmd.PersistentFlags().BoolVar(
+		&flags.diffAlignArrays, "diff-align-arrays", false,
+		"Show elements inserted into or removed from the middle of arrays as such in the rich diff, rather than "+
+			"as changes to every element that follows them")
+	cmd.PersistentFlags().IntVar(
+		&flags.diffArrayWindow, "diff-array-window", 0,
+		"Show only the first and last N changed elements of each array in the rich diff, counting the changes "+
+			"in between (0 to show all)")
+	cmd.PersistentFlags().IntVar(
+		&flags.diffContext, "diff-context", 0,
+		"Show only N unchanged properties or elements before and after each change in the rich diff, eliding "+
+			"the rest (0 to show all)")
+	cmd.PersistentFlags().BoolVar(
+		&flags.diffDependencyOrder, "diff-dependency-order", false,
+		"Show each resource's diff just before those of the resources that depend on it, rather than in the "+
+			"order in which the resources are processed")
+	cmd.PersistentFlags().IntVar(
+		&flags.diffFlattenDepth, "diff-flatten-depth", 0,
+		"Flatten chains of objects with a single changed property into dotted paths in the rich diff, from "+
+			"nesting depth N (0 to never flatten)")
+	cmd.PersistentFlags().IntVar(
+		&flags.diffIndentWidth, "diff-indent", 0,
+		"Indent nested properties in the rich diff by N columns per level (0 for the default layout)")
+	cmd.PersistentFlags().BoolVar(
+		&flags.diffMatchKeyCasing, "diff-match-key-casing", false,
+		"Diff properties whose names differ only in casing convention (e.g. max_size and maxSize) as a single "+
+			"renamed property in the rich diff. Use with care, as this can hide genuine renames")
+	cmd.PersistentFlags().BoolVar(
+		&flags.diffMoves, "diff-moves", false,
+		"Show properties that are deleted and re-added with identical values elsewhere in a resource (e.g. "+
+			"after a provider schema change) as moves in the rich diff")
+	cmd.PersistentFlags().StringSliceVar(
+		&flags.diffNormalize, "diff-normalize", []string{},
+		"Display updates whose changes are all cosmetic under the given normalizations as unchanged: numbers, "+
+			"whitespace, nulls, or empty-strings")
+	cmd.PersistentFlags().BoolVar(
+		&flags.diffPathsOnly, "diff-paths-only", false,
+		"Show only the paths of changed properties in the rich diff, marked with their kind of change, "+
+			"without their values")
+	cmd.PersistentFlags().BoolVar(
+		&flags.diffSummarizeReplacements, "diff-summarize-replacements", false,
+		"List the changes that force each replaced resource to be replaced after the diff")
+	cmd.PersistentFlags().StringVar(
+		&flags.diffTreeStyle, "diff-tree-style", "",
+		"Connect nested properties in the rich diff to their parents with lines drawn in the given style: none, "+
+			"ascii, or unicode")
+	cmd.PersistentFlags().IntVar(
+		&flags.diffWidth, "diff-width", 0,
+		"Wrap lines of the rich diff that are wider than N columns (0 to wrap at the terminal's width, if any)")
+	cmd.PersistentFlags().StringArrayVar(
+		&flags.ignoreDiffPaths, "ignore-diff-path", []string{},
+		"Omit changes to properties matching the given path pattern (e.g. **.metadata.generation) from the "+
+			"displayed diffs, in addition to those listed in the stack's settings")
+	cmd.PersistentFlags().StringArrayVar(
+		&flags.jsonStringPaths, "json-string-path", []string{},
+		"Diff the JSON contents of string properties matching the given path pattern (e.g. **.policy) "+
+			"rather than the strings themselves")
+	cmd.PersistentFlags().BoolVar(
+		&flags.plainDiff, "plain", false,
+		"Display the differences without color")
+	cmd.PersistentFlags().BoolVar(
+		&flags.showReplacementReasons, "show-replacement-reasons", false,
+		"Show which changes force each replacement, apart from the properties it recreates unchanged")
+	cmd.PersistentFlags().StringArrayVar(
+		&flags.tupleArrayPaths, "tuple-array-path", []string{},
+		"Diff the arrays at properties matching the given path pattern (e.g. **.ports) as tuples, comparing "+
+			"the elements at each position rather than aligning similar elements")
+	cmd.PersistentFlags().StringArrayVar(
+		&flags.unorderedArrayPaths, "unordered-array-path", []string{},
+		"Diff the arrays at properties matching the given path pattern (e.g. **.tags) without regard to the "+
+			"order of their elements")
+}
+
+// addUpdateDiffFlags registers the flags that control how the diffs of resources are rendered as an update runs on the
+// given command, in addition to those registered by addDiffFlags.
+func addUpdateDiffFlags(cmd *cobra.Command, flags *diffFlags) {
+	addDiffFlags(cmd, flags)
+
+	cmd.PersistentFlags().IntVar(
+		&flags.diffBudget, "diff-budget", 0,
+		"Truncate the rich diff of each resource after showing N property changes (0 for no limit)")
+	cmd.PersistentFlags().BoolVar(
+		&flags.globalDiffBudget, "diff-budget-global", false,
+		"Apply --diff-budget to the rich diff as a whole rather than to each resource")
+	cmd.PersistentFlags().StringVar(
+		&flags.diffFormat, "diff-format", "",
+		"Write resource diffs in the given format: text, or jsonlines to write one JSON record per resource to "+
+			"stdout as soon as its step completes, in the order in which steps complete")
+	cmd.PersistentFlags().BoolVar(
+		&flags.groupReplacements, "diff-group-replacements", false,
+		"Show the changes that force each resource's replacement in the rich diff apart from its in-place changes")
+	cmd.PersistentFlags().BoolVar(
+		&flags.diffLegend, "diff-legend", false,
+		"Explain the markers (e.g. +, -, ~, and +-) that appear in the rich diff before the summary")
+	cmd.PersistentFlags().StringVar(
+		&flags.diffLogPath, "diff-log", "",
+		"Write a structured log record for each property change to the given file as JSON Lines, without values")
+	cmd.PersistentFlags().BoolVar(
+		&flags.diffLogValues, "diff-log-values", false,
+		"Include the old and new values of properties in the --diff-log records; secrets are never included")
+	cmd.PersistentFlags().IntVar(
+		&flags.diffSummaryThreshold, "diff-summary-threshold", 0,
+		"Summarize the rich diff of each resource with more than N property changes (0 for no limit)")
+	cmd.PersistentFlags().BoolVar(
+		&flags.groupChangesByKind, "group-changes-by-kind", false,
+		"List the property changes of all resources grouped by kind (deletions, then replacements, then "+
+			"in-place changes) before the summary")
+	cmd.PersistentFlags().IntVar(
+		&flags.maxStringDisplayLength, "max-string-display-length", 0,
+		"Truncate strings longer than N characters in the rich diff (0 for no limit)")
+	cmd.PersistentFlags().BoolVar(
+		&flags.showSecretChanges, "show-secret-changes", false,
+		"List the secret properties that changed, by path and kind of change, before the summary (their values "+
+			"are never shown)")
+}
+
+// diffFlagsToOptions ensures that the given diff flags represent a valid combination, given whether the command's
+// output is serialized as JSON. If so, the display options and diff format that the flags select are returned with a
+// nil error; otherwise, the non-nil error contains information about why the combination is invalid. Paths to ignore
+// are not included in the options, as those listed in the stack's settings must be added to them.
+func diffFlagsToOptions(flags *diffFlags, jsonDisplay bool) (display.DiffOptions, display.DiffFormat, error) {
+	if err := display.ValidateUnorderedArrayPaths(flags.unorderedArrayPaths); err != nil {
+		return display.DiffOptions{}, "", err
+	}
+	if err := display.ValidateTupleArrayPaths(flags.tupleArrayPaths); err != nil {
+		return display.DiffOptions{}, "", err
+	}
+	if err := display.ValidateJSONStringPaths(flags.jsonStringPaths); err != nil {
+		return display.DiffOptions{}, "", err
+	}
+	treeStyle, err := display.ParseDiffTreeStyle(flags.diffTreeStyle)
+	if err != nil {
+		return display.DiffOptions{}, "", err
+	}
+	format, err := display.ParseDiffFormat(flags.diffFormat)
+	if err != nil {
+		return display.DiffOptions{}, "", err
+	}
+	if jsonDisplay && format == display.DiffFormatJSONLines {
+		return display.DiffOptions{}, "", errors.New("--json and --diff-format=jsonlines cannot be used together")
+	}
+	keyedArrays, err := display.ParseKeyedArrays(flags.arrayKeys)
+	if err != nil {
+		return display.DiffOptions{}, "", err
+	}
+	normalization, err := display.ParseDiffNormalization(flags.diffNormalize)
+	if err != nil {
+		return display.DiffOptions{}, "", err
+	}
+
+	return display.DiffOptions{
+		DiffBudget:             flags.diffBudget,
+		DiffSummaryThreshold:   flags.diffSummaryThreshold,
+		GlobalDiffBudget:       flags.globalDiffBudget,
+		GroupChangesByKind:     flags.groupChangesByKind,
+		GroupReplacements:      flags.groupReplacements,
+		MaxStringDisplayLength: flags.maxStringDisplayLength,
+		PlainDiff:              flags.plainDiff,
+		UnorderedArrayPaths:    flags.unorderedArrayPaths,
+		AlignArrays:            flags.diffAlignArrays,
+		TupleArrayPaths:        flags.tupleArrayPaths,
+		JSONStringPaths:        flags.jsonStringPaths,
+		DiffIndentWidth:        flags.diffIndentWidth,
+		DiffContext:            flags.diffContext,
+		ArrayDiffWindow:        flags.diffArrayWindow,
+		OrderByDependencies:    flags.diffDependencyOrder,
+		SummarizeReplacements:  flags.diffSummarizeReplacements,
+		FlattenDiffDepth:       flags.diffFlattenDepth,
+		DiffTreeStyle:          treeStyle,
+		DiffWidth:              flags.diffWidth,
+		DiffPathsOnly:          flags.diffPathsOnly,
+		ShowReplacementReasons: flags.showReplacementReasons,
+		KeyedArrays:            keyedArrays,
+		NormalizeDiffs:         normalization,
+		MatchKeyCasing:         flags.diffMatchKeyCasing,
+		DetectMovedProperties:  flags.diffMoves,
+		ShowSecretChanges:      flags.showSecretChanges,
+		ShowDiffLegend:         flags.diffLegend,
+		StrictDetailedDiff:     useStrictDetailedDiff(),
+		TrustDetailedDiffKinds: useTrustedDetailedDiffKinds(),
+	}, format, nil
+}
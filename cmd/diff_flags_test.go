@@ -0,0 +1,49 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/backend/display"
+)
+
+func TestDiffFlagsToOptions(t *testing.T) {
+	var flags diffFlags
+	cmd := &cobra.Command{}
+	addUpdateDiffFlags(cmd, &flags)
+	assert.NoError(t, cmd.ParseFlags([]string{
+		"--diff-budget", "5", "--diff-tree-style", "ascii", "--array-key", "**.rules=name", "--diff-format", "jsonlines",
+	}))
+
+	opts, format, err := diffFlagsToOptions(&flags, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, opts.DiffBudget)
+	assert.Equal(t, display.DiffTreeASCII, opts.DiffTreeStyle)
+	assert.Len(t, opts.KeyedArrays, 1)
+	assert.Equal(t, display.DiffFormatJSONLines, format)
+
+	// JSON Lines diffs cannot be written alongside JSON output.
+	_, _, err = diffFlagsToOptions(&flags, true)
+	assert.Error(t, err)
+
+	// Invalid values are rejected.
+	flags.diffFormat, flags.diffTreeStyle = "", "dotted"
+	_, _, err = diffFlagsToOptions(&flags, false)
+	assert.Error(t, err)
+}
@@ -27,6 +27,7 @@ import (
 
 func newPreviewCmd() *cobra.Command {
 	var debug bool
+	var diffs diffFlags
 	var expectNop bool
 	var message string
 	var stack string
@@ -59,6 +60,12 @@ func newPreviewCmd() *cobra.Command {
 			"`--cwd` flag to use a different directory.",
 		Args: cmdutil.NoArgs,
 		Run: cmdutil.RunResultFunc(func(cmd *cobra.Command, args []string) result.Result {
+			diffOpts, format, err := diffFlagsToOptions(&diffs, jsonDisplay)
+			if err != nil {
+				return result.FromError(err)
+			}
+			diffOpts.DiffBudgetHint = "use --json for full detail"
+
 			var displayType = display.DisplayProgress
 			if diffDisplay {
 				displayType = display.DisplayDiff
@@ -81,6 +88,10 @@ func newPreviewCmd() *cobra.Command {
 					Type:                 displayType,
 					JSONDisplay:          jsonDisplay,
 					Debug:                debug,
+					DiffLogPath:          diffs.diffLogPath,
+					DiffLogValues:        diffs.diffLogValues,
+					DiffFormat:           format,
+					DiffOptions:          diffOpts,
 				},
 			}
 
@@ -109,6 +120,11 @@ func newPreviewCmd() *cobra.Command {
 				return result.FromError(errors.Wrap(err, "getting stack configuration"))
 			}
 
+			opts.Display.IgnoreDiffPaths, err = getIgnoreDiffPaths(s, diffs.ignoreDiffPaths)
+			if err != nil {
+				return result.FromError(err)
+			}
+
 			changes, res := s.Preview(commandContext(), backend.UpdateOperation{
 				Proj:               proj,
 				Root:               root,
@@ -173,5 +189,7 @@ func newPreviewCmd() *cobra.Command {
 		&suppressOutputs, "suppress-outputs", false,
 		"Suppress display of stack outputs (in case they contain sensitive values)")
 
+	addUpdateDiffFlags(cmd, &diffs)
+
 	return cmd
 }
@@ -159,6 +159,7 @@ func NewPulumiCmd() *cobra.Command {
 	//     - Deploy Commands
 	cmd.AddCommand(newUpCmd())
 	cmd.AddCommand(newPreviewCmd())
+	cmd.AddCommand(newDiffCmd())
 	cmd.AddCommand(newDestroyCmd())
 	//     - Stack Management Commands:
 	cmd.AddCommand(newStackCmd())
@@ -36,6 +36,7 @@ func newRefreshCmd() *cobra.Command {
 	// Flags for engine.UpdateOptions.
 	var analyzers []string
 	var diffDisplay bool
+	var importPlanPath string
 	var parallel int
 	var showConfig bool
 	var showReplacementSteps bool
@@ -82,6 +83,7 @@ func newRefreshCmd() *cobra.Command {
 				IsInteractive:        interactive,
 				Type:                 displayType,
 				Debug:                debug,
+				ImportPlanPath:       importPlanPath,
 			}
 
 			s, err := requireStack(stack, true, opts.Display, true /*setCurrent*/)
@@ -163,6 +165,10 @@ func newRefreshCmd() *cobra.Command {
 	cmd.PersistentFlags().BoolVar(
 		&diffDisplay, "diff", false,
 		"Display operation as a rich diff showing the overall change")
+	cmd.PersistentFlags().StringVar(
+		&importPlanPath, "import-plan", "",
+		"Write the properties that exist in the cloud but not in the stack's state to the given file as an import "+
+			"plan. Only additions are planned, and secrets are written as placeholders to be entered by hand")
 	cmd.PersistentFlags().IntVarP(
 		&parallel, "parallel", "p", defaultParallel,
 		"Allow P resource operations to run in parallel at once (1 for no parallelism). Defaults to unbounded.")
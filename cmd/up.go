@@ -46,6 +46,7 @@ const (
 // nolint: vetshadow
 func newUpCmd() *cobra.Command {
 	var debug bool
+	var diffs diffFlags
 	var expectNop bool
 	var message string
 	var stack string
@@ -53,9 +54,11 @@ func newUpCmd() *cobra.Command {
 
 	// Flags for engine.UpdateOptions.
 	var analyzers []string
+	var changelogPath string
 	var diffDisplay bool
 	var parallel int
 	var refresh bool
+	var resolveComputedDiffs bool
 	var showConfig bool
 	var showReplacementSteps bool
 	var showSames bool
@@ -103,6 +106,11 @@ func newUpCmd() *cobra.Command {
 			return result.FromError(errors.Wrap(err, "getting stack configuration"))
 		}
 
+		opts.Display.IgnoreDiffPaths, err = getIgnoreDiffPaths(s, diffs.ignoreDiffPaths)
+		if err != nil {
+			return result.FromError(err)
+		}
+
 		opts.Engine = engine.UpdateOptions{
 			Analyzers:     analyzers,
 			Parallel:      parallel,
@@ -261,6 +269,11 @@ func newUpCmd() *cobra.Command {
 			return result.FromError(errors.Wrap(err, "getting stack configuration"))
 		}
 
+		opts.Display.IgnoreDiffPaths, err = getIgnoreDiffPaths(s, diffs.ignoreDiffPaths)
+		if err != nil {
+			return result.FromError(err)
+		}
+
 		opts.Engine = engine.UpdateOptions{
 			Analyzers: analyzers,
 			Parallel:  parallel,
@@ -322,6 +335,13 @@ func newUpCmd() *cobra.Command {
 				return result.FromError(err)
 			}
 
+			diffOpts, format, err := diffFlagsToOptions(&diffs, false)
+			if err != nil {
+				return result.FromError(err)
+			}
+			diffOpts.DiffBudgetHint = "use --diff-format=jsonlines for full detail"
+			diffOpts.ResolveComputedDiffs = resolveComputedDiffs
+
 			var displayType = display.DisplayProgress
 			if diffDisplay {
 				displayType = display.DisplayDiff
@@ -336,6 +356,11 @@ func newUpCmd() *cobra.Command {
 				IsInteractive:        interactive,
 				Type:                 displayType,
 				Debug:                debug,
+				ChangelogPath:        changelogPath,
+				DiffLogPath:          diffs.diffLogPath,
+				DiffLogValues:        diffs.diffLogValues,
+				DiffFormat:           format,
+				DiffOptions:          diffOpts,
 			}
 
 			if len(args) > 0 {
@@ -374,9 +399,16 @@ func newUpCmd() *cobra.Command {
 	cmd.PersistentFlags().StringSliceVar(
 		&analyzers, "analyzer", []string{},
 		"Run one or more analyzers as part of this update")
+	cmd.PersistentFlags().StringVar(
+		&changelogPath, "changelog", "",
+		"Write a changelog of the property changes made to each resource to the given file, as JSON Lines")
 	cmd.PersistentFlags().BoolVar(
 		&diffDisplay, "diff", false,
 		"Display operation as a rich diff showing the overall change")
+	cmd.PersistentFlags().BoolVar(
+		&resolveComputedDiffs, "experimental-resolve-computed-diffs", false,
+		"Show the values of computed properties in the rich diff of each resource as they resolve")
+	contract.AssertNoError(cmd.PersistentFlags().MarkHidden("experimental-resolve-computed-diffs"))
 	cmd.PersistentFlags().IntVarP(
 		&parallel, "parallel", "p", defaultParallel,
 		"Allow P resource operations to run in parallel at once (1 for no parallelism). Defaults to unbounded.")
@@ -402,6 +434,8 @@ func newUpCmd() *cobra.Command {
 		&yes, "yes", "y", false,
 		"Automatically approve and perform the update after previewing it")
 
+	addUpdateDiffFlags(cmd, &diffs)
+
 	return cmd
 }
 
@@ -59,6 +59,19 @@ func useLegacyDiff() bool {
 	return cmdutil.IsTruthy(os.Getenv("PULUMI_ENABLE_LEGACY_DIFF"))
 }
 
+// useStrictDetailedDiff returns true if the display should report detailed diff entries whose kinds conflict with the
+// changes that are displayed for them. This is intended to help provider authors debug their detailed diffs.
+func useStrictDetailedDiff() bool {
+	return cmdutil.IsTruthy(os.Getenv("PULUMI_STRICT_DETAILED_DIFF"))
+}
+
+// useTrustedDetailedDiffKinds returns true if the display should use the kinds that providers report for the properties
+// in their detailed diffs at every level, rather than inferring the kinds of properties that contain other changes.
+// This is intended for providers whose detailed diffs include entries for such properties.
+func useTrustedDetailedDiffKinds() bool {
+	return cmdutil.IsTruthy(os.Getenv("PULUMI_TRUST_DETAILED_DIFF_KINDS"))
+}
+
 func currentBackend(opts display.Options) (backend.Backend, error) {
 	url, err := workspace.GetCurrentCloudURL()
 	if err != nil {
@@ -607,3 +620,18 @@ func updateFlagsToOptions(interactive, skipPreview, yes bool) (backend.UpdateOpt
 		SkipPreview: skipPreview,
 	}, nil
 }
+
+// getIgnoreDiffPaths returns the property path patterns whose changes should be omitted from displayed diffs: those
+// listed in the stack's settings, followed by those given on the command line.
+func getIgnoreDiffPaths(s backend.Stack, paths []string) ([]string, error) {
+	ps, err := loadProjectStack(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading stack settings")
+	}
+
+	result := append(append([]string{}, ps.IgnoreDiffPaths...), paths...)
+	if err = display.ValidateIgnoreDiffPaths(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,283 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+	"sort"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// maxArrayAlignmentCells bounds the size of the table used to align a pair of arrays. Larger arrays are left diffed by
+// position.
+const maxArrayAlignmentCells = 1 << 20
+
+// alignDiffArrays returns a copy of the given diff between the given old and new properties in which the elements of
+// each updated array are aligned by the arrays' longest common subsequence, so that an element inserted into or
+// removed from the middle of an array is shown as a single add or delete rather than as a change to each element that
+// follows it. Arrays whose alignment records no fewer changes than their diff by position are left as they are, as are
+// arrays whose property paths match one of the given tuple patterns: each position in a tuple has a meaning of its own.
+// The indices of aligned elements are recorded in their arrays' moves wherever they differ from their positions.
+//
+// Elements are compared by their hashes before they are compared deeply, and elements that the diff by position
+// already compared are not diffed again.
+func alignDiffArrays(diff *resource.ObjectDiff, olds, news resource.PropertyMap,
+	tuples []diffPathPattern) *resource.ObjectDiff {
+
+	if diff == nil {
+		return nil
+	}
+	return alignObjectDiffArrays(nil, diff, resource.NewObjectProperty(olds), resource.NewObjectProperty(news), tuples)
+}
+
+func alignObjectDiffArrays(path []interface{}, diff *resource.ObjectDiff, old, new resource.PropertyValue,
+	tuples []diffPathPattern) *resource.ObjectDiff {
+
+	result := *diff
+	result.Updates = make(map[resource.PropertyKey]resource.ValueDiff)
+	for k, update := range diff.Updates {
+		elementOld, elementNew := getUpdatedValues(string(k), update, old, new)
+		result.Updates[k] = alignValueDiffArrays(appendPath(path, string(k)), update, elementOld, elementNew, tuples)
+	}
+	return &result
+}
+
+func alignArrayDiffArrays(path []interface{}, diff *resource.ArrayDiff,
+	tuples []diffPathPattern) *resource.ArrayDiff {
+
+	result := *diff
+	result.Updates = make(map[int]resource.ValueDiff)
+	for i, update := range diff.Updates {
+		// Updates within arrays always record their old and new values.
+		result.Updates[i] = alignValueDiffArrays(appendPath(path, diff.Index(i)), update, update.Old, update.New,
+			tuples)
+	}
+	return &result
+}
+
+func alignValueDiffArrays(path []interface{}, diff resource.ValueDiff, old, new resource.PropertyValue,
+	tuples []diffPathPattern) resource.ValueDiff {
+
+	if diff.Array != nil && old.IsArray() && new.IsArray() && !matchesDiffPath(path, tuples) {
+		if aligned, ok := alignArrays(diff.Array, old.ArrayValue(), new.ArrayValue()); ok {
+			diff.Array = aligned
+		}
+	}
+
+	// The elements of arrays may themselves contain arrays.
+	switch {
+	case diff.Array != nil:
+		diff.Array = alignArrayDiffArrays(path, diff.Array, tuples)
+	case diff.Object != nil:
+		diff.Object = alignObjectDiffArrays(path, diff.Object, old, new, tuples)
+	}
+	return diff
+}
+
+// matchesDiffPath returns true if the given property path matches any of the given patterns.
+func matchesDiffPath(path []interface{}, patterns []diffPathPattern) bool {
+	for _, pattern := range patterns {
+		if pattern.matches(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// arrayPair pairs the index of an element of an old array with the index of an element of a new array. An index is
+// -1 if the pair has no element from that array, i.e. if the element was added or deleted.
+type arrayPair struct {
+	from int
+	to   int
+}
+
+// alignArrays aligns the given old and new arrays, whose diff by position is given, and returns their aligned diff. It
+// returns false if the arrays are too large to align or if their alignment records no fewer changes than their diff
+// by position.
+func alignArrays(diff *resource.ArrayDiff, old, new []resource.PropertyValue) (*resource.ArrayDiff, bool) {
+	if len(old) == 0 || len(new) == 0 || len(old)*len(new) > maxArrayAlignmentCells {
+		return nil, false
+	}
+
+	oldHashes, newHashes := hashPropertyValues(old), hashPropertyValues(new)
+	equal := func(i, j int) bool {
+		return oldHashes[i] == newHashes[j] && old[i].DeepEquals(new[j])
+	}
+
+	// lcs[i][j] holds the length of the longest common subsequence of old[i:] and new[j:].
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			switch {
+			case oldHashes[i] == newHashes[j] && lcs[i+1][j+1]+1 > lcs[i+1][j] && lcs[i+1][j+1]+1 > lcs[i][j+1] &&
+				old[i].DeepEquals(new[j]):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// Walk the table to pair the elements of the common subsequence. The elements removed and inserted between each
+	// pair of common elements are paired with one another as updates, and any that remain are deletes or adds.
+	var pairs []arrayPair
+	var removed, inserted []int
+	flush := func() {
+		for len(removed) > 0 && len(inserted) > 0 {
+			pairs = append(pairs, arrayPair{from: removed[0], to: inserted[0]})
+			removed, inserted = removed[1:], inserted[1:]
+		}
+		for _, i := range removed {
+			pairs = append(pairs, arrayPair{from: i, to: -1})
+		}
+		for _, j := range inserted {
+			pairs = append(pairs, arrayPair{from: -1, to: j})
+		}
+		removed, inserted = nil, nil
+	}
+	for i, j := 0, 0; i < len(old) || j < len(new); {
+		switch {
+		case i < len(old) && j < len(new) && lcs[i][j] == lcs[i+1][j+1]+1 && equal(i, j):
+			flush()
+			pairs = append(pairs, arrayPair{from: i, to: j})
+			i, j = i+1, j+1
+		case j == len(new) || (i < len(old) && lcs[i+1][j] >= lcs[i][j+1]):
+			removed = append(removed, i)
+			i++
+		default:
+			inserted = append(inserted, j)
+			j++
+		}
+	}
+	flush()
+
+	changes := 0
+	for _, p := range pairs {
+		if p.from == -1 || p.to == -1 || !equal(p.from, p.to) {
+			changes++
+		}
+	}
+	if changes >= len(diff.Adds)+len(diff.Deletes)+len(diff.Updates) {
+		return nil, false
+	}
+
+	result := &resource.ArrayDiff{
+		Adds:    make(map[int]resource.PropertyValue),
+		Deletes: make(map[int]resource.PropertyValue),
+		Sames:   make(map[int]resource.PropertyValue),
+		Updates: make(map[int]resource.ValueDiff),
+		Moves:   make(map[int]resource.ArrayMove),
+	}
+	for pos, p := range pairs {
+		switch {
+		case p.to == -1:
+			result.Deletes[pos] = old[p.from]
+		case p.from == -1:
+			result.Adds[pos] = new[p.to]
+		case p.from == p.to:
+			// The diff by position has already compared these elements.
+			if update, ok := diff.Updates[p.from]; ok {
+				result.Updates[pos] = update
+			} else {
+				result.Sames[pos] = old[p.from]
+			}
+		default:
+			if update := old[p.from].Diff(new[p.to]); update != nil {
+				result.Updates[pos] = *update
+			} else {
+				result.Sames[pos] = old[p.from]
+			}
+		}
+		if (p.from != -1 && p.from != pos) || (p.to != -1 && p.to != pos) {
+			result.Moves[pos] = resource.ArrayMove{From: p.from, To: p.to}
+		}
+	}
+	return result, true
+}
+
+// hashPropertyValues returns the hashes of the given values.
+func hashPropertyValues(values []resource.PropertyValue) []uint64 {
+	hashes := make([]uint64, len(values))
+	for i, v := range values {
+		h := fnv.New64a()
+		writePropertyValueHash(h, v)
+		hashes[i] = h.Sum64()
+	}
+	return hashes
+}
+
+// writePropertyValueHash writes the given value to the given hash such that values that are deeply equal hash
+// equally. Values whose hashes are equal must still be compared deeply.
+func writePropertyValueHash(h hash.Hash64, v resource.PropertyValue) {
+	var buf [8]byte
+	switch {
+	case v.IsNull():
+		_, _ = h.Write([]byte{0})
+	case v.IsBool():
+		if v.BoolValue() {
+			_, _ = h.Write([]byte{1, 1})
+		} else {
+			_, _ = h.Write([]byte{1, 0})
+		}
+	case v.IsNumber():
+		n := v.NumberValue()
+		if n == 0 {
+			n = 0 // -0 equals 0.
+		}
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(n))
+		_, _ = h.Write([]byte{2})
+		_, _ = h.Write(buf[:])
+	case v.IsString():
+		_, _ = h.Write([]byte{3})
+		_, _ = h.Write([]byte(v.StringValue()))
+		_, _ = h.Write([]byte{0})
+	case v.IsArray():
+		_, _ = h.Write([]byte{4})
+		for _, e := range v.ArrayValue() {
+			writePropertyValueHash(h, e)
+		}
+	case v.IsObject():
+		// Properties without values equal absent properties, so they are left out of the hash.
+		obj := v.ObjectValue()
+		var keys []string
+		for k, e := range obj {
+			if e.HasValue() {
+				keys = append(keys, string(k))
+			}
+		}
+		sort.Strings(keys)
+		_, _ = h.Write([]byte{5})
+		for _, k := range keys {
+			_, _ = h.Write([]byte(k))
+			_, _ = h.Write([]byte{0})
+			writePropertyValueHash(h, obj[resource.PropertyKey(k)])
+		}
+	case v.IsSecret():
+		_, _ = h.Write([]byte{6})
+		writePropertyValueHash(h, v.SecretValue().Element)
+	default:
+		// Computed values, outputs, assets, and archives are left to the deep comparison.
+		_, _ = h.Write([]byte{7})
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+func TestAlignDiffArrays(t *testing.T) {
+	str := resource.NewStringProperty
+	align := func(old, new []string, tuples ...string) *resource.ArrayDiff {
+		olds := resource.PropertyMap{"hosts": resource.NewPropertyValue(old)}
+		news := resource.PropertyMap{"hosts": resource.NewPropertyValue(new)}
+		var patterns []diffPathPattern
+		for _, tuple := range tuples {
+			pattern, err := parseDiffPathPattern(tuple)
+			assert.NoError(t, err)
+			patterns = append(patterns, pattern)
+		}
+		return alignDiffArrays(olds.Diff(news), olds, news, patterns).Updates["hosts"].Array
+	}
+
+	cases := []struct {
+		name     string
+		old, new []string
+		expected *resource.ArrayDiff
+	}{
+		{
+			name: "insert at front",
+			old:  []string{"b", "c", "d"},
+			new:  []string{"a", "b", "c", "d"},
+			expected: &resource.ArrayDiff{
+				Adds:    map[int]resource.PropertyValue{0: str("a")},
+				Deletes: map[int]resource.PropertyValue{},
+				Sames:   map[int]resource.PropertyValue{1: str("b"), 2: str("c"), 3: str("d")},
+				Updates: map[int]resource.ValueDiff{},
+				Moves:   map[int]resource.ArrayMove{1: {From: 0, To: 1}, 2: {From: 1, To: 2}, 3: {From: 2, To: 3}},
+			},
+		},
+		{
+			name: "delete in middle",
+			old:  []string{"a", "b", "c", "d"},
+			new:  []string{"a", "b", "d"},
+			expected: &resource.ArrayDiff{
+				Adds:    map[int]resource.PropertyValue{},
+				Deletes: map[int]resource.PropertyValue{2: str("c")},
+				Sames:   map[int]resource.PropertyValue{0: str("a"), 1: str("b"), 3: str("d")},
+				Updates: map[int]resource.ValueDiff{},
+				Moves:   map[int]resource.ArrayMove{3: {From: 3, To: 2}},
+			},
+		},
+		{
+			name: "replace and insert before the tail",
+			old:  []string{"a", "b", "x", "y"},
+			new:  []string{"a", "c", "d", "x", "y"},
+			expected: &resource.ArrayDiff{
+				Adds:    map[int]resource.PropertyValue{2: str("d")},
+				Deletes: map[int]resource.PropertyValue{},
+				Sames:   map[int]resource.PropertyValue{0: str("a"), 3: str("x"), 4: str("y")},
+				Updates: map[int]resource.ValueDiff{1: {Old: str("b"), New: str("c")}},
+				Moves:   map[int]resource.ArrayMove{3: {From: 2, To: 3}, 4: {From: 3, To: 4}},
+			},
+		},
+		{
+			// Aligning the arrays records no fewer changes than comparing them by position.
+			name: "swap",
+			old:  []string{"a", "b"},
+			new:  []string{"b", "a"},
+			expected: &resource.ArrayDiff{
+				Adds:    map[int]resource.PropertyValue{},
+				Deletes: map[int]resource.PropertyValue{},
+				Sames:   map[int]resource.PropertyValue{},
+				Updates: map[int]resource.ValueDiff{
+					0: {Old: str("a"), New: str("b")},
+					1: {Old: str("b"), New: str("a")},
+				},
+			},
+		},
+		{
+			// Moving the first element to the end is recorded as a delete and an add.
+			name: "rotation",
+			old:  []string{"a", "b", "c"},
+			new:  []string{"b", "c", "a"},
+			expected: &resource.ArrayDiff{
+				Adds:    map[int]resource.PropertyValue{3: str("a")},
+				Deletes: map[int]resource.PropertyValue{0: str("a")},
+				Sames:   map[int]resource.PropertyValue{1: str("b"), 2: str("c")},
+				Updates: map[int]resource.ValueDiff{},
+				Moves:   map[int]resource.ArrayMove{1: {From: 1, To: 0}, 2: {From: 2, To: 1}, 3: {From: -1, To: 2}},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, align(c.old, c.new))
+		})
+	}
+
+	// Tuples are left diffed by position.
+	positional := resource.NewPropertyValue([]string{"b", "c"}).Diff(resource.NewPropertyValue([]string{"a", "b", "c"}))
+	assert.Equal(t, positional.Array, align([]string{"b", "c"}, []string{"a", "b", "c"}, "hosts"))
+
+	// Aligned elements are addressed and labeled by index.
+	aligned := align([]string{"a", "b", "c"}, []string{"b", "c", "a"})
+	assert.Equal(t, 3, aligned.Len())
+	assert.Equal(t, []string{"[0]", "[0]", "[1]", "[2]"},
+		[]string{aligned.Label(0), aligned.Label(1), aligned.Label(2), aligned.Label(3)})
+}
+
+func TestHashPropertyValues(t *testing.T) {
+	values := []resource.PropertyValue{
+		resource.NewPropertyValue(map[string]interface{}{"a": 1, "b": []interface{}{"x", true}}),
+		resource.NewObjectProperty(resource.PropertyMap{
+			"b": resource.NewPropertyValue([]interface{}{"x", true}),
+			"a": resource.NewNumberProperty(1),
+			"c": resource.NewNullProperty(),
+		}),
+		resource.NewPropertyValue(map[string]interface{}{"a": 1, "b": []interface{}{"x", false}}),
+		resource.NewNumberProperty(0),
+		resource.NewNumberProperty(math.Copysign(0, -1)),
+	}
+	hashes := hashPropertyValues(values)
+
+	// Deeply equal values hash equally, including objects that differ only in their properties without values.
+	assert.True(t, values[0].DeepEquals(values[1]))
+	assert.Equal(t, hashes[0], hashes[1])
+	assert.NotEqual(t, hashes[0], hashes[2])
+	assert.Equal(t, hashes[3], hashes[4])
+}
@@ -0,0 +1,85 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// windowObjectDiffArrays returns a copy of the given diff in which each array with more than twice the given number of
+// changed elements shows only its first and last window changed elements. The elements between them, changed or not,
+// are omitted, and the display counts the omitted changes instead. A non-positive window leaves the diff as it is.
+func windowObjectDiffArrays(diff *resource.ObjectDiff, window int) *resource.ObjectDiff {
+	if diff == nil || window <= 0 {
+		return diff
+	}
+
+	result := &resource.ObjectDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Renames: diff.Renames,
+		Paths:   diff.Paths,
+		Sames:   diff.Sames,
+		Updates: make(map[resource.PropertyKey]resource.ValueDiff),
+		Leading: diff.Leading,
+		Elided:  diff.Elided,
+	}
+	for k, update := range diff.Updates {
+		result.Updates[k] = windowValueDiffArrays(update, window)
+	}
+	return result
+}
+
+func windowArrayDiffArrays(diff *resource.ArrayDiff, window int) *resource.ArrayDiff {
+	result := &resource.ArrayDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Sames:   diff.Sames,
+		Updates: make(map[int]resource.ValueDiff),
+		Moves:   diff.Moves,
+		Labels:  diff.Labels,
+		Elided:  diff.Elided,
+	}
+	for i, update := range diff.Updates {
+		result.Updates[i] = windowValueDiffArrays(update, window)
+	}
+
+	var changed []int
+	for _, i := range arrayDiffIndices(diff) {
+		if _, same := diff.Sames[i]; !same {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) > 2*window {
+		// Omit everything after the first window changes and before the last window changes.
+		result.Omitted = make(map[int]bool)
+		for i := changed[window-1] + 1; i < changed[len(changed)-window]; i++ {
+			result.Omitted[i] = true
+		}
+	}
+	return result
+}
+
+func windowValueDiffArrays(diff resource.ValueDiff, window int) resource.ValueDiff {
+	switch {
+	case diff.Array != nil:
+		diff.Array = windowArrayDiffArrays(diff.Array, window)
+	case diff.Object != nil:
+		diff.Object = windowObjectDiffArrays(diff.Object, window)
+	}
+	return diff
+}
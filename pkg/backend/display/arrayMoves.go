@@ -0,0 +1,78 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// labelArrayMoves returns a copy of the given diff in which each aligned array element that is in both the old and new
+// arrays at different indices is labeled by both, e.g. [2→3], rather than by its index in the new array alone.
+func labelArrayMoves(diff *resource.ObjectDiff) *resource.ObjectDiff {
+	if diff == nil {
+		return nil
+	}
+
+	updates := make(map[resource.PropertyKey]resource.ValueDiff)
+	for k, update := range diff.Updates {
+		updates[k] = labelValueArrayMoves(update)
+	}
+	return &resource.ObjectDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Renames: diff.Renames,
+		Sames:   diff.Sames,
+		Updates: updates,
+		Leading: diff.Leading,
+	}
+}
+
+func labelValueArrayMoves(diff resource.ValueDiff) resource.ValueDiff {
+	switch {
+	case diff.Array != nil:
+		diff.Array = labelArrayDiffMoves(diff.Array)
+	case diff.Object != nil:
+		diff.Object = labelArrayMoves(diff.Object)
+	}
+	return diff
+}
+
+func labelArrayDiffMoves(diff *resource.ArrayDiff) *resource.ArrayDiff {
+	result := &resource.ArrayDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Sames:   diff.Sames,
+		Updates: make(map[int]resource.ValueDiff),
+		Moves:   diff.Moves,
+	}
+	for i, update := range diff.Updates {
+		result.Updates[i] = labelValueArrayMoves(update)
+	}
+	if len(diff.Moves) == 0 {
+		return result
+	}
+
+	result.Labels = make(map[int]string)
+	for i, move := range diff.Moves {
+		if move.From != -1 && move.To != -1 && move.From != move.To {
+			result.Labels[i] = fmt.Sprintf("[%d→%d]", move.From, move.To)
+		}
+	}
+	return result
+}
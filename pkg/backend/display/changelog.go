@@ -0,0 +1,134 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// changelogEntry records the property-level changes that an update made to a single resource.
+type changelogEntry struct {
+	// URN is the resource that was changed.
+	URN resource.URN `json:"urn"`
+	// OldURN is the resource's previous URN, if it was renamed.
+	OldURN resource.URN `json:"oldUrn,omitempty"`
+	// Op is the operation that was performed.
+	Op deploy.StepOp `json:"op"`
+	// Diff is the property-level diff between the resource's old and new states.
+	Diff *objectDiffJSON `json:"diff,omitempty"`
+}
+
+// recordChangelog interposes on the given stream of engine events, recording the steps that the engine performs.
+// When the stream is canceled, a changelog with one JSON record per changed resource is written to the given path.
+// The changelog is written before the cancellation is forwarded so that it is complete once the display finishes.
+func recordChangelog(path string, events <-chan engine.Event, opts Options) <-chan engine.Event {
+	out := make(chan engine.Event)
+	go func() {
+		var steps []engine.StepEventMetadata
+		failed := make(map[resource.URN]bool)
+		for e := range events {
+			switch e.Type {
+			case engine.ResourcePreEvent:
+				steps = append(steps, e.Payload.(engine.ResourcePreEventPayload).Metadata)
+			case engine.ResourceOperationFailed:
+				failed[e.Payload.(engine.ResourceOperationFailedPayload).Metadata.URN] = true
+			case engine.CancelEvent:
+				if err := writeChangelog(path, steps, failed, opts); err != nil {
+					fprintfIgnoreError(os.Stderr, opts.Color.Colorize(
+						colors.SpecWarning+"warning:"+colors.Reset+" %v\n"), err)
+				}
+				out <- e
+				return
+			}
+			out <- e
+		}
+		close(out)
+	}()
+	return out
+}
+
+// writeChangelog writes the changelog for the given steps to the given path as JSON Lines, in step order.
+func writeChangelog(path string, steps []engine.StepEventMetadata, failed map[resource.URN]bool,
+	opts Options) error {
+
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "could not create changelog")
+	}
+	defer contract.IgnoreClose(f)
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, step := range steps {
+		if !isChangelogStep(step) || failed[step.URN] {
+			continue
+		}
+		entry := changelogEntry{
+			URN:    step.URN,
+			OldURN: getRenamedURN(step),
+			Op:     step.Op,
+			Diff:   serializeObjectDiff(getChangelogDiff(step, opts), false),
+		}
+		if err = enc.Encode(&entry); err != nil {
+			return errors.Wrap(err, "could not write changelog")
+		}
+	}
+	return errors.Wrap(w.Flush(), "could not write changelog")
+}
+
+// isChangelogStep returns true if the given step represents a change that should be recorded in the changelog.
+func isChangelogStep(step engine.StepEventMetadata) bool {
+	if !step.Logical {
+		return false
+	}
+	switch step.Op {
+	case deploy.OpSame, deploy.OpRead, deploy.OpReadReplacement, deploy.OpReadDiscard:
+		return false
+	default:
+		return true
+	}
+}
+
+// getChangelogDiff returns the property diff to record for the given step. Creates and deletes are recorded as the
+// addition or deletion of all of the resource's input properties, respectively.
+func getChangelogDiff(step engine.StepEventMetadata, opts Options) *resource.ObjectDiff {
+	switch {
+	case step.Old == nil && step.New != nil:
+		return resource.PropertyMap{}.Diff(step.New.Inputs, engine.IsInternalPropertyKey)
+	case step.Old != nil && step.New == nil:
+		return step.Old.Inputs.Diff(resource.PropertyMap{}, engine.IsInternalPropertyKey)
+	default:
+		return getStepDiff(step, opts)
+	}
+}
+
+// getRenamedURN returns the previous URN of the given step's resource if it was renamed, e.g. by way of an alias, and
+// the empty URN otherwise.
+func getRenamedURN(step engine.StepEventMetadata) resource.URN {
+	if step.Old != nil && step.Old.URN != "" && step.Old.URN != step.URN {
+		return step.Old.URN
+	}
+	return ""
+}
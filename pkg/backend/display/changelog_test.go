@@ -0,0 +1,114 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+func TestChangelog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "changelog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "changelog.jsonl")
+
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name":  "web",
+		"spec":  map[string]interface{}{"replicas": 3, "ports": []interface{}{80}},
+		"debug": true,
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name":  "web",
+		"spec":  map[string]interface{}{"replicas": 5, "ports": []interface{}{80, 8080}},
+		"owner": "ops",
+	})
+	makeStep := func(op deploy.StepOp, suffix string) engine.StepEventMetadata {
+		step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+			"spec.replicas": {Kind: plugin.DiffUpdate},
+			"spec.ports[1]": {Kind: plugin.DiffAdd},
+			"debug":         {Kind: plugin.DiffDelete},
+			"owner":         {Kind: plugin.DiffAdd},
+		})
+		step.Op, step.URN = op, step.URN+resource.URN(suffix)
+		if op == deploy.OpCreate {
+			step.Old = nil
+		}
+		return step
+	}
+
+	update := makeStep(deploy.OpUpdate, "")
+	created := makeStep(deploy.OpCreate, "-created")
+	same := makeStep(deploy.OpSame, "-same")
+	failed := makeStep(deploy.OpUpdate, "-failed")
+
+	events := make(chan engine.Event)
+	out := recordChangelog(path, events, Options{})
+	go func() {
+		for _, step := range []engine.StepEventMetadata{update, created, same, failed} {
+			events <- engine.Event{Type: engine.ResourcePreEvent, Payload: engine.ResourcePreEventPayload{Metadata: step}}
+		}
+		events <- engine.Event{
+			Type:    engine.ResourceOperationFailed,
+			Payload: engine.ResourceOperationFailedPayload{Metadata: failed},
+		}
+		events <- engine.Event{Type: engine.CancelEvent}
+	}()
+
+	// All events, including the cancellation, must be forwarded.
+	var forwarded int
+	for e := range out {
+		forwarded++
+		if e.Type == engine.CancelEvent {
+			break
+		}
+	}
+	assert.Equal(t, 6, forwarded)
+
+	contents, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	assert.Len(t, lines, 2)
+
+	var entries []changelogEntry
+	for _, line := range lines {
+		var entry changelogEntry
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+
+	assert.Equal(t, update.URN, entries[0].URN)
+	assert.Equal(t, deploy.OpUpdate, entries[0].Op)
+	assert.Equal(t, "ops", entries[0].Diff.Adds["owner"])
+	assert.Equal(t, true, entries[0].Diff.Deletes["debug"])
+	assert.Equal(t, float64(3), entries[0].Diff.Updates["spec"].Object.Updates["replicas"].Old)
+	assert.Equal(t, float64(5), entries[0].Diff.Updates["spec"].Object.Updates["replicas"].New)
+	assert.Equal(t, float64(8080), entries[0].Diff.Updates["spec"].Object.Updates["ports"].Array.Adds[1])
+
+	assert.Equal(t, created.URN, entries[1].URN)
+	assert.Equal(t, deploy.OpCreate, entries[1].Op)
+	assert.Equal(t, "web", entries[1].Diff.Adds["name"])
+}
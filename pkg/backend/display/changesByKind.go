@@ -0,0 +1,153 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+// changeKinds lists the kinds of property changes in the order in which they are grouped: deletions first, then the
+// remaining changes that force replacements, then the remaining in-place changes.
+var changeKinds = []plugin.DiffKind{
+	plugin.DiffDelete,
+	plugin.DiffDeleteReplace,
+	plugin.DiffUpdateReplace,
+	plugin.DiffAddReplace,
+	plugin.DiffUpdate,
+	plugin.DiffAdd,
+}
+
+// propertyChange is a single changed property of a resource.
+type propertyChange struct {
+	URN      resource.URN           // the resource whose property changed.
+	Path     []interface{}          // the path to the property.
+	Old, New resource.PropertyValue // the old and new values; adds carry only a new value and deletes an old one.
+}
+
+// groupChangesByKind collects the changed properties of all of the given steps that would be shown by the display and
+// groups them by kind. Within each group, changes are sorted by URN and then in the order in which diffs are walked.
+func groupChangesByKind(steps []engine.StepEventMetadata, opts Options) map[plugin.DiffKind][]propertyChange {
+	groups := make(map[plugin.DiffKind][]propertyChange)
+	for _, step := range steps {
+		if step.Op == deploy.OpSame || !shouldShow(step, opts) {
+			continue
+		}
+
+		replacementPaths := getReplacementPaths(step)
+		walkObjectDiff(getStepDiff(step, opts), func(path []interface{}, op deploy.StepOp,
+			old, new resource.PropertyValue) {
+
+			kind, changed := getChangeKind(op, isReplacementPath(path, replacementPaths))
+			if !changed {
+				return
+			}
+			groups[kind] = append(groups[kind], propertyChange{URN: step.URN, Path: path, Old: old, New: new})
+		})
+	}
+
+	for _, changes := range groups {
+		sort.SliceStable(changes, func(i, j int) bool { return changes[i].URN < changes[j].URN })
+	}
+	return groups
+}
+
+// getChangeKind returns the kind of a property change with the given op, given whether the change forces a
+// replacement. The second result is false if the op is not a change.
+func getChangeKind(op deploy.StepOp, replace bool) (plugin.DiffKind, bool) {
+	switch {
+	case op == deploy.OpCreate && replace:
+		return plugin.DiffAddReplace, true
+	case op == deploy.OpCreate:
+		return plugin.DiffAdd, true
+	case op == deploy.OpDelete && replace:
+		return plugin.DiffDeleteReplace, true
+	case op == deploy.OpDelete:
+		return plugin.DiffDelete, true
+	case op == deploy.OpUpdate && replace:
+		return plugin.DiffUpdateReplace, true
+	case op == deploy.OpUpdate:
+		return plugin.DiffUpdate, true
+	default:
+		return 0, false
+	}
+}
+
+// getChangeKindOp returns the op whose color and marker represent property changes of the given kind.
+func getChangeKindOp(kind plugin.DiffKind) deploy.StepOp {
+	switch {
+	case kind.IsReplace():
+		return deploy.OpReplace
+	case kind == plugin.DiffAdd:
+		return deploy.OpCreate
+	case kind == plugin.DiffDelete:
+		return deploy.OpDelete
+	default:
+		return deploy.OpUpdate
+	}
+}
+
+// renderChangesByKind renders the changed properties of all of the given steps grouped by kind, e.g.
+//
+//	Changes by kind:
+//	    delete:
+//	        - urn:pulumi:dev::web::aws:ec2/instance:Instance::web tags.Owner: "ops"
+//	    update-replace:
+//	        +-urn:pulumi:dev::web::aws:ec2/instance:Instance::web ami: "ami-1" => "ami-2"
+//
+// Nothing is rendered unless the options request it or if no properties changed.
+func renderChangesByKind(steps []engine.StepEventMetadata, planning bool, opts Options) string {
+	if !opts.GroupChangesByKind {
+		return ""
+	}
+	groups := groupChangesByKind(steps, opts)
+	if len(groups) == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	fprintfIgnoreError(&buf, "%sChanges by kind:%s\n", colors.SpecHeadline, colors.Reset)
+	for _, kind := range changeKinds {
+		changes := groups[kind]
+		if len(changes) == 0 {
+			continue
+		}
+
+		op := getChangeKindOp(kind)
+		fprintfIgnoreError(&buf, "    %s%s:%s\n", op.Color(), kind, colors.Reset)
+		for _, change := range changes {
+			var values string
+			switch kind {
+			case plugin.DiffAdd, plugin.DiffAddReplace:
+				values = engine.FormatPropertyValue(change.New, planning)
+			case plugin.DiffDelete, plugin.DiffDeleteReplace:
+				values = engine.FormatPropertyValue(change.Old, planning)
+			default:
+				values = engine.FormatPropertyValue(change.Old, planning) + " => " +
+					engine.FormatPropertyValue(change.New, planning)
+			}
+			fprintfIgnoreError(&buf, "        %s%s %s: %s%s\n", op.Prefix(), change.URN,
+				engine.FormatPropertyPath(change.Path), values, colors.Reset)
+		}
+	}
+	fprintIgnoreError(&buf, "\n")
+	return buf.String()
+}
@@ -0,0 +1,127 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// CompactDiffOptions controls the output of FormatCompactDiff.
+type CompactDiffOptions struct {
+	IncludeSames   bool // true to include a line for each property that is unchanged.
+	MaxValueLength int  // if positive, the number of characters after which to cut each formatted value.
+}
+
+// FormatCompactDiff formats the given diff as one line per changed property, e.g.
+//
+//	spec.replicas: 3 → 5 [update]
+//	spec.labels.tier: "frontend" [delete]
+//
+// Each line names the full path to the property, its old and/or new value, and the kind of change. Scalar values are
+// formatted as they are in the rich diff, while arrays and objects are summarized as <array> and <object>; changes
+// within arrays and objects that are updated in place are listed individually. The lines are sorted by property key
+// and array index.
+func FormatCompactDiff(diff *resource.ObjectDiff, opts CompactDiffOptions) []string {
+	if diff == nil {
+		return nil
+	}
+	f := &compactDiffFormatter{opts: opts}
+	f.formatObjectDiff(nil, diff)
+	return f.lines
+}
+
+type compactDiffFormatter struct {
+	opts  CompactDiffOptions
+	lines []string
+}
+
+func (f *compactDiffFormatter) formatObjectDiff(path []interface{}, diff *resource.ObjectDiff) {
+	for _, k := range diff.Keys() {
+		elementPath := appendPath(path, string(k))
+		if add, isAdd := diff.Adds[k]; isAdd {
+			f.printValue(elementPath, add, "add")
+		} else if delete, isDelete := diff.Deletes[k]; isDelete {
+			f.printValue(elementPath, delete, "delete")
+		} else if update, isUpdate := diff.Updates[k]; isUpdate {
+			f.formatValueDiff(elementPath, update)
+		} else if f.opts.IncludeSames {
+			f.printValue(elementPath, diff.Sames[k], "same")
+		}
+	}
+}
+
+func (f *compactDiffFormatter) formatArrayDiff(path []interface{}, diff *resource.ArrayDiff) {
+	for _, i := range arrayDiffIndices(diff) {
+		elementPath := appendPath(path, diff.Index(i))
+		if add, isAdd := diff.Adds[i]; isAdd {
+			f.printValue(elementPath, add, "add")
+		} else if delete, isDelete := diff.Deletes[i]; isDelete {
+			f.printValue(elementPath, delete, "delete")
+		} else if update, isUpdate := diff.Updates[i]; isUpdate {
+			f.formatValueDiff(elementPath, update)
+		} else if f.opts.IncludeSames {
+			f.printValue(elementPath, diff.Sames[i], "same")
+		}
+	}
+}
+
+// arrayDiffIndices returns the sorted indices of the elements tracked by the given diff.
+func arrayDiffIndices(diff *resource.ArrayDiff) []int {
+	var indices []int
+	for i := range diff.Adds {
+		indices = append(indices, i)
+	}
+	for i := range diff.Deletes {
+		indices = append(indices, i)
+	}
+	for i := range diff.Sames {
+		indices = append(indices, i)
+	}
+	for i := range diff.Updates {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	return indices
+}
+
+func (f *compactDiffFormatter) formatValueDiff(path []interface{}, diff resource.ValueDiff) {
+	switch {
+	case diff.Array != nil:
+		f.formatArrayDiff(path, diff.Array)
+	case diff.Object != nil:
+		f.formatObjectDiff(path, diff.Object)
+	default:
+		f.lines = append(f.lines, fmt.Sprintf("%s: %s → %s [update]",
+			engine.FormatPropertyPath(path), f.formatValue(diff.Old), f.formatValue(diff.New)))
+	}
+}
+
+func (f *compactDiffFormatter) printValue(path []interface{}, v resource.PropertyValue, kind string) {
+	f.lines = append(f.lines, fmt.Sprintf("%s: %s [%s]", engine.FormatPropertyPath(path), f.formatValue(v), kind))
+}
+
+// formatValue returns the compact text of the given value, cut to the maximum value length.
+func (f *compactDiffFormatter) formatValue(v resource.PropertyValue) string {
+	text := engine.FormatPropertyValue(v, true /*planning*/)
+	if max := f.opts.MaxValueLength; max > 0 && utf8.RuneCountInString(text) > max {
+		text = text[:runeOffset(text, max)] + "…"
+	}
+	return text
+}
@@ -0,0 +1,138 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// computedLeaf is a leaf of a rendered diff whose new value was computed when the diff was rendered.
+type computedLeaf struct {
+	path  []interface{}          // the path to the leaf.
+	value resource.PropertyValue // the computed value that was rendered.
+}
+
+// computedDiffLeaves tracks the computed leaves of the diffs rendered for each resource, keyed by their formatted
+// property paths, so that the leaves can be patched once their values resolve. Leaves are tracked from the rendering
+// of a resource's diff until the resource's outputs arrive.
+type computedDiffLeaves struct {
+	leaves map[resource.URN]map[string]computedLeaf
+}
+
+func newComputedDiffLeaves() *computedDiffLeaves {
+	return &computedDiffLeaves{leaves: make(map[resource.URN]map[string]computedLeaf)}
+}
+
+// record records the computed leaves of the new properties of the given step, which are those displayed by the step's
+// diff. Any leaves previously recorded for the step's resource are replaced.
+func (c *computedDiffLeaves) record(step engine.StepEventMetadata) {
+	delete(c.leaves, step.URN)
+	if step.New == nil {
+		return
+	}
+
+	leaves := make(map[string]computedLeaf)
+	var walk func(path []interface{}, v resource.PropertyValue)
+	walk = func(path []interface{}, v resource.PropertyValue) {
+		switch {
+		case isUnknown(v):
+			leaves[engine.FormatPropertyPath(path)] = computedLeaf{path: path, value: v}
+		case v.IsArray():
+			for i, elem := range v.ArrayValue() {
+				walk(appendPath(path, i), elem)
+			}
+		case v.IsObject():
+			for k, elem := range v.ObjectValue() {
+				walk(appendPath(path, string(k)), elem)
+			}
+		}
+	}
+	walk(nil, resource.NewObjectProperty(step.New.Inputs))
+
+	if len(leaves) > 0 {
+		c.leaves[step.URN] = leaves
+	}
+}
+
+// resolvedLeaf is a computed leaf whose value has resolved.
+type resolvedLeaf struct {
+	path     string                 // the formatted path to the leaf.
+	elements []interface{}          // the elements of the path to the leaf.
+	computed resource.PropertyValue // the computed value that was rendered.
+	resolved resource.PropertyValue // the resolved value.
+}
+
+// resolve returns the leaves recorded for the given step's resource whose values are known in the step's outputs,
+// sorted by path, and stops tracking the resource's leaves. Leaves that are missing from the outputs or are still
+// computed are not returned.
+func (c *computedDiffLeaves) resolve(step engine.StepEventMetadata) []resolvedLeaf {
+	leaves := c.leaves[step.URN]
+	delete(c.leaves, step.URN)
+	if len(leaves) == 0 || step.New == nil {
+		return nil
+	}
+
+	var resolved []resolvedLeaf
+	for path, leaf := range leaves {
+		v, ok := resource.NewObjectProperty(step.New.Outputs), true
+		for _, key := range leaf.path {
+			if v, ok = lookupProperty(key, v); !ok {
+				break
+			}
+		}
+		if ok && !isUnknown(v) && !v.ContainsUnknowns() {
+			resolved = append(resolved, resolvedLeaf{
+				path:     path,
+				elements: leaf.path,
+				computed: leaf.value,
+				resolved: v,
+			})
+		}
+	}
+	sort.Slice(resolved, func(i, j int) bool { return resolved[i].path < resolved[j].path })
+	return resolved
+}
+
+// renderResolvedComputedLeaves renders the resolved values of the computed leaves of the given step's previously
+// rendered diff. Nothing is rendered unless computed leaves are being tracked.
+func renderResolvedComputedLeaves(step engine.StepEventMetadata, indent int, opts Options) string {
+	if opts.computed == nil {
+		return ""
+	}
+	resolved := opts.computed.resolve(step)
+	if len(resolved) == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	fprintfIgnoreError(&buf, "%v%v--resolved:--%v\n", step.Op.Color(), engine.GetIndentationString(indent+1),
+		colors.Reset)
+	for _, leaf := range resolved {
+		value := leaf.resolved
+		if opts.ValueTransform != nil {
+			value = transformValue(leaf.elements, value, opts.ValueTransform)
+		}
+		fprintfIgnoreError(&buf, "%v%v%v: %v => %v%v\n", engine.GetIndentationString(indent+1),
+			deploy.OpUpdate.Prefix(), leaf.path, engine.FormatPropertyValue(leaf.computed, false /*planning*/),
+			engine.FormatPropertyValue(value, false /*planning*/), colors.Reset)
+	}
+	return buf.String()
+}
@@ -0,0 +1,155 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"sort"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// getStepDependencies returns the URNs of the resources on which the resource of the given step depends: its parent
+// and the dependencies recorded by its states.
+func getStepDependencies(step engine.StepEventMetadata) []resource.URN {
+	var dependencies []resource.URN
+	for _, state := range []*engine.StepEventStateMetadata{step.Old, step.New, step.Res} {
+		if state == nil {
+			continue
+		}
+		if state.Parent != "" {
+			dependencies = append(dependencies, state.Parent)
+		}
+		if state.State != nil {
+			dependencies = append(dependencies, state.State.Dependencies...)
+		}
+	}
+	return dependencies
+}
+
+// orderByDependencies returns the URNs of the resources of the given steps in an order in which each resource comes
+// after the resources on which it depends, so that the cause of a change is shown before its effects. Each resource is
+// followed immediately by those of its dependents that depend on nothing else that has yet to be shown; otherwise,
+// resources are ordered by URN. Only dependencies among the given steps' resources constrain the order.
+func orderByDependencies(steps []engine.StepEventMetadata) []resource.URN {
+	dependencies := make(map[resource.URN]map[resource.URN]bool)
+	for _, step := range steps {
+		if dependencies[step.URN] == nil {
+			dependencies[step.URN] = make(map[resource.URN]bool)
+		}
+		for _, dependency := range getStepDependencies(step) {
+			if dependency != step.URN {
+				dependencies[step.URN][dependency] = true
+			}
+		}
+	}
+
+	urns := make([]resource.URN, 0, len(dependencies))
+	for urn := range dependencies {
+		urns = append(urns, urn)
+	}
+	sort.Slice(urns, func(i, j int) bool { return urns[i] < urns[j] })
+
+	// Count the dependencies of each resource that are yet to be shown, and list the dependents of each in URN order.
+	remaining := make(map[resource.URN]int)
+	dependents := make(map[resource.URN][]resource.URN)
+	for _, urn := range urns {
+		for dependency := range dependencies[urn] {
+			if _, has := dependencies[dependency]; has {
+				remaining[urn]++
+				dependents[dependency] = append(dependents[dependency], urn)
+			}
+		}
+	}
+
+	var order []resource.URN
+	shown := make(map[resource.URN]bool)
+	var show func(urn resource.URN)
+	show = func(urn resource.URN) {
+		shown[urn] = true
+		order = append(order, urn)
+		for _, dependent := range dependents[urn] {
+			if remaining[dependent]--; remaining[dependent] == 0 {
+				show(dependent)
+			}
+		}
+	}
+	for _, urn := range urns {
+		if !shown[urn] && remaining[urn] == 0 {
+			show(urn)
+		}
+	}
+
+	// Resources in dependency cycles, which the engine does not permit, are shown last.
+	for _, urn := range urns {
+		if !shown[urn] {
+			order = append(order, urn)
+		}
+	}
+	return order
+}
+
+// orderStepsByDependencies returns the given steps ordered by the dependencies among their resources, as by
+// orderByDependencies. The steps of each resource are kept together, in the order in which they were given.
+func orderStepsByDependencies(steps []engine.StepEventMetadata) []engine.StepEventMetadata {
+	byURN := make(map[resource.URN][]engine.StepEventMetadata)
+	for _, step := range steps {
+		byURN[step.URN] = append(byURN[step.URN], step)
+	}
+
+	ordered := make([]engine.StepEventMetadata, 0, len(steps))
+	for _, urn := range orderByDependencies(steps) {
+		ordered = append(ordered, byURN[urn]...)
+	}
+	return ordered
+}
+
+// dependencyOrderedEvents holds back the events of resources so that they can be rendered in the order of their
+// dependencies once all of them are known, rather than in the order in which the engine reports them.
+type dependencyOrderedEvents struct {
+	steps  []engine.StepEventMetadata
+	events map[resource.URN][]engine.Event
+}
+
+func newDependencyOrderedEvents() *dependencyOrderedEvents {
+	return &dependencyOrderedEvents{events: make(map[resource.URN][]engine.Event)}
+}
+
+// add holds back the given event if it is a resource pre-event or outputs event, and returns true if it did so.
+func (d *dependencyOrderedEvents) add(event engine.Event) bool {
+	var step engine.StepEventMetadata
+	switch event.Type {
+	case engine.ResourcePreEvent:
+		step = event.Payload.(engine.ResourcePreEventPayload).Metadata
+	case engine.ResourceOutputsEvent:
+		step = event.Payload.(engine.ResourceOutputsEventPayload).Metadata
+	default:
+		return false
+	}
+	d.steps = append(d.steps, step)
+	d.events[step.URN] = append(d.events[step.URN], event)
+	return true
+}
+
+// flush returns the events held back so far ordered by the dependencies among their resources, as by
+// orderByDependencies. The events of each resource are kept together, in the order in which they were added.
+func (d *dependencyOrderedEvents) flush() []engine.Event {
+	var events []engine.Event
+	for _, urn := range orderByDependencies(d.steps) {
+		events = append(events, d.events[urn]...)
+	}
+	d.steps, d.events = nil, make(map[resource.URN][]engine.Event)
+	return events
+}
@@ -1,127 +1,193 @@
 package display
 
 import (
-	"strconv"
-	"strings"
+	"sort"
 
-	"github.com/pkg/errors"
 	"github.com/pulumi/pulumi/pkg/engine"
 	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
 	"github.com/pulumi/pulumi/pkg/util/contract"
+	"github.com/pulumi/pulumi/pkg/util/logging"
 )
 
-func parseDiffPath(path string) ([]interface{}, error) {
-	// Complete paths obey the following EBNF-ish grammar:
-	//
-	//   propertyName := [a-zA-Z_$] { [a-zA-Z0-9_$] }
-	//   quotedPropertyName := '"' ( '\' '"' | [^"] ) { ( '\' '"' | [^"] ) } '"'
-	//   arrayIndex := { [0-9] }
-	//
-	//   propertyIndex := '[' ( quotedPropertyName | arrayIndex ) ']'
-	//   rootProperty := ( propertyName | propertyIndex )
-	//   propertyAccessor := ( ( '.' propertyName ) |  propertyIndex )
-	//   path := rootProperty { propertyAccessor }
-	//
-	// We interpret this a little loosely in order to keep things simple. Specifically, we will accept something close
-	// to the following:
-	// pathElement := { '.' } ( '[' ( [0-9]+ | '"' ('\' '"' | [^"] )+ '"' ']' | [a-zA-Z_$][a-zA-Z0-9_$] )
-	// path := { pathElement }
-
-	var elements []interface{}
-	for len(path) > 0 {
-		switch path[0] {
-		case '.':
-			path = path[1:]
-		case '[':
-			// If the character following the '[' is a '"', parse a string key.
-			var pathElement interface{}
-			if path[1] == '"' {
-				var propertyKey []byte
-				var i int
-				for i = 2; ; {
-					if i == len(path) {
-						return nil, errors.New("missing closing quote in property name")
-					} else if path[i] == '"' {
-						i++
-						break
-					} else if path[i] == '\\' && i+1 < len(path) && path[i+1] == '"' {
-						propertyKey = append(propertyKey, '"')
-						i += 2
-					} else {
-						propertyKey = append(propertyKey, path[i])
-						i++
-					}
-				}
-				if i == len(path) || path[i] != ']' {
-					return nil, errors.New("missing closing bracket in property access")
-				}
-				pathElement, path = string(propertyKey), path[i:]
-			} else {
-				// Look for a closing ']'
-				rbracket := strings.IndexRune(path, ']')
-				if rbracket == -1 {
-					return nil, errors.New("missing closing bracket in array index")
-				}
-
-				index, err := strconv.ParseInt(path[1:rbracket], 10, 0)
-				if err != nil {
-					return nil, errors.Wrap(err, "invalid array index")
-				}
-				pathElement, path = int(index), path[rbracket:]
-			}
-			elements, path = append(elements, pathElement), path[1:]
-		default:
-			for i := 0; ; i++ {
-				if i == len(path) || path[i] == '.' || path[i] == '[' {
-					elements, path = append(elements, path[:i]), path[i:]
-					break
-				}
-			}
-		}
-	}
-	return elements, nil
-}
-
 // getProperty fetches the child property with the indicated key from the given property value. If the key does not
 // exist, it returns an empty `PropertyValue`.
 func getProperty(key interface{}, v resource.PropertyValue) resource.PropertyValue {
+	child, _ := lookupProperty(key, v)
+	return child
+}
+
+// lookupProperty fetches the child property with the indicated key from the given property value. The second result
+// distinguishes a child that is absent, in which case it is false, from a child that is present but explicitly null.
+func lookupProperty(key interface{}, v resource.PropertyValue) (resource.PropertyValue, bool) {
 	switch {
 	case v.IsArray():
 		index, ok := key.(int)
 		if !ok || index < 0 || index >= len(v.ArrayValue()) {
-			return resource.PropertyValue{}
+			return resource.PropertyValue{}, false
 		}
-		return v.ArrayValue()[index]
+		return v.ArrayValue()[index], true
 	case v.IsObject():
 		k, ok := key.(string)
 		if !ok {
-			return resource.PropertyValue{}
+			return resource.PropertyValue{}, false
+		}
+		child, has := v.ObjectValue()[resource.PropertyKey(k)]
+		return child, has
+	case v.IsArchive():
+		// Archives that are composed of other assets and archives may be indexed by member name.
+		k, ok := key.(string)
+		if !ok {
+			return resource.PropertyValue{}, false
+		}
+		assets, ok := v.ArchiveValue().GetAssets()
+		if !ok {
+			return resource.PropertyValue{}, false
+		}
+		switch member := assets[k].(type) {
+		case *resource.Asset:
+			return resource.NewAssetProperty(member), true
+		case *resource.Archive:
+			return resource.NewArchiveProperty(member), true
+		default:
+			return resource.PropertyValue{}, false
 		}
-		return v.ObjectValue()[resource.PropertyKey(k)]
 	case v.IsComputed() || v.IsOutput() || v.IsSecret():
 		// We consider the contents of these values opaque and return them as-is, as we cannot know whether or not the
 		// value will or does contain an element with the given key.
-		return v
+		return v, true
 	default:
-		return resource.PropertyValue{}
+		return resource.PropertyValue{}, false
+	}
+}
+
+// resolvesDiffPath returns true if the given path leads to a property in the given old value, the given new value, or
+// both. A path that names a property that is absent from both, or that passes through such a property, describes no
+// change that can be displayed, e.g. because the provider's detailed diff does not match the resource's schema.
+func resolvesDiffPath(path []interface{}, old, new resource.PropertyValue) bool {
+	for _, element := range path {
+		var hasOld, hasNew bool
+		old, hasOld = lookupProperty(element, old)
+		new, hasNew = lookupProperty(element, new)
+		if !hasOld && !hasNew {
+			return false
+		}
 	}
+	return true
 }
 
-// addDiff inserts a diff of the given kind at the given path into the parent ValueDiff.
+// isUnknown returns true if the given value's contents are not yet known.
+func isUnknown(v resource.PropertyValue) bool {
+	return v.IsComputed() || v.IsOutput()
+}
+
+// leafDiffKind returns the kind of diff to record for a leaf property given the kind reported by the provider.
+// Providers may report a property that is set to or from an explicit null as an Add or a Delete; such changes are
+// recorded as Updates so that they are displayed differently from properties that are added or removed outright.
+func leafDiffKind(kind plugin.DiffKind, old resource.PropertyValue, hasOld bool, new resource.PropertyValue,
+	hasNew bool) plugin.DiffKind {
+
+	switch kind {
+	case plugin.DiffAdd, plugin.DiffAddReplace:
+		if hasOld && old.IsNull() && !new.IsNull() {
+			return plugin.DiffUpdate
+		}
+	case plugin.DiffDelete, plugin.DiffDeleteReplace:
+		if hasNew && new.IsNull() && !old.IsNull() {
+			return plugin.DiffUpdate
+		}
+	}
+	return kind
+}
+
+// inferLeafDiffKind returns the kind of diff to record for a leaf property whose reported kind is unknown, derived from
+// whether the property exists in the old and new values: the property is added, deleted, or otherwise updated.
+func inferLeafDiffKind(hasOld, hasNew bool) plugin.DiffKind {
+	switch {
+	case !hasOld && hasNew:
+		return plugin.DiffAdd
+	case hasOld && !hasNew:
+		return plugin.DiffDelete
+	default:
+		return plugin.DiffUpdate
+	}
+}
+
+// isExplicitNullDiff returns true if one side of an update is an explicit null and the other is not null.
+func isExplicitNullDiff(old resource.PropertyValue, hasOld bool, new resource.PropertyValue, hasNew bool) bool {
+	return hasOld && old.IsNull() && !new.IsNull() || hasNew && new.IsNull() && !old.IsNull()
+}
+
+// addDiff inserts a diff of the given kind at the given path into the parent ValueDiff. Any updates that are recorded
+// are marked with the diff's source (i.e. whether the old value was drawn from the resource's inputs or outputs).
 //
 // If the path consists of a single element, a diff of the indicated kind is inserted directly. Otherwise, if the
 // property named by the first element of the path exists in both parents, we snip off the first element of the path
 // and recurse into the property itself. If the property does not exist in one parent or the other, the diff kind is
-// disregarded and the change is treated as either an Add or a Delete.
-func addDiff(path []interface{}, kind plugin.DiffKind, parent *resource.ValueDiff,
-	oldParent, newParent resource.PropertyValue) {
+// disregarded and the change is treated as either an Add or a Delete. A property that is explicitly null is distinct
+// from one that does not exist: if the property is null in one parent but not the other, the property as a whole is
+// recorded as an Update from or to null. Similarly, if the property is unknown in one parent but known in the other,
+// the property as a whole is recorded as an Update: the unknown side cannot be traversed, and the known side's
+// structure is preserved so that it can be shown in its entirety. Likewise, if the property is a secret in one parent
+// but not the other, the property as a whole is recorded as an Update, as the contents of secrets are opaque; such an
+// update is displayed as a change to whether the property is secret, with both sides masked.
+//
+// When the path passes through an element for which a diff has already been recorded, e.g. because the detailed diff
+// also contains an entry for that element, the recorded diff is replaced by the diff for the path, which is more
+// specific. Diffs that were recorded for the element's other descendants are kept.
+//
+// If the given kinds are non-nil, the kinds that the provider reported are trusted at every level: neither the kinds
+// of leaves nor those of the elements along the path that have entries of their own are inferred from the old and new
+// values, so that an element that the provider reports as added or deleted is recorded as such and one that it
+// reports as updated is recursed into if possible. The prefix is the path from the root to the parent, by which the
+// elements' entries are found. Elements without entries are inferred as usual.
+//
+// If matchCasing is true, a property that was renamed to a different casing convention, e.g. from max_size to maxSize,
+// is diffed as a single property whether the path names it by its old or its new name: its diff is recorded under its
+// new name, and its old name is recorded in the renames of the parent's diff. See matchKeyNames.
+func addDiff(prefix, path []interface{}, pdiff plugin.PropertyDiff, kinds trustedDiffKinds, matchCasing bool,
+	parent *resource.ValueDiff, oldParent, newParent resource.PropertyValue) {
 
 	contract.Require(len(path) > 0, "len(path) > 0")
 
 	element := path[0]
+	oldElement, newElement, renamed := element, element, false
+	if key, ok := element.(string); ok && matchCasing {
+		from, to := matchKeyName(resource.PropertyKey(key), oldParent, newParent)
+		oldElement, newElement, renamed = string(from), string(to), from != to
+	}
+
+	old, hasOld := lookupProperty(oldElement, oldParent)
+	new, hasNew := lookupProperty(newElement, newParent)
+
+	// For leaves, only the provider's kind is trusted. For other elements, the kind of the element's own entry is.
+	// Kinds that are unknown to this version of the CLI are inferred from the values; the engine warns about them.
+	leafKind := pdiff.Kind
+	if !leafKind.IsKnown() {
+		leafKind = inferLeafDiffKind(hasOld, hasNew)
+	}
+	var elementPath []interface{}
+	var kind plugin.DiffKind
+	var trusted bool
+	if kinds == nil {
+		leafKind = leafDiffKind(leafKind, old, hasOld, new, hasNew)
+	} else if len(path) > 1 {
+		elementPath = appendPath(prefix, element)
+		kind, trusted = kinds.lookup(elementPath)
+	}
+	trustedAdd := trusted && (kind == plugin.DiffAdd || kind == plugin.DiffAddReplace)
+	trustedDelete := trusted && (kind == plugin.DiffDelete || kind == plugin.DiffDeleteReplace)
 
-	old, new := getProperty(element, oldParent), getProperty(element, newParent)
+	// A renamed property exists on both sides, so it is updated even if the provider reports one of its names as added
+	// or deleted. The values of its update are recorded, so that later stages need not look them up by name.
+	if renamed {
+		if leafKind.IsReplace() {
+			leafKind = plugin.DiffUpdateReplace
+		} else {
+			leafKind = plugin.DiffUpdate
+		}
+		trustedAdd, trustedDelete = false, false
+	}
 
 	switch element := element.(type) {
 	case int:
@@ -137,25 +203,43 @@ func addDiff(path []interface{}, kind plugin.DiffKind, parent *resource.ValueDif
 		// For leaf diffs, the provider tells us exactly what to record. For other diffs, we will derive the
 		// difference from the old and new property values.
 		if len(path) == 1 {
-			switch kind {
+			switch leafKind {
 			case plugin.DiffAdd, plugin.DiffAddReplace:
 				parent.Array.Adds[element] = new
 			case plugin.DiffDelete, plugin.DiffDeleteReplace:
 				parent.Array.Deletes[element] = old
-			case plugin.DiffUpdate, plugin.DiffUpdateReplace:
-				parent.Array.Updates[element] = resource.ValueDiff{Old: old, New: new}
 			default:
-				contract.Failf("unexpected diff kind %v", kind)
+				parent.Array.Updates[element] = resource.ValueDiff{
+					Old:       old,
+					New:       new,
+					InputDiff: pdiff.InputDiff,
+					NullDiff:  isExplicitNullDiff(old, hasOld, new, hasNew),
+				}
 			}
 		} else {
+			// The diff of a descendant is more specific than any diff recorded for the element itself, so it replaces
+			// that diff. Only the nested diffs recorded for the element's other descendants are kept.
+			recorded := parent.Array.Updates[element]
+			ed := resource.ValueDiff{Array: recorded.Array, Object: recorded.Object}
+			delete(parent.Array.Adds, element)
+			delete(parent.Array.Deletes, element)
+			delete(parent.Array.Updates, element)
+
 			switch {
-			case old.IsNull() && !new.IsNull():
+			case trustedAdd, !trusted && !hasOld && hasNew:
 				parent.Array.Adds[element] = new
-			case !old.IsNull() && new.IsNull():
+			case trustedDelete, !trusted && hasOld && !hasNew:
 				parent.Array.Deletes[element] = old
+			case trusted && (!hasOld || !hasNew), old.IsNull() != new.IsNull(), isUnknown(old) != isUnknown(new),
+				old.IsSecret() != new.IsSecret():
+				parent.Array.Updates[element] = resource.ValueDiff{
+					Old:       old,
+					New:       new,
+					InputDiff: pdiff.InputDiff,
+					NullDiff:  isExplicitNullDiff(old, hasOld, new, hasNew),
+				}
 			default:
-				ed := parent.Array.Updates[element]
-				addDiff(path[1:], kind, &ed, old, new)
+				addDiff(elementPath, path[1:], pdiff, kinds, matchCasing, &ed, old, new)
 				parent.Array.Updates[element] = ed
 			}
 		}
@@ -169,27 +253,54 @@ func addDiff(path []interface{}, kind plugin.DiffKind, parent *resource.ValueDif
 			}
 		}
 
-		e := resource.PropertyKey(element)
+		e := resource.PropertyKey(newElement.(string))
+		if renamed {
+			if parent.Object.Renames == nil {
+				parent.Object.Renames = make(map[resource.PropertyKey]resource.PropertyKey)
+			}
+			parent.Object.Renames[e] = resource.PropertyKey(oldElement.(string))
+		}
 		if len(path) == 1 {
-			switch kind {
+			switch leafKind {
 			case plugin.DiffAdd, plugin.DiffAddReplace:
 				parent.Object.Adds[e] = new
 			case plugin.DiffDelete, plugin.DiffDeleteReplace:
 				parent.Object.Deletes[e] = old
-			case plugin.DiffUpdate, plugin.DiffUpdateReplace:
-				parent.Object.Updates[e] = resource.ValueDiff{Old: old, New: new}
 			default:
-				contract.Failf("unexpected diff kind %v", kind)
+				parent.Object.Updates[e] = resource.ValueDiff{
+					Old:       old,
+					New:       new,
+					InputDiff: pdiff.InputDiff,
+					NullDiff:  isExplicitNullDiff(old, hasOld, new, hasNew),
+				}
 			}
 		} else {
+			// The diff of a descendant is more specific than any diff recorded for the element itself, so it replaces
+			// that diff. Only the nested diffs recorded for the element's other descendants are kept.
+			recorded := parent.Object.Updates[e]
+			ed := resource.ValueDiff{Array: recorded.Array, Object: recorded.Object}
+			delete(parent.Object.Adds, e)
+			delete(parent.Object.Deletes, e)
+			delete(parent.Object.Updates, e)
+
 			switch {
-			case old.IsNull() && !new.IsNull():
+			case trustedAdd, !trusted && !hasOld && hasNew:
 				parent.Object.Adds[e] = new
-			case !old.IsNull() && new.IsNull():
+			case trustedDelete, !trusted && hasOld && !hasNew:
 				parent.Object.Deletes[e] = old
+			case trusted && (!hasOld || !hasNew), old.IsNull() != new.IsNull(), isUnknown(old) != isUnknown(new),
+				old.IsSecret() != new.IsSecret():
+				parent.Object.Updates[e] = resource.ValueDiff{
+					Old:       old,
+					New:       new,
+					InputDiff: pdiff.InputDiff,
+					NullDiff:  isExplicitNullDiff(old, hasOld, new, hasNew),
+				}
 			default:
-				ed := parent.Object.Updates[e]
-				addDiff(path[1:], kind, &ed, old, new)
+				addDiff(elementPath, path[1:], pdiff, kinds, matchCasing, &ed, old, new)
+				if renamed {
+					ed.Old, ed.New = old, new
+				}
 				parent.Object.Updates[e] = ed
 			}
 		}
@@ -198,26 +309,116 @@ func addDiff(path []interface{}, kind plugin.DiffKind, parent *resource.ValueDif
 	}
 }
 
+// trustedDiffKinds maps the paths of the entries of a detailed diff, as formatted by engine.FormatPropertyPath, to the
+// entries. It is used to trust the kinds that providers report for the properties along the paths of other entries.
+type trustedDiffKinds map[string]plugin.PropertyDiff
+
+// lookup returns the kind of the entry for the given path and true if the detailed diff has an entry for the path.
+// Entries of kinds that are unknown to this version of the CLI cannot be trusted, so they are treated as missing.
+func (kinds trustedDiffKinds) lookup(path []interface{}) (plugin.DiffKind, bool) {
+	pdiff, has := kinds[engine.FormatPropertyPath(path)]
+	return pdiff.Kind, has && pdiff.Kind.IsKnown()
+}
+
 // translateDetailedDiff converts the detailed diff stored in the step event into an ObjectDiff that is appropriate
-// for display.
-func translateDetailedDiff(step engine.StepEventMetadata) *resource.ObjectDiff {
+// for display. If the step's provider has been upgraded, changes that likely stem from the upgrade are marked as
+// such. If the resulting diff contains no actual changes, translateDetailedDiff returns nil.
+//
+// If trustKinds is true, the kinds that the provider reports are trusted at every level of the detailed diff rather
+// than inferred from the old and new values, for providers whose detailed diffs include entries for the properties
+// that contain other changed properties. If matchCasing is true, properties that were renamed to a different casing
+// convention are diffed as single properties. See addDiff.
+func translateDetailedDiff(step engine.StepEventMetadata, trustKinds, matchCasing bool) *resource.ObjectDiff {
 	contract.Assert(step.DetailedDiff != nil)
 
+	// A step without old state, e.g. a create, has nothing to update or delete, so each entry in its detailed diff is
+	// translated as an add. Whether the entry forces a replacement is preserved.
+	if step.Old == nil {
+		adds := make(map[string]plugin.PropertyDiff, len(step.DetailedDiff))
+		for path, pdiff := range step.DetailedDiff {
+			if pdiff.Kind.IsReplace() {
+				pdiff.Kind = plugin.DiffAddReplace
+			} else {
+				pdiff.Kind = plugin.DiffAdd
+			}
+			adds[path] = pdiff
+		}
+		return diffPropertyMaps(nil, nil, step.New.Inputs, adds, trustKinds, false)
+	}
+
+	diff := diffPropertyMaps(step.Old.Outputs, step.Old.Inputs, step.New.Inputs, step.DetailedDiff, trustKinds,
+		matchCasing)
+	if diff != nil && isProviderUpgrade(step) {
+		markSchemaDiffs(step, diff)
+	}
+	return diff
+}
+
+// DiffPropertyMaps converts the given detailed diff between two property maps into an ObjectDiff that is appropriate
+// for display. Old values are taken from old and new values from new. If the resulting diff contains no actual
+// changes, DiffPropertyMaps returns nil.
+func DiffPropertyMaps(old, new resource.PropertyMap,
+	detailedDiff map[string]plugin.PropertyDiff) *resource.ObjectDiff {
+
+	return diffPropertyMaps(old, old, new, detailedDiff, false, false)
+}
+
+func diffPropertyMaps(oldOutputs, oldInputs, newInputs resource.PropertyMap,
+	detailedDiff map[string]plugin.PropertyDiff, trustKinds, matchCasing bool) *resource.ObjectDiff {
+
 	// The rich diff is presented as a list of simple JS property paths and corresponding diffs. We translate this to
 	// an ObjectDiff by iterating the list and inserting ValueDiffs that reflect the changes in the detailed diff. Old
-	// values are always taken from a step's Outputs; new values are always taken from its Inputs.
-
-	var diff resource.ValueDiff
-	for path, pdiff := range step.DetailedDiff {
-		elements, err := parseDiffPath(path)
+	// values are taken from the old outputs unless the provider reports an input diff, in which case they are taken
+	// from the old inputs; new values are always taken from the new inputs.
+	//
+	// A detailed diff may contain entries for both a property and its descendants, e.g. an update of spec as well as
+	// an update of spec.replicas. The entries are inserted in order of depth, so that the diffs of descendants refine
+	// those of their ancestors regardless of the order in which the entries are enumerated. Paths of equal depth are
+	// inserted in lexical order to keep the result deterministic.
+	type entry struct {
+		path     string
+		elements []interface{}
+	}
+	var kinds trustedDiffKinds
+	if trustKinds {
+		kinds = make(trustedDiffKinds, len(detailedDiff))
+	}
+	entries := make([]entry, 0, len(detailedDiff))
+	for path := range detailedDiff {
+		elements, err := parsedDiffPaths.parse(path)
 		contract.Assert(err == nil)
+		entries = append(entries, entry{path: path, elements: elements})
+		if kinds != nil {
+			kinds[engine.FormatPropertyPath(elements)] = detailedDiff[path]
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if len(entries[i].elements) != len(entries[j].elements) {
+			return len(entries[i].elements) < len(entries[j].elements)
+		}
+		return entries[i].path < entries[j].path
+	})
 
-		olds := resource.NewObjectProperty(step.Old.Outputs)
+	var diff resource.ValueDiff
+	for _, entry := range entries {
+		pdiff := detailedDiff[entry.path]
+		olds := resource.NewObjectProperty(oldOutputs)
 		if pdiff.InputDiff {
-			olds = resource.NewObjectProperty(step.Old.Inputs)
+			olds = resource.NewObjectProperty(oldInputs)
 		}
-		addDiff(elements, pdiff.Kind, &diff, olds, resource.NewObjectProperty(step.New.Inputs))
+		news := resource.NewObjectProperty(newInputs)
+
+		// Entries whose paths exist on neither side would be displayed as meaningless updates, so they are skipped.
+		if !resolvesDiffPath(entry.elements, olds, news) {
+			logging.V(7).Infof("skipping detailed diff entry %q: the path exists in neither the old nor the new "+
+				"properties", entry.path)
+			continue
+		}
+		addDiff(nil, entry.elements, pdiff, kinds, matchCasing, &diff, olds, news)
 	}
 
+	if !diff.Object.AnyChanges() {
+		return nil
+	}
 	return diff.Object
 }
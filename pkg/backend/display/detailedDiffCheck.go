@@ -0,0 +1,112 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+// checkDetailedDiff returns a description of each entry in the given step's detailed diff whose kind conflicts with
+// the kind that addDiff infers for it. When an ancestor of a non-leaf path is missing from the old or new properties,
+// addDiff disregards the provider's diff kind and records the ancestor as an add or a delete. If the provider reported
+// a different kind of change, the displayed diff does not reflect what the provider intended, which usually indicates
+// a bug in the provider's detailed diff.
+func checkDetailedDiff(step engine.StepEventMetadata) []string {
+	if step.DetailedDiff == nil || step.Old == nil || step.New == nil {
+		return nil
+	}
+
+	var mismatches []string
+	for path, pdiff := range step.DetailedDiff {
+		// Kinds that are unknown to this version of the CLI are warned about by the engine.
+		elements, err := parsedDiffPaths.parse(path)
+		if err != nil || !pdiff.Kind.IsKnown() {
+			continue
+		}
+
+		olds := resource.NewObjectProperty(step.Old.Outputs)
+		if pdiff.InputDiff {
+			olds = resource.NewObjectProperty(step.Old.Inputs)
+		}
+		ancestor, inferred, ok := inferDiffKind(elements, olds, resource.NewObjectProperty(step.New.Inputs))
+		if !ok || isCompatibleDiffKind(inferred, pdiff.Kind) {
+			continue
+		}
+
+		inferredText := "added"
+		if inferred == plugin.DiffDelete {
+			inferredText = "deleted"
+		}
+		mismatches = append(mismatches, fmt.Sprintf("%s: the provider reported %q, but %s was inferred to be %s",
+			engine.FormatPropertyPath(elements), pdiff.Kind, engine.FormatPropertyPath(ancestor), inferredText))
+	}
+	sort.Strings(mismatches)
+	return mismatches
+}
+
+// inferDiffKind follows the given non-leaf path through the given old and new values in the same way as addDiff. If
+// addDiff would record one of the path's ancestors as an add or a delete regardless of the provider's diff kind,
+// inferDiffKind returns the path to that ancestor, the inferred kind, and true.
+func inferDiffKind(path []interface{}, old, new resource.PropertyValue) ([]interface{}, plugin.DiffKind, bool) {
+	for i := 0; i < len(path)-1; i++ {
+		var hasOld, hasNew bool
+		old, hasOld = lookupProperty(path[i], old)
+		new, hasNew = lookupProperty(path[i], new)
+		switch {
+		case !hasOld && hasNew:
+			return path[:i+1], plugin.DiffAdd, true
+		case hasOld && !hasNew:
+			return path[:i+1], plugin.DiffDelete, true
+		case old.IsNull() != new.IsNull(), isUnknown(old) != isUnknown(new):
+			return nil, 0, false
+		}
+	}
+	return nil, 0, false
+}
+
+// isCompatibleDiffKind returns true if a provider-reported diff of the given kind agrees with the inferred kind,
+// irrespective of whether the reported diff requires a replacement.
+func isCompatibleDiffKind(inferred, reported plugin.DiffKind) bool {
+	switch reported {
+	case plugin.DiffAdd, plugin.DiffAddReplace:
+		return inferred == plugin.DiffAdd
+	case plugin.DiffDelete, plugin.DiffDeleteReplace:
+		return inferred == plugin.DiffDelete
+	default:
+		return false
+	}
+}
+
+// renderDetailedDiffMismatches renders a diagnostic for each entry in the given step's detailed diff whose kind
+// conflicts with the kind that is displayed for it. Nothing is rendered unless strict detailed diffs are enabled.
+func renderDetailedDiffMismatches(step engine.StepEventMetadata, indent int, opts Options) string {
+	if !opts.StrictDetailedDiff {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	for _, mismatch := range checkDetailedDiff(step) {
+		fprintfIgnoreError(&buf, "%s%sdetailed diff mismatch:%s %s\n",
+			engine.GetIndentationString(indent+1), colors.SpecWarning, colors.Reset, mismatch)
+	}
+	return buf.String()
+}
@@ -5,84 +5,84 @@ import (
 
 	"github.com/pulumi/pulumi/pkg/engine"
 	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/contract"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestParseDiffPath(t *testing.T) {
-	cases := []struct {
-		path     string
-		elements []interface{}
-	}{
-		{
-			"root",
-			[]interface{}{"root"},
-		},
-		{
-			"root.nested",
-			[]interface{}{"root", "nested"},
-		},
-		{
-			`root["nested"]`,
-			[]interface{}{"root", "nested"},
-		},
-		{
-			"root.double.nest",
-			[]interface{}{"root", "double", "nest"},
-		},
-		{
-			`root["double"].nest`,
-			[]interface{}{"root", "double", "nest"},
-		},
-		{
-			`root["double"]["nest"]`,
-			[]interface{}{"root", "double", "nest"},
-		},
-		{
-			"root.array[0]",
-			[]interface{}{"root", "array", 0},
-		},
-		{
-			"root.array[100]",
-			[]interface{}{"root", "array", 100},
-		},
-		{
-			"root.array[0].nested",
-			[]interface{}{"root", "array", 0, "nested"},
-		},
-		{
-			"root.array[0][1].nested",
-			[]interface{}{"root", "array", 0, 1, "nested"},
-		},
-		{
-			"root.nested.array[0].double[1]",
-			[]interface{}{"root", "nested", "array", 0, "double", 1},
-		},
-		{
-			`root["key with \"escaped\" quotes"]`,
-			[]interface{}{"root", `key with "escaped" quotes`},
-		},
-		{
-			`root["key with a ."]`,
-			[]interface{}{"root", "key with a ."},
-		},
-		{
-			`["root key with \"escaped\" quotes"].nested`,
-			[]interface{}{`root key with "escaped" quotes`, "nested"},
-		},
-		{
-			`["root key with a ."][100]`,
-			[]interface{}{"root key with a .", 100},
-		},
-	}
+func TestDiffPathCache(t *testing.T) {
+	cache := newDiffPathCache(2)
 
-	for _, c := range cases {
-		elements, err := parseDiffPath(c.path)
-		assert.NoError(t, err)
-		assert.Equal(t, c.elements, elements)
+	elements, err := cache.parse(`tags["Name"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"tags", "Name"}, elements)
+
+	// Appending to a cached path must not affect the cache.
+	_ = append(elements, "extra")
+	elements, err = cache.parse(`tags["Name"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"tags", "Name"}, elements)
+
+	// Malformed paths are reported but not cached.
+	_, err = cache.parse(`tags["unterminated`)
+	assert.Error(t, err)
+	assert.Equal(t, 1, cache.lru.Len())
+
+	// Once the cache is full, the least recently used path is evicted.
+	_, err = cache.parse("spec.replicas")
+	assert.NoError(t, err)
+	_, err = cache.parse(`tags["Name"]`)
+	assert.NoError(t, err)
+	_, err = cache.parse("spec.ports[0]")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cache.lru.Len())
+	assert.Contains(t, cache.entries, `tags["Name"]`)
+	assert.Contains(t, cache.entries, "spec.ports[0]")
+	assert.NotContains(t, cache.entries, "spec.replicas")
+}
+
+// diffPaths returns a set of property paths typical of the detailed diffs reported by providers.
+func diffPaths() []string {
+	return []string{
+		`tags["Name"]`,
+		`tags["Environment"]`,
+		`tags["kubernetes.io/cluster/prod"]`,
+		"metadata.labels.app",
+		`metadata.annotations["kubectl.kubernetes.io/last-applied-configuration"]`,
+		"spec.replicas",
+		"spec.template.spec.containers[0].image",
+		"spec.template.spec.containers[0].env[3].value",
+		"spec.template.spec.containers[1].ports[0].containerPort",
+		"ingress[2].cidrBlocks[0]",
+		"rootBlockDevice.volumeSize",
+		"environment.variables.LOG_LEVEL",
 	}
 }
 
+func BenchmarkParseDiffPath(b *testing.B) {
+	paths := diffPaths()
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, path := range paths {
+				_, err := engine.ParsePropertyPath(path)
+				contract.IgnoreError(err)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		cache := newDiffPathCache(maxCachedDiffPaths)
+		for i := 0; i < b.N; i++ {
+			for _, path := range paths {
+				_, err := cache.parse(path)
+				contract.IgnoreError(err)
+			}
+		}
+	})
+}
+
 func TestTranslateDetailedDiff(t *testing.T) {
 	var (
 		A = plugin.PropertyDiff{Kind: plugin.DiffAdd}
@@ -246,6 +246,35 @@ func TestTranslateDetailedDiff(t *testing.T) {
 				Updates: map[resource.PropertyKey]resource.ValueDiff{},
 			},
 		},
+		{
+			state: map[string]interface{}{
+				"foo": 24,
+			},
+			oldInputs: map[string]interface{}{
+				"foo": 42,
+			},
+			inputs: map[string]interface{}{
+				"foo": 24,
+			},
+			detailedDiff: map[string]plugin.PropertyDiff{
+				"foo": {
+					Kind:      plugin.DiffUpdate,
+					InputDiff: true,
+				},
+			},
+			expected: &resource.ObjectDiff{
+				Adds:    resource.PropertyMap{},
+				Deletes: resource.PropertyMap{},
+				Sames:   resource.PropertyMap{},
+				Updates: map[resource.PropertyKey]resource.ValueDiff{
+					"foo": {
+						Old:       resource.NewNumberProperty(42),
+						New:       resource.NewNumberProperty(24),
+						InputDiff: true,
+					},
+				},
+			},
+		},
 		{
 			state: map[string]interface{}{
 				"foo": []interface{}{
@@ -365,26 +394,8 @@ func TestTranslateDetailedDiff(t *testing.T) {
 			detailedDiff: map[string]plugin.PropertyDiff{
 				"foo[100]": U,
 			},
-			expected: &resource.ObjectDiff{
-				Adds:    resource.PropertyMap{},
-				Deletes: resource.PropertyMap{},
-				Sames:   resource.PropertyMap{},
-				Updates: map[resource.PropertyKey]resource.ValueDiff{
-					"foo": {
-						Array: &resource.ArrayDiff{
-							Adds:    map[int]resource.PropertyValue{},
-							Deletes: map[int]resource.PropertyValue{},
-							Sames:   map[int]resource.PropertyValue{},
-							Updates: map[int]resource.ValueDiff{
-								100: {
-									Old: resource.PropertyValue{},
-									New: resource.PropertyValue{},
-								},
-							},
-						},
-					},
-				},
-			},
+			// Paths that exist on neither side are skipped.
+			expected: nil,
 		},
 		{
 			state: map[string]interface{}{
@@ -402,35 +413,8 @@ func TestTranslateDetailedDiff(t *testing.T) {
 			detailedDiff: map[string]plugin.PropertyDiff{
 				"foo[100][200]": U,
 			},
-			expected: &resource.ObjectDiff{
-				Adds:    resource.PropertyMap{},
-				Deletes: resource.PropertyMap{},
-				Sames:   resource.PropertyMap{},
-				Updates: map[resource.PropertyKey]resource.ValueDiff{
-					"foo": {
-						Array: &resource.ArrayDiff{
-							Adds:    map[int]resource.PropertyValue{},
-							Deletes: map[int]resource.PropertyValue{},
-							Sames:   map[int]resource.PropertyValue{},
-							Updates: map[int]resource.ValueDiff{
-								100: {
-									Array: &resource.ArrayDiff{
-										Adds:    map[int]resource.PropertyValue{},
-										Deletes: map[int]resource.PropertyValue{},
-										Sames:   map[int]resource.PropertyValue{},
-										Updates: map[int]resource.ValueDiff{
-											200: {
-												Old: resource.PropertyValue{},
-												New: resource.PropertyValue{},
-											},
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
+			// Paths that exist on neither side are skipped.
+			expected: nil,
 		},
 		{
 			state: map[string]interface{}{
@@ -585,26 +569,8 @@ func TestTranslateDetailedDiff(t *testing.T) {
 			detailedDiff: map[string]plugin.PropertyDiff{
 				"foo.missing": U,
 			},
-			expected: &resource.ObjectDiff{
-				Adds:    resource.PropertyMap{},
-				Deletes: resource.PropertyMap{},
-				Sames:   resource.PropertyMap{},
-				Updates: map[resource.PropertyKey]resource.ValueDiff{
-					"foo": {
-						Object: &resource.ObjectDiff{
-							Adds:    resource.PropertyMap{},
-							Deletes: resource.PropertyMap{},
-							Sames:   resource.PropertyMap{},
-							Updates: map[resource.PropertyKey]resource.ValueDiff{
-								"missing": {
-									Old: resource.PropertyValue{},
-									New: resource.PropertyValue{},
-								},
-							},
-						},
-					},
-				},
-			},
+			// Paths that exist on neither side are skipped.
+			expected: nil,
 		},
 		{
 			state: map[string]interface{}{
@@ -622,35 +588,8 @@ func TestTranslateDetailedDiff(t *testing.T) {
 			detailedDiff: map[string]plugin.PropertyDiff{
 				"foo.nested.missing": U,
 			},
-			expected: &resource.ObjectDiff{
-				Adds:    resource.PropertyMap{},
-				Deletes: resource.PropertyMap{},
-				Sames:   resource.PropertyMap{},
-				Updates: map[resource.PropertyKey]resource.ValueDiff{
-					"foo": {
-						Object: &resource.ObjectDiff{
-							Adds:    resource.PropertyMap{},
-							Deletes: resource.PropertyMap{},
-							Sames:   resource.PropertyMap{},
-							Updates: map[resource.PropertyKey]resource.ValueDiff{
-								"nested": {
-									Object: &resource.ObjectDiff{
-										Adds:    resource.PropertyMap{},
-										Deletes: resource.PropertyMap{},
-										Sames:   resource.PropertyMap{},
-										Updates: map[resource.PropertyKey]resource.ValueDiff{
-											"missing": {
-												Old: resource.PropertyValue{},
-												New: resource.PropertyValue{},
-											},
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
+			// Paths that exist on neither side are skipped.
+			expected: nil,
 		},
 		{
 			state: map[string]interface{}{
@@ -696,7 +635,673 @@ func TestTranslateDetailedDiff(t *testing.T) {
 			Old:          &engine.StepEventStateMetadata{Inputs: oldInputs, Outputs: state},
 			New:          &engine.StepEventStateMetadata{Inputs: inputs},
 			DetailedDiff: c.detailedDiff,
-		})
+		}, false, false)
 		assert.Equal(t, c.expected, diff)
 	}
 }
+
+func TestTranslateDetailedDiffNoChanges(t *testing.T) {
+	state := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"foo": 42,
+	})
+	diff := translateDetailedDiff(engine.StepEventMetadata{
+		Old:          &engine.StepEventStateMetadata{Inputs: state, Outputs: state},
+		New:          &engine.StepEventStateMetadata{Inputs: state},
+		DetailedDiff: map[string]plugin.PropertyDiff{},
+	}, false, false)
+	assert.Nil(t, diff)
+}
+
+func TestTranslateDetailedDiffUnresolvedPaths(t *testing.T) {
+	state := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"foo":  map[string]interface{}{"bar": "baz"},
+		"tags": []interface{}{"a"},
+	})
+	inputs := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"foo":  map[string]interface{}{"bar": "qux"},
+		"tags": []interface{}{"a"},
+	})
+
+	// Entries whose paths exist on neither side are skipped, while the entries that resolve are translated as usual.
+	diff := translateDetailedDiff(engine.StepEventMetadata{
+		Old: &engine.StepEventStateMetadata{Inputs: state, Outputs: state},
+		New: &engine.StepEventStateMetadata{Inputs: inputs},
+		DetailedDiff: map[string]plugin.PropertyDiff{
+			"foo.bar":         {Kind: plugin.DiffUpdate},
+			"foo.missing":     {Kind: plugin.DiffDelete},
+			"missing.nested":  {Kind: plugin.DiffAdd},
+			"tags[3]":         {Kind: plugin.DiffUpdateReplace},
+			"tags[0].missing": {Kind: plugin.DiffUpdate},
+		},
+	}, false, false)
+	assert.Equal(t, &resource.ObjectDiff{
+		Adds:    resource.PropertyMap{},
+		Deletes: resource.PropertyMap{},
+		Sames:   resource.PropertyMap{},
+		Updates: map[resource.PropertyKey]resource.ValueDiff{
+			"foo": {
+				Object: &resource.ObjectDiff{
+					Adds:    resource.PropertyMap{},
+					Deletes: resource.PropertyMap{},
+					Sames:   resource.PropertyMap{},
+					Updates: map[resource.PropertyKey]resource.ValueDiff{
+						"bar": {Old: resource.NewStringProperty("baz"), New: resource.NewStringProperty("qux")},
+					},
+				},
+			},
+		},
+	}, diff)
+
+	// A diff none of whose entries resolve has no changes.
+	diff = translateDetailedDiff(engine.StepEventMetadata{
+		Old:          &engine.StepEventStateMetadata{Inputs: state, Outputs: state},
+		New:          &engine.StepEventStateMetadata{Inputs: inputs},
+		DetailedDiff: map[string]plugin.PropertyDiff{"missing": {Kind: plugin.DiffUpdate}},
+	}, false, false)
+	assert.Nil(t, diff)
+
+	// Paths that pass through unknown values may exist once the values are known, so they are kept.
+	unknown := resource.PropertyMap{"foo": resource.MakeComputed(resource.NewStringProperty(""))}
+	diff = translateDetailedDiff(engine.StepEventMetadata{
+		Old:          &engine.StepEventStateMetadata{Inputs: state, Outputs: state},
+		New:          &engine.StepEventStateMetadata{Inputs: unknown},
+		DetailedDiff: map[string]plugin.PropertyDiff{"foo.missing": {Kind: plugin.DiffUpdate}},
+	}, false, false)
+	assert.True(t, diff.Updated("foo"))
+}
+
+func TestDiffPropertyMaps(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"foo":  42,
+		"bar":  map[string]interface{}{"baz": "qux", "zed": "old"},
+		"tags": []interface{}{"a", "b"},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"bar":  map[string]interface{}{"baz": "qux", "zed": "new"},
+		"tags": []interface{}{"a", "b", "c"},
+		"name": "web",
+	})
+
+	diff := DiffPropertyMaps(olds, news, map[string]plugin.PropertyDiff{
+		"foo":     {Kind: plugin.DiffDelete},
+		"bar.zed": {Kind: plugin.DiffUpdate},
+		"tags[2]": {Kind: plugin.DiffAdd},
+		"name":    {Kind: plugin.DiffAddReplace},
+	})
+
+	expected := &resource.ObjectDiff{
+		Adds: resource.PropertyMap{
+			"name": resource.NewStringProperty("web"),
+		},
+		Deletes: resource.PropertyMap{
+			"foo": resource.NewNumberProperty(42),
+		},
+		Sames: resource.PropertyMap{},
+		Updates: map[resource.PropertyKey]resource.ValueDiff{
+			"bar": {
+				Object: &resource.ObjectDiff{
+					Adds:    resource.PropertyMap{},
+					Deletes: resource.PropertyMap{},
+					Sames:   resource.PropertyMap{},
+					Updates: map[resource.PropertyKey]resource.ValueDiff{
+						"zed": {
+							Old: resource.NewStringProperty("old"),
+							New: resource.NewStringProperty("new"),
+						},
+					},
+				},
+			},
+			"tags": {
+				Array: &resource.ArrayDiff{
+					Adds: map[int]resource.PropertyValue{
+						2: resource.NewStringProperty("c"),
+					},
+					Deletes: map[int]resource.PropertyValue{},
+					Sames:   map[int]resource.PropertyValue{},
+					Updates: map[int]resource.ValueDiff{},
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, diff)
+
+	// An empty detailed diff produces no diff, even if the maps differ.
+	assert.Nil(t, DiffPropertyMaps(olds, news, map[string]plugin.PropertyDiff{}))
+	assert.Nil(t, DiffPropertyMaps(nil, nil, nil))
+}
+
+func TestTranslateDetailedDiffArchives(t *testing.T) {
+	textAsset := func(text string) *resource.Asset {
+		a, err := resource.NewTextAsset(text)
+		assert.NoError(t, err)
+		return a
+	}
+	assetArchive := func(assets map[string]interface{}) *resource.Archive {
+		a, err := resource.NewAssetArchive(assets)
+		assert.NoError(t, err)
+		return a
+	}
+
+	oldIndex, newIndex, lib := textAsset("old index"), textAsset("new index"), textAsset("lib")
+	oldScript, newScript := textAsset("echo old"), textAsset("echo new")
+
+	olds := resource.PropertyMap{
+		"code":   resource.NewArchiveProperty(assetArchive(map[string]interface{}{"index.js": oldIndex, "lib.js": lib})),
+		"script": resource.NewAssetProperty(oldScript),
+	}
+	news := resource.PropertyMap{
+		"code":   resource.NewArchiveProperty(assetArchive(map[string]interface{}{"index.js": newIndex, "lib.js": lib})),
+		"script": resource.NewAssetProperty(newScript),
+	}
+
+	diff := translateDetailedDiff(engine.StepEventMetadata{
+		Old: &engine.StepEventStateMetadata{Inputs: olds, Outputs: olds},
+		New: &engine.StepEventStateMetadata{Inputs: news},
+		DetailedDiff: map[string]plugin.PropertyDiff{
+			`code["index.js"]`: {Kind: plugin.DiffUpdate},
+			"script":           {Kind: plugin.DiffUpdate},
+		},
+	}, false, false)
+
+	expected := &resource.ObjectDiff{
+		Adds:    resource.PropertyMap{},
+		Deletes: resource.PropertyMap{},
+		Sames:   resource.PropertyMap{},
+		Updates: map[resource.PropertyKey]resource.ValueDiff{
+			"code": {
+				Object: &resource.ObjectDiff{
+					Adds:    resource.PropertyMap{},
+					Deletes: resource.PropertyMap{},
+					Sames:   resource.PropertyMap{},
+					Updates: map[resource.PropertyKey]resource.ValueDiff{
+						"index.js": {
+							Old: resource.NewAssetProperty(oldIndex),
+							New: resource.NewAssetProperty(newIndex),
+						},
+					},
+				},
+			},
+			"script": {
+				Old: resource.NewAssetProperty(oldScript),
+				New: resource.NewAssetProperty(newScript),
+			},
+		},
+	}
+	assert.Equal(t, expected, diff)
+}
+
+func TestTranslateDetailedDiffComputed(t *testing.T) {
+	computed := resource.MakeComputed(resource.NewStringProperty(""))
+	known := resource.NewObjectProperty(resource.NewPropertyMapFromMap(map[string]interface{}{
+		"bar": map[string]interface{}{
+			"baz": 42,
+		},
+		"qux": []interface{}{"a", "b"},
+	}))
+
+	cases := []struct {
+		olds         resource.PropertyMap
+		news         resource.PropertyMap
+		detailedDiff map[string]plugin.PropertyDiff
+		expected     map[resource.PropertyKey]resource.ValueDiff
+	}{
+		{
+			// Known to computed, one level below the unknown value.
+			olds:         resource.PropertyMap{"foo": known},
+			news:         resource.PropertyMap{"foo": computed},
+			detailedDiff: map[string]plugin.PropertyDiff{"foo.bar": {Kind: plugin.DiffUpdate}},
+			expected: map[resource.PropertyKey]resource.ValueDiff{
+				"foo": {Old: known, New: computed},
+			},
+		},
+		{
+			// Known to computed, several levels below the unknown value.
+			olds:         resource.PropertyMap{"foo": known},
+			news:         resource.PropertyMap{"foo": computed},
+			detailedDiff: map[string]plugin.PropertyDiff{"foo.qux[1]": {Kind: plugin.DiffUpdate}},
+			expected: map[resource.PropertyKey]resource.ValueDiff{
+				"foo": {Old: known, New: computed},
+			},
+		},
+		{
+			// Computed to known.
+			olds:         resource.PropertyMap{"foo": computed},
+			news:         resource.PropertyMap{"foo": known},
+			detailedDiff: map[string]plugin.PropertyDiff{"foo.bar.baz": {Kind: plugin.DiffUpdate}},
+			expected: map[resource.PropertyKey]resource.ValueDiff{
+				"foo": {Old: computed, New: known},
+			},
+		},
+		{
+			// Known to computed within an array element.
+			olds:         resource.PropertyMap{"arr": resource.NewArrayProperty([]resource.PropertyValue{known})},
+			news:         resource.PropertyMap{"arr": resource.NewArrayProperty([]resource.PropertyValue{computed})},
+			detailedDiff: map[string]plugin.PropertyDiff{"arr[0].bar.baz": {Kind: plugin.DiffUpdate}},
+			expected: map[resource.PropertyKey]resource.ValueDiff{
+				"arr": {
+					Array: &resource.ArrayDiff{
+						Adds:    map[int]resource.PropertyValue{},
+						Deletes: map[int]resource.PropertyValue{},
+						Sames:   map[int]resource.PropertyValue{},
+						Updates: map[int]resource.ValueDiff{
+							0: {Old: known, New: computed},
+						},
+					},
+				},
+			},
+		},
+		{
+			// Both sides computed: the path is traversed as usual.
+			olds:         resource.PropertyMap{"foo": computed},
+			news:         resource.PropertyMap{"foo": computed},
+			detailedDiff: map[string]plugin.PropertyDiff{"foo.bar": {Kind: plugin.DiffUpdate}},
+			expected: map[resource.PropertyKey]resource.ValueDiff{
+				"foo": {
+					Object: &resource.ObjectDiff{
+						Adds:    resource.PropertyMap{},
+						Deletes: resource.PropertyMap{},
+						Sames:   resource.PropertyMap{},
+						Updates: map[resource.PropertyKey]resource.ValueDiff{
+							"bar": {Old: computed, New: computed},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		diff := translateDetailedDiff(engine.StepEventMetadata{
+			Old:          &engine.StepEventStateMetadata{Inputs: c.olds, Outputs: c.olds},
+			New:          &engine.StepEventStateMetadata{Inputs: c.news},
+			DetailedDiff: c.detailedDiff,
+		}, false, false)
+		assert.Equal(t, &resource.ObjectDiff{
+			Adds:    resource.PropertyMap{},
+			Deletes: resource.PropertyMap{},
+			Sames:   resource.PropertyMap{},
+			Updates: c.expected,
+		}, diff)
+	}
+}
+
+func TestTranslateDetailedDiffNulls(t *testing.T) {
+	null := resource.NewNullProperty()
+	known := resource.NewObjectProperty(resource.NewPropertyMapFromMap(map[string]interface{}{
+		"bar": 42,
+	}))
+
+	cases := []struct {
+		olds         resource.PropertyMap
+		news         resource.PropertyMap
+		detailedDiff map[string]plugin.PropertyDiff
+		expected     *resource.ObjectDiff
+	}{
+		{
+			// Absent to null.
+			olds:         resource.PropertyMap{},
+			news:         resource.PropertyMap{"foo": null},
+			detailedDiff: map[string]plugin.PropertyDiff{"foo": {Kind: plugin.DiffAdd}},
+			expected: &resource.ObjectDiff{
+				Adds:    resource.PropertyMap{"foo": null},
+				Deletes: resource.PropertyMap{},
+				Sames:   resource.PropertyMap{},
+				Updates: map[resource.PropertyKey]resource.ValueDiff{},
+			},
+		},
+		{
+			// Null to absent.
+			olds:         resource.PropertyMap{"foo": null},
+			news:         resource.PropertyMap{},
+			detailedDiff: map[string]plugin.PropertyDiff{"foo": {Kind: plugin.DiffDelete}},
+			expected: &resource.ObjectDiff{
+				Adds:    resource.PropertyMap{},
+				Deletes: resource.PropertyMap{"foo": null},
+				Sames:   resource.PropertyMap{},
+				Updates: map[resource.PropertyKey]resource.ValueDiff{},
+			},
+		},
+		{
+			// Null to a value, reported as an add.
+			olds:         resource.PropertyMap{"foo": null},
+			news:         resource.PropertyMap{"foo": known},
+			detailedDiff: map[string]plugin.PropertyDiff{"foo": {Kind: plugin.DiffAdd}},
+			expected: &resource.ObjectDiff{
+				Adds:    resource.PropertyMap{},
+				Deletes: resource.PropertyMap{},
+				Sames:   resource.PropertyMap{},
+				Updates: map[resource.PropertyKey]resource.ValueDiff{"foo": {Old: null, New: known, NullDiff: true}},
+			},
+		},
+		{
+			// A value to null, reported as a delete.
+			olds:         resource.PropertyMap{"foo": known},
+			news:         resource.PropertyMap{"foo": null},
+			detailedDiff: map[string]plugin.PropertyDiff{"foo": {Kind: plugin.DiffDelete}},
+			expected: &resource.ObjectDiff{
+				Adds:    resource.PropertyMap{},
+				Deletes: resource.PropertyMap{},
+				Sames:   resource.PropertyMap{},
+				Updates: map[resource.PropertyKey]resource.ValueDiff{"foo": {Old: known, New: null, NullDiff: true}},
+			},
+		},
+		{
+			// Absent to a value, below the added property.
+			olds:         resource.PropertyMap{},
+			news:         resource.PropertyMap{"foo": known},
+			detailedDiff: map[string]plugin.PropertyDiff{"foo.bar": {Kind: plugin.DiffAdd}},
+			expected: &resource.ObjectDiff{
+				Adds:    resource.PropertyMap{"foo": known},
+				Deletes: resource.PropertyMap{},
+				Sames:   resource.PropertyMap{},
+				Updates: map[resource.PropertyKey]resource.ValueDiff{},
+			},
+		},
+		{
+			// Null to a value, below the updated property.
+			olds:         resource.PropertyMap{"foo": null},
+			news:         resource.PropertyMap{"foo": known},
+			detailedDiff: map[string]plugin.PropertyDiff{"foo.bar": {Kind: plugin.DiffAdd}},
+			expected: &resource.ObjectDiff{
+				Adds:    resource.PropertyMap{},
+				Deletes: resource.PropertyMap{},
+				Sames:   resource.PropertyMap{},
+				Updates: map[resource.PropertyKey]resource.ValueDiff{"foo": {Old: null, New: known, NullDiff: true}},
+			},
+		},
+		{
+			// A value to null, below the updated property.
+			olds:         resource.PropertyMap{"foo": known},
+			news:         resource.PropertyMap{"foo": null},
+			detailedDiff: map[string]plugin.PropertyDiff{"foo.bar": {Kind: plugin.DiffDelete}},
+			expected: &resource.ObjectDiff{
+				Adds:    resource.PropertyMap{},
+				Deletes: resource.PropertyMap{},
+				Sames:   resource.PropertyMap{},
+				Updates: map[resource.PropertyKey]resource.ValueDiff{"foo": {Old: known, New: null, NullDiff: true}},
+			},
+		},
+		{
+			// A value to absent, below the deleted property.
+			olds:         resource.PropertyMap{"foo": known},
+			news:         resource.PropertyMap{},
+			detailedDiff: map[string]plugin.PropertyDiff{"foo.bar": {Kind: plugin.DiffDelete}},
+			expected: &resource.ObjectDiff{
+				Adds:    resource.PropertyMap{},
+				Deletes: resource.PropertyMap{"foo": known},
+				Sames:   resource.PropertyMap{},
+				Updates: map[resource.PropertyKey]resource.ValueDiff{},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		diff := translateDetailedDiff(engine.StepEventMetadata{
+			Old:          &engine.StepEventStateMetadata{Inputs: c.olds, Outputs: c.olds},
+			New:          &engine.StepEventStateMetadata{Inputs: c.news},
+			DetailedDiff: c.detailedDiff,
+		}, false, false)
+		assert.Equal(t, c.expected, diff)
+	}
+}
+
+func TestTranslateDetailedDiffCreate(t *testing.T) {
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name": "web",
+		"spec": map[string]interface{}{
+			"replicas": 3,
+		},
+		"zone": "us-west-2a",
+	})
+	step := engine.StepEventMetadata{
+		Op:  deploy.OpCreate,
+		New: &engine.StepEventStateMetadata{Inputs: news},
+		DetailedDiff: map[string]plugin.PropertyDiff{
+			"name":          {Kind: plugin.DiffUpdate},
+			"spec.replicas": {Kind: plugin.DiffUpdate},
+			"zone":          {Kind: plugin.DiffUpdateReplace},
+		},
+	}
+
+	diff := translateDetailedDiff(step, false, false)
+	assert.Equal(t, &resource.ObjectDiff{
+		Adds:    news,
+		Deletes: resource.PropertyMap{},
+		Sames:   resource.PropertyMap{},
+		Updates: map[resource.PropertyKey]resource.ValueDiff{},
+	}, diff)
+	assert.Equal(t, diff, newDiffTranslator(1, false, false).translate(step))
+}
+
+func TestTranslateDetailedDiffOverlappingPaths(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": 3,
+			"ports":    []interface{}{80, 443},
+		},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": 5,
+			"ports":    []interface{}{80},
+		},
+	})
+	oldSpec, newSpec := olds["spec"].ObjectValue(), news["spec"].ObjectValue()
+
+	newObjectDiff := func() *resource.ObjectDiff {
+		return &resource.ObjectDiff{
+			Adds:    resource.PropertyMap{},
+			Deletes: resource.PropertyMap{},
+			Sames:   resource.PropertyMap{},
+			Updates: map[resource.PropertyKey]resource.ValueDiff{},
+		}
+	}
+	replicas := newObjectDiff()
+	replicas.Updates["replicas"] = resource.ValueDiff{Old: oldSpec["replicas"], New: newSpec["replicas"]}
+	ports := newObjectDiff()
+	ports.Updates["ports"] = resource.ValueDiff{
+		Array: &resource.ArrayDiff{
+			Adds:    map[int]resource.PropertyValue{},
+			Deletes: map[int]resource.PropertyValue{1: resource.NewNumberProperty(443)},
+			Sames:   map[int]resource.PropertyValue{},
+			Updates: map[int]resource.ValueDiff{},
+		},
+	}
+
+	cases := []struct {
+		detailedDiff map[string]plugin.PropertyDiff
+		spec         *resource.ObjectDiff
+	}{
+		{
+			// An update of an object and of one of its properties.
+			detailedDiff: map[string]plugin.PropertyDiff{
+				"spec":          {Kind: plugin.DiffUpdate},
+				"spec.replicas": {Kind: plugin.DiffUpdate},
+			},
+			spec: replicas,
+		},
+		{
+			// An object reported as added, although it exists on both sides, and an update of one of its properties.
+			detailedDiff: map[string]plugin.PropertyDiff{
+				"spec":          {Kind: plugin.DiffAdd},
+				"spec.replicas": {Kind: plugin.DiffUpdate},
+			},
+			spec: replicas,
+		},
+		{
+			// Updates of an object and an array and the deletion of one of the array's elements, which is listed twice.
+			detailedDiff: map[string]plugin.PropertyDiff{
+				"spec":             {Kind: plugin.DiffUpdate},
+				"spec.ports":       {Kind: plugin.DiffUpdateReplace},
+				"spec.ports[1]":    {Kind: plugin.DiffDelete},
+				`spec["ports"][1]`: {Kind: plugin.DiffDelete},
+			},
+			spec: ports,
+		},
+	}
+
+	// Map iteration order varies from run to run, so translate each diff several times to check that the result is
+	// stable.
+	for _, c := range cases {
+		step := engine.StepEventMetadata{
+			Old:          &engine.StepEventStateMetadata{Inputs: olds, Outputs: olds},
+			New:          &engine.StepEventStateMetadata{Inputs: news},
+			DetailedDiff: c.detailedDiff,
+		}
+		for i := 0; i < 20; i++ {
+			expected := newObjectDiff()
+			expected.Updates["spec"] = resource.ValueDiff{Object: c.spec}
+			assert.Equal(t, expected, translateDetailedDiff(step, false, false))
+		}
+	}
+
+	// Whether a change forces a replacement is still determined by its ancestors' entries.
+	step := engine.StepEventMetadata{
+		Old: &engine.StepEventStateMetadata{Inputs: olds, Outputs: olds},
+		New: &engine.StepEventStateMetadata{Inputs: news},
+		DetailedDiff: map[string]plugin.PropertyDiff{
+			"spec":          {Kind: plugin.DiffUpdateReplace},
+			"spec.replicas": {Kind: plugin.DiffUpdate},
+		},
+	}
+	assert.True(t, isReplacementPath([]interface{}{"spec", "replicas"}, getReplacementPaths(step)))
+}
+
+func TestTranslateDetailedDiffTrustedKinds(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name":   nil,
+		"spec":   map[string]interface{}{"replicas": 3, "image": "nginx"},
+		"tags":   nil,
+		"ports":  []interface{}{80, 443},
+		"labels": map[string]interface{}{"app": "web"},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name":   "web",
+		"spec":   map[string]interface{}{"replicas": 5, "image": "nginx"},
+		"tags":   map[string]interface{}{"owner": "ops"},
+		"ports":  []interface{}{80, 8443},
+		"labels": map[string]interface{}{"app": "api"},
+		"meta":   map[string]interface{}{"zone": "a"},
+	})
+
+	// The provider reports kinds for the intermediate properties spec, tags, and ports that disagree with what would
+	// be inferred from their values. Neither labels nor meta has an entry of its own.
+	step := engine.StepEventMetadata{
+		Old: &engine.StepEventStateMetadata{Inputs: olds, Outputs: olds},
+		New: &engine.StepEventStateMetadata{Inputs: news},
+		DetailedDiff: map[string]plugin.PropertyDiff{
+			"name":          {Kind: plugin.DiffAdd},
+			"spec":          {Kind: plugin.DiffAddReplace},
+			"spec.replicas": {Kind: plugin.DiffUpdate},
+			"tags":          {Kind: plugin.DiffAdd},
+			"tags.owner":    {Kind: plugin.DiffAdd},
+			"ports":         {Kind: plugin.DiffDelete},
+			"ports[1]":      {Kind: plugin.DiffUpdate},
+			"labels.app":    {Kind: plugin.DiffUpdate},
+			"meta.zone":     {Kind: plugin.DiffUpdate},
+		},
+	}
+
+	// By default, the kinds of intermediate properties are inferred from their values, as is the kind of a leaf that
+	// was set from null.
+	inferred := translateDetailedDiff(step, false, false)
+	assert.Equal(t, resource.PropertyMap{"meta": news["meta"]}, inferred.Adds)
+	assert.Empty(t, inferred.Deletes)
+	assert.True(t, inferred.Updates["name"].NullDiff)
+	assert.NotNil(t, inferred.Updates["spec"].Object)
+	assert.True(t, inferred.Updates["tags"].NullDiff)
+	assert.NotNil(t, inferred.Updates["ports"].Array)
+
+	// When kinds are trusted, the provider's kinds are used at every level at which they are reported. Properties
+	// without entries of their own are still inferred.
+	trusted := translateDetailedDiff(step, true, false)
+	assert.Equal(t, resource.PropertyMap{
+		"name": news["name"],
+		"spec": news["spec"],
+		"tags": news["tags"],
+		"meta": news["meta"],
+	}, trusted.Adds)
+	assert.Equal(t, resource.PropertyMap{"ports": olds["ports"]}, trusted.Deletes)
+	assert.Equal(t, map[resource.PropertyKey]resource.ValueDiff{
+		"labels": {Object: &resource.ObjectDiff{
+			Adds:    resource.PropertyMap{},
+			Deletes: resource.PropertyMap{},
+			Sames:   resource.PropertyMap{},
+			Updates: map[resource.PropertyKey]resource.ValueDiff{
+				"app": {Old: resource.NewStringProperty("web"), New: resource.NewStringProperty("api")},
+			},
+		}},
+	}, trusted.Updates)
+
+	// An intermediate property that the provider reports as updated is recursed into if both of its values are known.
+	step.DetailedDiff["spec"] = plugin.PropertyDiff{Kind: plugin.DiffUpdate}
+	trusted = translateDetailedDiff(step, true, false)
+	assert.Equal(t, map[resource.PropertyKey]resource.ValueDiff{
+		"replicas": {Old: resource.NewNumberProperty(3), New: resource.NewNumberProperty(5)},
+	}, trusted.Updates["spec"].Object.Updates)
+
+	// Translators translate with their own setting.
+	assert.Equal(t, trusted, newDiffTranslator(1, true, false).translate(step))
+	assert.Equal(t, trusted, translateStepDiff(step, Options{DiffOptions: DiffOptions{TrustDetailedDiffKinds: true}}))
+}
+
+func TestTranslateDetailedDiffSecretTransitions(t *testing.T) {
+	plain := resource.NewObjectProperty(resource.NewPropertyMapFromMap(map[string]interface{}{"user": "admin"}))
+	secret := resource.MakeSecret(plain)
+
+	for _, c := range []struct{ old, new resource.PropertyValue }{{plain, secret}, {secret, plain}} {
+		// A path beneath a property that becomes (or stops being) a secret does not descend into the secret.
+		diff := translateDetailedDiff(engine.StepEventMetadata{
+			Old:          &engine.StepEventStateMetadata{Outputs: resource.PropertyMap{"db": c.old}},
+			New:          &engine.StepEventStateMetadata{Inputs: resource.PropertyMap{"db": c.new}},
+			DetailedDiff: map[string]plugin.PropertyDiff{"db.user": {Kind: plugin.DiffUpdate}},
+		}, false, false)
+		assert.Equal(t, &resource.ObjectDiff{
+			Adds:    resource.PropertyMap{},
+			Deletes: resource.PropertyMap{},
+			Sames:   resource.PropertyMap{},
+			Updates: map[resource.PropertyKey]resource.ValueDiff{"db": {Old: c.old, New: c.new}},
+		}, diff)
+	}
+}
+
+func TestTranslateDetailedDiffUnknownKind(t *testing.T) {
+	// A kind from a provider that is newer than the CLI is displayed as whatever change the values show.
+	unknown := plugin.DiffKind(1000)
+	step := engine.StepEventMetadata{
+		Old: &engine.StepEventStateMetadata{
+			Outputs: resource.NewPropertyMapFromMap(map[string]interface{}{"size": "small", "zone": "a"}),
+		},
+		New: &engine.StepEventStateMetadata{
+			Inputs: resource.NewPropertyMapFromMap(map[string]interface{}{
+				"labels": map[string]interface{}{"app": "web"},
+				"size":   "large",
+				"tags":   "web",
+			}),
+		},
+		DetailedDiff: map[string]plugin.PropertyDiff{
+			"labels.app": {Kind: unknown},
+			"size":       {Kind: unknown},
+			"tags":       {Kind: unknown},
+			"zone":       {Kind: unknown},
+		},
+	}
+	for _, trustKinds := range []bool{false, true} {
+		diff := translateDetailedDiff(step, trustKinds, false)
+		assert.Equal(t, &resource.ObjectDiff{
+			Adds:    resource.PropertyMap{"labels": step.New.Inputs["labels"], "tags": step.New.Inputs["tags"]},
+			Deletes: resource.PropertyMap{"zone": resource.NewStringProperty("a")},
+			Sames:   resource.PropertyMap{},
+			Updates: map[resource.PropertyKey]resource.ValueDiff{
+				"size": {Old: resource.NewStringProperty("small"), New: resource.NewStringProperty("large")},
+			},
+		}, diff)
+	}
+
+	// Nor is an entry of an unknown kind reported as conflicting with the kind inferred for its ancestors.
+	assert.Empty(t, checkDetailedDiff(step))
+
+	// The kind is serialized by its number.
+	assert.Equal(t, "kind(1000)", formatDiffKind(unknown))
+	assert.Equal(t, "update-replace", formatDiffKind(plugin.DiffUpdateReplace))
+}
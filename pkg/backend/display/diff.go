@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"runtime"
 	"sort"
 	"time"
 
@@ -57,6 +58,45 @@ func ShowDiffEvents(op string, action apitype.UpdateKind,
 	}()
 
 	seen := make(map[resource.URN]engine.StepEventMetadata)
+	budget := newDiffBudget(opts)
+
+	// Translate the detailed diffs of resources ahead of their display, as this can be expensive for large resources.
+	opts.translator = newDiffTranslator(runtime.NumCPU(), opts.TrustDetailedDiffKinds, opts.MatchKeyCasing)
+	events = prefetchDiffs(events, opts.translator)
+
+	// Long lines are wrapped at the terminal's width unless the options specify a width.
+	opts.DiffWidth = getDiffWidth(os.Stdout, opts)
+
+	// Track the computed leaves of each resource's diff so that they can be patched as their values resolve.
+	if opts.ResolveComputedDiffs {
+		opts.computed = newComputedDiffLeaves()
+	}
+
+	// Record the steps that replace resources so that the causes of the replacements can be summarized.
+	if opts.SummarizeReplacements {
+		opts.replacements = newReplacementSteps()
+	}
+
+	// Hold back the events of resources until the summary if they are to be shown in the order of their dependencies.
+	var ordered *dependencyOrderedEvents
+	if opts.OrderByDependencies {
+		ordered = newDependencyOrderedEvents()
+	}
+
+	render := func(event engine.Event) {
+		out := os.Stdout
+		if event.Type == engine.DiagEvent {
+			payload := event.Payload.(engine.DiagEventPayload)
+			if payload.Severity == diag.Error || payload.Severity == diag.Warning {
+				out = os.Stderr
+			}
+		}
+
+		msg := renderDiffEvent(action, event, seen, budget, opts)
+		if msg != "" && out != nil {
+			fprintIgnoreError(out, msg)
+		}
+	}
 
 	for {
 		select {
@@ -65,18 +105,17 @@ func ShowDiffEvents(op string, action apitype.UpdateKind,
 		case event := <-events:
 			spinner.Reset()
 
-			out := os.Stdout
-			if event.Type == engine.DiagEvent {
-				payload := event.Payload.(engine.DiagEventPayload)
-				if payload.Severity == diag.Error || payload.Severity == diag.Warning {
-					out = os.Stderr
+			if ordered != nil {
+				if ordered.add(event) {
+					continue
+				}
+				if event.Type == engine.SummaryEvent || event.Type == engine.CancelEvent {
+					for _, e := range ordered.flush() {
+						render(e)
+					}
 				}
 			}
-
-			msg := RenderDiffEvent(action, event, seen, opts)
-			if msg != "" && out != nil {
-				fprintIgnoreError(out, msg)
-			}
+			render(event)
 
 			if event.Type == engine.CancelEvent {
 				return
@@ -85,9 +124,17 @@ func ShowDiffEvents(op string, action apitype.UpdateKind,
 	}
 }
 
+// RenderDiffEvent renders a single engine event using the diff view. A global diff budget is not tracked across calls;
+// each resource's diff is instead truncated individually if a budget is set.
 func RenderDiffEvent(action apitype.UpdateKind, event engine.Event,
 	seen map[resource.URN]engine.StepEventMetadata, opts Options) string {
 
+	return renderDiffEvent(action, event, seen, newDiffBudget(opts), opts)
+}
+
+func renderDiffEvent(action apitype.UpdateKind, event engine.Event,
+	seen map[resource.URN]engine.StepEventMetadata, budget *diffBudget, opts Options) string {
+
 	switch event.Type {
 	case engine.CancelEvent:
 		return ""
@@ -97,7 +144,11 @@ func RenderDiffEvent(action apitype.UpdateKind, event engine.Event,
 	case engine.PreludeEvent:
 		return renderPreludeEvent(event.Payload.(engine.PreludeEventPayload), opts)
 	case engine.SummaryEvent:
-		return renderSummaryEvent(action, event.Payload.(engine.SummaryEventPayload), opts)
+		steps := make([]engine.StepEventMetadata, 0, len(seen))
+		for _, step := range seen {
+			steps = append(steps, step)
+		}
+		return renderSummaryEvent(action, event.Payload.(engine.SummaryEventPayload), steps, opts)
 	case engine.StdoutColorEvent:
 		return renderStdoutColorEvent(event.Payload.(engine.StdoutEventPayload), opts)
 
@@ -109,7 +160,7 @@ func RenderDiffEvent(action apitype.UpdateKind, event engine.Event,
 	case engine.ResourceOutputsEvent:
 		return renderDiffResourceOutputsEvent(event.Payload.(engine.ResourceOutputsEventPayload), seen, opts)
 	case engine.ResourcePreEvent:
-		return renderDiffResourcePreEvent(event.Payload.(engine.ResourcePreEventPayload), seen, opts)
+		return renderDiffResourcePreEvent(event.Payload.(engine.ResourcePreEventPayload), seen, budget, opts)
 	case engine.DiagEvent:
 		return renderDiffDiagEvent(event.Payload.(engine.DiagEventPayload), opts)
 	case engine.PolicyViolationEvent:
@@ -136,10 +187,15 @@ func renderStdoutColorEvent(payload engine.StdoutEventPayload, opts Options) str
 	return opts.Color.Colorize(payload.Message)
 }
 
-func renderSummaryEvent(action apitype.UpdateKind, event engine.SummaryEventPayload, opts Options) string {
+func renderSummaryEvent(action apitype.UpdateKind, event engine.SummaryEventPayload,
+	steps []engine.StepEventMetadata, opts Options) string {
 	changes := event.ResourceChanges
 
 	out := &bytes.Buffer{}
+	fprintIgnoreError(out, opts.Color.Colorize(renderDiffLegend(steps, opts)))
+	fprintIgnoreError(out, opts.Color.Colorize(renderChangesByKind(steps, event.IsPreview, opts)))
+	fprintIgnoreError(out, opts.Color.Colorize(renderSecretChanges(steps, opts)))
+	fprintIgnoreError(out, opts.Color.Colorize(renderReplacementCauses(steps, opts)))
 	fprintIgnoreError(out, opts.Color.Colorize(
 		fmt.Sprintf("%sResources:%s\n", colors.SpecHeadline, colors.Reset)))
 
@@ -186,6 +242,11 @@ func renderSummaryEvent(action apitype.UpdateKind, event engine.SummaryEventPayl
 		summaryPieces = append(summaryPieces, fmt.Sprintf("%d unchanged", sameCount))
 	}
 
+	if propertyChangeCount := getPropertyChangeCount(steps, opts); propertyChangeCount != 0 {
+		summaryPieces = append(summaryPieces, fmt.Sprintf("%d %s", propertyChangeCount,
+			english.PluralWord(propertyChangeCount, "property change", "")))
+	}
+
 	if len(summaryPieces) > 0 {
 		fprintfIgnoreError(out, "    ")
 
@@ -252,9 +313,17 @@ func renderDiffResourceOperationFailedEvent(
 func renderDiffResourcePreEvent(
 	payload engine.ResourcePreEventPayload,
 	seen map[resource.URN]engine.StepEventMetadata,
+	budget *diffBudget,
 	opts Options) string {
 
+	// The step's translated diff is no longer needed once the step has been rendered.
+	defer opts.translator.evict(payload.Metadata)
+
+	payload.Metadata = normalizeStepOp(payload.Metadata, opts)
 	seen[payload.Metadata.URN] = payload.Metadata
+	if opts.replacements != nil {
+		opts.replacements.record(payload.Metadata)
+	}
 	if payload.Metadata.Op == deploy.OpRefresh {
 		return ""
 	}
@@ -264,28 +333,184 @@ func renderDiffResourcePreEvent(
 		indent := engine.GetIndent(payload.Metadata, seen)
 		summary := engine.GetResourcePropertiesSummary(payload.Metadata, indent)
 
-		var details string
-		if payload.Metadata.DetailedDiff != nil {
-			var buf bytes.Buffer
-			if diff := translateDetailedDiff(payload.Metadata); diff != nil {
-				engine.PrintObjectDiff(&buf, *diff, nil /*include*/, payload.Planning, indent, opts.SummaryDiff, payload.Debug)
-			} else {
-				engine.PrintObject(
-					&buf, payload.Metadata.Old.Inputs, payload.Planning, indent, deploy.OpSame, true /*prefix*/, payload.Debug)
-			}
-			details = buf.String()
-		} else {
-			details = engine.GetResourcePropertiesDetails(
-				payload.Metadata, indent, payload.Planning, opts.SummaryDiff, payload.Debug)
+		// If only the paths of changes are requested, they are rendered alone. Otherwise, if the resource's diff is too
+		// large to be read, only a summary of it is rendered, and if it exceeds the remaining diff budget, only a prefix
+		// of it is rendered.
+		details, rendered := renderDiffPaths(payload, indent, opts)
+		if !rendered {
+			details, rendered = renderSummarizedDiff(payload, indent, opts)
+		}
+		if !rendered {
+			details, rendered = renderCustomDiff(payload, indent, budget, opts)
+		}
+		if !rendered {
+			details = renderResourceDetails(payload, indent, opts)
+		}
+
+		// Plain diffs are intended for logs and other non-terminal destinations, so we strip all color from the
+		// resource's diff irrespective of the global colorization setting.
+		color := opts.Color
+		if opts.PlainDiff {
+			color = colors.Never
 		}
 
-		fprintIgnoreError(out, opts.Color.Colorize(summary))
-		fprintIgnoreError(out, opts.Color.Colorize(details))
-		fprintIgnoreError(out, opts.Color.Colorize(colors.Reset))
+		fprintIgnoreError(out, color.Colorize(wrapDiffLines(summary, opts.DiffWidth)))
+		fprintIgnoreError(out, color.Colorize(renderIgnoredReplacementWarnings(payload.Metadata, indent, opts)))
+		fprintIgnoreError(out, color.Colorize(renderDetailedDiffMismatches(payload.Metadata, indent, opts)))
+		fprintIgnoreError(out, color.Colorize(renderReplacementReasons(payload.Metadata, indent, opts)))
+		fprintIgnoreError(out, color.Colorize(wrapDiffLines(details, opts.DiffWidth)))
+		fprintIgnoreError(out, color.Colorize(colors.Reset))
+
+		if opts.computed != nil && !payload.Planning {
+			opts.computed.record(payload.Metadata)
+		}
 	}
 	return out.String()
 }
 
+// renderCustomDiff renders the properties of the given step if usesCustomDiff reports that the given options
+// customize the rendering of diffs, or if the step's diff exceeds the remaining diff budget. Only the first changes
+// that fit within the budget are rendered, in the order visited by walkObjectDiff, followed by a notice that counts the
+// changes that were omitted. The second result is false if the step's properties are not rendered as a diff or if
+// there is nothing to customize, in which case the caller should render the step's properties as usual.
+func renderCustomDiff(payload engine.ResourcePreEventPayload, indent int, budget *diffBudget,
+	opts Options) (string, bool) {
+
+	limit := budget.remaining()
+	custom := usesCustomDiff(opts)
+	if !custom && limit < 0 {
+		return "", false
+	}
+
+	// Moved properties are detected before values are transformed, so that values that are only equal once they have
+	// been transformed (e.g. masked) are never mistaken for moves.
+	untransformed := opts
+	untransformed.ValueTransform = nil
+	diff, include, indent := getRenderedDiff(payload.Metadata, indent, untransformed)
+	if diff == nil {
+		return "", false
+	}
+	var moves []movedProperty
+	if opts.DetectMovedProperties {
+		diff, moves = detectMovedProperties(diff, include, opts.MovedPropertiesAnyKey, getValueEquality(opts))
+	}
+
+	// If the diff exceeds the budget, moved properties are counted among the omitted changes rather than rendered.
+	changes := countIncludedChanges(diff, include) + len(moves)
+	truncated := limit >= 0 && changes > limit
+	if !truncated {
+		budget.spend(changes)
+		if !custom {
+			return "", false
+		}
+	} else {
+		budget.spend(limit)
+		diff, moves = truncateObjectDiff(diff, include, limit), nil
+	}
+
+	var buf bytes.Buffer
+	if !truncated || limit > 0 {
+		diff = formatRenderedDiff(transformObjectDiff(nil, diff, opts.ValueTransform), opts)
+		grouped, ok := "", false
+		if opts.GroupReplacements {
+			grouped, ok = renderReplacementGroups(payload, diff, include, indent, opts)
+		}
+		if ok {
+			buf.WriteString(grouped)
+		} else {
+			printObjectDiff(&buf, *diff, include, payload.Planning, indent, payload.Debug, opts)
+		}
+		buf.WriteString(renderMovedProperties(moves, payload.Planning, indent, opts))
+	}
+	if truncated {
+		buf.WriteString(renderOmittedChanges(changes-limit, indent, opts))
+	}
+	return buf.String(), true
+}
+
+// formatRenderedDiff returns a copy of the given diff arranged for display: properties are ordered by the property
+// order, moved array elements are labeled by their indices if requested, deeply nested objects are flattened,
+// unchanged properties and elements far from any change are elided, and the changes in the middle of long array diffs
+// are omitted. The values themselves are formatted as they are printed, by getPropertyRenderer.
+func formatRenderedDiff(diff *resource.ObjectDiff, opts Options) *resource.ObjectDiff {
+	diff = orderObjectDiff(nil, diff, getPropertyOrder(opts))
+	if opts.ShowArrayMoves {
+		diff = labelArrayMoves(diff)
+	}
+	diff = flattenObjectDiff(diff, 1, opts.FlattenDiffDepth)
+	diff = limitObjectDiffContext(diff, opts.DiffContext)
+	return windowObjectDiffArrays(diff, opts.ArrayDiffWindow)
+}
+
+// getPropertyFormatting returns the renderer that applies the given options' custom formatters to the values of the
+// given diff, which may be nil, and then truncates any strings they leave unformatted to the maximum string display
+// length. It returns nil if the options neither format nor truncate values.
+func getPropertyFormatting(diff *resource.ObjectDiff, opts Options) engine.PropertyRenderer {
+	var renderers []engine.PropertyRenderer
+	if opts.PropertyFormatters != nil {
+		renderers = append(renderers, opts.PropertyFormatters.renderer(diff))
+	}
+	if truncation := getStringTruncation(opts); truncation != nil {
+		renderers = append(renderers, truncation.renderer(diff))
+	}
+	if len(renderers) == 0 {
+		return nil
+	}
+
+	return func(path []interface{}, v resource.PropertyValue) (string, bool) {
+		for _, render := range renderers {
+			if text, ok := render(path, v); ok {
+				return text, true
+			}
+		}
+		return "", false
+	}
+}
+
+// getPropertyRenderer returns the renderer for the values of the given diff: values are formatted by
+// getPropertyFormatting and then colored by any property color overrides. It returns nil if the options change
+// neither.
+func getPropertyRenderer(diff *resource.ObjectDiff, opts Options) engine.PropertyRenderer {
+	return colorRenderer(getPropertyFormatting(diff, opts), getPropertyColors(opts))
+}
+
+// usesCustomDiff returns true if the given options customize the rendering of diffs, in which case diffs are rendered
+// by renderCustomDiff rather than by the engine. This is the only place that decides which diff options need custom
+// rendering, so options that change how diffs are rendered must be added here.
+func usesCustomDiff(opts Options) bool {
+	return opts.ValueTransform != nil || opts.PropertyFormatters != nil || opts.MaxStringDisplayLength > 0 ||
+		len(opts.IgnoreDiffPaths) > 0 || len(opts.UnorderedArrayPaths) > 0 || opts.AlignArrays ||
+		opts.ShowFullUpdates != nil || opts.GroupReplacements || len(opts.JSONStringPaths) > 0 || usesDiffTree(opts) ||
+		len(opts.PropertyColors) > 0 || opts.DetectMovedProperties || len(opts.KeyedArrays) > 0 ||
+		len(opts.PropertyOrder) > 0 || opts.ShowArrayMoves || opts.FlattenDiffDepth > 0 || opts.MatchKeyCasing ||
+		opts.DiffContext > 0 || opts.ArrayDiffWindow > 0 || getCustomValueEquality(opts) != nil
+}
+
+// renderResourceDetails renders the properties of the resource affected by the given event.
+func renderResourceDetails(payload engine.ResourcePreEventPayload, indent int, opts Options) string {
+	// Values are transformed and long strings truncated here only when the resource's properties are not rendered as a
+	// diff; diffs that transform values or truncate long strings are rendered by renderCustomDiff.
+	metadata := transformStepValues(payload.Metadata, opts)
+	var truncate engine.PropertyRenderer
+	if truncation := getStringTruncation(opts); truncation != nil {
+		truncate = truncation.renderer(nil)
+	}
+	if payload.Metadata.DetailedDiff == nil {
+		return engine.GetResourcePropertiesDetails(
+			metadata, indent, payload.Planning, opts.SummaryDiff, payload.Debug, truncate)
+	}
+
+	var buf bytes.Buffer
+	if diff := translateStepDiff(payload.Metadata, opts); diff != nil {
+		engine.PrintObjectDiff(
+			&buf, *diff, nil /*include*/, payload.Planning, indent, opts.SummaryDiff, payload.Debug, nil /*render*/)
+	} else {
+		engine.PrintObject(
+			&buf, metadata.Old.Inputs, payload.Planning, indent, deploy.OpSame, true /*prefix*/, payload.Debug, truncate)
+	}
+	return buf.String()
+}
+
 func renderDiffResourceOutputsEvent(
 	payload engine.ResourceOutputsEventPayload,
 	seen map[resource.URN]engine.StepEventMetadata,
@@ -303,8 +528,8 @@ func renderDiffResourceOutputsEvent(
 		}
 
 		if !opts.SuppressOutputs {
-			if text := engine.GetResourceOutputsPropertiesString(
-				payload.Metadata, indent+1, payload.Planning, payload.Debug, refresh); text != "" {
+			if text := engine.GetResourceOutputsPropertiesString(transformStepValues(payload.Metadata, opts),
+				indent+1, payload.Planning, payload.Debug, refresh); text != "" {
 
 				header := fmt.Sprintf("%v%v--outputs:--%v\n",
 					payload.Metadata.Op.Color(), engine.GetIndentationString(indent+1), colors.Reset)
@@ -312,6 +537,8 @@ func renderDiffResourceOutputsEvent(
 				fprintIgnoreError(out, opts.Color.Colorize(text))
 			}
 		}
+
+		fprintIgnoreError(out, opts.Color.Colorize(renderResolvedComputedLeaves(payload.Metadata, indent, opts)))
 	}
 	return out.String()
 }
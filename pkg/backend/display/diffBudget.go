@@ -0,0 +1,221 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"github.com/dustin/go-humanize/english"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// diffBudget tracks the number of property changes that the diff display may still render before it begins to
+// truncate resource diffs.
+type diffBudget struct {
+	limit  int  // the maximum number of changes to render, or zero for no limit.
+	global bool // true if the limit applies across all resources rather than to each resource.
+	spent  int  // the number of changes rendered so far; only tracked for global budgets.
+}
+
+func newDiffBudget(opts Options) *diffBudget {
+	return &diffBudget{limit: opts.DiffBudget, global: opts.GlobalDiffBudget}
+}
+
+// remaining returns the number of changes that may be rendered for the next resource, or -1 if there is no limit.
+func (b *diffBudget) remaining() int {
+	switch {
+	case b == nil || b.limit <= 0:
+		return -1
+	case !b.global:
+		return b.limit
+	case b.spent >= b.limit:
+		return 0
+	default:
+		return b.limit - b.spent
+	}
+}
+
+// spend records that the given number of changes were rendered.
+func (b *diffBudget) spend(changes int) {
+	if b != nil && b.global {
+		b.spent += changes
+	}
+}
+
+// getRenderedDiff returns the diff that the diff display renders for the given step, the set of top-level keys to which
+// that rendering is restricted (if any), and the indentation at which its properties are rendered. It returns a nil
+// diff if the step's properties are not rendered as a diff. Properties renamed to a different casing convention are
+// matched if requested, JSON-encoded strings are diffed structurally, keyed arrays are diffed by key, unordered arrays
+// are diffed as multisets, updated objects are expanded for resources whose updates are shown in full, changes at
+// ignored property paths are omitted, and the value transform in the given options, if any, is applied to the diff's
+// values.
+func getRenderedDiff(step engine.StepEventMetadata, indent int,
+	opts Options) (*resource.ObjectDiff, []resource.PropertyKey, int) {
+
+	if step.Old == nil || step.New == nil {
+		return nil, nil, 0
+	}
+
+	var diff *resource.ObjectDiff
+	var include []resource.PropertyKey
+	var olds, news resource.PropertyMap
+	switch {
+	case step.DetailedDiff != nil:
+		diff, olds, news = translateStepDiff(step, opts), step.Old.Outputs, step.New.Inputs
+	case len(step.New.Outputs) > 0:
+		olds, news, indent = step.Old.Outputs, step.New.Outputs, indent+1
+		diff = olds.Diff(news, engine.IsInternalPropertyKey)
+	default:
+		olds, news, include, indent = step.Old.Inputs, step.New.Inputs, step.Diffs, indent+1
+		diff = olds.Diff(news, engine.IsInternalPropertyKey)
+	}
+	if step.DetailedDiff == nil {
+		// Renamed properties in detailed diffs are already matched, and the elements of their arrays are addressed by
+		// index, so they are not aligned.
+		if opts.MatchKeyCasing {
+			diff = matchKeyCasing(diff, olds, news)
+		}
+		if opts.AlignArrays {
+			diff = alignDiffArrays(diff, olds, news, getTupleArrayPaths(opts))
+		}
+	}
+	diff = diffJSONStrings(diff, getJSONStringPaths(opts))
+	diff = diffKeyedArrays(diff, olds, news, getKeyedArrays(opts))
+	diff = diffUnorderedArrays(diff, olds, news, getUnorderedArrayPaths(opts), getValueEquality(opts))
+	diff = diffEqualValues(diff, olds, news, getCustomValueEquality(opts))
+	if showFullUpdates(step, opts) {
+		diff = expandUpdates(diff, olds, news, getValueEquality(opts))
+	}
+	diff = filterIgnoredDiffs(diff, getIgnoredDiffPaths(opts))
+	return transformObjectDiff(nil, diff, opts.ValueTransform), include, indent
+}
+
+// renderOmittedChanges renders a notice that counts the given number of changes that were omitted from a resource's
+// diff because it exceeded the diff budget, followed by the options' hint on how to see them, if any.
+func renderOmittedChanges(omitted, indent int, opts Options) string {
+	hint := ""
+	if opts.DiffBudgetHint != "" {
+		hint = " (" + opts.DiffBudgetHint + ")"
+	}
+	return fmt.Sprintf("%s%s… and %s %s%s%s\n", deploy.OpSame.Color(), engine.GetIndentationString(indent),
+		humanize.Comma(int64(omitted)), english.PluralWord(omitted, "more change", "more changes"), hint, colors.Reset)
+}
+
+// countIncludedChanges counts the changed leaves of the given diff, ignoring any top-level properties that are not in
+// the include set. A nil include set includes all properties.
+func countIncludedChanges(diff *resource.ObjectDiff, include []resource.PropertyKey) int {
+	includeSet := makeIncludeSet(include)
+
+	count := 0
+	walkObjectDiff(diff, func(path []interface{}, op deploy.StepOp, _, _ resource.PropertyValue) {
+		if op != deploy.OpSame && (includeSet == nil || includeSet[resource.PropertyKey(path[0].(string))]) {
+			count++
+		}
+	})
+	return count
+}
+
+func makeIncludeSet(include []resource.PropertyKey) map[resource.PropertyKey]bool {
+	if include == nil {
+		return nil
+	}
+	includeSet := make(map[resource.PropertyKey]bool)
+	for _, k := range include {
+		includeSet[k] = true
+	}
+	return includeSet
+}
+
+// truncateObjectDiff returns a copy of the given diff that retains only its first limit changes, in the order visited
+// by walkObjectDiff. Unchanged properties that precede the last retained change are kept, so the truncated diff
+// renders as a prefix of the full diff.
+func truncateObjectDiff(diff *resource.ObjectDiff, include []resource.PropertyKey, limit int) *resource.ObjectDiff {
+	t := &diffTruncator{remaining: limit}
+	return t.truncateObject(diff, makeIncludeSet(include))
+}
+
+type diffTruncator struct {
+	remaining int // the number of changes that may still be retained.
+}
+
+func (t *diffTruncator) truncateObject(diff *resource.ObjectDiff,
+	includeSet map[resource.PropertyKey]bool) *resource.ObjectDiff {
+
+	result := *diff
+	result.Adds = make(resource.PropertyMap)
+	result.Deletes = make(resource.PropertyMap)
+	result.Sames = make(resource.PropertyMap)
+	result.Updates = make(map[resource.PropertyKey]resource.ValueDiff)
+	for _, k := range diff.Keys() {
+		if t.remaining == 0 {
+			break
+		}
+		if includeSet != nil && !includeSet[k] {
+			continue
+		}
+
+		if add, isadd := diff.Adds[k]; isadd {
+			result.Adds[k] = add
+			t.remaining--
+		} else if delete, isdelete := diff.Deletes[k]; isdelete {
+			result.Deletes[k] = delete
+			t.remaining--
+		} else if update, isupdate := diff.Updates[k]; isupdate {
+			result.Updates[k] = t.truncateValue(update)
+		} else {
+			result.Sames[k] = diff.Sames[k]
+		}
+	}
+	return &result
+}
+
+func (t *diffTruncator) truncateArray(diff *resource.ArrayDiff) *resource.ArrayDiff {
+	result := *diff
+	result.Adds = make(map[int]resource.PropertyValue)
+	result.Deletes = make(map[int]resource.PropertyValue)
+	result.Sames = make(map[int]resource.PropertyValue)
+	result.Updates = make(map[int]resource.ValueDiff)
+	for i := 0; i < diff.Positions() && t.remaining > 0; i++ {
+		if add, isadd := diff.Adds[i]; isadd {
+			result.Adds[i] = add
+			t.remaining--
+		} else if delete, isdelete := diff.Deletes[i]; isdelete {
+			result.Deletes[i] = delete
+			t.remaining--
+		} else if update, isupdate := diff.Updates[i]; isupdate {
+			result.Updates[i] = t.truncateValue(update)
+		} else if same, issame := diff.Sames[i]; issame {
+			result.Sames[i] = same
+		}
+	}
+	return &result
+}
+
+func (t *diffTruncator) truncateValue(diff resource.ValueDiff) resource.ValueDiff {
+	switch {
+	case diff.Array != nil:
+		diff.Array = t.truncateArray(diff.Array)
+	case diff.Object != nil:
+		diff.Object = t.truncateObject(diff.Object, nil)
+	default:
+		t.remaining--
+	}
+	return diff
+}
@@ -0,0 +1,118 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// limitObjectDiffContext returns a copy of the given diff in which, within each object and array, the unchanged
+// properties and elements that are more than the given number of siblings away from a change are elided, like the
+// context of a unified diff. Siblings are counted in the order in which they are displayed. A non-positive context
+// leaves the diff as it is.
+func limitObjectDiffContext(diff *resource.ObjectDiff, context int) *resource.ObjectDiff {
+	if diff == nil || context <= 0 {
+		return diff
+	}
+
+	result := &resource.ObjectDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Renames: diff.Renames,
+		Paths:   diff.Paths,
+		Sames:   diff.Sames,
+		Updates: make(map[resource.PropertyKey]resource.ValueDiff),
+		Leading: diff.Leading,
+	}
+	for k, update := range diff.Updates {
+		result.Updates[k] = limitValueDiffContext(update, context)
+	}
+
+	keys := diff.Keys()
+	changed := make([]bool, len(keys))
+	for i, k := range keys {
+		changed[i] = diff.Changed(k)
+	}
+	for _, i := range getElidedSames(changed, context) {
+		if result.Elided == nil {
+			result.Elided = make(map[resource.PropertyKey]bool)
+		}
+		result.Elided[keys[i]] = true
+	}
+	return result
+}
+
+func limitArrayDiffContext(diff *resource.ArrayDiff, context int) *resource.ArrayDiff {
+	result := &resource.ArrayDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Sames:   diff.Sames,
+		Updates: make(map[int]resource.ValueDiff),
+		Moves:   diff.Moves,
+		Labels:  diff.Labels,
+	}
+	for i, update := range diff.Updates {
+		result.Updates[i] = limitValueDiffContext(update, context)
+	}
+
+	changed := make([]bool, diff.Positions())
+	for i := range changed {
+		_, same := diff.Sames[i]
+		changed[i] = !same
+	}
+	for _, i := range getElidedSames(changed, context) {
+		if result.Elided == nil {
+			result.Elided = make(map[int]bool)
+		}
+		result.Elided[i] = true
+	}
+	return result
+}
+
+func limitValueDiffContext(diff resource.ValueDiff, context int) resource.ValueDiff {
+	switch {
+	case diff.Array != nil:
+		diff.Array = limitArrayDiffContext(diff.Array, context)
+	case diff.Object != nil:
+		diff.Object = limitObjectDiffContext(diff.Object, context)
+	}
+	return diff
+}
+
+// getElidedSames returns the indices of the siblings that are more than the given number of siblings away from every
+// changed sibling, given whether each sibling changed.
+func getElidedSames(changed []bool, context int) []int {
+	near := make([]bool, len(changed))
+	for i, c := range changed {
+		if !c {
+			continue
+		}
+		for j := i - context; j <= i+context; j++ {
+			if j >= 0 && j < len(near) {
+				near[j] = true
+			}
+		}
+	}
+
+	var elided []int
+	for i, n := range near {
+		if !n {
+			elided = append(elided, i)
+		}
+	}
+	return elided
+}
@@ -0,0 +1,116 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// DiffFormat selects the format in which the diffs of resources are written as an update runs.
+type DiffFormat string
+
+const (
+	// DiffFormatText renders diffs as text, using the display type selected by the options.
+	DiffFormatText DiffFormat = "text"
+	// DiffFormatJSONLines writes one JSON record per resource to stdout as each resource's step completes.
+	DiffFormatJSONLines DiffFormat = "jsonlines"
+)
+
+// ParseDiffFormat parses the name of a diff format. The empty string selects the default, text.
+func ParseDiffFormat(name string) (DiffFormat, error) {
+	switch format := DiffFormat(name); format {
+	case "", DiffFormatText, DiffFormatJSONLines:
+		return format, nil
+	default:
+		return "", errors.Errorf("unknown diff format %q (expected one of text or jsonlines)", name)
+	}
+}
+
+// jsonLinesRecord is the diff of a single resource in the JSON Lines diff format. Each record stands on its own, so
+// that consumers need not correlate it with any other output.
+type jsonLinesRecord struct {
+	// URN is the resource that the step operated on.
+	URN resource.URN `json:"urn"`
+	// OldURN is the resource's previous URN, if it was renamed.
+	OldURN resource.URN `json:"oldUrn,omitempty"`
+	// Op is the operation that the step performed, or planned to perform in a preview.
+	Op deploy.StepOp `json:"op"`
+	// Preview is true if the step was only planned.
+	Preview bool `json:"preview,omitempty"`
+	// Failed is true if the step failed, in which case the diff is what the step attempted.
+	Failed bool `json:"failed,omitempty"`
+	// Diff is the property-level diff between the resource's old and new states.
+	Diff *objectDiffJSON `json:"diff,omitempty"`
+}
+
+// ShowJSONLinesEvents writes the diff of each resource that the display would show to stdout as a JSON record on a
+// line of its own, as soon as the resource's step completes, so that consumers can process the diffs while the update
+// is still running. Records are written in the order in which steps complete, which is not URN order: independent
+// steps run concurrently. Creates and deletes are recorded as the addition or deletion of all of the resource's inputs,
+// and secrets are always masked. Errors and warnings are written to stderr so that stdout holds nothing but records.
+func ShowJSONLinesEvents(events <-chan engine.Event, done chan<- bool, opts Options) {
+	// Ensure we close the done channel before exiting.
+	defer func() { close(done) }()
+
+	for e := range events {
+		var err error
+		switch e.Type {
+		case engine.CancelEvent:
+			return
+		case engine.ResourceOutputsEvent:
+			p := e.Payload.(engine.ResourceOutputsEventPayload)
+			err = writeJSONLinesRecord(os.Stdout, p.Metadata, p.Planning, false, opts)
+		case engine.ResourceOperationFailed:
+			p := e.Payload.(engine.ResourceOperationFailedPayload)
+			err = writeJSONLinesRecord(os.Stdout, p.Metadata, false, true, opts)
+		case engine.DiagEvent:
+			p := e.Payload.(engine.DiagEventPayload)
+			if !p.Ephemeral && (p.Severity == diag.Error || p.Severity == diag.Warning) {
+				fprintfIgnoreError(os.Stderr, "%s", colors.Never.Colorize(p.Prefix+p.Message))
+			}
+		}
+		if err != nil {
+			fprintfIgnoreError(os.Stderr, opts.Color.Colorize(colors.SpecWarning+"warning:"+colors.Reset+" %v\n"), err)
+		}
+	}
+}
+
+// writeJSONLinesRecord writes the JSON Lines record of the given step to the given writer in a single write, unless the
+// display would not show the step or the step left its resource as it was.
+func writeJSONLinesRecord(w io.Writer, step engine.StepEventMetadata, preview, failed bool, opts Options) error {
+	if step.Op == deploy.OpSame || !shouldShow(step, opts) {
+		return nil
+	}
+
+	record := jsonLinesRecord{
+		URN:     step.URN,
+		OldURN:  getRenamedURN(step),
+		Op:      step.Op,
+		Preview: preview,
+		Failed:  failed,
+		Diff:    serializeObjectDiff(getChangelogDiff(step, opts), false),
+	}
+	return errors.Wrap(json.NewEncoder(w).Encode(&record), "could not write diff record")
+}
@@ -0,0 +1,83 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+func TestParseDiffFormat(t *testing.T) {
+	for _, name := range []string{"", "text", "jsonlines"} {
+		format, err := ParseDiffFormat(name)
+		assert.NoError(t, err)
+		assert.Equal(t, DiffFormat(name), format)
+	}
+	_, err := ParseDiffFormat("json")
+	assert.Error(t, err)
+}
+
+func TestJSONLinesRecords(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"size": "small"})
+	olds["password"] = resource.MakeSecret(resource.NewStringProperty("hunter2"))
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{"size": "large"})
+	news["password"] = resource.MakeSecret(resource.NewStringProperty("hunter3"))
+	update := makeUpdateStep(olds, news, nil)
+
+	create := makeUpdateStep(nil, news, nil)
+	create.Op, create.Old, create.URN = deploy.OpCreate, nil, update.URN+"-new"
+
+	same := makeUpdateStep(news, news, nil)
+	same.Op, same.URN = deploy.OpSame, update.URN+"-same"
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeJSONLinesRecord(&buf, update, true, false, Options{}))
+	assert.NoError(t, writeJSONLinesRecord(&buf, same, true, false, Options{}))
+	assert.NoError(t, writeJSONLinesRecord(&buf, create, false, true, Options{}))
+	assert.NotContains(t, buf.String(), "hunter")
+
+	// Each shown step is written on a line of its own, in the order in which it was written; sames are skipped.
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var records []map[string]interface{}
+	for _, line := range lines {
+		var record map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+
+	assert.Equal(t, string(update.URN), records[0]["urn"])
+	assert.Equal(t, "update", records[0]["op"])
+	assert.Equal(t, true, records[0]["preview"])
+	assert.Nil(t, records[0]["failed"])
+	assert.Contains(t, records[0]["diff"].(map[string]interface{})["updates"], "size")
+
+	// Creates are recorded as the addition of all of their inputs.
+	assert.Equal(t, string(create.URN), records[1]["urn"])
+	assert.Equal(t, "create", records[1]["op"])
+	assert.Nil(t, records[1]["preview"])
+	assert.Equal(t, true, records[1]["failed"])
+	adds := records[1]["diff"].(map[string]interface{})["adds"].(map[string]interface{})
+	assert.Equal(t, "large", adds["size"])
+	assert.Equal(t, "[secret]", adds["password"])
+}
@@ -0,0 +1,173 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// ValidateIgnoreDiffPaths returns an error if any of the given property path patterns is malformed.
+func ValidateIgnoreDiffPaths(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := parseDiffPathPattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getIgnoredDiffPaths returns the parsed forms of the ignored property path patterns in the given options. Malformed
+// patterns are skipped; they are expected to have been rejected by ValidateIgnoreDiffPaths.
+func getIgnoredDiffPaths(opts Options) []diffPathPattern {
+	var patterns []diffPathPattern
+	for _, pattern := range opts.IgnoreDiffPaths {
+		if p, err := parseDiffPathPattern(pattern); err == nil {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// isIgnoredDiffPath returns true if the given property path, or any path that contains it, matches one of the given
+// patterns.
+func isIgnoredDiffPath(path []interface{}, patterns []diffPathPattern) bool {
+	for _, pattern := range patterns {
+		for i := 1; i <= len(path); i++ {
+			if pattern.matches(path[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterIgnoredDiffs returns a copy of the given diff from which all changes at ignored property paths have been
+// removed. Updates that contain only ignored changes are removed entirely.
+func filterIgnoredDiffs(diff *resource.ObjectDiff, patterns []diffPathPattern) *resource.ObjectDiff {
+	if diff == nil || len(patterns) == 0 {
+		return diff
+	}
+	return filterIgnoredObjectDiffs(nil, diff, patterns)
+}
+
+func filterIgnoredObjectDiffs(path []interface{}, diff *resource.ObjectDiff,
+	patterns []diffPathPattern) *resource.ObjectDiff {
+
+	result := &resource.ObjectDiff{
+		Adds:    make(resource.PropertyMap),
+		Deletes: make(resource.PropertyMap),
+		Schema:  diff.Schema,
+		Renames: diff.Renames,
+		Sames:   diff.Sames,
+		Updates: make(map[resource.PropertyKey]resource.ValueDiff),
+	}
+	for k, add := range diff.Adds {
+		if !isIgnoredDiffPath(appendPath(path, string(k)), patterns) {
+			result.Adds[k] = add
+		}
+	}
+	for k, delete := range diff.Deletes {
+		if !isIgnoredDiffPath(appendPath(path, string(k)), patterns) {
+			result.Deletes[k] = delete
+		}
+	}
+	for k, update := range diff.Updates {
+		elementPath := appendPath(path, string(k))
+		if isIgnoredDiffPath(elementPath, patterns) {
+			continue
+		}
+		if update = filterIgnoredValueDiffs(elementPath, update, patterns); update.AnyChanges() {
+			result.Updates[k] = update
+		}
+	}
+	return result
+}
+
+func filterIgnoredArrayDiffs(path []interface{}, diff *resource.ArrayDiff,
+	patterns []diffPathPattern) *resource.ArrayDiff {
+
+	result := &resource.ArrayDiff{
+		Adds:    make(map[int]resource.PropertyValue),
+		Deletes: make(map[int]resource.PropertyValue),
+		Schema:  diff.Schema,
+		Sames:   diff.Sames,
+		Updates: make(map[int]resource.ValueDiff),
+		Moves:   diff.Moves,
+	}
+	for i, add := range diff.Adds {
+		if !isIgnoredDiffPath(appendPath(path, diff.Index(i)), patterns) {
+			result.Adds[i] = add
+		}
+	}
+	for i, delete := range diff.Deletes {
+		if !isIgnoredDiffPath(appendPath(path, diff.Index(i)), patterns) {
+			result.Deletes[i] = delete
+		}
+	}
+	for i, update := range diff.Updates {
+		elementPath := appendPath(path, diff.Index(i))
+		if isIgnoredDiffPath(elementPath, patterns) {
+			continue
+		}
+		if update = filterIgnoredValueDiffs(elementPath, update, patterns); update.AnyChanges() {
+			result.Updates[i] = update
+		}
+	}
+	return result
+}
+
+func filterIgnoredValueDiffs(path []interface{}, diff resource.ValueDiff,
+	patterns []diffPathPattern) resource.ValueDiff {
+
+	switch {
+	case diff.Array != nil:
+		diff.Array = filterIgnoredArrayDiffs(path, diff.Array, patterns)
+	case diff.Object != nil:
+		diff.Object = filterIgnoredObjectDiffs(path, diff.Object, patterns)
+	}
+	return diff
+}
+
+// getIgnoredReplacements returns the formatted property paths of the ignored changes that the provider reports will
+// force the given step's resource to be replaced, in sorted order.
+func getIgnoredReplacements(step engine.StepEventMetadata, patterns []diffPathPattern) []string {
+	var paths []string
+	for _, path := range step.ReplaceReasons() {
+		if elements, err := parsedDiffPaths.parse(path); err == nil && isIgnoredDiffPath(elements, patterns) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// renderIgnoredReplacementWarnings renders a warning for each ignored change that forces the replacement of the given
+// step's resource. Ignoring such changes only hides them from the display; the resource is still replaced.
+func renderIgnoredReplacementWarnings(step engine.StepEventMetadata, indent int, opts Options) string {
+	patterns := getIgnoredDiffPaths(opts)
+	if len(patterns) == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	for _, path := range getIgnoredReplacements(step, patterns) {
+		fprintfIgnoreError(&buf, "%s%swarning:%s changes to %s are ignored, but still force this resource to be "+
+			"replaced\n", engine.GetIndentationString(indent+1), colors.SpecWarning, colors.Reset, path)
+	}
+	return buf.String()
+}
@@ -0,0 +1,89 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// legendDescriptions describes the meaning of the marker of each step op that may appear in a diff.
+var legendDescriptions = map[deploy.StepOp]string{
+	deploy.OpCreate:            "created, or property added",
+	deploy.OpUpdate:            "updated in place, or property changed",
+	deploy.OpDelete:            "deleted, or property removed",
+	deploy.OpReplace:           "replaced",
+	deploy.OpCreateReplacement: "replacement created",
+	deploy.OpDeleteReplaced:    "replaced resource deleted",
+	deploy.OpRead:              "read",
+	deploy.OpReadReplacement:   "read, replacing an existing resource",
+	deploy.OpRefresh:           "refreshed",
+	deploy.OpReadDiscard:       "discarded from the stack's state",
+	deploy.OpDiscardReplaced:   "replaced resource discarded from the stack's state",
+}
+
+// getLegendOps returns the set of step ops whose markers appear in the display of the given steps: the ops of the
+// resources that would be shown and the kinds of the changes to their properties.
+func getLegendOps(steps []engine.StepEventMetadata, opts Options) map[deploy.StepOp]bool {
+	ops := make(map[deploy.StepOp]bool)
+	for _, step := range steps {
+		if step.Op == deploy.OpSame || !shouldShow(step, opts) {
+			continue
+		}
+		ops[step.Op] = true
+
+		walkObjectDiff(getStepDiff(step, opts), func(path []interface{}, op deploy.StepOp,
+			old, new resource.PropertyValue) {
+
+			if op != deploy.OpSame {
+				ops[op] = true
+			}
+		})
+	}
+	return ops
+}
+
+// renderDiffLegend renders a legend that explains the markers that appear in the display of the given steps, e.g.
+//
+//	Legend:
+//	    ~ updated in place, or property changed
+//	    +-replaced
+//
+// Each marker is shown in its color. Markers that do not appear are omitted, and nothing is rendered unless the
+// options request it or if no markers appear. As the diff display is streamed, the legend is rendered along with the
+// summary, once the changes to all resources are known.
+func renderDiffLegend(steps []engine.StepEventMetadata, opts Options) string {
+	if !opts.ShowDiffLegend {
+		return ""
+	}
+	ops := getLegendOps(steps, opts)
+	if len(ops) == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	fprintfIgnoreError(&buf, "%sLegend:%s\n", colors.SpecHeadline, colors.Reset)
+	for _, op := range deploy.StepOps {
+		if description, ok := legendDescriptions[op]; ok && ops[op] {
+			fprintfIgnoreError(&buf, "    %s%s%s\n", op.Prefix(), description, colors.Reset)
+		}
+	}
+	fprintIgnoreError(&buf, "\n")
+	return buf.String()
+}
@@ -0,0 +1,148 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// diffLogRecord is a single property change in a diff log. Records follow the OpenTelemetry log data model, so that
+// they can be ingested by logging and tracing backends as they are.
+type diffLogRecord struct {
+	// Timestamp is the time at which the change was logged.
+	Timestamp time.Time `json:"timestamp"`
+	// SeverityText is the severity of the record, which is always INFO.
+	SeverityText string `json:"severityText"`
+	// Body describes the record.
+	Body string `json:"body"`
+	// Attributes describes the change.
+	Attributes diffLogAttributes `json:"attributes"`
+}
+
+// diffLogAttributes describes a single property change in a diff log.
+type diffLogAttributes struct {
+	// URN is the resource whose property changed.
+	URN resource.URN `json:"pulumi.resource.urn"`
+	// Op is the operation that the engine performs on the resource.
+	Op deploy.StepOp `json:"pulumi.resource.op"`
+	// Path is the path to the property that changed, e.g. spec.ports[2].
+	Path string `json:"pulumi.property.path"`
+	// Kind is the kind of the change, e.g. update-replace.
+	Kind string `json:"pulumi.property.kind"`
+	// Replace is true if the change forces the resource to be replaced.
+	Replace bool `json:"pulumi.property.replace"`
+	// Old is the property's old value, if values are logged and the property had one.
+	Old interface{} `json:"pulumi.property.old,omitempty"`
+	// New is the property's new value, if values are logged and the property has one.
+	New interface{} `json:"pulumi.property.new,omitempty"`
+}
+
+// recordDiffLog interposes on the given stream of engine events, writing a structured log record to the given path
+// for each property change of each step that the display would show. Records are written as the steps are announced,
+// one JSON object per line, so that the log can be followed while an update is in progress.
+func recordDiffLog(path string, events <-chan engine.Event, opts Options) <-chan engine.Event {
+	f, err := os.Create(path)
+	if err != nil {
+		warnDiffLog(errors.Wrap(err, "could not create diff log"), opts)
+		return events
+	}
+
+	out := make(chan engine.Event)
+	go func() {
+		defer contract.IgnoreClose(f)
+		for e := range events {
+			switch e.Type {
+			case engine.ResourcePreEvent:
+				step := e.Payload.(engine.ResourcePreEventPayload).Metadata
+				if err := writeDiffLog(f, step, time.Now(), opts); err != nil {
+					warnDiffLog(err, opts)
+				}
+			case engine.CancelEvent:
+				out <- e
+				return
+			}
+			out <- e
+		}
+		close(out)
+	}()
+	return out
+}
+
+// writeDiffLog writes a diff log record for each property change of the given step to the given writer, with the
+// given timestamp. Values are only written if the options request it, and secret values are never written.
+func writeDiffLog(w io.Writer, step engine.StepEventMetadata, timestamp time.Time, opts Options) error {
+	if step.Op == deploy.OpSame || !shouldShow(step, opts) {
+		return nil
+	}
+
+	var records []diffLogRecord
+	replacementPaths := getReplacementPaths(step)
+	walkObjectDiff(getStepDiff(step, opts), func(path []interface{}, op deploy.StepOp,
+		old, new resource.PropertyValue) {
+
+		replace := isReplacementPath(path, replacementPaths)
+		kind, changed := getChangeKind(op, replace)
+		if !changed {
+			return
+		}
+
+		attributes := diffLogAttributes{
+			URN:     step.URN,
+			Op:      step.Op,
+			Path:    engine.FormatPropertyPath(path),
+			Kind:    kind.String(),
+			Replace: replace,
+		}
+		if opts.DiffLogValues {
+			// serializeDiffValue replaces secrets with placeholders, as their values must never be logged.
+			if op != deploy.OpCreate {
+				attributes.Old = serializeDiffValue(old, false)
+			}
+			if op != deploy.OpDelete {
+				attributes.New = serializeDiffValue(new, false)
+			}
+		}
+		records = append(records, diffLogRecord{
+			Timestamp:    timestamp,
+			SeverityText: "INFO",
+			Body:         "property changed",
+			Attributes:   attributes,
+		})
+	})
+
+	enc := json.NewEncoder(w)
+	for i := range records {
+		if err := enc.Encode(&records[i]); err != nil {
+			return errors.Wrap(err, "could not write diff log")
+		}
+	}
+	return nil
+}
+
+// warnDiffLog reports the given diff log error as a warning, as such errors should not fail an update.
+func warnDiffLog(err error, opts Options) {
+	fprintfIgnoreError(os.Stderr, opts.Color.Colorize(colors.SpecWarning+"warning:"+colors.Reset+" %v\n"), err)
+}
@@ -0,0 +1,130 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+func TestDiffLog(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"size": "small", "zone": "a"})
+	olds["password"] = resource.MakeSecret(resource.NewStringProperty("hunter2"))
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"size": "large",
+		"zone": "b",
+		"tags": map[string]interface{}{"team": "web"},
+	})
+	news["password"] = resource.MakeSecret(resource.NewStringProperty("hunter3"))
+	step := makeUpdateStep(olds, news, nil)
+	step.Op, step.Keys = deploy.OpReplace, []resource.PropertyKey{"zone"}
+
+	readRecords := func(opts Options) []diffLogRecord {
+		var buf bytes.Buffer
+		assert.NoError(t, writeDiffLog(&buf, step, time.Unix(0, 0), opts))
+		assert.NotContains(t, buf.String(), "hunter")
+
+		var records []diffLogRecord
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var record diffLogRecord
+			assert.NoError(t, json.Unmarshal([]byte(line), &record))
+			records = append(records, record)
+		}
+		return records
+	}
+
+	// By default, records carry no values.
+	records := readRecords(Options{})
+	assert.Len(t, records, 4)
+	for _, record := range records {
+		assert.Equal(t, "INFO", record.SeverityText)
+		assert.Equal(t, step.URN, record.Attributes.URN)
+		assert.Equal(t, deploy.OpReplace, record.Attributes.Op)
+		assert.Nil(t, record.Attributes.Old)
+		assert.Nil(t, record.Attributes.New)
+	}
+	assert.Equal(t, "password", records[0].Attributes.Path)
+	assert.Equal(t, "update", records[0].Attributes.Kind)
+	assert.Equal(t, "size", records[1].Attributes.Path)
+	assert.Equal(t, "tags", records[2].Attributes.Path)
+	assert.Equal(t, "add", records[2].Attributes.Kind)
+	assert.Equal(t, "zone", records[3].Attributes.Path)
+	assert.Equal(t, "update-replace", records[3].Attributes.Kind)
+	assert.True(t, records[3].Attributes.Replace)
+
+	// Values may be included, but secrets never are.
+	records = readRecords(Options{DiffLogValues: true})
+	assert.Equal(t, "[secret]", records[0].Attributes.Old)
+	assert.Equal(t, "[secret]", records[0].Attributes.New)
+	assert.Equal(t, "small", records[1].Attributes.Old)
+	assert.Equal(t, "large", records[1].Attributes.New)
+	assert.Nil(t, records[2].Attributes.Old)
+	assert.Equal(t, map[string]interface{}{"team": "web"}, records[2].Attributes.New)
+}
+
+func TestRecordDiffLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diff-log")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "diff.jsonl")
+
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"debug": true, "replicas": 3})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{"owner": "ops", "replicas": 5})
+	detailedDiff := map[string]plugin.PropertyDiff{
+		"debug":    {Kind: plugin.DiffDelete},
+		"owner":    {Kind: plugin.DiffAdd},
+		"replicas": {Kind: plugin.DiffUpdate},
+	}
+	update := makeUpdateStep(olds, news, detailedDiff)
+	same := makeUpdateStep(olds, news, detailedDiff)
+	same.Op, same.URN = deploy.OpSame, same.URN+"-same"
+
+	events := make(chan engine.Event)
+	out := recordDiffLog(path, events, Options{})
+	go func() {
+		for _, step := range []engine.StepEventMetadata{update, same} {
+			events <- engine.Event{Type: engine.ResourcePreEvent, Payload: engine.ResourcePreEventPayload{Metadata: step}}
+		}
+		events <- engine.Event{Type: engine.CancelEvent}
+	}()
+
+	// All events, including the cancellation, must be forwarded.
+	var forwarded int
+	for e := range out {
+		forwarded++
+		if e.Type == engine.CancelEvent {
+			break
+		}
+	}
+	assert.Equal(t, 3, forwarded)
+
+	// Only the update's three property changes are logged.
+	contents, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Len(t, strings.Split(strings.TrimSpace(string(contents)), "\n"), 3)
+}
@@ -0,0 +1,83 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+)
+
+// maxCachedDiffPaths is the number of parsed property paths retained by parsedDiffPaths. Resources of the same type
+// tend to report changes to the same paths, so a modest cache covers most of the paths in even a large update.
+const maxCachedDiffPaths = 4096
+
+// parsedDiffPaths caches the paths parsed while translating detailed diffs, which may be translated concurrently.
+var parsedDiffPaths = newDiffPathCache(maxCachedDiffPaths)
+
+// diffPathCache is a concurrency-safe cache of parsed property paths, keyed by the raw path, that evicts its least
+// recently used entry once it is full. Only paths that parse successfully are cached.
+type diffPathCache struct {
+	m        sync.Mutex
+	capacity int
+	entries  map[string]*list.Element // the cache's entries, keyed by raw path.
+	lru      *list.List               // the cache's entries, from most to least recently used.
+}
+
+type diffPathCacheEntry struct {
+	path     string
+	elements []interface{}
+}
+
+func newDiffPathCache(capacity int) *diffPathCache {
+	return &diffPathCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// parse returns the elements of the given path as parsed by engine.ParsePropertyPath. The returned slice is shared with
+// other callers and must not be modified.
+func (c *diffPathCache) parse(path string) ([]interface{}, error) {
+	c.m.Lock()
+	if e, has := c.entries[path]; has {
+		c.lru.MoveToFront(e)
+		elements := e.Value.(*diffPathCacheEntry).elements
+		c.m.Unlock()
+		return elements, nil
+	}
+	c.m.Unlock()
+
+	elements, err := engine.ParsePropertyPath(path)
+	if err != nil {
+		return nil, err
+	}
+	// Cap the slice so that appending to it never writes to the cached array.
+	elements = elements[:len(elements):len(elements)]
+
+	c.m.Lock()
+	defer c.m.Unlock()
+	if _, has := c.entries[path]; !has {
+		c.entries[path] = c.lru.PushFront(&diffPathCacheEntry{path: path, elements: elements})
+		if c.lru.Len() > c.capacity {
+			oldest := c.lru.Back()
+			c.lru.Remove(oldest)
+			delete(c.entries, oldest.Value.(*diffPathCacheEntry).path)
+		}
+	}
+	return elements, nil
+}
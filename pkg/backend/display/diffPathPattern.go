@@ -0,0 +1,61 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+)
+
+// diffPathPattern matches property paths. Patterns are written using the same grammar as the property paths in
+// detailed diffs (see engine.ParsePropertyPath), with two wildcards: a "*" element matches any single path element,
+// and a "**" element matches any sequence of zero or more path elements. Wildcards match array indices as well as
+// property names, but must be written as names (e.g. "volumes.*" rather than "volumes[*]"). For example, "*.sizeBytes"
+// matches "disk.sizeBytes", and "**.sizeBytes" additionally matches "sizeBytes" and "spec.volumes[0].sizeBytes".
+type diffPathPattern []interface{}
+
+// parseDiffPathPattern parses the given property path pattern.
+func parseDiffPathPattern(pattern string) (diffPathPattern, error) {
+	elements, err := engine.ParsePropertyPath(pattern)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid property path pattern %q", pattern)
+	}
+	if len(elements) == 0 {
+		return nil, errors.New("property path patterns must not be empty")
+	}
+	return diffPathPattern(elements), nil
+}
+
+// matches returns true if the given property path matches the pattern.
+func (p diffPathPattern) matches(path []interface{}) bool {
+	if len(p) == 0 {
+		return len(path) == 0
+	}
+
+	switch p[0] {
+	case "**":
+		for i := 0; i <= len(path); i++ {
+			if p[1:].matches(path[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(path) > 0 && p[1:].matches(path[1:])
+	default:
+		return len(path) > 0 && p[0] == path[0] && p[1:].matches(path[1:])
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// renderDiffPaths renders only the paths of the changed properties of the given step, without their values, if the
+// options request it. Each path is prefixed by the marker of its kind of change, e.g.
+//
+//	~ spec.replicas
+//	+ spec.ports[2]
+//	- spec.labels.tier
+//	+-zone
+//
+// Paths are rendered in the order visited by walkObjectDiff. Steps whose properties are not rendered as a diff, e.g.
+// creates and deletes, render no paths, as the markers of their resources already describe them. The second result
+// is false if paths were not requested, in which case the caller should render the step's properties as usual.
+func renderDiffPaths(payload engine.ResourcePreEventPayload, indent int, opts Options) (string, bool) {
+	if !opts.DiffPathsOnly {
+		return "", false
+	}
+
+	diff, include, indent := getRenderedDiff(payload.Metadata, indent, opts)
+	if diff == nil {
+		return "", true
+	}
+
+	// Paths are aligned with the properties that a full diff would render.
+	indentation := engine.GetIndentationString(indent + 1)
+	includeSet := makeIncludeSet(include)
+	replacementPaths := getReplacementPaths(payload.Metadata)
+
+	var buf bytes.Buffer
+	walkObjectDiff(diff, func(path []interface{}, op deploy.StepOp, _, _ resource.PropertyValue) {
+		if includeSet != nil && !includeSet[resource.PropertyKey(path[0].(string))] {
+			return
+		}
+		kind, changed := getChangeKind(op, isReplacementPath(path, replacementPaths))
+		if !changed {
+			return
+		}
+
+		op = getChangeKindOp(kind)
+		fprintfIgnoreError(&buf, "%s%s%s%s%s\n", op.Color(), indentation[:len(indentation)-2], op.RawPrefix(),
+			engine.FormatPropertyPath(path), colors.Reset)
+	})
+	return buf.String(), true
+}
@@ -0,0 +1,87 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/dustin/go-humanize/english"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// renderSummarizedDiff renders a compact summary of the properties of the given step if its diff has more changes
+// than the options' summary threshold, e.g.
+//
+//	~ 1,204 changes: 3 added, 1 deleted, 1,200 updated
+//	  in data, metadata
+//	  (use --diff-summary-threshold 0 for full detail)
+//
+// The second result is false if the step's diff is within the threshold, in which case the caller should render the
+// step's properties as usual.
+func renderSummarizedDiff(payload engine.ResourcePreEventPayload, indent int, opts Options) (string, bool) {
+	if opts.DiffSummaryThreshold <= 0 {
+		return "", false
+	}
+
+	diff, include, indent := getRenderedDiff(payload.Metadata, indent, opts)
+	if diff == nil {
+		return "", false
+	}
+
+	includeSet := makeIncludeSet(include)
+	var stats diffStats
+	var keys []string
+	walkObjectDiff(diff, func(path []interface{}, op deploy.StepOp, _, _ resource.PropertyValue) {
+		key := path[0].(string)
+		if op == deploy.OpSame || includeSet != nil && !includeSet[resource.PropertyKey(key)] {
+			return
+		}
+		switch op {
+		case deploy.OpCreate:
+			stats.Adds++
+		case deploy.OpDelete:
+			stats.Deletes++
+		default:
+			stats.Updates++
+		}
+		if len(keys) == 0 || keys[len(keys)-1] != key {
+			keys = append(keys, key)
+		}
+	})
+	changes := stats.Changes()
+	if changes <= opts.DiffSummaryThreshold {
+		return "", false
+	}
+
+	indentation := engine.GetIndentationString(indent)
+	prefix := indentation[:len(indentation)-2] + deploy.OpUpdate.RawPrefix()
+
+	var buf bytes.Buffer
+	fprintfIgnoreError(&buf, "%s%s%s %s: %s added, %s deleted, %s updated%s\n", deploy.OpUpdate.Color(), prefix,
+		humanize.Comma(int64(changes)), english.PluralWord(changes, "change", "changes"),
+		humanize.Comma(int64(stats.Adds)), humanize.Comma(int64(stats.Deletes)), humanize.Comma(int64(stats.Updates)),
+		colors.Reset)
+	fprintfIgnoreError(&buf, "%s%sin %s%s\n", deploy.OpSame.Color(), indentation, strings.Join(keys, ", "),
+		colors.Reset)
+	fprintfIgnoreError(&buf, "%s%s(use --diff-summary-threshold 0 for full detail)%s\n", deploy.OpSame.Color(),
+		indentation, colors.Reset)
+	return buf.String(), true
+}
@@ -0,0 +1,177 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"sync"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// diffTranslator translates the detailed diffs of steps ahead of their display using a bounded pool of workers, and
+// caches the results until the steps have been rendered so that each step's detailed diff is translated only once.
+//
+// Translating a detailed diff is a pure function of the step, so translations may safely run concurrently with each
+// other and with the display. The display itself remains serial, so the order of its output is unaffected.
+type diffTranslator struct {
+	workers     chan struct{} // a semaphore that bounds the number of concurrent translations.
+	trustKinds  bool          // true to trust the kinds that providers report at every level of detailed diffs.
+	matchCasing bool          // true to match properties that were renamed to a different casing convention.
+
+	m       sync.Mutex
+	results map[diffTranslationKey]*diffTranslation
+}
+
+// diffTranslationKey identifies the step that a translation belongs to. An update performs at most one step of each
+// kind on each resource, so steps are identified by their resources' URNs and their operations.
+type diffTranslationKey struct {
+	urn resource.URN
+	op  deploy.StepOp
+}
+
+func getDiffTranslationKey(step engine.StepEventMetadata) diffTranslationKey {
+	return diffTranslationKey{urn: step.URN, op: step.Op}
+}
+
+// diffTranslation is the result of translating a step's detailed diff. The diff is only valid once done is closed.
+type diffTranslation struct {
+	done chan struct{}
+	diff *resource.ObjectDiff
+}
+
+func newDiffTranslator(workers int, trustKinds, matchCasing bool) *diffTranslator {
+	if workers < 1 {
+		workers = 1
+	}
+	return &diffTranslator{
+		workers:     make(chan struct{}, workers),
+		trustKinds:  trustKinds,
+		matchCasing: matchCasing,
+		results:     make(map[diffTranslationKey]*diffTranslation),
+	}
+}
+
+// lookup returns the translation of the given step's detailed diff and true if its translation has already begun.
+// Otherwise, if record is true, it records a pending translation for the step and returns it along with false, in which
+// case the caller is responsible for completing it.
+func (t *diffTranslator) lookup(step engine.StepEventMetadata, record bool) (*diffTranslation, bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	key := getDiffTranslationKey(step)
+	if result, has := t.results[key]; has {
+		return result, true
+	}
+	result := &diffTranslation{done: make(chan struct{})}
+	if record {
+		t.results[key] = result
+	}
+	return result, false
+}
+
+// evict discards the translation of the given step's detailed diff, if any. Steps are evicted once they have been
+// rendered so that the translator's memory does not grow with the size of the update.
+func (t *diffTranslator) evict(step engine.StepEventMetadata) {
+	if t == nil {
+		return
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+	delete(t.results, getDiffTranslationKey(step))
+}
+
+// start begins translating the given step's detailed diff in the background, if it has one and its translation has
+// not already begun. start blocks while all of the translator's workers are busy.
+func (t *diffTranslator) start(step engine.StepEventMetadata) {
+	if t == nil || step.DetailedDiff == nil || step.New == nil {
+		return
+	}
+
+	result, started := t.lookup(step, true)
+	if started {
+		return
+	}
+
+	t.workers <- struct{}{}
+	go func() {
+		defer func() { <-t.workers }()
+
+		result.diff = translateDetailedDiff(step, t.trustKinds, t.matchCasing)
+		close(result.done)
+	}()
+}
+
+// translate returns the translation of the given step's detailed diff, waiting for it to complete if it is being
+// translated in the background. If the step's translation has not begun, e.g. because the step has been evicted, it is
+// translated immediately and not cached. A nil translator translates each step's detailed diff on demand, inferring
+// the kinds of properties as usual.
+func (t *diffTranslator) translate(step engine.StepEventMetadata) *resource.ObjectDiff {
+	if t == nil || step.New == nil {
+		return translateDetailedDiff(step, t != nil && t.trustKinds, t != nil && t.matchCasing)
+	}
+
+	result, started := t.lookup(step, false)
+	if !started {
+		result.diff = translateDetailedDiff(step, t.trustKinds, t.matchCasing)
+		close(result.done)
+	}
+	<-result.done
+	return result.diff
+}
+
+// translateStepDiff returns the translation of the given step's detailed diff under the given options, by way of the
+// options' translator if the display has one. The unchanged elements of the translation's arrays are recorded, so that
+// they are displayed with their values.
+func translateStepDiff(step engine.StepEventMetadata, opts Options) *resource.ObjectDiff {
+	var diff *resource.ObjectDiff
+	if opts.translator == nil {
+		diff = translateDetailedDiff(step, opts.TrustDetailedDiffKinds, opts.MatchKeyCasing)
+	} else {
+		diff = opts.translator.translate(step)
+	}
+
+	var oldOutputs, oldInputs, news resource.PropertyMap
+	if step.Old != nil {
+		oldOutputs, oldInputs = step.Old.Outputs, step.Old.Inputs
+	}
+	if step.New != nil {
+		news = step.New.Inputs
+	}
+	return recordUnchangedElements(diff, oldOutputs, oldInputs, news)
+}
+
+// prefetchDiffs forwards the events in the given channel to the returned channel, beginning the translation of the
+// detailed diff of each resource step as it passes. The returned channel is buffered so that translations may run
+// ahead of the display. Forwarding stops after a cancellation event.
+func prefetchDiffs(events <-chan engine.Event, t *diffTranslator) <-chan engine.Event {
+	prefetched := make(chan engine.Event, 2*cap(t.workers))
+	go func() {
+		for {
+			event := <-events
+			if event.Type == engine.ResourcePreEvent {
+				t.start(event.Payload.(engine.ResourcePreEventPayload).Metadata)
+			}
+
+			prefetched <- event
+			if event.Type == engine.CancelEvent {
+				return
+			}
+		}
+	}()
+	return prefetched
+}
@@ -0,0 +1,141 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+// largeDiffStep returns an update step whose detailed diff updates the given number of nested properties.
+func largeDiffStep(properties int) engine.StepEventMetadata {
+	olds, news := resource.PropertyMap{}, resource.PropertyMap{}
+	detailedDiff := make(map[string]plugin.PropertyDiff)
+	for i := 0; i < properties; i++ {
+		key := resource.PropertyKey(fmt.Sprintf("prop%d", i))
+		olds[key] = resource.NewPropertyValue(map[string]interface{}{"value": i, "tags": []interface{}{"a", "b"}})
+		news[key] = resource.NewPropertyValue(map[string]interface{}{"value": i + 1, "tags": []interface{}{"a", "c"}})
+		detailedDiff[fmt.Sprintf("prop%d.value", i)] = plugin.PropertyDiff{Kind: plugin.DiffUpdate}
+		detailedDiff[fmt.Sprintf("prop%d.tags[1]", i)] = plugin.PropertyDiff{Kind: plugin.DiffUpdate}
+	}
+	step := makeUpdateStep(olds, news, detailedDiff)
+	step.URN = resource.NewURN("stack", "project", "", "pkg:index:Service", tokens.QName(fmt.Sprintf("web%d", properties)))
+	return step
+}
+
+func TestDiffTranslator(t *testing.T) {
+	steps := make([]engine.StepEventMetadata, 64)
+	for i := range steps {
+		steps[i] = largeDiffStep(i + 1)
+	}
+
+	translator := newDiffTranslator(4, false, false)
+	events := make(chan engine.Event)
+	prefetched := prefetchDiffs(events, translator)
+	go func() {
+		for _, step := range steps {
+			events <- engine.Event{Type: engine.ResourcePreEvent, Payload: engine.ResourcePreEventPayload{Metadata: step}}
+		}
+		events <- engine.Event{Type: engine.CancelEvent}
+	}()
+
+	// Events are forwarded in order.
+	for _, step := range steps {
+		event := <-prefetched
+		assert.Equal(t, step.URN, event.Payload.(engine.ResourcePreEventPayload).Metadata.URN)
+	}
+	assert.Equal(t, engine.CancelEvent, (<-prefetched).Type)
+
+	// Translations may be requested concurrently, and match those made directly.
+	var wg sync.WaitGroup
+	for _, step := range steps {
+		wg.Add(1)
+		go func(step engine.StepEventMetadata) {
+			defer wg.Done()
+			assert.Equal(t, translateDetailedDiff(step, false, false), translator.translate(step))
+		}(step)
+	}
+	wg.Wait()
+
+	// Each step is translated once.
+	for _, step := range steps {
+		assert.True(t, translator.translate(step) == translator.translate(step))
+	}
+
+	// Steps are cached by URN and operation until they are evicted.
+	for _, step := range steps {
+		translator.evict(step)
+	}
+	assert.Empty(t, translator.results)
+
+	// Steps that were never started or have been evicted are translated on demand without being cached, as are all
+	// steps when there is no translator.
+	step := steps[2]
+	assert.Equal(t, translateDetailedDiff(step, false, false), translator.translate(step))
+	assert.Empty(t, translator.results)
+	var none *diffTranslator
+	assert.Equal(t, translateDetailedDiff(step, false, false), none.translate(step))
+
+	// Rendering a step evicts its translation.
+	translator.start(step)
+	assert.Len(t, translator.results, 1)
+	renderStepDiff(step, Options{Color: colors.Never, Type: DisplayDiff, translator: translator})
+	assert.Empty(t, translator.results)
+
+	// The progress display's rows take their translations from the translator, and keep them once they are evicted.
+	translator.start(step)
+	translation := translator.translate(step)
+	row := &resourceRowData{display: &ProgressDisplay{opts: Options{translator: translator}}}
+	assert.True(t, row.translateDetailedDiff(step) == translation)
+	assert.Empty(t, translator.results)
+	assert.True(t, row.translateDetailedDiff(step) == translation)
+}
+
+func BenchmarkDiffTranslation(b *testing.B) {
+	steps := make([]engine.StepEventMetadata, 256)
+	for i := range steps {
+		steps[i] = largeDiffStep(200)
+	}
+
+	b.Run("serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, step := range steps {
+				translateDetailedDiff(step, false, false)
+			}
+		}
+	})
+
+	b.Run("prefetched", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			translator := newDiffTranslator(runtime.NumCPU(), false, false)
+			for _, step := range steps {
+				translator.start(step)
+			}
+			for _, step := range steps {
+				translator.translate(step)
+			}
+		}
+	})
+}
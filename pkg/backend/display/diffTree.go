@@ -0,0 +1,328 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize/english"
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// DiffTreeStyle selects the lines that connect the nested properties of a diff to their parents.
+type DiffTreeStyle string
+
+const (
+	// DiffTreeNone nests properties using indentation alone.
+	DiffTreeNone DiffTreeStyle = "none"
+	// DiffTreeASCII connects nested properties using the ASCII characters '|', '+', and '-'.
+	DiffTreeASCII DiffTreeStyle = "ascii"
+	// DiffTreeUnicode connects nested properties using Unicode box-drawing characters.
+	DiffTreeUnicode DiffTreeStyle = "unicode"
+)
+
+// ParseDiffTreeStyle parses the name of a diff tree style. The empty string selects the default rendering of diffs.
+func ParseDiffTreeStyle(name string) (DiffTreeStyle, error) {
+	switch style := DiffTreeStyle(name); style {
+	case "", DiffTreeNone, DiffTreeASCII, DiffTreeUnicode:
+		return style, nil
+	default:
+		return "", errors.Errorf("unknown diff tree style %q (expected one of none, ascii, or unicode)", name)
+	}
+}
+
+// defaultDiffIndentWidth is the number of columns per level of nesting in diffs rendered as trees when no indent
+// width is configured. It matches the indentation of the default rendering.
+const defaultDiffIndentWidth = 4
+
+// usesDiffTree returns true if the given options customize the layout of diffs, in which case diffs are rendered by
+// printDiffTree rather than by the engine.
+func usesDiffTree(opts Options) bool {
+	return opts.DiffIndentWidth > 0 || opts.DiffTreeStyle != ""
+}
+
+// printObjectDiff prints the given diff in the layout selected by the given options.
+func printObjectDiff(b *bytes.Buffer, diff resource.ObjectDiff, include []resource.PropertyKey, planning bool,
+	indent int, debug bool, opts Options) {
+
+	render := getPropertyRenderer(&diff, opts)
+	if usesDiffTree(opts) {
+		printDiffTree(b, diff, include, planning, indent, render, opts)
+	} else {
+		engine.PrintObjectDiff(b, diff, include, planning, indent, opts.SummaryDiff, debug, render)
+	}
+}
+
+// diffTreeGlyphs are the strings that precede a property in a diff tree, one for each of its ancestors and one for the
+// property itself. Each is as wide as one level of indentation.
+type diffTreeGlyphs struct {
+	branch   string // precedes a property that has later siblings.
+	last     string // precedes a property that is the last of its siblings.
+	vertical string // precedes the descendants of an ancestor that has later siblings.
+	blank    string // precedes the descendants of an ancestor that is the last of its siblings.
+}
+
+func getDiffTreeGlyphs(opts Options) diffTreeGlyphs {
+	width := opts.DiffIndentWidth
+	if width <= 0 {
+		width = defaultDiffIndentWidth
+	}
+
+	var vertical, branch, last, horizontal string
+	switch opts.DiffTreeStyle {
+	case DiffTreeASCII:
+		vertical, branch, last, horizontal = "|", "+", "+", "-"
+	case DiffTreeUnicode:
+		vertical, branch, last, horizontal = "│", "├", "└", "─"
+	default:
+		blank := strings.Repeat(" ", width)
+		return diffTreeGlyphs{branch: blank, last: blank, vertical: blank, blank: blank}
+	}
+
+	// Connecting lines need room for a corner, a horizontal line, and a space.
+	if width < 3 {
+		width = 3
+	}
+	line := strings.Repeat(horizontal, width-2) + " "
+	return diffTreeGlyphs{
+		branch:   branch + line,
+		last:     last + line,
+		vertical: vertical + strings.Repeat(" ", width-1),
+		blank:    strings.Repeat(" ", width),
+	}
+}
+
+// diffTreeNode is a property in a diff tree. Arrays and objects that are not empty are represented by their children;
+// all other values are formatted as the node's text.
+type diffTreeNode struct {
+	key      string
+	op       deploy.StepOp
+	text     string
+	children []diffTreeNode
+	note     bool // true if the node notes elided siblings, in which case it has text but no key.
+}
+
+// printDiffTree prints the given diff as a tree in which each property is printed on its own line beneath its parent.
+// Properties are connected to their parents using the given options' tree style and indented by their indent width.
+// If the given renderer is non-nil, it is consulted for the display text of each scalar value.
+func printDiffTree(b *bytes.Buffer, diff resource.ObjectDiff, include []resource.PropertyKey, planning bool,
+	indent int, render engine.PropertyRenderer, opts Options) {
+
+	p := &diffTreePrinter{b: b, planning: planning, summary: opts.SummaryDiff, glyphs: getDiffTreeGlyphs(opts),
+		width: opts.DiffWidth, render: render}
+
+	includeSet := make(map[resource.PropertyKey]bool)
+	for _, k := range include {
+		includeSet[k] = true
+	}
+	var roots []diffTreeNode
+	for _, node := range p.objectDiffNodes(nil, &diff) {
+		if include == nil || includeSet[resource.PropertyKey(node.key)] {
+			roots = append(roots, node)
+		}
+	}
+	p.printNodes(engine.GetIndentationString(indent+1), roots)
+}
+
+type diffTreePrinter struct {
+	b        *bytes.Buffer
+	planning bool
+	summary  bool
+	glyphs   diffTreeGlyphs
+	width    int                     // if positive, the width at which long lines are wrapped.
+	render   engine.PropertyRenderer // if non-nil, renders scalar values.
+}
+
+func (p *diffTreePrinter) objectDiffNodes(path []interface{}, diff *resource.ObjectDiff) []diffTreeNode {
+	var nodes []diffTreeNode
+	elided := 0
+	for _, k := range diff.Keys() {
+		if diff.Elided[k] && diff.Same(k) {
+			elided++
+			continue
+		}
+		nodes = p.appendElidedNode(nodes, elided, "property", "properties")
+		elided = 0
+
+		elementPath := appendPath(path, diff.Path(k)...)
+		if add, isAdd := diff.Adds[k]; isAdd {
+			nodes = append(nodes, p.valueNode(elementPath, diff.Label(k), deploy.OpCreate, add))
+		} else if delete, isDelete := diff.Deletes[k]; isDelete {
+			nodes = append(nodes, p.valueNode(elementPath, diff.Label(k), deploy.OpDelete, delete))
+		} else if update, isUpdate := diff.Updates[k]; isUpdate {
+			nodes = append(nodes, p.valueDiffNode(elementPath, diff.Label(k), update))
+		} else if !p.summary {
+			nodes = append(nodes, p.valueNode(elementPath, diff.Label(k), deploy.OpSame, diff.Sames[k]))
+		}
+	}
+	return p.appendElidedNode(nodes, elided, "property", "properties")
+}
+
+func (p *diffTreePrinter) arrayDiffNodes(path []interface{}, diff *resource.ArrayDiff) []diffTreeNode {
+	var nodes []diffTreeNode
+	elided, omitted := 0, 0
+	for _, i := range arrayDiffIndices(diff) {
+		if diff.Omitted[i] {
+			nodes = p.appendElidedNode(nodes, elided, "element", "elements")
+			elided = 0
+			if _, same := diff.Sames[i]; !same {
+				omitted++
+			}
+			continue
+		}
+		nodes = appendOmittedNode(nodes, omitted)
+		omitted = 0
+		if _, same := diff.Sames[i]; same && diff.Elided[i] {
+			elided++
+			continue
+		}
+		nodes = p.appendElidedNode(nodes, elided, "element", "elements")
+		elided = 0
+
+		key, elementPath := diff.Label(i), appendPath(path, diff.Index(i))
+		if add, isAdd := diff.Adds[i]; isAdd {
+			nodes = append(nodes, p.valueNode(elementPath, key, deploy.OpCreate, add))
+		} else if delete, isDelete := diff.Deletes[i]; isDelete {
+			nodes = append(nodes, p.valueNode(elementPath, key, deploy.OpDelete, delete))
+		} else if update, isUpdate := diff.Updates[i]; isUpdate {
+			nodes = append(nodes, p.valueDiffNode(elementPath, key, update))
+		} else if !p.summary {
+			nodes = append(nodes, p.valueNode(elementPath, key, deploy.OpSame, diff.Sames[i]))
+		}
+	}
+	nodes = appendOmittedNode(nodes, omitted)
+	return p.appendElidedNode(nodes, elided, "element", "elements")
+}
+
+// appendElidedNode appends a note that counts the given number of consecutive elided sames to the given nodes, unless
+// no sames were elided or the diff is summarized.
+func (p *diffTreePrinter) appendElidedNode(nodes []diffTreeNode, count int, singular, plural string) []diffTreeNode {
+	if count == 0 || p.summary {
+		return nodes
+	}
+	text := "... " + english.Plural(count, "unchanged "+singular, "unchanged "+plural)
+	return append(nodes, diffTreeNode{op: deploy.OpSame, text: text, note: true})
+}
+
+// appendOmittedNode appends a note that counts the given number of changes among a run of omitted array elements to the
+// given nodes, unless none of the omitted elements changed.
+func appendOmittedNode(nodes []diffTreeNode, count int) []diffTreeNode {
+	if count == 0 {
+		return nodes
+	}
+	return append(nodes, diffTreeNode{op: deploy.OpSame, text: fmt.Sprintf("… (%d more)", count), note: true})
+}
+
+func (p *diffTreePrinter) valueDiffNode(path []interface{}, key string, diff resource.ValueDiff) diffTreeNode {
+	node := diffTreeNode{key: key, op: deploy.OpUpdate}
+	switch {
+	case diff.Array != nil:
+		node.children = p.arrayDiffNodes(path, diff.Array)
+	case diff.Object != nil:
+		node.children = p.objectDiffNodes(path, diff.Object)
+	default:
+		if text, ok := engine.FormatSecretTransition(diff.Old, diff.New); ok {
+			node.text = text
+		} else {
+			node.text = p.formatValue(path, diff.Old) + " => " + p.formatValue(path, diff.New)
+		}
+	}
+	return node
+}
+
+// valueNode returns the node for a property that is added, deleted, or unchanged as a whole. The elements of arrays and
+// objects are expanded into child nodes with the same operation.
+func (p *diffTreePrinter) valueNode(path []interface{}, key string, op deploy.StepOp,
+	v resource.PropertyValue) diffTreeNode {
+
+	node := diffTreeNode{key: key, op: op}
+	switch {
+	case v.IsArray() && len(v.ArrayValue()) > 0:
+		for i, elem := range v.ArrayValue() {
+			node.children = append(node.children, p.valueNode(appendPath(path, i), fmt.Sprintf("[%d]", i), op, elem))
+		}
+	case v.IsObject() && len(v.ObjectValue()) > 0:
+		for _, k := range v.ObjectValue().StableKeys() {
+			node.children = append(node.children,
+				p.valueNode(appendPath(path, string(k)), string(k), op, v.ObjectValue()[k]))
+		}
+	default:
+		node.text = p.formatValue(path, v)
+	}
+	return node
+}
+
+// formatValue formats the scalar value or empty array or object at the given path.
+func (p *diffTreePrinter) formatValue(path []interface{}, v resource.PropertyValue) string {
+	if p.render != nil {
+		if text, ok := p.render(path, v); ok {
+			return text
+		}
+	}
+	switch {
+	case v.IsArray() && len(v.ArrayValue()) == 0:
+		return "[]"
+	case v.IsObject() && len(v.ObjectValue()) == 0:
+		return "{}"
+	default:
+		return engine.FormatPropertyValue(v, p.planning)
+	}
+}
+
+func (p *diffTreePrinter) printNodes(prefix string, nodes []diffTreeNode) {
+	for i, node := range nodes {
+		glyph, childPrefix := p.glyphs.branch, prefix+p.glyphs.vertical
+		if i == len(nodes)-1 {
+			glyph, childPrefix = p.glyphs.last, prefix+p.glyphs.blank
+		}
+
+		if node.note {
+			head := prefix + glyph + node.op.Prefix()
+			p.printLine(head+node.text+colors.Reset, head, childPrefix)
+			continue
+		}
+
+		head := prefix + glyph + node.op.Prefix() + node.key + ":"
+		if node.text == "" {
+			p.printLine(head+colors.Reset, head, childPrefix)
+		} else {
+			p.printLine(head+" "+node.text+colors.Reset, head+" ", childPrefix)
+		}
+		p.printNodes(childPrefix, node.children)
+	}
+}
+
+// printLine prints the given line of a node whose text follows the given head. If the line is too wide, it is wrapped
+// so that its continuation lines follow the given prefix of the node's children, which continues the lines that
+// connect the node's later siblings, and are aligned with the start of the node's text.
+func (p *diffTreePrinter) printLine(line, head, childPrefix string) {
+	column := utf8.RuneCountInString(stripColorCommands(head))
+	indent := utf8.RuneCountInString(stripColorCommands(childPrefix))
+	if p.width-column < minDiffWrapWidth {
+		column = indent
+	}
+	continuation := childPrefix + strings.Repeat(" ", column-indent)
+	for _, l := range wrapDiffLine(line, p.width, column, continuation) {
+		fprintfIgnoreError(p.b, "%s\n", l)
+	}
+}
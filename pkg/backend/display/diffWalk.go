@@ -0,0 +1,155 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// diffLeafVisitor is called for each leaf of a diff. The path is the sequence of property keys (strings) and array
+// indices (ints) that leads to the leaf, and op describes the change: OpCreate for adds, OpDelete for deletes,
+// OpUpdate for updates, and OpSame for sames. Adds carry only a new value and deletes carry only an old value.
+type diffLeafVisitor func(path []interface{}, op deploy.StepOp, old, new resource.PropertyValue)
+
+// walkObjectDiff visits each leaf of the given object diff in a stable order: object properties are visited in key
+// order and array elements in the order of their positions, though their paths hold their indices. Updates that carry
+// a nested array or object diff are not themselves visited; instead, their nested leaves are.
+func walkObjectDiff(diff *resource.ObjectDiff, visit diffLeafVisitor) {
+	if diff != nil {
+		walkObjectDiffAt(nil, diff, visit)
+	}
+}
+
+func walkObjectDiffAt(path []interface{}, diff *resource.ObjectDiff, visit diffLeafVisitor) {
+	for _, k := range diff.Keys() {
+		elementPath := appendPath(path, diff.Path(k)...)
+		if add, isadd := diff.Adds[k]; isadd {
+			visit(elementPath, deploy.OpCreate, resource.PropertyValue{}, add)
+		} else if delete, isdelete := diff.Deletes[k]; isdelete {
+			visit(elementPath, deploy.OpDelete, delete, resource.PropertyValue{})
+		} else if update, isupdate := diff.Updates[k]; isupdate {
+			walkValueDiffAt(elementPath, update, visit)
+		} else {
+			visit(elementPath, deploy.OpSame, diff.Sames[k], diff.Sames[k])
+		}
+	}
+}
+
+func walkArrayDiffAt(path []interface{}, diff *resource.ArrayDiff, visit diffLeafVisitor) {
+	for i := 0; i < diff.Positions(); i++ {
+		elementPath := appendPath(path, diff.Index(i))
+		if add, isadd := diff.Adds[i]; isadd {
+			visit(elementPath, deploy.OpCreate, resource.PropertyValue{}, add)
+		} else if delete, isdelete := diff.Deletes[i]; isdelete {
+			visit(elementPath, deploy.OpDelete, delete, resource.PropertyValue{})
+		} else if update, isupdate := diff.Updates[i]; isupdate {
+			walkValueDiffAt(elementPath, update, visit)
+		} else if same, issame := diff.Sames[i]; issame {
+			visit(elementPath, deploy.OpSame, same, same)
+		}
+	}
+}
+
+func walkValueDiffAt(path []interface{}, diff resource.ValueDiff, visit diffLeafVisitor) {
+	switch {
+	case diff.Array != nil:
+		walkArrayDiffAt(path, diff.Array, visit)
+	case diff.Object != nil:
+		walkObjectDiffAt(path, diff.Object, visit)
+	default:
+		visit(path, deploy.OpUpdate, diff.Old, diff.New)
+	}
+}
+
+// appendPath returns a copy of the given path with the given elements appended. A copy is made so that paths handed
+// to visitors may be retained without being clobbered by subsequent siblings.
+func appendPath(path []interface{}, elements ...interface{}) []interface{} {
+	result := make([]interface{}, len(path), len(path)+len(elements))
+	copy(result, path)
+	return append(result, elements...)
+}
+
+// diffStats summarizes the property-level changes recorded by a diff.
+type diffStats struct {
+	Adds    int // the number of properties added.
+	Deletes int // the number of properties deleted.
+	Updates int // the number of properties updated.
+}
+
+// Changes returns the total number of property-level changes.
+func (stats diffStats) Changes() int {
+	return stats.Adds + stats.Deletes + stats.Updates
+}
+
+// getDiffStats counts the changed leaves of the given diff. Sames are not counted.
+func getDiffStats(diff *resource.ObjectDiff) diffStats {
+	var stats diffStats
+	walkObjectDiff(diff, func(_ []interface{}, op deploy.StepOp, _, _ resource.PropertyValue) {
+		switch op {
+		case deploy.OpCreate:
+			stats.Adds++
+		case deploy.OpDelete:
+			stats.Deletes++
+		case deploy.OpUpdate:
+			stats.Updates++
+		}
+	})
+	return stats
+}
+
+// getStepDiff returns the property diff for the given step, preferring the provider's detailed diff if one is
+// available. JSON-encoded strings are diffed structurally, unordered arrays are diffed as multisets, and changes at
+// ignored property paths are omitted. It returns nil if the step has no old or new state or if no changes were found.
+func getStepDiff(step engine.StepEventMetadata, opts Options) *resource.ObjectDiff {
+	if step.Old == nil || step.New == nil {
+		return nil
+	}
+
+	var diff *resource.ObjectDiff
+	if step.DetailedDiff != nil {
+		diff = diffJSONStrings(translateStepDiff(step, opts), getJSONStringPaths(opts))
+		diff = diffUnorderedArrays(diff, step.Old.Outputs, step.New.Inputs, getUnorderedArrayPaths(opts),
+			getValueEquality(opts))
+		diff = diffEqualValues(diff, step.Old.Outputs, step.New.Inputs, getCustomValueEquality(opts))
+	} else {
+		diff = step.Old.Inputs.Diff(step.New.Inputs, engine.IsInternalPropertyKey)
+		if opts.AlignArrays {
+			diff = alignDiffArrays(diff, step.Old.Inputs, step.New.Inputs, getTupleArrayPaths(opts))
+		}
+		diff = diffJSONStrings(diff, getJSONStringPaths(opts))
+		diff = diffUnorderedArrays(diff, step.Old.Inputs, step.New.Inputs, getUnorderedArrayPaths(opts),
+			getValueEquality(opts))
+		diff = diffEqualValues(diff, step.Old.Inputs, step.New.Inputs, getCustomValueEquality(opts))
+	}
+	if diff = filterIgnoredDiffs(diff, getIgnoredDiffPaths(opts)); !diff.AnyChanges() {
+		return nil
+	}
+	return diff
+}
+
+// getPropertyChangeCount returns the total number of property-level changes across the given steps. Only steps that
+// would be shown by the display are counted.
+func getPropertyChangeCount(steps []engine.StepEventMetadata, opts Options) int {
+	count := 0
+	for _, step := range steps {
+		if step.Op == deploy.OpSame || !shouldShow(step, opts) {
+			continue
+		}
+		count += getDiffStats(getStepDiff(step, opts)).Changes()
+	}
+	return count
+}
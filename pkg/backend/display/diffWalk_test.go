@@ -0,0 +1,90 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+func TestWalkObjectDiff(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"a": "same",
+		"b": []interface{}{1, 2},
+		"c": map[string]interface{}{
+			"d": true,
+		},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"a": "same",
+		"b": []interface{}{1, 3, 4},
+		"e": "added",
+	})
+
+	type leaf struct {
+		path []interface{}
+		op   deploy.StepOp
+	}
+	var leaves []leaf
+	walkObjectDiff(olds.Diff(news), func(path []interface{}, op deploy.StepOp, _, _ resource.PropertyValue) {
+		leaves = append(leaves, leaf{path, op})
+	})
+
+	assert.Equal(t, []leaf{
+		{[]interface{}{"a"}, deploy.OpSame},
+		{[]interface{}{"b", 0}, deploy.OpSame},
+		{[]interface{}{"b", 1}, deploy.OpUpdate},
+		{[]interface{}{"b", 2}, deploy.OpCreate},
+		{[]interface{}{"c"}, deploy.OpDelete},
+		{[]interface{}{"e"}, deploy.OpCreate},
+	}, leaves)
+
+	stats := getDiffStats(olds.Diff(news))
+	assert.Equal(t, diffStats{Adds: 2, Deletes: 1, Updates: 1}, stats)
+	assert.Equal(t, 4, stats.Changes())
+
+	assert.Equal(t, 0, getDiffStats(nil).Changes())
+}
+
+func TestWalkAlignedArrayDiff(t *testing.T) {
+	str := resource.NewStringProperty
+
+	// "x" was inserted at the front of ["a", "b", "c"] and "c" was removed, so the elements' positions differ from
+	// their indices. Paths hold the indices: the new index of each remaining element and the old index of "c".
+	diff := &resource.ObjectDiff{Updates: map[resource.PropertyKey]resource.ValueDiff{
+		"hosts": {Array: &resource.ArrayDiff{
+			Adds:    map[int]resource.PropertyValue{0: str("x")},
+			Deletes: map[int]resource.PropertyValue{3: str("c")},
+			Sames:   map[int]resource.PropertyValue{1: str("a"), 2: str("b")},
+			Updates: map[int]resource.ValueDiff{},
+			Moves: map[int]resource.ArrayMove{
+				1: {From: 0, To: 1},
+				2: {From: 1, To: 2},
+				3: {From: 2, To: -1},
+			},
+		}},
+	}}
+
+	var paths []string
+	walkObjectDiff(diff, func(path []interface{}, op deploy.StepOp, _, _ resource.PropertyValue) {
+		paths = append(paths, fmt.Sprintf("%v %s", path, op))
+	})
+	assert.Equal(t, []string{"[hosts 0] create", "[hosts 1] same", "[hosts 2] same", "[hosts 2] delete"}, paths)
+}
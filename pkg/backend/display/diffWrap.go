@@ -0,0 +1,166 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Color commands are zero-width delimited sequences, e.g. <{%fg 2%}>, that are replaced with escape codes once a diff
+// is colorized.
+const (
+	colorCommandLeft  = "<{%"
+	colorCommandRight = "%}>"
+)
+
+// minDiffWrapWidth is the fewest columns in which the continuation lines of a wrapped line may be laid out. Lines
+// whose continuations would be narrower are continued at a lesser indentation.
+const minDiffWrapWidth = 20
+
+// getDiffWidth returns the width at which the lines of diffs written to the given writer are wrapped: the given
+// options' width if it is positive, otherwise the terminal's width if the writer is a terminal, and otherwise zero,
+// i.e. lines are not wrapped.
+func getDiffWidth(w io.Writer, opts Options) int {
+	if opts.DiffWidth > 0 {
+		return opts.DiffWidth
+	}
+	if f, ok := w.(*os.File); ok && terminal.IsTerminal(int(f.Fd())) {
+		if width, _, err := terminal.GetSize(int(f.Fd())); err == nil {
+			return width
+		}
+	}
+	return 0
+}
+
+// wrapDiffLines wraps each line of the given rendered diff that is wider than the given width. The continuation lines
+// of a property are indented to the column at which its value starts, so that the layout of the diff is preserved. A
+// non-positive width leaves the diff as it is.
+func wrapDiffLines(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		plain := stripColorCommands(line)
+		if utf8.RuneCountInString(plain) <= width {
+			continue
+		}
+
+		// Values start after the first colon that follows the line's indentation and marker, e.g. "  ~ key: value".
+		// Lines that are not properties, and properties whose values start too far to the right, are continued at the
+		// line's indentation instead.
+		indent := len(plain) - len(strings.TrimLeft(plain, " "))
+		column := indent
+		if colon := strings.Index(plain[indent:], ": "); colon >= 0 {
+			column = utf8.RuneCountInString(plain[:indent+colon]) + 2
+		}
+		if width-column < minDiffWrapWidth {
+			column = indent
+		}
+		lines[i] = strings.Join(wrapDiffLine(line, width, column, strings.Repeat(" ", column)), "\n")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapDiffLine breaks the given line, which may contain color commands, into lines that are no wider than the given
+// width. Each continuation line starts with the given continuation, followed by the color commands that precede it so
+// that its color is preserved. Lines are broken at the last space that fits if it is past the given column, i.e.
+// within the line's value, and otherwise at the width itself. The space at which a line is broken is dropped.
+func wrapDiffLine(line string, width, column int, continuation string) []string {
+	if width <= 0 || utf8.RuneCountInString(stripColorCommands(line)) <= width {
+		return []string{line}
+	}
+	indent := utf8.RuneCountInString(stripColorCommands(continuation))
+	if width-indent < minDiffWrapWidth {
+		continuation, indent = "", 0
+	}
+
+	tokens := splitColorCommands(line)
+	var lines []string
+	head, start, min := "", 0, column
+	for start < len(tokens) {
+		// Find the tokens that fit on this line, and the last space among them at which it may be broken.
+		col, end, space := utf8.RuneCountInString(stripColorCommands(head)), start, -1
+		for ; end < len(tokens); end++ {
+			if isColorCommand(tokens[end]) {
+				continue
+			}
+			if col == width {
+				break
+			}
+			if tokens[end] == " " && col >= min && end > start {
+				space = end
+			}
+			col++
+		}
+
+		if end == len(tokens) {
+			lines = append(lines, head+strings.Join(tokens[start:], ""))
+			break
+		}
+		cut, next := end, end
+		if space >= 0 {
+			cut, next = space, space+1
+		}
+		lines = append(lines, head+strings.Join(tokens[start:cut], ""))
+
+		var commands strings.Builder
+		for _, t := range tokens[:next] {
+			if isColorCommand(t) {
+				commands.WriteString(t)
+			}
+		}
+		head, start, min = continuation+commands.String(), next, indent
+	}
+	return lines
+}
+
+// splitColorCommands splits the given text into its color commands and its individual characters.
+func splitColorCommands(text string) []string {
+	var tokens []string
+	for len(text) > 0 {
+		if strings.HasPrefix(text, colorCommandLeft) {
+			if end := strings.Index(text, colorCommandRight); end >= 0 {
+				end += len(colorCommandRight)
+				tokens, text = append(tokens, text[:end]), text[end:]
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(text)
+		tokens, text = append(tokens, text[:size]), text[size:]
+	}
+	return tokens
+}
+
+func isColorCommand(token string) bool {
+	return strings.HasPrefix(token, colorCommandLeft) && strings.HasSuffix(token, colorCommandRight)
+}
+
+// stripColorCommands returns the given text without its color commands.
+func stripColorCommands(text string) string {
+	var b strings.Builder
+	for _, t := range splitColorCommands(text) {
+		if !isColorCommand(t) {
+			b.WriteString(t)
+		}
+	}
+	return b.String()
+}
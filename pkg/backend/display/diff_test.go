@@ -0,0 +1,2153 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+// assertGolden compares the given output against the contents of the named file in testdata. Setting the
+// PULUMI_ACCEPT environment variable rewrites the golden file with the actual output instead.
+func assertGolden(t *testing.T, name string, actual string) {
+	path := filepath.Join("testdata", name)
+	if os.Getenv("PULUMI_ACCEPT") != "" {
+		assert.NoError(t, ioutil.WriteFile(path, []byte(actual), 0600))
+		return
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), actual)
+}
+
+// makeUpdateStep returns a logical update step with the given old and new properties and detailed diff.
+func makeUpdateStep(olds, news resource.PropertyMap,
+	detailedDiff map[string]plugin.PropertyDiff) engine.StepEventMetadata {
+
+	stackURN := resource.NewURN("stack", "project", "", resource.RootStackType, "project-stack")
+	urn := resource.NewURN("stack", "project", "", "pkg:index:Service", "web")
+	return engine.StepEventMetadata{
+		Op:           deploy.OpUpdate,
+		URN:          urn,
+		Type:         urn.Type(),
+		Old:          &engine.StepEventStateMetadata{Inputs: olds, Outputs: olds},
+		New:          &engine.StepEventStateMetadata{Inputs: news, Outputs: news},
+		Res:          &engine.StepEventStateMetadata{Inputs: news, Outputs: news, Parent: stackURN},
+		Logical:      true,
+		DetailedDiff: detailedDiff,
+	}
+}
+
+// nestedDiffStep returns a representative update step whose detailed diff touches nested objects and arrays.
+func nestedDiffStep() engine.StepEventMetadata {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name": "web",
+		"spec": map[string]interface{}{
+			"replicas": 3,
+			"ports":    []interface{}{80, 443},
+			"labels": map[string]interface{}{
+				"app":  "web",
+				"tier": "frontend",
+			},
+		},
+		"debug": true,
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name": "web",
+		"spec": map[string]interface{}{
+			"replicas": 5,
+			"ports":    []interface{}{80, 443, 8080},
+			"labels": map[string]interface{}{
+				"app": "web",
+			},
+		},
+		"owner": "ops",
+	})
+
+	return makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"spec.replicas":    {Kind: plugin.DiffUpdate},
+		"spec.ports[2]":    {Kind: plugin.DiffAdd},
+		"spec.labels.tier": {Kind: plugin.DiffDelete},
+		"debug":            {Kind: plugin.DiffDelete},
+		"owner":            {Kind: plugin.DiffAdd},
+	})
+}
+
+func renderStepDiff(step engine.StepEventMetadata, opts Options) string {
+	// Register the step's parent stack so that its properties are rendered at the usual indentation.
+	seen := map[resource.URN]engine.StepEventMetadata{
+		step.Res.Parent: {Res: &engine.StepEventStateMetadata{}},
+	}
+	return renderDiffResourcePreEvent(engine.ResourcePreEventPayload{
+		Metadata: step,
+		Planning: true,
+	}, seen, newDiffBudget(opts), opts)
+}
+
+func TestPlainDiff(t *testing.T) {
+	opts := Options{Color: colors.Always, Type: DisplayDiff, DiffOptions: DiffOptions{PlainDiff: true}}
+	actual := renderStepDiff(nestedDiffStep(), opts)
+	assert.NotContains(t, actual, "\x1b")
+	assert.NotContains(t, actual, "<null>")
+	assertGolden(t, "plain_diff.txt", actual)
+}
+
+func TestTypeChangeDiff(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"policy":  "allow-all",
+		"port":    "8080",
+		"retries": 3,
+		"tags":    []interface{}{"a"},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"policy": map[string]interface{}{
+			"effect": "allow",
+		},
+		"port":    8080,
+		"retries": 4,
+		"timeout": 30,
+	})
+
+	step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"policy":  {Kind: plugin.DiffUpdate},
+		"port":    {Kind: plugin.DiffUpdate},
+		"retries": {Kind: plugin.DiffUpdate},
+		"tags":    {Kind: plugin.DiffUpdate},
+		"timeout": {Kind: plugin.DiffUpdate},
+	})
+
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	assertGolden(t, "type_change_diff.txt", renderStepDiff(step, opts))
+}
+
+func TestSummaryPropertyChanges(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"replicas": 3, "debug": true})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{"replicas": 5, "owner": "ops"})
+	detailedDiff := map[string]plugin.PropertyDiff{
+		"replicas": {Kind: plugin.DiffUpdate},
+		"debug":    {Kind: plugin.DiffDelete},
+		"owner":    {Kind: plugin.DiffAdd},
+	}
+	step := makeUpdateStep(olds, news, detailedDiff)
+	hidden := makeUpdateStep(olds, news, detailedDiff)
+	hidden.Logical = false
+
+	payload := engine.SummaryEventPayload{
+		IsPreview:       true,
+		ResourceChanges: engine.ResourceChanges{deploy.OpUpdate: 1},
+	}
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+
+	summary := renderSummaryEvent(apitype.UpdateUpdate, payload, []engine.StepEventMetadata{step, hidden}, opts)
+	assert.Contains(t, summary, "3 property changes")
+}
+
+func TestComputedDiff(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"config": map[string]interface{}{
+			"endpoint": "https://example.com",
+			"ports":    []interface{}{80, 443},
+		},
+		"name": "web",
+	})
+	news := resource.PropertyMap{
+		"config": resource.MakeComputed(resource.NewStringProperty("")),
+		"name":   resource.MakeComputed(resource.NewStringProperty("")),
+	}
+
+	step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"config.endpoint": {Kind: plugin.DiffUpdate},
+		"name":            {Kind: plugin.DiffUpdate},
+	})
+
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	assertGolden(t, "computed_diff.txt", renderStepDiff(step, opts))
+}
+
+// budgetDiffStep returns an update step with four changes, one of them nested, against which budgets are measured.
+func budgetDiffStep() engine.StepEventMetadata {
+	return makeUpdateStep(
+		resource.NewPropertyMapFromMap(map[string]interface{}{
+			"spec":  map[string]interface{}{"replicas": 3, "labels": map[string]interface{}{"tier": "frontend"}},
+			"debug": true,
+		}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{
+			"spec":  map[string]interface{}{"replicas": 5, "labels": map[string]interface{}{}},
+			"owner": "ops",
+		}),
+		map[string]plugin.PropertyDiff{
+			"spec.replicas":    {Kind: plugin.DiffUpdate},
+			"spec.labels.tier": {Kind: plugin.DiffDelete},
+			"debug":            {Kind: plugin.DiffDelete},
+			"owner":            {Kind: plugin.DiffAdd},
+		})
+}
+
+func TestDiffBudget(t *testing.T) {
+	step := budgetDiffStep()
+	opts := Options{Color: colors.Never, Type: DisplayDiff,
+		DiffOptions: DiffOptions{DiffBudget: 3, DiffBudgetHint: "use --json for full detail"}}
+	assertGolden(t, "diff_budget.txt", renderStepDiff(step, opts))
+
+	// Truncated diffs are rendered like any other, so the options that customize diffs still apply to them, and the
+	// hint is only shown if there is one.
+	truncated := renderStepDiff(step, Options{
+		Color: colors.Never,
+		Type:  DisplayDiff,
+		DiffOptions: DiffOptions{
+			DiffBudget:    3,
+			DiffTreeStyle: DiffTreeASCII,
+		},
+	})
+	assert.Contains(t, truncated, "+-- ")
+	assert.Contains(t, truncated, "… and 1 more change\n")
+
+	// Truncation keeps the annotations of the changes that it retains.
+	diff := truncateObjectDiff(&resource.ObjectDiff{
+		Updates: map[resource.PropertyKey]resource.ValueDiff{
+			"spec": {
+				Object: &resource.ObjectDiff{
+					Updates: map[resource.PropertyKey]resource.ValueDiff{
+						"replicas": {Old: resource.NewNumberProperty(3), New: resource.NewNumberProperty(5), InputDiff: true},
+						"size":     {Old: resource.NewStringProperty("a"), New: resource.NewStringProperty("b")},
+					},
+					Schema: map[resource.PropertyKey]bool{"size": true},
+				},
+			},
+		},
+	}, nil, 1)
+	assert.True(t, diff.Updates["spec"].Object.Schema["size"])
+	assert.True(t, diff.Updates["spec"].Object.Updates["replicas"].InputDiff)
+	assert.NotContains(t, diff.Updates["spec"].Object.Updates, "size")
+
+	// A budget that covers every change leaves the diff untouched.
+	opts.DiffBudget = 4
+	opts.PlainDiff = true
+	assert.Equal(t, renderStepDiff(step, Options{Color: colors.Never, Type: DisplayDiff}),
+		renderStepDiff(step, opts))
+}
+
+func TestGlobalDiffBudget(t *testing.T) {
+	opts := Options{Color: colors.Never, Type: DisplayDiff,
+		DiffOptions: DiffOptions{DiffBudget: 6, GlobalDiffBudget: true}}
+	budget := newDiffBudget(opts)
+
+	step := makeUpdateStep(
+		resource.NewPropertyMapFromMap(map[string]interface{}{"debug": true, "replicas": 3, "size": "small"}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{"owner": "ops", "replicas": 5, "size": "large"}),
+		map[string]plugin.PropertyDiff{
+			"debug":    {Kind: plugin.DiffDelete},
+			"owner":    {Kind: plugin.DiffAdd},
+			"replicas": {Kind: plugin.DiffUpdate},
+			"size":     {Kind: plugin.DiffUpdate},
+		})
+	seen := map[resource.URN]engine.StepEventMetadata{
+		step.Res.Parent: {Res: &engine.StepEventStateMetadata{}},
+	}
+	render := func() string {
+		return renderDiffResourcePreEvent(engine.ResourcePreEventPayload{
+			Metadata: step,
+			Planning: true,
+		}, seen, budget, opts)
+	}
+
+	// The first resource fits within the budget, the second is truncated after two changes, and the third is elided
+	// entirely.
+	assert.NotContains(t, render(), "more change")
+	assert.Contains(t, render(), "… and 2 more changes")
+	third := render()
+	assert.Contains(t, third, "… and 4 more changes")
+	assert.NotContains(t, third, "replicas")
+}
+
+func TestDiffSummaryThreshold(t *testing.T) {
+	step := budgetDiffStep()
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{DiffSummaryThreshold: 3}}
+	assertGolden(t, "diff_summary_threshold.txt", renderStepDiff(step, opts))
+
+	// A threshold that covers every change leaves the diff untouched.
+	opts.DiffSummaryThreshold = 4
+	assert.Equal(t, renderStepDiff(step, Options{Color: colors.Never, Type: DisplayDiff}),
+		renderStepDiff(step, opts))
+}
+
+func TestInputDiffAnnotation(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"replicas": 3, "size": "small"})
+	step := makeUpdateStep(olds, resource.NewPropertyMapFromMap(map[string]interface{}{"replicas": 5, "size": "large"}),
+		map[string]plugin.PropertyDiff{
+			"replicas": {Kind: plugin.DiffUpdate, InputDiff: true},
+			"size":     {Kind: plugin.DiffUpdate},
+		})
+	step.Old.Outputs = resource.NewPropertyMapFromMap(map[string]interface{}{"replicas": 4, "size": "small"})
+
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	assert.NotContains(t, renderStepDiff(step, opts), "[input diff]")
+
+	seen := map[resource.URN]engine.StepEventMetadata{
+		step.Res.Parent: {Res: &engine.StepEventStateMetadata{}},
+	}
+	actual := renderDiffResourcePreEvent(engine.ResourcePreEventPayload{
+		Metadata: step,
+		Planning: true,
+		Debug:    true,
+	}, seen, newDiffBudget(opts), opts)
+	assertGolden(t, "input_diff.txt", actual)
+}
+
+func TestIgnoreDiffPaths(t *testing.T) {
+	step := makeUpdateStep(
+		resource.NewPropertyMapFromMap(map[string]interface{}{
+			"spec":  map[string]interface{}{"replicas": 3, "labels": map[string]interface{}{"tier": "frontend"}},
+			"debug": true,
+		}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{
+			"spec":  map[string]interface{}{"replicas": 5, "labels": map[string]interface{}{}},
+			"owner": "ops",
+		}),
+		map[string]plugin.PropertyDiff{
+			"spec.replicas":    {Kind: plugin.DiffUpdateReplace},
+			"spec.labels.tier": {Kind: plugin.DiffDelete},
+			"debug":            {Kind: plugin.DiffDelete},
+			"owner":            {Kind: plugin.DiffAdd},
+		})
+
+	opts := Options{
+		Color: colors.Never,
+		Type:  DisplayDiff,
+		DiffOptions: DiffOptions{
+			IgnoreDiffPaths: []string{"debug", "spec.labels", "**.replicas"},
+		},
+	}
+	assertGolden(t, "ignore_diff_paths.txt", renderStepDiff(step, opts))
+	assert.Equal(t, 1, getPropertyChangeCount([]engine.StepEventMetadata{step}, opts))
+
+	// Ignoring every change leaves nothing to count.
+	opts.IgnoreDiffPaths = []string{"**"}
+	assert.Equal(t, 0, getPropertyChangeCount([]engine.StepEventMetadata{step}, opts))
+	assert.Nil(t, getStepDiff(step, opts))
+
+	assert.NoError(t, ValidateIgnoreDiffPaths([]string{"metadata.generation", `tags["a.b"]`}))
+	assert.Error(t, ValidateIgnoreDiffPaths([]string{`tags["unterminated`}))
+}
+
+func TestTruncateDisplayString(t *testing.T) {
+	cases := []struct {
+		s        string
+		max      int
+		expected string
+	}{
+		{"short", 0, ""},
+		{"short", 5, ""},
+		{"abcdefgh", 3, `"abc"… (8 characters)`},
+		{"héllo wörld", 4, `"héll"… (11 characters)`},
+		{"日本語のテキスト", 2, `"日本"… (8 characters)`},
+		{"😀😀😀", 1, `"😀"… (3 characters)`},
+		{strings.Repeat("x", 12345), 1, `"x"… (12,345 characters)`},
+	}
+	for _, c := range cases {
+		text, truncated := truncateDisplayString(c.s, c.max)
+		assert.Equal(t, c.expected != "", truncated, c.s)
+		assert.Equal(t, c.expected, text)
+	}
+
+	// Both sides of an update are cut to the same window, which shows the first difference.
+	old, new, truncated := truncateDisplayStrings("abcdefghij", "abcdefghiJ", 4)
+	assert.True(t, truncated)
+	assert.Equal(t, `…"ghij" (10 characters)`, old)
+	assert.Equal(t, `…"ghiJ" (10 characters)`, new)
+
+	old, new, truncated = truncateDisplayStrings("abcdefghij", "xyz", 4)
+	assert.True(t, truncated)
+	assert.Equal(t, `"abcd"… (10 characters)`, old)
+	assert.Equal(t, `"xyz"`, new)
+
+	_, _, truncated = truncateDisplayStrings("abc", "abd", 4)
+	assert.False(t, truncated)
+}
+
+func TestMaxStringDisplayLength(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"cert":  "-----BEGIN CERTIFICATE-----MIIB",
+		"name":  "web",
+		"notes": []interface{}{"a note that is long enough to be truncated"},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"cert":  "-----BEGIN CERTIFICATE-----MIIC",
+		"name":  "web",
+		"notes": []interface{}{"a note that is long enough to be truncated", "short"},
+	})
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{MaxStringDisplayLength: 11}}
+
+	update := makeUpdateStep(olds, news, nil)
+	create := makeUpdateStep(nil, news, nil)
+	create.Op, create.Old = deploy.OpCreate, nil
+	assertGolden(t, "max_string_display_length.txt", renderStepDiff(update, opts)+renderStepDiff(create, opts))
+
+	// The step's own properties are left untouched.
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----MIIC", create.New.Inputs["cert"].StringValue())
+
+	// Without a limit, strings are rendered in full.
+	opts.MaxStringDisplayLength = 0
+	assert.Contains(t, renderStepDiff(update, opts),
+		`"-----BEGIN CERTIFICATE-----MIIB" => "-----BEGIN CERTIFICATE-----MIIC"`)
+}
+
+func TestProviderUpgradeDiff(t *testing.T) {
+	step := makeUpdateStep(
+		resource.NewPropertyMapFromMap(map[string]interface{}{"debug": true, "replicas": 3}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{"owner": "ops", "replicas": 5}),
+		map[string]plugin.PropertyDiff{
+			"debug":    {Kind: plugin.DiffDelete},
+			"owner":    {Kind: plugin.DiffAdd},
+			"replicas": {Kind: plugin.DiffUpdate},
+			"status":   {Kind: plugin.DiffDelete},
+		})
+	step.Old.Outputs = resource.NewPropertyMapFromMap(map[string]interface{}{
+		"debug":    true,
+		"replicas": 3,
+		"status":   "ready",
+	})
+
+	// Without version information, no changes are attributed to the provider.
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	assert.NotContains(t, renderStepDiff(step, opts), "[provider upgrade]")
+
+	step.Old.ProviderVersion, step.New.ProviderVersion = "1.0.0", "2.0.0"
+	assertGolden(t, "provider_upgrade_diff.txt", renderStepDiff(step, opts))
+
+	// The marked changes are still counted as the adds and deletes that they are.
+	diff := getStepDiff(step, opts)
+	assert.Equal(t, map[resource.PropertyKey]bool{"owner": true, "status": true}, diff.Schema)
+	assert.Equal(t, diffStats{Adds: 1, Deletes: 2, Updates: 1}, getDiffStats(diff))
+}
+
+func TestValueTransform(t *testing.T) {
+	// Mask all but the last four characters of account IDs, wherever they appear.
+	maskAccountIDs := func(path []interface{}, v resource.PropertyValue) resource.PropertyValue {
+		if key, ok := path[len(path)-1].(string); ok && key == "accountId" && v.IsString() {
+			id := v.StringValue()
+			return resource.NewStringProperty("****" + id[len(id)-4:])
+		}
+		return v
+	}
+
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"accountId": "123456781234",
+		"grants": []interface{}{
+			map[string]interface{}{"accountId": "111122223333", "role": "reader"},
+		},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"accountId": "876543214321",
+		"grants": []interface{}{
+			map[string]interface{}{"accountId": "111122223333", "role": "writer"},
+			map[string]interface{}{"accountId": "444455556666", "role": "reader"},
+		},
+	})
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{ValueTransform: maskAccountIDs}}
+
+	update := makeUpdateStep(olds, news, nil)
+	detailed := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"accountId":      {Kind: plugin.DiffUpdate},
+		"grants[0].role": {Kind: plugin.DiffUpdate},
+		"grants[1]":      {Kind: plugin.DiffAdd},
+	})
+	create := makeUpdateStep(nil, news, nil)
+	create.Op, create.Old = deploy.OpCreate, nil
+	assertGolden(t, "value_transform.txt",
+		renderStepDiff(update, opts)+renderStepDiff(detailed, opts)+renderStepDiff(create, opts))
+
+	// The steps' own properties are left untouched.
+	assert.Equal(t, "876543214321", update.New.Inputs["accountId"].StringValue())
+}
+
+func TestDiffMultisets(t *testing.T) {
+	values := func(elements ...interface{}) []resource.PropertyValue {
+		return resource.NewPropertyValue(elements).ArrayValue()
+	}
+
+	// Reordering alone is not a change.
+	diff := diffMultisets(values("a", "b", "c"), values("c", "a", "b"), resource.PropertyValue.DeepEquals)
+	assert.False(t, diff.AnyChanges())
+	assert.Equal(t, map[int]resource.PropertyValue{0: values("c")[0], 1: values("a")[0], 2: values("b")[0]},
+		diff.Sames)
+
+	// Duplicates are matched one for one.
+	diff = diffMultisets(values("a", "a", "b"), values("b", "a", "b"), resource.PropertyValue.DeepEquals)
+	assert.Equal(t, &resource.ArrayDiff{
+		Adds:    map[int]resource.PropertyValue{2: values("b")[0]},
+		Deletes: map[int]resource.PropertyValue{3: values("a")[0]},
+		Sames:   map[int]resource.PropertyValue{0: values("b")[0], 1: values("a")[0]},
+		Updates: map[int]resource.ValueDiff{},
+	}, diff)
+
+	// Structured elements are compared deeply.
+	diff = diffMultisets(
+		values(map[string]interface{}{"port": 80}, map[string]interface{}{"port": 443}),
+		values(map[string]interface{}{"port": 443}, map[string]interface{}{"port": 8080}),
+		resource.PropertyValue.DeepEquals)
+	assert.Len(t, diff.Sames, 1)
+	assert.Equal(t, values(map[string]interface{}{"port": 8080})[0], diff.Adds[1])
+	assert.Equal(t, values(map[string]interface{}{"port": 80})[0], diff.Deletes[2])
+}
+
+func TestUnorderedArrayPaths(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"securityGroups": []interface{}{"sg-1", "sg-2", "sg-3"},
+		"spec": map[string]interface{}{
+			"ports": []interface{}{80, 443},
+			"hosts": []interface{}{"a", "b"},
+		},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"securityGroups": []interface{}{"sg-3", "sg-1", "sg-2"},
+		"spec": map[string]interface{}{
+			"ports": []interface{}{443, 80, 8080},
+			"hosts": []interface{}{"b", "a"},
+		},
+	})
+	opts := Options{
+		Color: colors.Never,
+		Type:  DisplayDiff,
+		DiffOptions: DiffOptions{
+			UnorderedArrayPaths: []string{"securityGroups", "**.ports"},
+		},
+	}
+
+	update := makeUpdateStep(olds, news, nil)
+	detailed := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"securityGroups": {Kind: plugin.DiffUpdate},
+		"spec.ports[0]":  {Kind: plugin.DiffUpdate},
+		"spec.ports[1]":  {Kind: plugin.DiffUpdate},
+		"spec.ports[2]":  {Kind: plugin.DiffAdd},
+		"spec.hosts[0]":  {Kind: plugin.DiffUpdate},
+		"spec.hosts[1]":  {Kind: plugin.DiffUpdate},
+	})
+	assertGolden(t, "unordered_array_paths.txt", renderStepDiff(update, opts)+renderStepDiff(detailed, opts))
+
+	// Only the added port and the reordered hosts, which are not unordered, are counted.
+	assert.Equal(t, 3, getPropertyChangeCount([]engine.StepEventMetadata{detailed}, opts))
+	assert.Error(t, ValidateUnorderedArrayPaths([]string{`tags["unterminated`}))
+}
+
+func TestKeyedArrays(t *testing.T) {
+	rule := func(name string, port int) map[string]interface{} {
+		return map[string]interface{}{"name": name, "port": port}
+	}
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"rules":      []interface{}{rule("allow-http", 80), rule("allow-ssh", 22), rule("deny-all", 0)},
+		"duplicates": []interface{}{rule("web", 80), rule("web", 443)},
+		"missing":    []interface{}{rule("web", 80), map[string]interface{}{"port": 443}},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"rules":      []interface{}{rule("deny-all", 0), rule("allow-https", 443), rule("allow-http", 8080)},
+		"duplicates": []interface{}{rule("web", 443), rule("web", 80)},
+		"missing":    []interface{}{map[string]interface{}{"port": 443}, rule("web", 80)},
+	})
+	opts := Options{
+		Color: colors.Never,
+		Type:  DisplayDiff,
+		DiffOptions: DiffOptions{
+			KeyedArrays: []KeyedArray{
+				{Path: "rules", Key: "name"},
+				{Path: "duplicates", Key: "name"},
+				{Path: "missing", Key: "name"},
+			},
+		},
+	}
+
+	// The rules are matched by name; the arrays with duplicate or missing names are diffed by position.
+	update := makeUpdateStep(olds, news, nil)
+	detailed := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"rules[0]":      {Kind: plugin.DiffUpdate},
+		"rules[1]":      {Kind: plugin.DiffUpdate},
+		"rules[2]":      {Kind: plugin.DiffUpdate},
+		"duplicates[0]": {Kind: plugin.DiffUpdate},
+		"duplicates[1]": {Kind: plugin.DiffUpdate},
+		"missing[0]":    {Kind: plugin.DiffUpdate},
+		"missing[1]":    {Kind: plugin.DiffUpdate},
+	})
+	assertGolden(t, "keyed_arrays.txt", renderStepDiff(update, opts)+renderStepDiff(detailed, opts))
+
+	keyed, err := ParseKeyedArrays([]string{"**.rules=name", `tags["a=b"]=id`})
+	assert.NoError(t, err)
+	assert.Equal(t, []KeyedArray{{Path: "**.rules", Key: "name"}, {Path: `tags["a=b"]`, Key: "id"}}, keyed)
+	for _, spec := range []string{"rules", "=name", "rules=", `tags["unterminated=name`} {
+		_, err = ParseKeyedArrays([]string{spec})
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestNormalizeDiffs(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"port": 80, "script": "echo hi\n"})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{"port": "80", "script": "echo hi  \n"})
+	step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"port":   {Kind: plugin.DiffUpdate},
+		"script": {Kind: plugin.DiffUpdate},
+	})
+
+	// The update is only hidden if every one of its changes is cosmetic.
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	assert.Contains(t, renderStepDiff(step, opts), "+ port  : \"80\"")
+	opts.NormalizeDiffs = resource.DiffNormalization{NumericEquivalence: true}
+	assert.Contains(t, renderStepDiff(step, opts), "(update)")
+	opts.NormalizeDiffs.TrailingWhitespace = true
+	assert.Equal(t, "", renderStepDiff(step, opts))
+
+	// If sames are shown, the update is displayed as a same.
+	opts.ShowSameResources = true
+	assert.NotContains(t, renderStepDiff(step, opts), "update")
+
+	norm, err := ParseDiffNormalization([]string{"numbers", "whitespace", "nulls"})
+	assert.NoError(t, err)
+	assert.Equal(t, resource.DiffNormalization{
+		NumericEquivalence: true,
+		TrailingWhitespace: true,
+		NullEqualsAbsent:   true,
+	}, norm)
+	_, err = ParseDiffNormalization([]string{"case"})
+	assert.Error(t, err)
+}
+
+func TestStrictDetailedDiff(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": 3,
+		},
+		"debug": map[string]interface{}{
+			"level": "info",
+		},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": 3,
+			"strategy": map[string]interface{}{
+				"type": "rolling",
+			},
+		},
+		"tags": map[string]interface{}{
+			"env": "prod",
+		},
+	})
+	step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"spec.strategy.type": {Kind: plugin.DiffUpdate},
+		"tags.env":           {Kind: plugin.DiffAddReplace},
+		"debug.level":        {Kind: plugin.DiffAdd},
+	})
+
+	assert.Equal(t, []string{
+		`debug.level: the provider reported "add", but debug was inferred to be deleted`,
+		`spec.strategy.type: the provider reported "update", but spec.strategy was inferred to be added`,
+	}, checkDetailedDiff(step))
+
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	assert.NotContains(t, renderStepDiff(step, opts), "detailed diff mismatch")
+
+	opts.StrictDetailedDiff = true
+	assert.Contains(t, renderStepDiff(step, opts),
+		`detailed diff mismatch: debug.level: the provider reported "add", but debug was inferred to be deleted`)
+}
+
+func TestFormatCompactDiff(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name":  "web",
+		"notes": "a fairly long note",
+		"spec":  map[string]interface{}{"replicas": 3, "ports": []interface{}{80}},
+		"debug": true,
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name":  "web",
+		"notes": "an even longer note",
+		"spec":  map[string]interface{}{"replicas": 5, "ports": []interface{}{80, 8080}},
+		"owner": "ops",
+	})
+	step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"notes":         {Kind: plugin.DiffUpdate},
+		"spec.replicas": {Kind: plugin.DiffUpdate},
+		"spec.ports[1]": {Kind: plugin.DiffAdd},
+		"debug":         {Kind: plugin.DiffDelete},
+		"owner":         {Kind: plugin.DiffAdd},
+	})
+	diff := translateDetailedDiff(step, false, false)
+
+	assert.Equal(t, []string{
+		"debug: true [delete]",
+		`notes: "a fairly long note" → "an even longer note" [update]`,
+		`owner: "ops" [add]`,
+		"spec.ports[1]: 8080 [add]",
+		"spec.replicas: 3 → 5 [update]",
+	}, FormatCompactDiff(diff, CompactDiffOptions{}))
+
+	assert.Equal(t, []string{
+		"debug: true [delete]",
+		`name: "web" [same]`,
+		`notes: "a fair… → "an eve… [update]`,
+		`owner: "ops" [add]`,
+		"spec.ports[0]: 80 [same]",
+		"spec.ports[1]: 8080 [add]",
+		"spec.replicas: 3 → 5 [update]",
+	}, FormatCompactDiff(olds.Diff(news), CompactDiffOptions{
+		IncludeSames:   true,
+		MaxValueLength: 7,
+	}))
+}
+
+func TestFormatFlatDiff(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name": "web",
+		"spec": map[string]interface{}{"replicas": 3, "ports": []interface{}{80}},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name":    "web",
+		"spec":    map[string]interface{}{"replicas": 5, "ports": []interface{}{80, 8080}},
+		"volumes": []interface{}{map[string]interface{}{"name": "data", "tags": []interface{}{}}},
+	})
+	step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"spec.replicas": {Kind: plugin.DiffUpdate},
+		"spec.ports[1]": {Kind: plugin.DiffAdd},
+		"volumes":       {Kind: plugin.DiffAdd},
+	})
+	diff := translateDetailedDiff(step, false, false)
+
+	assert.Equal(t, []string{
+		"+ spec.ports[1]=8080",
+		"- spec.replicas=3",
+		"+ spec.replicas=5",
+		`+ volumes[0].name="data"`,
+		"+ volumes[0].tags=[]",
+	}, FormatFlatDiff(diff, FlatDiffOptions{}))
+
+	assert.Equal(t, []string{
+		`  name="web"`,
+		"  spec.ports[0]=80",
+		"+ spec.ports[1]=8080",
+		"- spec.replicas=3",
+		"+ spec.replicas=5",
+		`+ volumes[0].name="data"`,
+		"+ volumes[0].tags=[]",
+	}, FormatFlatDiff(olds.Diff(news), FlatDiffOptions{IncludeSames: true}))
+
+	assert.Nil(t, FormatFlatDiff(nil, FlatDiffOptions{}))
+}
+
+func TestFormatFlatDiffEscaping(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"script": "echo a\nrm -rf /tmp/cache\n",
+		"env":    map[string]interface{}{"OPTS": "--level=1"},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"script": "echo a\necho b\n",
+		"env":    map[string]interface{}{"OPTS": "--level=2", "A=B": "x=y"},
+	})
+	olds["notes"] = resource.NewStringProperty("line one\nline two")
+
+	assert.Equal(t, []string{
+		`+ env["A=B"]="x=y"`,
+		`- env.OPTS="--level=1"`,
+		`+ env.OPTS="--level=2"`,
+		`- notes="line one\nline two"`,
+		`- script="echo a\nrm -rf /tmp/cache\n"`,
+		`+ script="echo a\necho b\n"`,
+	}, FormatFlatDiff(olds.Diff(news), FlatDiffOptions{}))
+}
+
+func TestExplicitNullDiff(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"replicas": nil,
+		"owner":    "ops",
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"replicas": 3,
+	})
+	news["owner"] = resource.NewNullProperty()
+	step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"replicas": {Kind: plugin.DiffAdd},
+		"owner":    {Kind: plugin.DiffDelete},
+	})
+
+	rendered := renderStepDiff(step, Options{Color: colors.Never, Type: DisplayDiff})
+	assert.Contains(t, rendered, `~ replicas: <null> => 3`)
+	assert.Contains(t, rendered, `~ owner   : "ops" => <null>`)
+}
+
+func TestShowFullUpdates(t *testing.T) {
+	step := makeUpdateStep(
+		resource.NewPropertyMapFromMap(map[string]interface{}{
+			"labels":   map[string]interface{}{"app": "web", "tier": "frontend"},
+			"replicas": 3,
+		}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{
+			"labels":   map[string]interface{}{"app": "web"},
+			"replicas": 5,
+		}),
+		map[string]plugin.PropertyDiff{
+			"labels.tier": {Kind: plugin.DiffDelete},
+			"replicas":    {Kind: plugin.DiffUpdate},
+		})
+	opts := Options{
+		Color: colors.Never,
+		Type:  DisplayDiff,
+		DiffOptions: DiffOptions{
+			ShowFullUpdates: func(urn resource.URN) bool {
+				return urn == step.URN
+			},
+		},
+	}
+	assertGolden(t, "show_full_updates.txt", renderStepDiff(step, opts))
+
+	// Other resources are rendered as usual.
+	opts.ShowFullUpdates = func(urn resource.URN) bool { return false }
+	assert.Equal(t, renderStepDiff(step, Options{Color: colors.Never, Type: DisplayDiff}), renderStepDiff(step, opts))
+}
+
+func TestGroupReplacements(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"spec":  map[string]interface{}{"replicas": 3, "ports": []interface{}{80}},
+		"debug": true,
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"spec":  map[string]interface{}{"replicas": 5, "ports": []interface{}{80, 8080}},
+		"owner": "ops",
+	})
+	step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"spec.replicas": {Kind: plugin.DiffUpdateReplace},
+		"spec.ports[1]": {Kind: plugin.DiffAdd},
+		"debug":         {Kind: plugin.DiffDelete},
+		"owner":         {Kind: plugin.DiffAddReplace},
+	})
+	step.Op = deploy.OpReplace
+
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{GroupReplacements: true}}
+	grouped := renderStepDiff(step, opts)
+	assertGolden(t, "group_replacements.txt", grouped)
+
+	// Without replacing changes, the diff is rendered as usual.
+	step = makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"spec.replicas": {Kind: plugin.DiffUpdate},
+		"spec.ports[1]": {Kind: plugin.DiffAdd},
+		"debug":         {Kind: plugin.DiffDelete},
+		"owner":         {Kind: plugin.DiffAdd},
+	})
+	assert.Equal(t, renderStepDiff(step, Options{Color: colors.Never, Type: DisplayDiff}), renderStepDiff(step, opts))
+}
+
+func TestPropertyValueFormatting(t *testing.T) {
+	props := resource.PropertyMap{
+		"bool":     resource.NewBoolProperty(true),
+		"number":   resource.NewNumberProperty(3.5),
+		"string":   resource.NewStringProperty(`a "quoted" string`),
+		"computed": resource.MakeComputed(resource.NewStringProperty("")),
+		"output":   resource.MakeOutput(resource.NewNumberProperty(0)),
+		"secret":   resource.MakeSecret(resource.NewStringProperty("hunter2")),
+	}
+	added := resource.ObjectDiff{
+		Adds:    props,
+		Deletes: resource.PropertyMap{},
+		Sames:   resource.PropertyMap{},
+		Updates: map[resource.PropertyKey]resource.ValueDiff{},
+	}
+
+	var state, diff bytes.Buffer
+	engine.PrintObject(
+		&state, props, true /*planning*/, 1, deploy.OpSame, false /*prefix*/, false /*debug*/, nil /*render*/)
+	engine.PrintObjectDiff(
+		&diff, added, nil /*include*/, true /*planning*/, 1, false /*summary*/, false /*debug*/, nil /*render*/)
+	stateText, diffText := colors.Never.Colorize(state.String()), colors.Never.Colorize(diff.String())
+	assertGolden(t, "property_values.txt", stateText+diffText)
+
+	// Each value is displayed identically in a resource's state and in a diff.
+	stateLines, diffLines := strings.Split(stateText, "\n"), strings.Split(diffText, "\n")
+	for i, k := range props.StableKeys() {
+		expected := ": " + engine.FormatPropertyValue(props[k], true /*planning*/)
+		assert.True(t, strings.HasSuffix(stateLines[i], expected), stateLines[i])
+		assert.True(t, strings.HasSuffix(diffLines[i], expected), diffLines[i])
+	}
+
+	assert.Equal(t, "<null>", engine.FormatPropertyValue(resource.NewNullProperty(), true /*planning*/))
+	assert.Equal(t, "undefined", engine.FormatPropertyValue(props["computed"], false /*planning*/))
+	assert.Equal(t, "<object>", engine.FormatPropertyValue(resource.NewObjectProperty(props), true /*planning*/))
+}
+
+func TestJSONStringPaths(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"policy": `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": ["s3:GetObject"]}]}`,
+		"config": `{"debug": false}`,
+		"notes":  "not json",
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"policy": `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": ["s3:GetObject", "s3:PutObject"]}]}`,
+		"config": `{"debug": true}`,
+		"notes":  "still not json",
+	})
+	opts := Options{
+		Color: colors.Never,
+		Type:  DisplayDiff,
+		DiffOptions: DiffOptions{
+			JSONStringPaths: []string{"policy", "notes"},
+		},
+	}
+	assertGolden(t, "json_string_paths.txt", renderStepDiff(makeUpdateStep(olds, news, nil), opts))
+
+	// Strings that differ only in their formatting or in the order of their keys do not differ. Strings that are not
+	// JSON objects or arrays are diffed as strings.
+	diff, ok := diffJSONString(`{"a": 1, "b": {"c": [1, 2], "d": true}}`, `{"b":{"d":true,"c":[1,2]},"a":1}`)
+	assert.True(t, ok)
+	assert.Nil(t, diff)
+	_, ok = diffJSONString(`"a"`, `"b"`)
+	assert.False(t, ok)
+	assert.Error(t, ValidateJSONStringPaths([]string{`policy["unterminated`}))
+}
+
+func TestJSONStringReserialization(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"policy": `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "s3:GetObject"}]}`,
+		"rules":  []interface{}{`{"port": 80, "cidr": "0.0.0.0/0"}`},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"policy": `{"Statement":[{"Action":"s3:GetObject","Effect":"Allow"}],"Version":"2012-10-17"}`,
+		"rules":  []interface{}{`{"cidr": "0.0.0.0/0", "port": 80}`},
+	})
+	step := makeUpdateStep(olds, news, nil)
+
+	// Without structural diffing, the re-serialized strings are updates.
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	assert.NotNil(t, getStepDiff(step, opts))
+	assert.True(t, shouldShow(step, opts))
+
+	// With it, they produce no diff, and the update is displayed as a same.
+	opts.JSONStringPaths = []string{"policy", "rules.*"}
+	assert.Nil(t, getStepDiff(step, opts))
+	assert.False(t, shouldShow(step, opts))
+	assert.Equal(t, "", renderStepDiff(step, opts))
+
+	// Re-serialized strings alongside genuine changes are sames.
+	news["replicas"] = resource.NewNumberProperty(3)
+	step = makeUpdateStep(olds, news, nil)
+	assert.True(t, shouldShow(step, opts))
+	actual := renderStepDiff(step, opts)
+	assert.Contains(t, actual, "+ replicas: 3")
+	assert.NotContains(t, actual, "~ policy")
+	assert.NotContains(t, actual, "~ rules")
+}
+
+func TestResolveComputedDiffs(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"image": "nginx:1.16",
+	})
+	news := resource.PropertyMap{
+		"image": resource.NewStringProperty("nginx:1.17"),
+		"arn":   resource.MakeComputed(resource.NewStringProperty("")),
+		"endpoint": resource.NewObjectProperty(resource.PropertyMap{
+			"host": resource.MakeComputed(resource.NewStringProperty("")),
+			"port": resource.NewNumberProperty(443),
+		}),
+		"zone": resource.MakeComputed(resource.NewStringProperty("")),
+	}
+	step := makeUpdateStep(olds, news, nil)
+
+	resolved := step
+	resolved.New = &engine.StepEventStateMetadata{
+		Inputs: news,
+		Outputs: resource.NewPropertyMapFromMap(map[string]interface{}{
+			"image": "nginx:1.17",
+			"arn":   "arn:aws:ecs:us-west-2:123456789012:service/web",
+			"endpoint": map[string]interface{}{
+				"host": "web.example.com",
+				"port": 443,
+			},
+		}),
+	}
+
+	render := func(opts Options) string {
+		seen := map[resource.URN]engine.StepEventMetadata{
+			step.Res.Parent: {Res: &engine.StepEventStateMetadata{}},
+		}
+		var buf bytes.Buffer
+		buf.WriteString(renderDiffEvent(apitype.UpdateUpdate, engine.Event{
+			Type:    engine.ResourcePreEvent,
+			Payload: engine.ResourcePreEventPayload{Metadata: step},
+		}, seen, newDiffBudget(opts), opts))
+		buf.WriteString(renderDiffEvent(apitype.UpdateUpdate, engine.Event{
+			Type:    engine.ResourceOutputsEvent,
+			Payload: engine.ResourceOutputsEventPayload{Metadata: resolved},
+		}, seen, newDiffBudget(opts), opts))
+		return buf.String()
+	}
+
+	opts := Options{Color: colors.Never, Type: DisplayDiff, SuppressOutputs: true}
+	assert.NotContains(t, render(opts), "--resolved:--")
+
+	// The zone is missing from the outputs, so it is not resolved.
+	opts.computed = newComputedDiffLeaves()
+	assertGolden(t, "resolve_computed_diffs.txt", render(opts))
+	assert.Empty(t, opts.computed.leaves)
+}
+
+func TestFormatHTMLDiff(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"debug": true, "replicas": 3})
+	olds["password"] = resource.MakeSecret(resource.NewStringProperty("hunter2"))
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"replicas": 5,
+		"volumes":  []interface{}{map[string]interface{}{"name": "<data>", "sizeGb": 10}},
+	})
+	news["password"] = resource.MakeSecret(resource.NewStringProperty("correct horse"))
+	news["endpoint"] = resource.MakeComputed(resource.NewStringProperty(""))
+	step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"debug":    {Kind: plugin.DiffDelete},
+		"endpoint": {Kind: plugin.DiffAdd},
+		"password": {Kind: plugin.DiffUpdate},
+		"replicas": {Kind: plugin.DiffUpdate},
+		"volumes":  {Kind: plugin.DiffAddReplace},
+	})
+
+	assertGolden(t, "html_diff.html", FormatHTMLDiff(step, Options{}))
+
+	step.DetailedDiff = map[string]plugin.PropertyDiff{}
+	assert.Equal(t, "", FormatHTMLDiff(step, Options{}))
+}
+
+func TestFormatMarkdownDiff(t *testing.T) {
+	webOlds := resource.NewPropertyMapFromMap(map[string]interface{}{"replicas": 3, "tier": "frontend"})
+	webOlds["password"] = resource.MakeSecret(resource.NewStringProperty("hunter2"))
+	webNews := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"owner":    "ops",
+		"replicas": 5,
+		"volumes":  []interface{}{map[string]interface{}{"name": "data", "sizeGb": 10}},
+	})
+	webNews["password"] = resource.MakeSecret(resource.NewStringProperty("correct horse"))
+	web := makeUpdateStep(webOlds, webNews, map[string]plugin.PropertyDiff{
+		"owner":    {Kind: plugin.DiffAdd},
+		"password": {Kind: plugin.DiffUpdate},
+		"replicas": {Kind: plugin.DiffUpdate},
+		"tier":     {Kind: plugin.DiffDelete},
+		"volumes":  {Kind: plugin.DiffAddReplace},
+	})
+	web.Op = deploy.OpReplace
+
+	// Resources with many changes are collapsed.
+	olds, news := resource.PropertyMap{}, resource.PropertyMap{}
+	for i := 0; i < 12; i++ {
+		k := resource.PropertyKey(fmt.Sprintf("setting%02d", i))
+		olds[k] = resource.NewNumberProperty(float64(i))
+		news[k] = resource.NewNumberProperty(float64(i + 1))
+	}
+	news["script"] = resource.NewStringProperty("echo ```")
+	many := makeUpdateStep(olds, news, nil)
+	many.URN = resource.NewURN("stack", "project", "", "pkg:index:Config", "settings")
+	many.Type = many.URN.Type()
+
+	created := makeUpdateStep(nil, news, nil)
+	created.Op, created.Old = deploy.OpCreate, nil
+
+	steps := []engine.StepEventMetadata{web, many, created}
+	assertGolden(t, "diff_markdown.md", FormatMarkdownDiff(steps, Options{}))
+	assert.NotContains(t, FormatMarkdownDiff(steps, Options{}), "hunter2")
+
+	// The diff budget and string truncation apply.
+	text := FormatMarkdownDiff(steps, Options{DiffOptions: DiffOptions{DiffBudget: 3, GlobalDiffBudget: true}})
+	assert.Contains(t, text, "… and 2 more changes")
+	assert.Contains(t, text, "… and 13 more changes")
+	text = FormatMarkdownDiff(steps, Options{DiffOptions: DiffOptions{MaxStringDisplayLength: 3}})
+	assert.Contains(t, text, `- tier: "fro"… (8 characters)`)
+
+	assert.Equal(t, "", FormatMarkdownDiff(nil, Options{}))
+}
+
+func TestGroupChangesByKind(t *testing.T) {
+	web := makeUpdateStep(
+		resource.NewPropertyMapFromMap(map[string]interface{}{"debug": true, "spec": map[string]interface{}{"replicas": 3}}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{"owner": "ops", "spec": map[string]interface{}{"replicas": 5}}),
+		map[string]plugin.PropertyDiff{
+			"debug":         {Kind: plugin.DiffDelete},
+			"owner":         {Kind: plugin.DiffAdd},
+			"spec.replicas": {Kind: plugin.DiffUpdate},
+		})
+
+	db := makeUpdateStep(
+		resource.NewPropertyMapFromMap(map[string]interface{}{"engine": "postgres", "size": 10, "zone": "a"}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{"engine": "mysql", "size": 20}),
+		map[string]plugin.PropertyDiff{
+			"engine": {Kind: plugin.DiffUpdateReplace},
+			"size":   {Kind: plugin.DiffUpdate},
+			"zone":   {Kind: plugin.DiffDeleteReplace},
+		})
+	db.Op = deploy.OpReplace
+	db.URN = resource.NewURN("stack", "project", "", "pkg:index:Database", "db")
+
+	payload := engine.SummaryEventPayload{
+		IsPreview:       true,
+		ResourceChanges: engine.ResourceChanges{deploy.OpUpdate: 1, deploy.OpReplace: 1},
+	}
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	steps := []engine.StepEventMetadata{web, db}
+	assert.NotContains(t, renderSummaryEvent(apitype.UpdateUpdate, payload, steps, opts), "Changes by kind")
+
+	opts.GroupChangesByKind = true
+	assertGolden(t, "group_changes_by_kind.txt", renderSummaryEvent(apitype.UpdateUpdate, payload, steps, opts))
+}
+
+func TestDiffLegend(t *testing.T) {
+	web := makeUpdateStep(
+		resource.NewPropertyMapFromMap(map[string]interface{}{"replicas": 3}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{"replicas": 5}),
+		map[string]plugin.PropertyDiff{"replicas": {Kind: plugin.DiffUpdate}})
+
+	payload := engine.SummaryEventPayload{
+		IsPreview:       true,
+		ResourceChanges: engine.ResourceChanges{deploy.OpUpdate: 1},
+	}
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	steps := []engine.StepEventMetadata{web}
+	assert.NotContains(t, renderSummaryEvent(apitype.UpdateUpdate, payload, steps, opts), "Legend")
+
+	// Only the markers that appear are explained.
+	opts.ShowDiffLegend = true
+	actual := renderSummaryEvent(apitype.UpdateUpdate, payload, steps, opts)
+	assert.Contains(t, actual, "Legend:\n    ~ updated in place, or property changed\n\n")
+
+	db := makeUpdateStep(
+		resource.NewPropertyMapFromMap(map[string]interface{}{"engine": "postgres", "size": 10}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{"engine": "mysql", "size": 10}),
+		map[string]plugin.PropertyDiff{"engine": {Kind: plugin.DiffUpdateReplace}})
+	db.Op = deploy.OpReplace
+	db.URN = resource.NewURN("stack", "project", "", "pkg:index:Database", "db")
+
+	web = makeUpdateStep(
+		resource.NewPropertyMapFromMap(map[string]interface{}{"debug": true, "replicas": 3}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{"owner": "ops", "replicas": 5}),
+		map[string]plugin.PropertyDiff{
+			"debug":    {Kind: plugin.DiffDelete},
+			"owner":    {Kind: plugin.DiffAdd},
+			"replicas": {Kind: plugin.DiffUpdate},
+		})
+
+	payload.ResourceChanges = engine.ResourceChanges{deploy.OpUpdate: 1, deploy.OpReplace: 1}
+	steps = []engine.StepEventMetadata{web, db}
+	assertGolden(t, "diff_legend.txt", renderSummaryEvent(apitype.UpdateUpdate, payload, steps, opts))
+}
+
+func TestDiffTreeStyles(t *testing.T) {
+	step := makeUpdateStep(
+		resource.NewPropertyMapFromMap(map[string]interface{}{
+			"spec":  map[string]interface{}{"replicas": 3, "labels": map[string]interface{}{"tier": "frontend"}},
+			"debug": true,
+		}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{
+			"spec":    map[string]interface{}{"replicas": 5, "labels": map[string]interface{}{}},
+			"volumes": []interface{}{map[string]interface{}{"name": "data", "sizeGb": 10}},
+		}),
+		map[string]plugin.PropertyDiff{
+			"spec.replicas":    {Kind: plugin.DiffUpdate},
+			"spec.labels.tier": {Kind: plugin.DiffDelete},
+			"debug":            {Kind: plugin.DiffDelete},
+			"volumes":          {Kind: plugin.DiffAdd},
+		})
+
+	cases := []struct {
+		golden string
+		width  int
+		style  DiffTreeStyle
+	}{
+		{"diff_tree_none.txt", 2, DiffTreeNone},
+		{"diff_tree_ascii.txt", 0, DiffTreeASCII},
+		{"diff_tree_unicode.txt", 3, DiffTreeUnicode},
+	}
+	for _, c := range cases {
+		opts := Options{Color: colors.Never, Type: DisplayDiff,
+			DiffOptions: DiffOptions{DiffIndentWidth: c.width, DiffTreeStyle: c.style}}
+		actual := renderStepDiff(step, opts)
+		if c.style != DiffTreeUnicode {
+			for _, r := range actual {
+				assert.True(t, r < 0x80, "unexpected non-ASCII character %q in %s", r, c.golden)
+			}
+		}
+		assertGolden(t, c.golden, actual)
+	}
+
+	_, err := ParseDiffTreeStyle("fancy")
+	assert.Error(t, err)
+}
+
+func TestDiffExpectedProperties(t *testing.T) {
+	expected := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name": "web",
+		"spec": map[string]interface{}{
+			"replicas": 3,
+			"ports":    []interface{}{80, 443},
+		},
+	})
+	expected["password"] = resource.MakeSecret(resource.NewStringProperty("hunter2"))
+	expected["token"] = resource.MakeSecret(resource.NewStringProperty("abc"))
+	opts := Options{Color: colors.Never}
+
+	// Secrets match equal values, whether or not those values are also secrets.
+	actual := expected.Copy()
+	actual["password"] = resource.NewStringProperty("hunter2")
+	diff, match := DiffExpectedProperties(expected, actual, opts)
+	assert.True(t, match)
+	assert.Equal(t, "", diff)
+
+	actual = resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name": "web",
+		"spec": map[string]interface{}{
+			"replicas": 5,
+			"ports":    []interface{}{80, 443},
+		},
+		"owner": "ops",
+	})
+	actual["password"] = resource.MakeSecret(resource.NewStringProperty("hunter2"))
+	actual["token"] = resource.MakeSecret(resource.NewStringProperty("xyz"))
+	diff, match = DiffExpectedProperties(expected, actual, opts)
+	assert.False(t, match)
+	assert.NotContains(t, diff, "abc")
+	assert.NotContains(t, diff, "xyz")
+	assertGolden(t, "expected_properties.txt", diff)
+}
+
+func TestPropertyColors(t *testing.T) {
+	step := makeUpdateStep(
+		resource.NewPropertyMapFromMap(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"replicas": 3,
+				"ports":    []interface{}{80},
+				"labels":   map[string]interface{}{"tier": "frontend"},
+			},
+			"debug": true,
+		}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"replicas": 5,
+				"ports":    []interface{}{80, 8080},
+				"labels":   map[string]interface{}{},
+			},
+		}),
+		map[string]plugin.PropertyDiff{
+			"spec.replicas":    {Kind: plugin.DiffUpdate},
+			"spec.ports[1]":    {Kind: plugin.DiffAdd},
+			"spec.labels.tier": {Kind: plugin.DiffDelete},
+			"debug":            {Kind: plugin.DiffDelete},
+		})
+
+	opts := Options{Color: colors.Raw, Type: DisplayDiff, DiffOptions: DiffOptions{PropertyColors: []PropertyColor{
+		{Path: "spec.labels.**", Color: colors.BrightCyan},
+		{Path: "spec.*", Color: colors.Blue},
+		{Path: "**.ports.*", Color: colors.BrightRed},
+		{Path: "[invalid", Color: colors.Magenta},
+	}}}
+	assertGolden(t, "property_colors.txt", renderStepDiff(step, opts))
+
+	opts.DiffTreeStyle = DiffTreeASCII
+	assertGolden(t, "property_colors_tree.txt", renderStepDiff(step, opts))
+}
+
+func TestPropertyOrder(t *testing.T) {
+	step := makeUpdateStep(
+		resource.NewPropertyMapFromMap(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"size":     "small",
+				"replicas": 3,
+				"labels":   map[string]interface{}{"tier": "frontend"},
+			},
+			"debug": true,
+		}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"size":     "large",
+				"replicas": 5,
+				"labels":   map[string]interface{}{},
+			},
+			"owner": "ops",
+		}),
+		map[string]plugin.PropertyDiff{
+			"spec.size":        {Kind: plugin.DiffUpdate},
+			"spec.replicas":    {Kind: plugin.DiffUpdate},
+			"spec.labels.tier": {Kind: plugin.DiffDelete},
+			"debug":            {Kind: plugin.DiffDelete},
+			"owner":            {Kind: plugin.DiffAdd},
+		})
+
+	// Properties that match a pattern come first, in the order of the patterns; the others follow in key order.
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{PropertyOrder: []string{
+		"replicas",
+		"owner",
+		"spec.labels",
+		"[invalid",
+	}}}
+	assertGolden(t, "property_order.txt", renderStepDiff(step, opts))
+
+	opts.DiffTreeStyle = DiffTreeASCII
+	assertGolden(t, "property_order_tree.txt", renderStepDiff(step, opts))
+}
+
+func TestShowArrayMoves(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"hosts": []interface{}{"a", "b", "c", "d"},
+		"ports": []interface{}{80, 443},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"hosts": []interface{}{"x", "a", "b", "c"},
+		"ports": []interface{}{80, 8443},
+	})
+	step := makeUpdateStep(olds, news, nil)
+
+	// "x" was added at 0, which moved "a", "b", and "c" along by one, and "d" was removed from 3. The ports were not
+	// aligned, so their elements are labeled by position as usual.
+	opts := Options{Color: colors.Never, Type: DisplayDiff,
+		DiffOptions: DiffOptions{AlignArrays: true, ShowArrayMoves: true}}
+	assertGolden(t, "array_moves.txt", renderStepDiff(step, opts))
+
+	opts.DiffTreeStyle = DiffTreeASCII
+	assertGolden(t, "array_moves_tree.txt", renderStepDiff(step, opts))
+}
+
+func TestFlattenDiffDepth(t *testing.T) {
+	deployment := func(image, version string, replicas int) resource.PropertyMap {
+		return resource.NewPropertyMapFromMap(map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "web"},
+			"spec": map[string]interface{}{
+				"replicas": replicas,
+				"template": map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"annotations": map[string]interface{}{"app.kubernetes.io/version": version},
+					},
+					"spec": map[string]interface{}{
+						"containers": []interface{}{map[string]interface{}{"name": "web", "image": image}},
+					},
+				},
+			},
+		})
+	}
+	step := makeUpdateStep(deployment("nginx:1.16", "1", 3), deployment("nginx:1.17", "2", 3),
+		map[string]plugin.PropertyDiff{
+			`spec.template.metadata.annotations["app.kubernetes.io/version"]`: {Kind: plugin.DiffUpdate},
+			"spec.template.spec.containers[0].image":                          {Kind: plugin.DiffUpdate},
+		})
+
+	var actual string
+	for _, depth := range []int{1, 3, 4, 5} {
+		opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{FlattenDiffDepth: depth}}
+		actual += fmt.Sprintf("depth %d:\n", depth) + renderStepDiff(step, opts)
+	}
+	assertGolden(t, "flatten_diff_depth.txt", actual)
+}
+
+func TestMatchKeyCasing(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"maxSize":  10,
+		"min_size": 1,
+		"tags":     map[string]interface{}{"Owner_Name": "ops", "team": "web"},
+		"zone_a":   "us-west-2a",
+		"Zone-A":   "us-west-2b",
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"max_size": 20,
+		"minSize":  1,
+		"tags":     map[string]interface{}{"ownerName": "ops", "team": "api"},
+		"zoneA":    "us-west-2a",
+	})
+	step := makeUpdateStep(olds, news, nil)
+
+	// Without matching, each renamed property is a delete and an add.
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	actual := renderStepDiff(step, opts)
+	assert.Contains(t, actual, "- maxSize")
+	assert.Contains(t, actual, "+ max_size")
+
+	// With matching, renamed properties are labeled by both names. The zones are ambiguous and so are not matched.
+	opts.MatchKeyCasing = true
+	assertGolden(t, "match_key_casing.txt", renderStepDiff(step, opts))
+
+	// Properties are matched when a detailed diff only names one of them, too.
+	step = makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{"max_size": {Kind: plugin.DiffUpdate}})
+	actual = renderStepDiff(step, opts)
+	assert.Contains(t, actual, "~ maxSize→max_size: 10 => 20")
+	assert.NotContains(t, actual, "minSize")
+
+	// Renamed properties are keyed by their new names and record their old names, whichever name a detailed diff uses,
+	// so that ignore paths, ordering, and serialization all see the new names.
+	for _, name := range []string{"maxSize", "max_size"} {
+		step = makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{name: {Kind: plugin.DiffAdd}})
+		diff := translateStepDiff(step, opts)
+		assert.Equal(t, map[resource.PropertyKey]resource.PropertyKey{"max_size": "maxSize"}, diff.Renames)
+		assert.Equal(t, resource.NewNumberProperty(20), diff.Updates["max_size"].New)
+		assert.Equal(t, map[string]string{"max_size": "maxSize"}, serializeObjectDiff(diff, false).Renames)
+	}
+	opts.IgnoreDiffPaths = []string{"max_size"}
+	assert.NotContains(t, renderStepDiff(step, opts), "max_size")
+	assert.NotContains(t, renderStepDiff(makeUpdateStep(olds, news, nil), opts), "max_size")
+
+	// Secrets that are renamed are masked, like any other secret.
+	olds["maxSize"], news["max_size"] = resource.MakeSecret(olds["maxSize"]), resource.MakeSecret(news["max_size"])
+	actual = renderStepDiff(makeUpdateStep(olds, news, nil), Options{
+		Color: colors.Never,
+		Type:  DisplayDiff,
+		DiffOptions: DiffOptions{
+			MatchKeyCasing: true,
+		},
+	})
+	assert.Contains(t, actual, "~ maxSize→max_size: [secret]")
+	assert.NotContains(t, actual, "20")
+}
+
+func TestDiffContext(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6, "g": 7, "h": 8,
+		"hosts": []interface{}{"h0", "h1", "h2", "h3", "h4", "h5", "h6", "h7"},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"a": 1, "b": 2, "c": 30, "d": 4, "e": 5, "f": 6, "g": 7, "h": 8,
+		"hosts": []interface{}{"h0", "h1", "h2", "h3", "h4", "h5", "h6", "h7-new"},
+	})
+	step := makeUpdateStep(olds, news, nil)
+
+	// With a context of one, only the siblings next to each change are shown: b and d around c, h around hosts, and
+	// [6] before [7] within hosts. The other runs of sames are elided.
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{DiffContext: 1}}
+	assertGolden(t, "diff_context.txt", renderStepDiff(step, opts))
+
+	opts.DiffTreeStyle = DiffTreeASCII
+	assertGolden(t, "diff_context_tree.txt", renderStepDiff(step, opts))
+
+	// A context that covers all siblings elides nothing.
+	opts = Options{Color: colors.Never, Type: DisplayDiff}
+	full := renderStepDiff(step, opts)
+	opts.DiffContext = 9
+	assert.Equal(t, full, renderStepDiff(step, opts))
+	assert.NotContains(t, full, "unchanged")
+
+	assert.Equal(t, []int{0, 1, 5}, getElidedSames([]bool{false, false, false, true, false, false}, 1))
+	assert.Equal(t, []int{0, 6}, getElidedSames([]bool{false, false, false, true, false, false, false}, 2))
+	assert.Empty(t, getElidedSames([]bool{true, false, false, true}, 1))
+}
+
+func TestEmptyStringEqualsNull(t *testing.T) {
+	olds := resource.PropertyMap{
+		"description": resource.NewStringProperty(""),
+		"owner":       resource.NewNullProperty(),
+		"zone":        resource.NewStringProperty(""),
+	}
+	news := resource.PropertyMap{
+		"description": resource.NewNullProperty(),
+		"owner":       resource.NewStringProperty(""),
+		"zone":        resource.NewStringProperty(""),
+	}
+	step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"description": {Kind: plugin.DiffUpdate},
+		"owner":       {Kind: plugin.DiffUpdate},
+	})
+
+	// Updates between empty strings and nulls are only hidden if requested.
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	assert.Contains(t, renderStepDiff(step, opts), "(update)")
+	assert.Len(t, getStepDiff(step, opts).Updates, 2)
+	opts.NormalizeDiffs = resource.DiffNormalization{EmptyStringEqualsNull: true}
+	assert.Equal(t, "", renderStepDiff(step, opts))
+
+	// Genuine changes from empty strings still show, while the updates to and from null are displayed as sames.
+	news["zone"] = resource.NewStringProperty("us-west-2a")
+	step.DetailedDiff["zone"] = plugin.PropertyDiff{Kind: plugin.DiffUpdate}
+	diff := getStepDiff(step, opts)
+	assert.Equal(t, []resource.PropertyKey{"zone"}, diff.Keys()[2:])
+	assert.True(t, diff.Updated("zone"))
+	assert.True(t, diff.Same("description"))
+	assert.True(t, diff.Same("owner"))
+
+	equals := EmptyStringEqualsNull(nil)
+	assert.True(t, equals(resource.NewStringProperty(""), resource.NewNullProperty()))
+	assert.True(t, equals(resource.NewNullProperty(), resource.NewStringProperty("")))
+	assert.False(t, equals(resource.NewStringProperty(""), resource.NewStringProperty(" ")))
+	assert.True(t, equals(resource.NewStringProperty("a"), resource.NewStringProperty("a")))
+
+	norm, err := ParseDiffNormalization([]string{"empty-strings"})
+	assert.NoError(t, err)
+	assert.Equal(t, resource.DiffNormalization{EmptyStringEqualsNull: true}, norm)
+}
+
+func TestArrayDiffWindow(t *testing.T) {
+	var added, oldPorts, newPorts []interface{}
+	for i := 0; i < 500; i++ {
+		added = append(added, fmt.Sprintf("host-%d", i))
+	}
+	for i := 0; i < 20; i++ {
+		oldPorts = append(oldPorts, 8000+i)
+		if i%2 == 0 {
+			newPorts = append(newPorts, 9000+i)
+		} else {
+			newPorts = append(newPorts, 8000+i)
+		}
+	}
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"hosts": []interface{}{},
+		"ports": oldPorts,
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"hosts": added,
+		"ports": newPorts,
+	})
+	step := makeUpdateStep(olds, news, nil)
+
+	// Only the first and last two changes of each array are shown. The 496 hosts in between are counted, as are the
+	// 6 changed ports in between, whose unchanged neighbors are omitted along with them.
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{ArrayDiffWindow: 2}}
+	text := renderStepDiff(step, opts)
+	assert.Contains(t, text, "… (496 more)")
+	assert.Contains(t, text, "… (6 more)")
+	assert.NotContains(t, text, "host-2\"")
+	assert.Contains(t, text, "host-498")
+	assertGolden(t, "diff_array_window.txt", text)
+
+	opts.DiffTreeStyle = DiffTreeASCII
+	assert.Contains(t, renderStepDiff(step, opts), "… (496 more)")
+
+	// The omitted changes are counted exactly, whatever the window.
+	diff := getStepDiff(step, Options{})
+	for _, window := range []int{1, 3, 10, 100, 249} {
+		windowed := windowObjectDiffArrays(diff, window).Updates["hosts"].Array
+		omitted := 0
+		for i := range windowed.Adds {
+			if windowed.Omitted[i] {
+				omitted++
+			}
+		}
+		assert.Equal(t, 500-2*window, omitted)
+		assert.False(t, windowed.Omitted[window-1])
+		assert.True(t, windowed.Omitted[window])
+		assert.False(t, windowed.Omitted[500-window])
+	}
+
+	// Arrays with no more than twice the window's changes are shown in full.
+	assert.Nil(t, windowObjectDiffArrays(diff, 250).Updates["hosts"].Array.Omitted)
+	assert.Nil(t, windowObjectDiffArrays(diff, 5).Updates["ports"].Array.Omitted)
+	full := renderStepDiff(makeUpdateStep(olds, news, nil), Options{Color: colors.Never, Type: DisplayDiff})
+	opts = Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{ArrayDiffWindow: 250}}
+	assert.Equal(t, full, renderStepDiff(step, opts))
+}
+
+func TestValueEquality(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"region": "US-East-1",
+		"tags":   map[string]interface{}{"env": "Prod", "team": "web"},
+		"zones":  []interface{}{"a", "b"},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"region": "us-east-1",
+		"tags":   map[string]interface{}{"env": "prod", "team": "api"},
+		"zones":  []interface{}{"A", "b", "c"},
+	})
+	caseInsensitive := func(a, b resource.PropertyValue) bool {
+		if a.IsString() && b.IsString() {
+			return strings.EqualFold(a.StringValue(), b.StringValue())
+		}
+		return a.DeepEquals(b)
+	}
+
+	// Updates that are the same under the equality are displayed as unchanged, both in diffs computed by the display
+	// and in detailed diffs reported by providers.
+	for _, detailedDiff := range []map[string]plugin.PropertyDiff{nil, {
+		"region":    {Kind: plugin.DiffUpdate},
+		"tags.env":  {Kind: plugin.DiffUpdate},
+		"tags.team": {Kind: plugin.DiffUpdate},
+		"zones[0]":  {Kind: plugin.DiffUpdate},
+		"zones[2]":  {Kind: plugin.DiffAdd},
+	}} {
+		step := makeUpdateStep(olds, news, detailedDiff)
+		opts := Options{Color: colors.Never, Type: DisplayDiff}
+		assert.Contains(t, renderStepDiff(step, opts), "US-East-1")
+
+		opts.Equals = caseInsensitive
+		text := renderStepDiff(step, opts)
+		assert.NotContains(t, text, "US-East-1")
+		assert.NotContains(t, text, "Prod")
+		assert.NotContains(t, text, "\"a\" => \"A\"")
+		assert.Contains(t, text, "\"api\"")
+		assert.Contains(t, text, "\"c\"")
+		assert.Equal(t, 2, getDiffStats(getStepDiff(step, opts)).Changes())
+	}
+
+	// Updates all of whose changes are the same under the equality are hidden.
+	step := makeUpdateStep(
+		resource.PropertyMap{"region": resource.NewStringProperty("US-East-1")},
+		resource.PropertyMap{"region": resource.NewStringProperty("us-east-1")}, nil)
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{Equals: caseInsensitive}}
+	assert.Equal(t, "", renderStepDiff(step, opts))
+
+	// The equality also matches the elements of unordered arrays.
+	opts.UnorderedArrayPaths = []string{"zones"}
+	step = makeUpdateStep(olds, news, nil)
+	assert.Equal(t, &resource.ArrayDiff{
+		Adds:    map[int]resource.PropertyValue{2: resource.NewStringProperty("c")},
+		Deletes: map[int]resource.PropertyValue{},
+		Sames: map[int]resource.PropertyValue{
+			0: resource.NewStringProperty("A"),
+			1: resource.NewStringProperty("b"),
+		},
+		Updates: map[int]resource.ValueDiff{},
+	}, getStepDiff(step, opts).Updates["zones"].Array)
+}
+
+func TestTupleArrays(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"range":   []interface{}{80, 443, "tcp"},
+		"command": []interface{}{"run", "web"},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"range":   []interface{}{8080, 80, 443, "tcp"},
+		"command": []interface{}{"run", "--verbose", "web"},
+	})
+	step := makeUpdateStep(olds, news, nil)
+
+	// As lists, the arrays' common elements are aligned, so each array has a single insertion. As tuples, the elements
+	// at each position are compared, so each position whose value changed is an update.
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{AlignArrays: true}}
+	text := renderStepDiff(step, opts)
+	opts.TupleArrayPaths = []string{"range"}
+	text += renderStepDiff(step, opts)
+	assertGolden(t, "diff_tuple_arrays.txt", text)
+
+	rangeDiff := getStepDiff(step, opts).Updates["range"].Array
+	assert.Len(t, rangeDiff.Updates, 3)
+	assert.Equal(t, map[int]resource.PropertyValue{3: resource.NewStringProperty("tcp")}, rangeDiff.Adds)
+	assert.Nil(t, rangeDiff.Moves)
+	assert.NotNil(t, getStepDiff(step, opts).Updates["command"].Array.Moves)
+
+	// Detailed diffs already compare the elements of arrays by position.
+	step = makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{"range[0]": {Kind: plugin.DiffUpdate}})
+	assert.Len(t, getStepDiff(step, opts).Updates["range"].Array.Updates, 1)
+
+	assert.NoError(t, ValidateTupleArrayPaths([]string{"**.ports", "range"}))
+	assert.Error(t, ValidateTupleArrayPaths([]string{"foo["}))
+}
+
+func TestMultilineStringDiff(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"added":   "#!/bin/sh\nset -e\n",
+		"removed": "#!/bin/sh\nset -e\nset -x\necho start\n",
+		"changed": "[server]\nport = 80\nhost = example.com\n",
+		"name":    "web\n",
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"added":   "#!/bin/sh\nset -e\nmake build\nmake test\n",
+		"removed": "#!/bin/sh\nset -e\necho start\n",
+		"changed": "[server]\nport = 8080\nhost = example.com\n",
+		"name":    "api\n",
+	})
+
+	// Multi-line strings are diffed line by line, while single-line strings are rendered inline, even if they end in a
+	// newline.
+	var text string
+	for _, detailedDiff := range []map[string]plugin.PropertyDiff{nil, {
+		"added":   {Kind: plugin.DiffUpdate},
+		"removed": {Kind: plugin.DiffUpdate},
+		"changed": {Kind: plugin.DiffUpdate},
+		"name":    {Kind: plugin.DiffUpdate},
+	}} {
+		text += renderStepDiff(makeUpdateStep(olds, news, detailedDiff), Options{Color: colors.Never, Type: DisplayDiff})
+	}
+	assertGolden(t, "diff_multiline_strings.txt", text)
+}
+
+func TestDiffWidth(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"description": "a short description",
+		"role":        "arn:aws:iam::123456789012:role/service-role/web-execution-role",
+		"spec": map[string]interface{}{
+			"command": "run",
+			"zone":    "a",
+		},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"description": "a much longer description of the service that no longer fits on a single line",
+		"role":        "arn:aws:iam::123456789012:role/service-role/web-execution-role-v2",
+		"spec": map[string]interface{}{
+			"command": "run --listen 0.0.0.0:8080 --workers 16 --log-level debug",
+			"zone":    "a",
+		},
+	})
+	step := makeUpdateStep(olds, news, nil)
+
+	// Long values are wrapped at spaces if they have any and at the width otherwise, and are continued at the column at
+	// which they start. Trees continue the lines that connect later siblings.
+	for _, width := range []int{40, 72} {
+		opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{DiffWidth: width}}
+		text := renderStepDiff(step, opts)
+		opts.DiffTreeStyle = DiffTreeUnicode
+		text += renderStepDiff(step, opts)
+
+		for _, line := range strings.Split(text, "\n") {
+			assert.True(t, utf8.RuneCountInString(line) <= width, line)
+		}
+		assertGolden(t, fmt.Sprintf("diff_width_%d.txt", width), text)
+	}
+
+	// Continuation lines keep the colors of the lines that they continue.
+	a := strings.Repeat("a", 20)
+	assert.Equal(t, []string{colors.Green + "+ k: " + a, "     " + colors.Green + "bbbb" + colors.Reset},
+		wrapDiffLine(colors.Green+"+ k: "+a+" bbbb"+colors.Reset, 28, 5, "     "))
+
+	// Without a width, lines are not wrapped.
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	assert.Contains(t, renderStepDiff(step, opts), "no longer fits on a single line")
+}
+
+func TestDiffPathsOnly(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": 3, "labels": map[string]interface{}{"tier": "frontend"}},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"spec":  map[string]interface{}{"replicas": 5, "labels": map[string]interface{}{}},
+		"owner": "ops",
+	})
+	makeStep := func(replicas plugin.DiffKind) engine.StepEventMetadata {
+		return makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+			"spec.replicas":    {Kind: replicas},
+			"spec.labels.tier": {Kind: plugin.DiffDelete},
+			"owner":            {Kind: plugin.DiffAdd},
+		})
+	}
+
+	// Each changed path is rendered once, marked with its kind of change, and without its values.
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{DiffPathsOnly: true}}
+	text := renderStepDiff(makeStep(plugin.DiffUpdate), opts)
+	assert.NotContains(t, text, "frontend")
+	assert.NotContains(t, text, "ops")
+
+	// Paths that require replacement are marked as such.
+	step := makeStep(plugin.DiffUpdateReplace)
+	step.Op = deploy.OpReplace
+	step.Keys = []resource.PropertyKey{"spec"}
+	text += renderStepDiff(step, opts)
+	assertGolden(t, "diff_paths_only.txt", text)
+
+	// Creates render no paths.
+	step = makeUpdateStep(nil, news, nil)
+	step.Op, step.Old = deploy.OpCreate, nil
+	assert.NotContains(t, renderStepDiff(step, opts), "owner")
+}
+
+func TestReplacementReasons(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name": "web",
+		"zone": "a",
+		"size": 2,
+		"tags": map[string]interface{}{"app": "web"},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name": "web",
+		"zone": "b",
+		"size": 2,
+		"tags": map[string]interface{}{"app": "web"},
+	})
+
+	// The provider reports that both the zone and the name force the replacement, but only the zone changed; the
+	// properties that are recreated with the same values are listed apart.
+	expected := replacementReasons{
+		Changed:   []string{"zone"},
+		Unchanged: []string{"name"},
+		Recreated: []string{"name", "size", "tags"},
+	}
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{ShowReplacementReasons: true}}
+	var text string
+	for _, detailedDiff := range []map[string]plugin.PropertyDiff{nil, {
+		"zone": {Kind: plugin.DiffUpdateReplace},
+		"name": {Kind: plugin.DiffUpdateReplace},
+	}} {
+		step := makeUpdateStep(olds, news, detailedDiff)
+		step.Op = deploy.OpReplace
+		step.Keys = []resource.PropertyKey{"zone", "name"}
+		assert.Equal(t, expected, getReplacementReasons(step, opts))
+		text += renderStepDiff(step, opts)
+	}
+	assertGolden(t, "replacement_reasons.txt", text)
+
+	// Nothing is rendered for updates, or unless requested.
+	step := makeUpdateStep(olds, news, nil)
+	assert.NotContains(t, renderStepDiff(step, opts), "forcing replacement")
+	step.Op = deploy.OpReplace
+	assert.NotContains(t, renderStepDiff(step, Options{Color: colors.Never, Type: DisplayDiff}), "forcing replacement")
+}
+
+func TestDetectMovedProperties(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"network": map[string]interface{}{
+			"subnet": "subnet-1",
+			"rules":  []interface{}{map[string]interface{}{"port": 443}},
+			"zone":   "a",
+		},
+		"vpc":    map[string]interface{}{},
+		"tags":   map[string]interface{}{"team": "web", "owner": "web"},
+		"size":   "large",
+		"cpu":    2,
+		"mem":    2,
+		"limits": map[string]interface{}{},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"network": map[string]interface{}{
+			"zone": "a",
+		},
+		"vpc": map[string]interface{}{
+			"subnet": "subnet-1",
+			"rules":  []interface{}{map[string]interface{}{"port": 443}},
+		},
+		"labels":   map[string]interface{}{"team": "web", "owner": "web"},
+		"capacity": "large",
+		"limits":   map[string]interface{}{"cpu": 2},
+	})
+	step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"network.subnet": {Kind: plugin.DiffDelete},
+		"network.rules":  {Kind: plugin.DiffDelete},
+		"vpc.subnet":     {Kind: plugin.DiffAdd},
+		"vpc.rules":      {Kind: plugin.DiffAdd},
+		"tags":           {Kind: plugin.DiffDelete},
+		"labels":         {Kind: plugin.DiffAdd},
+		"size":           {Kind: plugin.DiffDelete},
+		"capacity":       {Kind: plugin.DiffAdd},
+		"cpu":            {Kind: plugin.DiffDelete},
+		"mem":            {Kind: plugin.DiffDelete},
+		"limits.cpu":     {Kind: plugin.DiffAdd},
+	})
+
+	// By default, only properties that keep their names are detected as moves. Once properties may also move between
+	// names, the deleted cpu and mem are both equal to the added limits.cpu, so neither is paired with it.
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{DetectMovedProperties: true}}
+	assertGolden(t, "moved_properties.txt", renderStepDiff(step, opts))
+
+	opts.MovedPropertiesAnyKey = true
+	assertGolden(t, "moved_properties_any_key.txt", renderStepDiff(step, opts))
+}
+
+func TestShowSecretChanges(t *testing.T) {
+	secret := func(s string) resource.PropertyValue { return resource.MakeSecret(resource.NewStringProperty(s)) }
+
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name":     "db",
+		"settings": map[string]interface{}{"port": 5432},
+	})
+	olds["password"] = secret("hunter2")
+	olds["legacyKey"] = secret("old-key")
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name":     "db-1",
+		"settings": map[string]interface{}{"port": 5432},
+		"replicas": []interface{}{map[string]interface{}{"zone": "a"}},
+	})
+	news["password"] = secret("correct-horse")
+	news["settings"].ObjectValue()["token"] = secret("t0ken")
+	news["replicas"].ArrayValue()[0].ObjectValue()["key"] = secret("replica-key")
+	db := makeStateUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"name":           {Kind: plugin.DiffUpdate},
+		"password":       {Kind: plugin.DiffUpdate},
+		"legacyKey":      {Kind: plugin.DiffDelete},
+		"settings.token": {Kind: plugin.DiffAdd},
+		"replicas":       {Kind: plugin.DiffAdd},
+	})
+
+	stack := engine.NewStepEventStateMetadata(&resource.State{URN: db.Res.Parent, Type: resource.RootStackType}, false)
+	events := []engine.Event{
+		{Type: engine.ResourcePreEvent, Payload: engine.ResourcePreEventPayload{
+			Metadata: engine.StepEventMetadata{
+				Op: deploy.OpSame, URN: stack.URN, Type: stack.Type, Old: stack, New: stack, Res: stack},
+			Planning: true,
+		}},
+		{Type: engine.ResourcePreEvent, Payload: engine.ResourcePreEventPayload{Metadata: db, Planning: true}},
+		{Type: engine.SummaryEvent, Payload: engine.SummaryEventPayload{
+			IsPreview:       true,
+			ResourceChanges: engine.ResourceChanges{deploy.OpUpdate: 1},
+		}},
+	}
+	render := func(opts Options) string {
+		var buf bytes.Buffer
+		seen := make(map[resource.URN]engine.StepEventMetadata)
+		for _, event := range events {
+			buf.WriteString(RenderDiffEvent(apitype.UpdateUpdate, event, seen, opts))
+		}
+		return buf.String()
+	}
+
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	assert.NotContains(t, render(opts), "Secret changes")
+
+	opts.ShowSecretChanges = true
+	actual := render(opts)
+	for _, s := range []string{"hunter2", "correct-horse", "old-key", "t0ken", "replica-key"} {
+		assert.NotContains(t, actual, s)
+	}
+	assertGolden(t, "secret_changes.txt", actual)
+}
+
+func TestSecretTransitionDiff(t *testing.T) {
+	secret := func(v interface{}) resource.PropertyValue { return resource.MakeSecret(resource.NewPropertyValue(v)) }
+
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"password": "hunter2",
+		"apiKey":   "key-1",
+		"db":       map[string]interface{}{"user": "admin"},
+	})
+	olds["region"] = secret("us-west-2")
+	olds["port"] = secret(5432)
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"region": "us-west-2",
+		"port":   5433,
+	})
+	news["password"] = secret("hunter2")
+	news["apiKey"] = secret("key-2")
+	news["db"] = secret(map[string]interface{}{"user": "admin"})
+	step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"password": {Kind: plugin.DiffUpdate},
+		"apiKey":   {Kind: plugin.DiffUpdate},
+		"db.user":  {Kind: plugin.DiffUpdate},
+		"region":   {Kind: plugin.DiffUpdate},
+		"port":     {Kind: plugin.DiffUpdate},
+	})
+
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	for _, golden := range []string{"secret_transition.txt", "secret_transition_tree.txt"} {
+		actual := renderStepDiff(step, opts)
+		for _, s := range []string{"hunter2", "key-1", "key-2", "admin", "us-west-2", "5432", "5433"} {
+			assert.NotContains(t, actual, s)
+		}
+		assertGolden(t, golden, actual)
+		opts.DiffTreeStyle = DiffTreeASCII
+	}
+}
+
+// makeStateUpdateStep returns an update step whose old and new states are the given properties of a resource, as the
+// engine would report them in its events.
+func makeStateUpdateStep(olds, news resource.PropertyMap,
+	detailedDiff map[string]plugin.PropertyDiff) engine.StepEventMetadata {
+
+	stackURN := resource.NewURN("stack", "project", "", resource.RootStackType, "project-stack")
+	urn := resource.NewURN("stack", "project", "", "pkg:index:Service", "web")
+	state := func(props resource.PropertyMap) *engine.StepEventStateMetadata {
+		return engine.NewStepEventStateMetadata(&resource.State{
+			Type:    urn.Type(),
+			URN:     urn,
+			Custom:  true,
+			Inputs:  props,
+			Outputs: props,
+			Parent:  stackURN,
+		}, false)
+	}
+	return engine.StepEventMetadata{
+		Op:           deploy.OpUpdate,
+		URN:          urn,
+		Type:         urn.Type(),
+		Old:          state(olds),
+		New:          state(news),
+		Res:          state(news),
+		Logical:      true,
+		DetailedDiff: detailedDiff,
+	}
+}
+
+func TestSecretTransitionEventDiff(t *testing.T) {
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"password": "hunter2", "tier": "frontend"})
+	olds["region"] = resource.MakeSecret(resource.NewStringProperty("us-west-2"))
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{"region": "us-west-2", "tier": "backend"})
+	news["password"] = resource.MakeSecret(resource.NewStringProperty("hunter2"))
+
+	// The engine masks the values of secrets in its events, but not whether they are secrets.
+	opts := Options{Color: colors.Never, Type: DisplayDiff}
+	for _, detailedDiff := range []map[string]plugin.PropertyDiff{nil, {
+		"password": {Kind: plugin.DiffUpdate},
+		"region":   {Kind: plugin.DiffUpdate},
+		"tier":     {Kind: plugin.DiffUpdate},
+	}} {
+		actual := renderStepDiff(makeStateUpdateStep(olds, news, detailedDiff), opts)
+		assert.NotContains(t, actual, "hunter2")
+		assert.Contains(t, actual, "~ password: [now marked secret] [secret]\n")
+		assert.Contains(t, actual, "~ region  : [no longer secret] [secret]\n")
+		assert.Contains(t, actual, `~ tier    : "frontend" => "backend"`)
+	}
+}
+
+func TestOrderByDependencies(t *testing.T) {
+	urn := func(name string) resource.URN {
+		return resource.NewURN("stack", "project", "", "pkg:index:Service", tokens.QName(name))
+	}
+	step := func(name, parent string, dependencies ...string) engine.StepEventMetadata {
+		state := &resource.State{URN: urn(name)}
+		if parent != "" {
+			state.Parent = urn(parent)
+		}
+		for _, dependency := range dependencies {
+			state.Dependencies = append(state.Dependencies, urn(dependency))
+		}
+		return engine.StepEventMetadata{
+			Op:  deploy.OpUpdate,
+			URN: state.URN,
+			New: engine.NewStepEventStateMetadata(state, false),
+		}
+	}
+	steps := []engine.StepEventMetadata{
+		step("d", "", "b", "c"),
+		step("e", "", "a", "external"),
+		step("c", ""),
+		step("b", "", "a"),
+		step("a", ""),
+		step("child", "c"),
+	}
+
+	// Each resource is followed by its dependents as soon as all of their dependencies have been shown, and peers are
+	// ordered by URN, whatever the order of the steps.
+	expected := []resource.URN{urn("a"), urn("b"), urn("e"), urn("c"), urn("child"), urn("d")}
+	assert.Equal(t, expected, orderByDependencies(steps))
+	for i := 0; i < 10; i++ {
+		shuffled := append([]engine.StepEventMetadata(nil), steps...)
+		rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		assert.Equal(t, expected, orderByDependencies(shuffled))
+	}
+
+	// Resources in cycles are shown last.
+	cyclic := append([]engine.StepEventMetadata{step("x", "", "y"), step("y", "", "x")}, steps...)
+	assert.Equal(t, append(expected, urn("x"), urn("y")), orderByDependencies(cyclic))
+
+	// The steps of each resource are kept together.
+	replace := step("a", "")
+	replace.Op = deploy.OpDeleteReplaced
+	ordered := orderStepsByDependencies(append(steps, replace))
+	assert.Equal(t, urn("a"), ordered[1].URN)
+	assert.Equal(t, deploy.OpDeleteReplaced, ordered[1].Op)
+
+	// Held back events are flushed in the same order, with the outputs of each resource following its pre-event.
+	events := newDependencyOrderedEvents()
+	for _, s := range steps {
+		assert.True(t, events.add(engine.Event{
+			Type:    engine.ResourcePreEvent,
+			Payload: engine.ResourcePreEventPayload{Metadata: s},
+		}))
+	}
+	assert.True(t, events.add(engine.Event{
+		Type:    engine.ResourceOutputsEvent,
+		Payload: engine.ResourceOutputsEventPayload{Metadata: steps[4]},
+	}))
+	assert.False(t, events.add(engine.Event{Type: engine.DiagEvent, Payload: engine.DiagEventPayload{}}))
+
+	var types []engine.EventType
+	var urns []resource.URN
+	for _, event := range events.flush() {
+		types = append(types, event.Type)
+		switch payload := event.Payload.(type) {
+		case engine.ResourcePreEventPayload:
+			urns = append(urns, payload.Metadata.URN)
+		case engine.ResourceOutputsEventPayload:
+			urns = append(urns, payload.Metadata.URN)
+		}
+	}
+	assert.Equal(t, append([]resource.URN{urn("a")}, expected...), urns)
+	assert.Equal(t, engine.ResourceOutputsEvent, types[1])
+	assert.Empty(t, events.flush())
+}
+
+func TestSummarizeReplacements(t *testing.T) {
+	web := makeUpdateStep(resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name": "web", "zone": "a", "size": 2,
+	}), resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name": "web", "zone": "b", "size": 3,
+	}), map[string]plugin.PropertyDiff{
+		"name": {Kind: plugin.DiffUpdateReplace},
+		"zone": {Kind: plugin.DiffUpdateReplace},
+		"size": {Kind: plugin.DiffUpdate},
+	})
+	web.Op = deploy.OpReplace
+
+	db := makeUpdateStep(resource.NewPropertyMapFromMap(map[string]interface{}{
+		"engine": "mysql", "tags": map[string]interface{}{"a": "b"}, "storage": 10,
+	}), resource.NewPropertyMapFromMap(map[string]interface{}{
+		"engine": "postgres", "tags": map[string]interface{}{"a": "b", "c": "d"}, "storage": 20,
+	}), nil)
+	db.Op, db.Keys = deploy.OpReplace, []resource.PropertyKey{"engine", "tags"}
+	db.URN = resource.NewURN("stack", "project", "", "pkg:index:Database", "db")
+	db.Type = db.URN.Type()
+
+	api := makeUpdateStep(resource.NewPropertyMapFromMap(map[string]interface{}{"image": "v1"}),
+		resource.NewPropertyMapFromMap(map[string]interface{}{"image": "v2"}), nil)
+	api.URN = resource.NewURN("stack", "project", "", "pkg:index:Service", "api")
+
+	// The deletion of a replaced resource's old state comes last, and supersedes the replacement in the display's
+	// record of the resource's step.
+	deleteReplaced := web
+	deleteReplaced.Op, deleteReplaced.New, deleteReplaced.DetailedDiff = deploy.OpDeleteReplaced, nil, nil
+	deleteReplaced.Logical = false
+
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{SummarizeReplacements: true}}
+	opts.replacements = newReplacementSteps()
+	seen := map[resource.URN]engine.StepEventMetadata{
+		web.Res.Parent: {Res: &engine.StepEventStateMetadata{}},
+	}
+	budget := newDiffBudget(opts)
+	for _, step := range []engine.StepEventMetadata{web, db, api, deleteReplaced} {
+		renderDiffEvent(apitype.UpdateUpdate, engine.Event{
+			Type:    engine.ResourcePreEvent,
+			Payload: engine.ResourcePreEventPayload{Metadata: step, Planning: true},
+		}, seen, budget, opts)
+	}
+	summary := renderDiffEvent(apitype.UpdateUpdate, engine.Event{
+		Type: engine.SummaryEvent,
+		Payload: engine.SummaryEventPayload{
+			IsPreview:       true,
+			ResourceChanges: engine.ResourceChanges{deploy.OpUpdate: 1, deploy.OpReplace: 2},
+		},
+	}, seen, budget, opts)
+	assertGolden(t, "replacement_causes.txt", summary)
+
+	// The changes listed for each resource are those that its diff marks as replacing.
+	causes := getReplacementCauses(nil, opts)
+	assert.Len(t, causes, 2)
+	for _, step := range []engine.StepEventMetadata{web, db} {
+		pathsOnly := opts
+		pathsOnly.DiffPathsOnly = true
+		var marked, listed []string
+		for _, line := range strings.Split(renderStepDiff(step, pathsOnly), "\n") {
+			if line = strings.TrimSpace(line); strings.HasPrefix(line, "+-") && !strings.Contains(line, ":") {
+				marked = append(marked, strings.TrimSpace(strings.TrimPrefix(line, "+-")))
+			}
+		}
+		for _, cause := range causes[step.URN] {
+			listed = append(listed, engine.FormatPropertyPath(cause.Path))
+		}
+		assert.NotEmpty(t, listed)
+		assert.Equal(t, marked, listed)
+	}
+
+	// The provider reports that the name forces the replacement, but its value is the same.
+	assert.Equal(t, []replacementCause{
+		{Path: []interface{}{"name"}, Op: deploy.OpSame},
+		{Path: []interface{}{"zone"}, Op: deploy.OpUpdate},
+	}, causes[web.URN])
+
+	// Without a record of the replacements, the given steps are used.
+	opts.replacements = nil
+	assert.Equal(t, causes, getReplacementCauses([]engine.StepEventMetadata{web, db, api}, opts))
+	assert.Empty(t, getReplacementCauses([]engine.StepEventMetadata{deleteReplaced, api}, opts))
+	opts.SummarizeReplacements = false
+	assert.Equal(t, "", renderReplacementCauses([]engine.StepEventMetadata{web, db}, opts))
+}
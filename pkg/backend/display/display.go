@@ -33,6 +33,21 @@ func ShowEvents(
 	op string, action apitype.UpdateKind, stack tokens.QName, proj tokens.PackageName,
 	events <-chan engine.Event, done chan<- bool, opts Options, isPreview bool) {
 
+	// Changelogs describe the changes that were actually made, so we only record them for real updates.
+	if opts.ChangelogPath != "" && !isPreview {
+		events = recordChangelog(opts.ChangelogPath, events, opts)
+	}
+
+	// Import plans describe the properties that exist in the cloud but not in state, which previews detect, too.
+	if opts.ImportPlanPath != "" {
+		events = recordImportPlan(opts.ImportPlanPath, events, opts)
+	}
+
+	// Diff logs are a side channel for observability pipelines, so they accompany whichever display is shown.
+	if opts.DiffLogPath != "" {
+		events = recordDiffLog(opts.DiffLogPath, events, opts)
+	}
+
 	if opts.JSONDisplay {
 		// TODO[pulumi/pulumi#2390]: enable JSON display for real deployments.
 		contract.Assertf(isPreview, "JSON display only available in preview mode")
@@ -40,6 +55,11 @@ func ShowEvents(
 		return
 	}
 
+	if opts.DiffFormat == DiffFormatJSONLines {
+		ShowJSONLinesEvents(events, done, opts)
+		return
+	}
+
 	switch opts.Type {
 	case DisplayDiff:
 		ShowDiffEvents(op, action, events, done, opts)
@@ -74,7 +94,7 @@ func isRootURN(urn resource.URN) bool {
 // shouldShow returns true if a step should show in the output.
 func shouldShow(step engine.StepEventMetadata, opts Options) bool {
 	// For certain operations, whether they are tracked is controlled by flags (to cut down on superfluous output).
-	if step.Op == deploy.OpSame {
+	if step.Op == deploy.OpSame || isCosmeticUpdate(step, opts) {
 		// If the op is the same, it is possible that the resource's metadata changed.  In that case, still show it.
 		if step.Old.Protect != step.New.Protect {
 			return true
@@ -0,0 +1,102 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// DiffExpectedProperties compares the given actual properties of a resource against the expected properties, e.g. a
+// test fixture, and returns true if they match. If they do not, it also returns a rich diff from the expected to the
+// actual properties that is suitable for inclusion in a test failure message.
+//
+// Secrets are compared by their plaintext values, so a secret matches an equal value whether or not that value is also
+// a secret. Secrets are still masked in the rendered diff. The options' ignored property paths, unordered array paths,
+// array alignment, tuple array paths, value equality, and value formatting apply as they do to the diffs of an update.
+func DiffExpectedProperties(expected, actual resource.PropertyMap, opts Options) (string, bool) {
+	olds, news := revealSecrets(expected), revealSecrets(actual)
+	diff := olds.Diff(news, engine.IsInternalPropertyKey)
+	if opts.AlignArrays {
+		diff = alignDiffArrays(diff, olds, news, getTupleArrayPaths(opts))
+	}
+	diff = diffUnorderedArrays(diff, olds, news, getUnorderedArrayPaths(opts), getValueEquality(opts))
+	diff = diffEqualValues(diff, olds, news, getCustomValueEquality(opts))
+	if diff = filterIgnoredDiffs(diff, getIgnoredDiffPaths(opts)); !diff.AnyChanges() {
+		return "", true
+	}
+
+	// Mask any values that were secret in either the expected or the actual properties.
+	diff = transformObjectDiff(nil, diff, func(path []interface{}, v resource.PropertyValue) resource.PropertyValue {
+		if isSecretPath(path, expected) || isSecretPath(path, actual) {
+			return resource.MakeSecret(v)
+		}
+		return v
+	})
+	if opts.ValueTransform != nil {
+		diff = transformObjectDiff(nil, diff, opts.ValueTransform)
+	}
+	diff = formatRenderedDiff(diff, opts)
+
+	var buf bytes.Buffer
+	printObjectDiff(&buf, *diff, nil /*include*/, false /*planning*/, 1 /*indent*/, opts.Debug, opts)
+	return opts.Color.Colorize(buf.String()), false
+}
+
+// revealSecrets returns a copy of the given properties in which each secret has been replaced by its plaintext value.
+func revealSecrets(props resource.PropertyMap) resource.PropertyMap {
+	if !props.ContainsSecrets() {
+		return props
+	}
+	result := make(resource.PropertyMap)
+	for k, v := range props {
+		result[k] = revealSecret(v)
+	}
+	return result
+}
+
+func revealSecret(v resource.PropertyValue) resource.PropertyValue {
+	switch {
+	case v.IsSecret():
+		return revealSecret(v.SecretValue().Element)
+	case v.IsArray():
+		arr := make([]resource.PropertyValue, len(v.ArrayValue()))
+		for i, elem := range v.ArrayValue() {
+			arr[i] = revealSecret(elem)
+		}
+		return resource.NewArrayProperty(arr)
+	case v.IsObject():
+		return resource.NewObjectProperty(revealSecrets(v.ObjectValue()))
+	default:
+		return v
+	}
+}
+
+// isSecretPath returns true if the property at the given path, or any property that contains it, is a secret.
+func isSecretPath(path []interface{}, props resource.PropertyMap) bool {
+	v := resource.NewObjectProperty(props)
+	for _, key := range path {
+		var ok bool
+		if v, ok = lookupProperty(key, v); !ok {
+			return false
+		}
+		if v.IsSecret() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// FlatDiffOptions controls the output of FormatFlatDiff.
+type FlatDiffOptions struct {
+	IncludeSames bool // true to include a line for each leaf that is unchanged.
+}
+
+// FormatFlatDiff formats the given diff as one key=value line per changed leaf, in the style of the diff command, e.g.
+// an update to a replica count is listed as the lines "- spec.replicas=3" and "+ spec.replicas=5".
+//
+// Added leaves are marked with "+ " and deleted leaves with "- "; an update to a leaf is listed as a delete of its old
+// value followed by an add of its new value. Unchanged leaves, if included, are marked with two spaces. Arrays and
+// objects are expanded into their leaves, with empty ones written as [] and {}. Paths use the property path grammar,
+// so the first "=" that is not within a quoted property name separates a line's path from its value. Values are
+// formatted as they are in the rich diff, with any newlines escaped, so that each line stands on its own and the lines
+// may be filtered, sorted, and compared with standard tools. The lines are ordered by property key and array index.
+func FormatFlatDiff(diff *resource.ObjectDiff, opts FlatDiffOptions) []string {
+	var lines []string
+	walkObjectDiff(diff, func(path []interface{}, op deploy.StepOp, old, new resource.PropertyValue) {
+		switch op {
+		case deploy.OpCreate:
+			lines = appendFlatValue(lines, "+ ", path, new)
+		case deploy.OpDelete:
+			lines = appendFlatValue(lines, "- ", path, old)
+		case deploy.OpUpdate:
+			lines = appendFlatValue(lines, "- ", path, old)
+			lines = appendFlatValue(lines, "+ ", path, new)
+		default:
+			if opts.IncludeSames {
+				lines = appendFlatValue(lines, "  ", path, new)
+			}
+		}
+	})
+	return lines
+}
+
+// appendFlatValue appends a line with the given prefix for each leaf of the given value to the given lines.
+func appendFlatValue(lines []string, prefix string, path []interface{}, v resource.PropertyValue) []string {
+	switch {
+	case v.IsArray() && len(v.ArrayValue()) > 0:
+		for i, elem := range v.ArrayValue() {
+			lines = appendFlatValue(lines, prefix, appendPath(path, i), elem)
+		}
+		return lines
+	case v.IsObject() && len(v.ObjectValue()) > 0:
+		for _, k := range v.ObjectValue().StableKeys() {
+			lines = appendFlatValue(lines, prefix, appendPath(path, string(k)), v.ObjectValue()[k])
+		}
+		return lines
+	}
+
+	var text string
+	switch {
+	case v.IsArray():
+		text = "[]"
+	case v.IsObject():
+		text = "{}"
+	default:
+		text = flatValueEscaper.Replace(engine.FormatPropertyValue(v, true /*planning*/))
+	}
+	return append(lines, prefix+engine.FormatPropertyPath(path)+"="+text)
+}
+
+// flatValueEscaper escapes the line breaks in formatted values. Strings are already quoted and escaped by the
+// formatting, but custom formatters may produce text that spans several lines.
+var flatValueEscaper = strings.NewReplacer("\n", `\n`, "\r", `\r`)
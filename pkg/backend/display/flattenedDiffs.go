@@ -0,0 +1,122 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// flattenObjectDiff returns a copy of the given diff in which each updated object that has a single changed property
+// and no unchanged ones, and that is nested at least minDepth levels deep (top-level properties are at depth 1), is
+// merged with that property into one property named by their dotted path, e.g. spec.template.metadata. Chains of such
+// objects, which only give context to the changes beneath them, are thereby rendered on a single line, while objects
+// with several properties are rendered as usual.
+func flattenObjectDiff(diff *resource.ObjectDiff, depth, minDepth int) *resource.ObjectDiff {
+	if diff == nil || minDepth <= 0 {
+		return diff
+	}
+
+	result := &resource.ObjectDiff{
+		Adds:    make(resource.PropertyMap),
+		Deletes: make(resource.PropertyMap),
+		Sames:   diff.Sames,
+		Updates: make(map[resource.PropertyKey]resource.ValueDiff),
+		Leading: diff.Leading,
+		Schema:  make(map[resource.PropertyKey]bool),
+		Renames: diff.Renames,
+		Paths:   make(map[resource.PropertyKey][]interface{}),
+	}
+	for k, add := range diff.Adds {
+		result.Adds[k] = add
+	}
+	for k, delete := range diff.Deletes {
+		result.Deletes[k] = delete
+	}
+	for k, schema := range diff.Schema {
+		result.Schema[k] = schema
+	}
+	for k, path := range diff.Paths {
+		result.Paths[k] = path
+	}
+	for k, update := range diff.Updates {
+		path, merged := []interface{}{string(k)}, false
+		for !merged && depth+len(path)-1 >= minDepth && update.Object != nil && isFlattenable(update.Object) {
+			child := update.Object.Keys()[0]
+			path = append(path, string(child))
+			if add, isAdd := update.Object.Adds[child]; isAdd {
+				result.Adds[flattenedKey(path)], merged = add, true
+			} else if delete, isDelete := update.Object.Deletes[child]; isDelete {
+				result.Deletes[flattenedKey(path)], merged = delete, true
+			} else {
+				update = update.Object.Updates[child]
+			}
+			if merged && update.Object.Schema[child] {
+				result.Schema[flattenedKey(path)] = true
+			}
+		}
+		if len(path) > 1 {
+			result.Paths[flattenedKey(path)] = path
+		}
+		if merged {
+			continue
+		}
+
+		switch {
+		case update.Array != nil:
+			update.Array = flattenArrayDiff(update.Array, depth+len(path), minDepth)
+		case update.Object != nil:
+			update.Object = flattenObjectDiff(update.Object, depth+len(path), minDepth)
+		}
+		result.Updates[flattenedKey(path)] = update
+	}
+	return result
+}
+
+// flattenedKey returns the name of the property that merges the properties along the given path. A path of a single
+// property keeps that property's name.
+func flattenedKey(path []interface{}) resource.PropertyKey {
+	if len(path) == 1 {
+		return resource.PropertyKey(path[0].(string))
+	}
+	return resource.PropertyKey(engine.FormatPropertyPath(path))
+}
+
+func flattenArrayDiff(diff *resource.ArrayDiff, depth, minDepth int) *resource.ArrayDiff {
+	result := &resource.ArrayDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Sames:   diff.Sames,
+		Updates: make(map[int]resource.ValueDiff),
+		Moves:   diff.Moves,
+		Labels:  diff.Labels,
+		Schema:  diff.Schema,
+	}
+	for i, update := range diff.Updates {
+		switch {
+		case update.Array != nil:
+			update.Array = flattenArrayDiff(update.Array, depth+1, minDepth)
+		case update.Object != nil:
+			update.Object = flattenObjectDiff(update.Object, depth+1, minDepth)
+		}
+		result.Updates[i] = update
+	}
+	return result
+}
+
+// isFlattenable returns true if the given object diff records a single property, which is changed.
+func isFlattenable(diff *resource.ObjectDiff) bool {
+	return len(diff.Sames) == 0 && len(diff.Adds)+len(diff.Deletes)+len(diff.Updates) == 1
+}
@@ -0,0 +1,123 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// ResourceFilter selects resources by URN.
+type ResourceFilter func(urn resource.URN) bool
+
+// showFullUpdates returns true if the objects and arrays updated by the given step should be shown in full.
+func showFullUpdates(step engine.StepEventMetadata, opts Options) bool {
+	return opts.ShowFullUpdates != nil && opts.ShowFullUpdates(step.URN)
+}
+
+// expandUpdates returns a copy of the given diff between the given old and new properties in which each updated
+// object or array also records its unchanged elements as sames, so that it is shown in its entirety rather than as
+// only the elements that changed. Detailed diffs in particular record only the changed elements of an object. An
+// unchanged element is one that is present in both the old and the new value and that the diff does not track. Array
+// elements that the diff does not track are only unchanged if they are the same under the given equality.
+func expandUpdates(diff *resource.ObjectDiff, olds, news resource.PropertyMap,
+	equals ValueEquality) *resource.ObjectDiff {
+
+	if diff == nil {
+		return nil
+	}
+
+	result := &resource.ObjectDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Renames: diff.Renames,
+		Sames:   make(resource.PropertyMap),
+		Updates: make(map[resource.PropertyKey]resource.ValueDiff),
+	}
+	for k, same := range diff.Sames {
+		result.Sames[k] = same
+	}
+	for k, update := range diff.Updates {
+		old, new := getUpdatedValues(string(k), update, resource.NewObjectProperty(olds),
+			resource.NewObjectProperty(news))
+		result.Updates[k] = expandValueUpdates(update, old, new, equals)
+	}
+	return result
+}
+
+func expandValueUpdates(diff resource.ValueDiff, old, new resource.PropertyValue,
+	equals ValueEquality) resource.ValueDiff {
+
+	switch {
+	case diff.Object != nil && old.IsObject() && new.IsObject():
+		expanded := expandUpdates(diff.Object, old.ObjectValue(), new.ObjectValue(), equals)
+		for k, v := range new.ObjectValue() {
+			if _, has := old.ObjectValue()[k]; has && !isTrackedKey(expanded, k) {
+				expanded.Sames[k] = v
+			}
+		}
+		diff.Object = expanded
+	case diff.Array != nil && old.IsArray() && new.IsArray():
+		diff.Array = expandArrayUpdates(diff.Array, old.ArrayValue(), new.ArrayValue(), equals)
+	}
+	return diff
+}
+
+func expandArrayUpdates(diff *resource.ArrayDiff, old, new []resource.PropertyValue,
+	equals ValueEquality) *resource.ArrayDiff {
+
+	result := &resource.ArrayDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Sames:   make(map[int]resource.PropertyValue),
+		Updates: make(map[int]resource.ValueDiff),
+		Moves:   diff.Moves,
+	}
+	for i, same := range diff.Sames {
+		result.Sames[i] = same
+	}
+	for i, update := range diff.Updates {
+		elementOld, elementNew := getUpdatedValues(i, update, resource.NewArrayProperty(old),
+			resource.NewArrayProperty(new))
+		result.Updates[i] = expandValueUpdates(update, elementOld, elementNew, equals)
+	}
+
+	// Array diffs are not necessarily keyed by the elements' indices, so an untracked index is only considered
+	// unchanged if the old and new elements at that index are equal.
+	for i := 0; i < len(old) && i < len(new); i++ {
+		if !isTrackedIndex(result, i) && equals(old[i], new[i]) {
+			result.Sames[i] = new[i]
+		}
+	}
+	return result
+}
+
+func isTrackedKey(diff *resource.ObjectDiff, k resource.PropertyKey) bool {
+	_, isAdd := diff.Adds[k]
+	_, isDelete := diff.Deletes[k]
+	_, isSame := diff.Sames[k]
+	_, isUpdate := diff.Updates[k]
+	return isAdd || isDelete || isSame || isUpdate
+}
+
+func isTrackedIndex(diff *resource.ArrayDiff, i int) bool {
+	_, isAdd := diff.Adds[i]
+	_, isDelete := diff.Deletes[i]
+	_, isSame := diff.Sames[i]
+	_, isUpdate := diff.Updates[i]
+	return isAdd || isDelete || isSame || isUpdate
+}
@@ -0,0 +1,193 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// FormatHTMLDiff renders the property diff of the given step as semantic HTML, e.g. for embedding in a web page. The
+// diff is rendered as nested lists that mirror the structure of the resource's properties:
+//
+//	<div class="diff" data-urn="..." data-op="update">
+//	  <ul>
+//	    <li class="diff-node diff-update" data-path="spec">
+//	      <span class="diff-key">spec</span>
+//	      <ul>
+//	        <li class="diff-update" data-path="spec.replicas">
+//	          <span class="diff-key">replicas</span>
+//	          <span class="diff-old">3</span>
+//	          <span class="diff-new">5</span>
+//	        </li>
+//	      </ul>
+//	    </li>
+//	  </ul>
+//	</div>
+//
+// Each changed property is marked with one of the classes diff-add, diff-delete, diff-update, or diff-same, and also
+// with diff-replace if its change forces the resource to be replaced. Values are marked with diff-old, diff-new, or
+// diff-value (for sames), and additionally with diff-secret or diff-computed as appropriate; secrets are always masked.
+// No styles are included, so that the presentation is entirely up to the page's CSS. An empty string is returned if the
+// step has no property changes.
+func FormatHTMLDiff(step engine.StepEventMetadata, opts Options) string {
+	diff := getStepDiff(step, opts)
+	if diff == nil {
+		return ""
+	}
+
+	r := &htmlDiffRenderer{replacementPaths: getReplacementPaths(step)}
+	r.printf(0, `<div class="diff" data-urn="%s" data-op="%s">`, html.EscapeString(string(step.URN)), step.Op)
+	r.printf(1, "<ul>")
+	walkObjectDiff(diff, r.visit)
+	r.closeNodes(0)
+	r.printf(1, "</ul>")
+	r.printf(0, "</div>")
+	return r.buf.String()
+}
+
+// htmlDiffRenderer renders the leaves of a diff, in the order in which they are walked, as nested HTML lists. The
+// lists for the objects and arrays that contain each leaf are opened and closed as the walk enters and leaves them.
+type htmlDiffRenderer struct {
+	buf              bytes.Buffer
+	nodes            []interface{}     // the path to the innermost open object or array.
+	replacementPaths []diffPathPattern // the paths whose changes force a replacement.
+}
+
+// printf writes a line of HTML at the given nesting depth.
+func (r *htmlDiffRenderer) printf(depth int, format string, args ...interface{}) {
+	r.buf.WriteString(strings.Repeat("  ", depth))
+	fprintfIgnoreError(&r.buf, format, args...)
+	r.buf.WriteString("\n")
+}
+
+// depth returns the nesting depth of the list items within the innermost open object or array.
+func (r *htmlDiffRenderer) depth() int {
+	return 2 + 2*len(r.nodes)
+}
+
+// closeNodes closes open objects and arrays until only the given number remain open.
+func (r *htmlDiffRenderer) closeNodes(n int) {
+	for len(r.nodes) > n {
+		r.nodes = r.nodes[:len(r.nodes)-1]
+		r.printf(r.depth()+1, "</ul>")
+		r.printf(r.depth(), "</li>")
+	}
+}
+
+func (r *htmlDiffRenderer) visit(path []interface{}, op deploy.StepOp, old, new resource.PropertyValue) {
+	// Close the open objects and arrays that do not contain this leaf, then open those that do.
+	parent := path[:len(path)-1]
+	common := 0
+	for common < len(r.nodes) && common < len(parent) && r.nodes[common] == parent[common] {
+		common++
+	}
+	r.closeNodes(common)
+	for len(r.nodes) < len(parent) {
+		nodePath := parent[:len(r.nodes)+1]
+		r.printf(r.depth(), `<li class="%s" data-path="%s">`, r.classes(nodePath, "diff-node", "diff-update"),
+			html.EscapeString(engine.FormatPropertyPath(nodePath)))
+		r.printf(r.depth()+1, `<span class="diff-key">%s</span>`, formatHTMLKey(nodePath[len(nodePath)-1]))
+		r.printf(r.depth()+1, "<ul>")
+		r.nodes = append(r.nodes, nodePath[len(nodePath)-1])
+	}
+
+	depth := r.depth()
+	switch op {
+	case deploy.OpCreate:
+		r.printLeaf(depth, path, "diff-add", func() { r.printValue(depth+1, path, new, "diff-new") })
+	case deploy.OpDelete:
+		r.printLeaf(depth, path, "diff-delete", func() { r.printValue(depth+1, path, old, "diff-old") })
+	case deploy.OpUpdate:
+		r.printLeaf(depth, path, "diff-update", func() {
+			r.printValue(depth+1, path, old, "diff-old")
+			r.printValue(depth+1, path, new, "diff-new")
+		})
+	default:
+		r.printLeaf(depth, path, "diff-same", func() { r.printValue(depth+1, path, new, "diff-value") })
+	}
+}
+
+// classes returns the classes of the list item for the property at the given path, including diff-replace if a change
+// to the property forces a replacement.
+func (r *htmlDiffRenderer) classes(path []interface{}, classes ...string) string {
+	if isReplacementPath(path, r.replacementPaths) {
+		classes = append(classes, "diff-replace")
+	}
+	return strings.Join(classes, " ")
+}
+
+// printLeaf prints the list item for the changed property at the given path. The given function prints its values.
+func (r *htmlDiffRenderer) printLeaf(depth int, path []interface{}, class string, printValues func()) {
+	classes := class
+	if class != "diff-same" {
+		classes = r.classes(path, class)
+	}
+	r.printf(depth, `<li class="%s" data-path="%s">`, classes, html.EscapeString(engine.FormatPropertyPath(path)))
+	r.printf(depth+1, `<span class="diff-key">%s</span>`, formatHTMLKey(path[len(path)-1]))
+	printValues()
+	r.printf(depth, "</li>")
+}
+
+// printValue prints the given value with the given class. Scalar values are printed as spans; arrays and objects are
+// printed as lists of their elements, each of which has the given class.
+func (r *htmlDiffRenderer) printValue(depth int, path []interface{}, v resource.PropertyValue, class string) {
+	var elements []interface{}
+	switch {
+	case v.IsArray():
+		for i := range v.ArrayValue() {
+			elements = append(elements, i)
+		}
+	case v.IsObject():
+		for _, k := range v.ObjectValue().StableKeys() {
+			elements = append(elements, string(k))
+		}
+	default:
+		classes := class
+		switch {
+		case v.IsSecret():
+			classes += " diff-secret"
+		case isUnknown(v):
+			classes += " diff-computed"
+		}
+		r.printf(depth, `<span class="%s">%s</span>`, classes,
+			html.EscapeString(engine.FormatPropertyValue(v, true /*planning*/)))
+		return
+	}
+
+	r.printf(depth, `<ul class="%s">`, class)
+	for _, key := range elements {
+		elementPath := appendPath(path, key)
+		r.printf(depth+1, `<li data-path="%s">`, html.EscapeString(engine.FormatPropertyPath(elementPath)))
+		r.printf(depth+2, `<span class="diff-key">%s</span>`, formatHTMLKey(key))
+		r.printValue(depth+2, elementPath, getProperty(key, v), class)
+		r.printf(depth+1, "</li>")
+	}
+	r.printf(depth, "</ul>")
+}
+
+// formatHTMLKey formats the given property key or array index as escaped HTML text.
+func formatHTMLKey(key interface{}) string {
+	if i, ok := key.(int); ok {
+		return fmt.Sprintf("[%d]", i)
+	}
+	return html.EscapeString(key.(string))
+}
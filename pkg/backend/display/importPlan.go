@@ -0,0 +1,173 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+// importPlanSecret stands in for the values of secrets in import plans. Secret values are never written to import
+// plans, so they must be entered by hand before a plan is applied.
+const importPlanSecret = "<secret: enter value>"
+
+// importPlan describes the properties that are present in the cloud but not in a stack's state, so that they can be
+// imported into the stack's program and state.
+type importPlan struct {
+	// Resources lists the resources that have properties to import, in step order.
+	Resources []importPlanResource `json:"resources"`
+}
+
+// importPlanResource describes the properties of a single resource that are present in the cloud but not in state.
+type importPlanResource struct {
+	// URN is the resource that has properties to import.
+	URN resource.URN `json:"urn"`
+	// Type is the resource's type.
+	Type tokens.Type `json:"type"`
+	// ID is the resource's provider ID, if any.
+	ID resource.ID `json:"id,omitempty"`
+	// Properties maps the paths of the properties to import, e.g. spec.ports[2], to their values.
+	Properties map[string]interface{} `json:"properties"`
+	// Secrets lists the paths of the secrets among the properties, whose values must be entered by hand.
+	Secrets []string `json:"secrets,omitempty"`
+}
+
+// recordImportPlan interposes on the given stream of engine events, recording the steps that the engine performs.
+// When the stream is canceled, an import plan of the properties that the steps add is written to the given path. The
+// plan is written before the cancellation is forwarded so that it is complete once the display finishes.
+//
+// A step's outputs event supersedes its pre-event, as the new state of a refresh is only known once the resource has
+// been read.
+func recordImportPlan(path string, events <-chan engine.Event, opts Options) <-chan engine.Event {
+	out := make(chan engine.Event)
+	go func() {
+		var steps []engine.StepEventMetadata
+		indices := make(map[resource.URN]int)
+		for e := range events {
+			switch e.Type {
+			case engine.ResourcePreEvent:
+				step := e.Payload.(engine.ResourcePreEventPayload).Metadata
+				indices[step.URN] = len(steps)
+				steps = append(steps, step)
+			case engine.ResourceOutputsEvent:
+				step := e.Payload.(engine.ResourceOutputsEventPayload).Metadata
+				if i, has := indices[step.URN]; has {
+					steps[i] = step
+				}
+			case engine.CancelEvent:
+				if err := writeImportPlan(path, steps, opts); err != nil {
+					fprintfIgnoreError(os.Stderr, opts.Color.Colorize(
+						colors.SpecWarning+"warning:"+colors.Reset+" %v\n"), err)
+				}
+				out <- e
+				return
+			}
+			out <- e
+		}
+		close(out)
+	}()
+	return out
+}
+
+// writeImportPlan writes the import plan for the given steps to the given path as JSON.
+func writeImportPlan(path string, steps []engine.StepEventMetadata, opts Options) error {
+	plan := getImportPlan(steps, opts)
+	b, err := json.MarshalIndent(&plan, "", "    ")
+	if err != nil {
+		return errors.Wrap(err, "could not write import plan")
+	}
+	return errors.Wrap(ioutil.WriteFile(path, append(b, '\n'), 0600), "could not write import plan")
+}
+
+// getImportPlan returns the import plan for the given steps. Only additions are planned: the properties that each
+// refresh or logical step adds, e.g. because a refresh read properties that are missing from the stack's state.
+// Updates and deletions are not. Secrets are replaced by placeholders.
+func getImportPlan(steps []engine.StepEventMetadata, opts Options) importPlan {
+	plan := importPlan{Resources: []importPlanResource{}}
+	for _, step := range steps {
+		if step.Op == deploy.OpSame || !step.Logical && step.Op != deploy.OpRefresh || step.New == nil {
+			continue
+		}
+
+		res := importPlanResource{
+			URN:        step.URN,
+			Type:       step.Type,
+			ID:         step.New.ID,
+			Properties: make(map[string]interface{}),
+		}
+		walkObjectDiff(getImportDiff(step, opts), func(path []interface{}, op deploy.StepOp,
+			old, new resource.PropertyValue) {
+
+			if op != deploy.OpCreate {
+				return
+			}
+			for _, secret := range findSecrets(path, new) {
+				res.Secrets = append(res.Secrets, engine.FormatPropertyPath(secret))
+			}
+			res.Properties[engine.FormatPropertyPath(path)] = serializeDiffValue(placeholdSecrets(new), false)
+		})
+		if len(res.Properties) > 0 {
+			plan.Resources = append(plan.Resources, res)
+		}
+	}
+	return plan
+}
+
+// getImportDiff returns the property diff whose additions are planned for import for the given step. Refreshes and
+// reads compare the state that was read from the cloud with the old state's outputs, if any; other steps use the
+// step's diff as displayed.
+func getImportDiff(step engine.StepEventMetadata, opts Options) *resource.ObjectDiff {
+	switch {
+	case step.Op == deploy.OpRefresh || step.Op == deploy.OpRead || step.Op == deploy.OpReadReplacement:
+		var olds resource.PropertyMap
+		if step.Old != nil {
+			olds = step.Old.Outputs
+		}
+		return olds.Diff(step.New.Outputs, engine.IsInternalPropertyKey)
+	default:
+		return getStepDiff(step, opts)
+	}
+}
+
+// placeholdSecrets returns a copy of the given value in which each secret is replaced by importPlanSecret.
+func placeholdSecrets(v resource.PropertyValue) resource.PropertyValue {
+	switch {
+	case v.IsSecret():
+		return resource.NewStringProperty(importPlanSecret)
+	case v.IsArray():
+		elements := make([]resource.PropertyValue, len(v.ArrayValue()))
+		for i, e := range v.ArrayValue() {
+			elements[i] = placeholdSecrets(e)
+		}
+		return resource.NewArrayProperty(elements)
+	case v.IsObject():
+		props := make(resource.PropertyMap, len(v.ObjectValue()))
+		for k, e := range v.ObjectValue() {
+			props[k] = placeholdSecrets(e)
+		}
+		return resource.NewObjectProperty(props)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+func TestImportPlan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import-plan")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "import.json")
+
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"size": "small",
+		"zone": "a",
+		"tags": map[string]interface{}{"team": "web"},
+	})
+	reads := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"size": "large",
+		"tags": map[string]interface{}{"team": "web", "owner": "ops"},
+		"auth": map[string]interface{}{"user": "admin"},
+	})
+	reads["auth"].ObjectValue()["password"] = resource.MakeSecret(resource.NewStringProperty("hunter2"))
+
+	// The refresh's new state is only known once the resource has been read. It is reported as the engine reports it,
+	// with the values of its secrets masked.
+	refreshed := makeUpdateStep(olds, olds, nil)
+	refreshed.Op, refreshed.Logical = deploy.OpRefresh, false
+	refreshed.New.ID = "db-1"
+	read := refreshed
+	read.New = engine.NewStepEventStateMetadata(&resource.State{
+		Type:    refreshed.Type,
+		URN:     refreshed.URN,
+		Custom:  true,
+		ID:      "db-1",
+		Inputs:  olds,
+		Outputs: reads,
+	}, false)
+
+	unchanged := makeUpdateStep(olds, olds, nil)
+	unchanged.Op, unchanged.URN = deploy.OpRefresh, unchanged.URN+"-unchanged"
+
+	same := makeUpdateStep(olds, olds, nil)
+	same.Op, same.URN = deploy.OpSame, same.URN+"-same"
+
+	events := make(chan engine.Event)
+	out := recordImportPlan(path, events, Options{})
+	go func() {
+		for _, step := range []engine.StepEventMetadata{refreshed, unchanged, same} {
+			events <- engine.Event{Type: engine.ResourcePreEvent, Payload: engine.ResourcePreEventPayload{Metadata: step}}
+		}
+		events <- engine.Event{
+			Type:    engine.ResourceOutputsEvent,
+			Payload: engine.ResourceOutputsEventPayload{Metadata: read},
+		}
+		events <- engine.Event{Type: engine.CancelEvent}
+	}()
+
+	// All events, including the cancellation, must be forwarded.
+	var forwarded int
+	for e := range out {
+		forwarded++
+		if e.Type == engine.CancelEvent {
+			break
+		}
+	}
+	assert.Equal(t, 5, forwarded)
+
+	contents, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(contents), "hunter2")
+
+	var plan importPlan
+	assert.NoError(t, json.Unmarshal(contents, &plan))
+	assert.Len(t, plan.Resources, 1)
+
+	// Only additions are planned: the updated size and the deleted zone are not.
+	res := plan.Resources[0]
+	assert.Equal(t, refreshed.URN, res.URN)
+	assert.Equal(t, resource.ID("db-1"), res.ID)
+	assert.Equal(t, map[string]interface{}{
+		"tags.owner": "ops",
+		"auth":       map[string]interface{}{"user": "admin", "password": importPlanSecret},
+	}, res.Properties)
+	assert.Equal(t, []string{"auth.password"}, res.Secrets)
+
+	// A plan without additions lists no resources.
+	assert.NoError(t, writeImportPlan(path, []engine.StepEventMetadata{unchanged}, Options{}))
+	contents, err = ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"resources":[]}`, strings.Join(strings.Fields(string(contents)), ""))
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
 	"github.com/pulumi/pulumi/pkg/resource/stack"
 	"github.com/pulumi/pulumi/pkg/util/contract"
 )
@@ -139,7 +140,7 @@ func ShowJSONEvents(op string, action apitype.UpdateKind, events <-chan engine.E
 					detailedDiff = make(map[string]propertyDiff)
 					for k, v := range m.DetailedDiff {
 						detailedDiff[k] = propertyDiff{
-							Kind:      v.Kind.String(),
+							Kind:      formatDiffKind(v.Kind),
 							InputDiff: v.InputDiff,
 						}
 					}
@@ -252,3 +253,131 @@ type previewDiagnostic struct {
 	Message  string        `json:"message,omitempty"`
 	Severity diag.Severity `json:"severity,omitempty"`
 }
+
+// objectDiffJSON is a JSON-serializable representation of an object diff. Sames are omitted.
+type objectDiffJSON struct {
+	// Adds contains the properties added in the new object.
+	Adds map[string]interface{} `json:"adds,omitempty"`
+	// Deletes contains the properties deleted from the old object.
+	Deletes map[string]interface{} `json:"deletes,omitempty"`
+	// Updates contains the properties that changed between the old and new objects.
+	Updates map[string]valueDiffJSON `json:"updates,omitempty"`
+	// Renames maps the new names of properties that were renamed to their old names.
+	Renames map[string]string `json:"renames,omitempty"`
+}
+
+// arrayDiffJSON is a JSON-serializable representation of an array diff. Sames are omitted.
+type arrayDiffJSON struct {
+	// Adds contains the elements added in the new array.
+	Adds map[int]interface{} `json:"adds,omitempty"`
+	// Deletes contains the elements deleted from the old array.
+	Deletes map[int]interface{} `json:"deletes,omitempty"`
+	// Updates contains the elements that changed between the old and new arrays.
+	Updates map[int]valueDiffJSON `json:"updates,omitempty"`
+}
+
+// valueDiffJSON is a JSON-serializable representation of a value diff.
+type valueDiffJSON struct {
+	// Old is the old value, if this is a leaf update.
+	Old interface{} `json:"old,omitempty"`
+	// New is the new value, if this is a leaf update.
+	New interface{} `json:"new,omitempty"`
+	// Array is the nested diff of an array value.
+	Array *arrayDiffJSON `json:"array,omitempty"`
+	// Object is the nested diff of an object value.
+	Object *objectDiffJSON `json:"object,omitempty"`
+}
+
+// serializeDiffValue converts a property value into a JSON-serializable value. If showSecrets is not true, any secret
+// values are replaced with "[secret]".
+func serializeDiffValue(v resource.PropertyValue, showSecrets bool) interface{} {
+	// Secrets are massaged out before serialization, so the crypter is never consulted.
+	s, err := stack.SerializePropertyValue(massagePropertyValue(v, showSecrets), config.NewPanicCrypter())
+	contract.AssertNoErrorf(err, "unexpected error serializing property value")
+	return s
+}
+
+// formatDiffKind returns the name of the given diff kind, or a name made of its number if the kind is unknown to this
+// version of the CLI, e.g. because the provider that reported it is newer.
+func formatDiffKind(kind plugin.DiffKind) string {
+	if !kind.IsKnown() {
+		return fmt.Sprintf("kind(%d)", int(kind))
+	}
+	return kind.String()
+}
+
+// serializeObjectDiff converts an object diff into its JSON-serializable form.
+func serializeObjectDiff(diff *resource.ObjectDiff, showSecrets bool) *objectDiffJSON {
+	if diff == nil {
+		return nil
+	}
+
+	result := &objectDiffJSON{}
+	if len(diff.Adds) > 0 {
+		result.Adds = make(map[string]interface{})
+		for k, v := range diff.Adds {
+			result.Adds[string(k)] = serializeDiffValue(v, showSecrets)
+		}
+	}
+	if len(diff.Deletes) > 0 {
+		result.Deletes = make(map[string]interface{})
+		for k, v := range diff.Deletes {
+			result.Deletes[string(k)] = serializeDiffValue(v, showSecrets)
+		}
+	}
+	if len(diff.Updates) > 0 {
+		result.Updates = make(map[string]valueDiffJSON)
+		for k, v := range diff.Updates {
+			result.Updates[string(k)] = serializeValueDiff(v, showSecrets)
+		}
+	}
+	if len(diff.Renames) > 0 {
+		result.Renames = make(map[string]string)
+		for to, from := range diff.Renames {
+			result.Renames[string(to)] = string(from)
+		}
+	}
+	return result
+}
+
+// serializeArrayDiff converts an array diff into its JSON-serializable form.
+func serializeArrayDiff(diff *resource.ArrayDiff, showSecrets bool) *arrayDiffJSON {
+	if diff == nil {
+		return nil
+	}
+
+	result := &arrayDiffJSON{}
+	if len(diff.Adds) > 0 {
+		result.Adds = make(map[int]interface{})
+		for i, v := range diff.Adds {
+			result.Adds[i] = serializeDiffValue(v, showSecrets)
+		}
+	}
+	if len(diff.Deletes) > 0 {
+		result.Deletes = make(map[int]interface{})
+		for i, v := range diff.Deletes {
+			result.Deletes[i] = serializeDiffValue(v, showSecrets)
+		}
+	}
+	if len(diff.Updates) > 0 {
+		result.Updates = make(map[int]valueDiffJSON)
+		for i, v := range diff.Updates {
+			result.Updates[i] = serializeValueDiff(v, showSecrets)
+		}
+	}
+	return result
+}
+
+// serializeValueDiff converts a value diff into its JSON-serializable form.
+func serializeValueDiff(diff resource.ValueDiff, showSecrets bool) valueDiffJSON {
+	if diff.Array != nil || diff.Object != nil {
+		return valueDiffJSON{
+			Array:  serializeArrayDiff(diff.Array, showSecrets),
+			Object: serializeObjectDiff(diff.Object, showSecrets),
+		}
+	}
+	return valueDiffJSON{
+		Old: serializeDiffValue(diff.Old, showSecrets),
+		New: serializeDiffValue(diff.New, showSecrets),
+	}
+}
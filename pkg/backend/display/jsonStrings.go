@@ -0,0 +1,170 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/json"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// ValidateJSONStringPaths returns an error if any of the given property path patterns is malformed.
+func ValidateJSONStringPaths(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := parseDiffPathPattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getJSONStringPaths returns the parsed forms of the JSON string path patterns in the given options. Malformed
+// patterns are skipped; they are expected to have been rejected by ValidateJSONStringPaths.
+func getJSONStringPaths(opts Options) []diffPathPattern {
+	var patterns []diffPathPattern
+	for _, pattern := range opts.JSONStringPaths {
+		if p, err := parseDiffPathPattern(pattern); err == nil {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// diffJSONStrings returns a copy of the given diff in which each update to a string whose property path matches one
+// of the given patterns, and whose old and new values are both JSON-encoded objects or arrays, is replaced by a
+// structural diff of the decoded values. As decoded objects are unordered, strings whose decoded values are equal,
+// e.g. because a provider re-serialized them with their keys in a different order, are sames rather than updates.
+// Updates to other strings, including those that are not valid JSON, are left as they are.
+func diffJSONStrings(diff *resource.ObjectDiff, patterns []diffPathPattern) *resource.ObjectDiff {
+	if diff == nil || len(patterns) == 0 {
+		return diff
+	}
+	return diffJSONObjectStrings(nil, diff, patterns)
+}
+
+func diffJSONObjectStrings(path []interface{}, diff *resource.ObjectDiff,
+	patterns []diffPathPattern) *resource.ObjectDiff {
+
+	result := &resource.ObjectDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Renames: diff.Renames,
+		Sames:   diff.Sames.Copy(),
+		Updates: make(map[resource.PropertyKey]resource.ValueDiff),
+	}
+	for k, update := range diff.Updates {
+		if update, changed := diffJSONValueStrings(appendPath(path, string(k)), update, patterns); changed {
+			result.Updates[k] = update
+		} else if update.New.V != nil {
+			result.Sames[k] = update.New
+		}
+	}
+	return result
+}
+
+func diffJSONArrayStrings(path []interface{}, diff *resource.ArrayDiff,
+	patterns []diffPathPattern) *resource.ArrayDiff {
+
+	result := &resource.ArrayDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Sames:   make(map[int]resource.PropertyValue),
+		Updates: make(map[int]resource.ValueDiff),
+		Moves:   diff.Moves,
+	}
+	for i, same := range diff.Sames {
+		result.Sames[i] = same
+	}
+	for i, update := range diff.Updates {
+		if update, changed := diffJSONValueStrings(appendPath(path, diff.Index(i)), update, patterns); changed {
+			result.Updates[i] = update
+		} else if update.New.V != nil {
+			result.Sames[i] = update.New
+		}
+	}
+	return result
+}
+
+// diffJSONValueStrings returns the given diff with the JSON strings within it diffed structurally. The second result
+// is false if the diff is an update to a JSON string whose decoded value did not change, or to an array or object
+// whose only changes were to such strings. Such updates are sames, or are omitted if their new values are not known.
+func diffJSONValueStrings(path []interface{}, diff resource.ValueDiff,
+	patterns []diffPathPattern) (resource.ValueDiff, bool) {
+
+	switch {
+	case diff.Array != nil:
+		changed := diff.Array.AnyChanges()
+		diff.Array = diffJSONArrayStrings(path, diff.Array, patterns)
+		return diff, !changed || diff.Array.AnyChanges()
+	case diff.Object != nil:
+		changed := diff.Object.AnyChanges()
+		diff.Object = diffJSONObjectStrings(path, diff.Object, patterns)
+		return diff, !changed || diff.Object.AnyChanges()
+	case diff.Old.IsString() && diff.New.IsString():
+		for _, pattern := range patterns {
+			if pattern.matches(path) {
+				if jsonDiff, ok := diffJSONString(diff.Old.StringValue(), diff.New.StringValue()); ok {
+					if jsonDiff == nil {
+						return diff, false
+					}
+					return *jsonDiff, true
+				}
+				break
+			}
+		}
+	}
+	return diff, true
+}
+
+// diffJSONString returns a structural diff of the given JSON-encoded strings, which is nil if the decoded values do
+// not differ (e.g. because only the strings' whitespace or the order of their objects' keys changed). The second
+// result is false if either string is not a JSON-encoded object or array, in which case the strings should be diffed
+// as strings.
+func diffJSONString(old, new string) (*resource.ValueDiff, bool) {
+	oldValue, ok := decodeJSONString(old)
+	if !ok {
+		return nil, false
+	}
+	newValue, ok := decodeJSONString(new)
+	if !ok {
+		return nil, false
+	}
+
+	diff := oldValue.Diff(newValue)
+	if diff == nil {
+		return nil, true
+	}
+	if diff.Array == nil && diff.Object == nil {
+		// A top-level change between an object and an array is shown as a change to the string.
+		return nil, false
+	}
+	return diff, true
+}
+
+// decodeJSONString decodes the given string as a JSON object or array.
+func decodeJSONString(s string) (resource.PropertyValue, bool) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return resource.PropertyValue{}, false
+	}
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return resource.NewPropertyValue(v), true
+	default:
+		return resource.PropertyValue{}, false
+	}
+}
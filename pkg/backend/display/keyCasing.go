@@ -0,0 +1,157 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// matchKeyCasing returns a copy of the given diff between the given old and new properties in which each object
+// property that was renamed to a different casing convention, e.g. from max_size to maxSize, is diffed as a single
+// property rather than as a delete and an add. Such a property is keyed by its new name, and its old name is recorded
+// in the renames of the object's diff, so that the renaming remains visible. See matchKeyNames for the properties that
+// are matched. Detailed diffs are matched as they are translated instead; see addDiff.
+func matchKeyCasing(diff *resource.ObjectDiff, olds, news resource.PropertyMap) *resource.ObjectDiff {
+	if diff == nil {
+		return nil
+	}
+	return matchObjectKeyCasing(diff, resource.NewObjectProperty(olds), resource.NewObjectProperty(news))
+}
+
+func matchObjectKeyCasing(diff *resource.ObjectDiff, old, new resource.PropertyValue) *resource.ObjectDiff {
+	result := &resource.ObjectDiff{
+		Adds:    diff.Adds.Copy(),
+		Deletes: diff.Deletes.Copy(),
+		Schema:  diff.Schema,
+		Sames:   diff.Sames.Copy(),
+		Updates: make(map[resource.PropertyKey]resource.ValueDiff),
+		Leading: diff.Leading,
+		Renames: diff.Renames,
+	}
+	for k, update := range diff.Updates {
+		elementOld, elementNew := getUpdatedValues(string(k), update, old, new)
+		result.Updates[k] = matchValueKeyCasing(update, elementOld, elementNew)
+	}
+	if !old.IsObject() || !new.IsObject() {
+		return result
+	}
+
+	olds, news := old.ObjectValue(), new.ObjectValue()
+	renames := make(map[resource.PropertyKey]resource.PropertyKey)
+	for from, to := range matchKeyNames(olds, news) {
+		_, deleted := result.Deletes[from]
+		_, added := result.Adds[to]
+		if !deleted || !added {
+			continue
+		}
+		delete(result.Deletes, from)
+		delete(result.Adds, to)
+
+		// The values are recorded along with their diff, so that later stages need not look them up by name.
+		if update := olds[from].Diff(news[to], engine.IsInternalPropertyKey); update != nil {
+			result.Updates[to] = matchValueKeyCasing(*update, olds[from], news[to])
+		} else {
+			result.Sames[to] = news[to]
+		}
+		renames[to] = from
+	}
+	if len(renames) > 0 {
+		for to, from := range diff.Renames {
+			renames[to] = from
+		}
+		result.Renames = renames
+	}
+	return result
+}
+
+func matchArrayKeyCasing(diff *resource.ArrayDiff, old, new resource.PropertyValue) *resource.ArrayDiff {
+	result := &resource.ArrayDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Sames:   diff.Sames,
+		Updates: make(map[int]resource.ValueDiff),
+		Moves:   diff.Moves,
+		Labels:  diff.Labels,
+	}
+	for i, update := range diff.Updates {
+		elementOld, elementNew := getUpdatedValues(i, update, old, new)
+		result.Updates[i] = matchValueKeyCasing(update, elementOld, elementNew)
+	}
+	return result
+}
+
+func matchValueKeyCasing(diff resource.ValueDiff, old, new resource.PropertyValue) resource.ValueDiff {
+	switch {
+	case diff.Array != nil:
+		diff.Array = matchArrayKeyCasing(diff.Array, old, new)
+	case diff.Object != nil:
+		diff.Object = matchObjectKeyCasing(diff.Object, old, new)
+	}
+	return diff
+}
+
+// matchKeyName returns the old and new names of the property with the given name in the given old and new objects,
+// which differ if the property was renamed to a different casing convention. The given name may be either the old or
+// the new name.
+func matchKeyName(k resource.PropertyKey,
+	old, new resource.PropertyValue) (resource.PropertyKey, resource.PropertyKey) {
+
+	if !old.IsObject() || !new.IsObject() {
+		return k, k
+	}
+	for from, to := range matchKeyNames(old.ObjectValue(), new.ObjectValue()) {
+		if k == from || k == to {
+			return from, to
+		}
+	}
+	return k, k
+}
+
+// matchKeyNames returns the old property names that are matched with new property names that differ only in their
+// casing convention, mapped to the new names. An old and a new property are only matched if neither name is present
+// on the other side and if they are the sole old and new properties of their object whose names are the same once
+// casing, underscores, and dashes are disregarded.
+func matchKeyNames(olds, news resource.PropertyMap) map[resource.PropertyKey]resource.PropertyKey {
+	oldNames := make(map[string][]resource.PropertyKey)
+	for k := range olds {
+		if _, has := news[k]; !has {
+			oldNames[normalizeKeyName(k)] = append(oldNames[normalizeKeyName(k)], k)
+		}
+	}
+	newNames := make(map[string][]resource.PropertyKey)
+	for k := range news {
+		if _, has := olds[k]; !has {
+			newNames[normalizeKeyName(k)] = append(newNames[normalizeKeyName(k)], k)
+		}
+	}
+
+	matches := make(map[resource.PropertyKey]resource.PropertyKey)
+	for name, from := range oldNames {
+		if to := newNames[name]; len(from) == 1 && len(to) == 1 {
+			matches[from[0]] = to[0]
+		}
+	}
+	return matches
+}
+
+// normalizeKeyName returns the given property name without regard to its casing convention, e.g. maxsize for maxSize,
+// MaxSize, max_size, and max-size.
+func normalizeKeyName(k resource.PropertyKey) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(string(k)))
+}
@@ -0,0 +1,193 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// KeyedArray identifies the arrays of objects whose elements are matched by the value of a key property rather than
+// by their positions when diffed, e.g. lists of rules or policies that are identified by their names. Each element of
+// such an array is labeled in diffs by its key, e.g. [name="allow-http"], and is shown as added, removed, changed, or
+// unchanged as a whole, wherever it moved within the array.
+type KeyedArray struct {
+	Path string // the property path pattern of the arrays, e.g. **.ingress.
+	Key  string // the name of the property that identifies each element of the arrays, e.g. name.
+}
+
+// ParseKeyedArrays parses the given keyed array specifications, each of the form PATTERN=KEY, e.g. **.rules=name.
+// Patterns use the property path grammar; the key is the text that follows the last "=".
+func ParseKeyedArrays(specs []string) ([]KeyedArray, error) {
+	var result []KeyedArray
+	for _, spec := range specs {
+		i := strings.LastIndex(spec, "=")
+		if i <= 0 || i == len(spec)-1 {
+			return nil, errors.Errorf("malformed keyed array %q (expected PATTERN=KEY)", spec)
+		}
+		path, key := spec[:i], spec[i+1:]
+		if _, err := parseDiffPathPattern(path); err != nil {
+			return nil, err
+		}
+		result = append(result, KeyedArray{Path: path, Key: key})
+	}
+	return result, nil
+}
+
+type keyedArray struct {
+	pattern diffPathPattern
+	key     resource.PropertyKey
+}
+
+// getKeyedArrays parses the keyed arrays of the given options. Keyed arrays whose patterns are invalid are skipped.
+func getKeyedArrays(opts Options) []keyedArray {
+	var result []keyedArray
+	for _, a := range opts.KeyedArrays {
+		if p, err := parseDiffPathPattern(a.Path); err == nil {
+			result = append(result, keyedArray{pattern: p, key: resource.PropertyKey(a.Key)})
+		}
+	}
+	return result
+}
+
+// diffKeyedArrays returns a copy of the given diff between the given old and new properties in which each updated
+// array whose property path matches one of the given keyed arrays has been diffed by key: its diff is an object diff
+// whose properties are the arrays' elements, labeled by their keys. If the first matching keyed array's key is missing
+// from, or is not a string, number, or bool in, any element of the old or new array, or if two elements of either
+// array share a key, the array is diffed by position as usual.
+func diffKeyedArrays(diff *resource.ObjectDiff, olds, news resource.PropertyMap,
+	keyed []keyedArray) *resource.ObjectDiff {
+
+	if diff == nil || len(keyed) == 0 {
+		return diff
+	}
+	return diffKeyedObjectArrays(nil, diff, resource.NewObjectProperty(olds), resource.NewObjectProperty(news), keyed)
+}
+
+func diffKeyedObjectArrays(path []interface{}, diff *resource.ObjectDiff, old, new resource.PropertyValue,
+	keyed []keyedArray) *resource.ObjectDiff {
+
+	result := &resource.ObjectDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Renames: diff.Renames,
+		Sames:   diff.Sames,
+		Updates: make(map[resource.PropertyKey]resource.ValueDiff),
+	}
+	for k, update := range diff.Updates {
+		elementOld, elementNew := getUpdatedValues(string(k), update, old, new)
+		result.Updates[k] = diffKeyedValueArrays(appendPath(path, string(k)), update, elementOld, elementNew, keyed)
+	}
+	return result
+}
+
+func diffKeyedArrayArrays(path []interface{}, diff *resource.ArrayDiff, old, new resource.PropertyValue,
+	keyed []keyedArray) *resource.ArrayDiff {
+
+	result := &resource.ArrayDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Sames:   diff.Sames,
+		Updates: make(map[int]resource.ValueDiff),
+		Moves:   diff.Moves,
+	}
+	for i, update := range diff.Updates {
+		elementOld, elementNew := getUpdatedValues(diff.Index(i), update, old, new)
+		result.Updates[i] = diffKeyedValueArrays(appendPath(path, diff.Index(i)), update, elementOld, elementNew, keyed)
+	}
+	return result
+}
+
+func diffKeyedValueArrays(path []interface{}, diff resource.ValueDiff, old, new resource.PropertyValue,
+	keyed []keyedArray) resource.ValueDiff {
+
+	if old.IsArray() && new.IsArray() {
+		for _, a := range keyed {
+			if !a.pattern.matches(path) {
+				continue
+			}
+			if object, ok := diffByKey(path, old.ArrayValue(), new.ArrayValue(), a.key, keyed); ok {
+				return resource.ValueDiff{Old: old, New: new, Object: object}
+			}
+			break
+		}
+	}
+
+	switch {
+	case diff.Array != nil:
+		diff.Array = diffKeyedArrayArrays(path, diff.Array, old, new, keyed)
+	case diff.Object != nil:
+		diff.Object = diffKeyedObjectArrays(path, diff.Object, old, new, keyed)
+	}
+	return diff
+}
+
+// diffByKey diffs the given arrays of objects by the values of the given key property, returning an object diff
+// whose properties are the elements of the arrays labeled by their keys. Nested keyed arrays within changed elements
+// are diffed by key in turn. The second result is false if the arrays cannot be diffed by key.
+func diffByKey(path []interface{}, old, new []resource.PropertyValue, key resource.PropertyKey,
+	keyed []keyedArray) (*resource.ObjectDiff, bool) {
+
+	olds, ok := labelElements(old, key)
+	if !ok {
+		return nil, false
+	}
+	news, ok := labelElements(new, key)
+	if !ok {
+		return nil, false
+	}
+
+	diff := olds.Diff(news)
+	if diff == nil {
+		diff = &resource.ObjectDiff{
+			Adds:    make(resource.PropertyMap),
+			Deletes: make(resource.PropertyMap),
+			Sames:   olds,
+			Updates: make(map[resource.PropertyKey]resource.ValueDiff),
+		}
+	}
+	return diffKeyedObjectArrays(path, diff, resource.NewObjectProperty(olds), resource.NewObjectProperty(news),
+		keyed), true
+}
+
+// labelElements returns the given elements as properties labeled by the values of the given key property, e.g.
+// [name="allow-http"]. The second result is false if any element is not an object with a unique key whose value is a
+// string, number, or bool.
+func labelElements(elements []resource.PropertyValue, key resource.PropertyKey) (resource.PropertyMap, bool) {
+	result := make(resource.PropertyMap)
+	for _, elem := range elements {
+		if !elem.IsObject() {
+			return nil, false
+		}
+		k, ok := elem.ObjectValue()[key]
+		if !ok || !k.IsString() && !k.IsNumber() && !k.IsBool() {
+			return nil, false
+		}
+
+		label := resource.PropertyKey(fmt.Sprintf("[%s=%s]", key, engine.FormatPropertyValue(k, false)))
+		if _, dup := result[label]; dup {
+			return nil, false
+		}
+		result[label] = elem
+	}
+	return result, true
+}
@@ -0,0 +1,184 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/dustin/go-humanize/english"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// markdownDetailsThreshold is the number of property changes after which a resource's diff is collapsed by default,
+// unless the options specify a summary threshold of their own.
+const markdownDetailsThreshold = 10
+
+// FormatMarkdownDiff renders the property diffs of the given steps as Markdown, e.g. for posting as a comment on a pull
+// request. Each resource that the display would show is rendered as a heading followed by a fenced diff block, so that
+// renderers that highlight diffs (like GitHub's) show removals in red and additions in green:
+//
+//	#### ~ `web` (`pkg:index:Service`): update
+//
+//	```diff
+//	- spec.replicas: 3
+//	+ spec.replicas: 5
+//	+ spec.ports[2]: 8080
+//	```
+//
+// Updates are rendered as the removal of the old value followed by the addition of the new one, and changes that force
+// a replacement are marked as such. The diffs of resources with many changes are collapsed into a <details> element
+// whose summary counts the changes. Secrets are always masked. The options' value transform, property formatters,
+// maximum string length, and diff budget apply as they do to the text display. An empty string is returned if no
+// resource is shown.
+func FormatMarkdownDiff(steps []engine.StepEventMetadata, opts Options) string {
+	threshold := opts.DiffSummaryThreshold
+	if threshold <= 0 {
+		threshold = markdownDetailsThreshold
+	}
+	budget := newDiffBudget(opts)
+
+	var sections []string
+	for _, step := range steps {
+		if step.Op == deploy.OpSame || !shouldShow(step, opts) {
+			continue
+		}
+
+		var buf bytes.Buffer
+		fprintfIgnoreError(&buf, "#### %s `%s` (`%s`): %s\n", strings.TrimSpace(step.Op.RawPrefix()), step.URN.Name(),
+			step.Type, step.Op)
+		if diff := getMarkdownDiff(step, opts); diff != nil {
+			stats := getDiffStats(diff)
+			block := formatMarkdownDiffBlock(diff, getReplacementPaths(step), getPropertyFormatting(diff, opts), budget)
+			if changes := stats.Changes(); changes > threshold {
+				fprintfIgnoreError(&buf, "\n<details>\n<summary>%s %s: %s added, %s deleted, %s updated</summary>\n",
+					humanize.Comma(int64(changes)), english.PluralWord(changes, "change", "changes"),
+					humanize.Comma(int64(stats.Adds)), humanize.Comma(int64(stats.Deletes)),
+					humanize.Comma(int64(stats.Updates)))
+				fprintfIgnoreError(&buf, "\n%s\n</details>\n", block)
+			} else {
+				fprintfIgnoreError(&buf, "\n%s", block)
+			}
+		}
+		sections = append(sections, buf.String())
+	}
+	return strings.Join(sections, "\n")
+}
+
+// getMarkdownDiff returns the property diff of the given step with the options' value transform applied, or nil if the
+// step's properties have no changes.
+func getMarkdownDiff(step engine.StepEventMetadata, opts Options) *resource.ObjectDiff {
+	diff := getStepDiff(step, opts)
+	if diff == nil {
+		return nil
+	}
+
+	return transformObjectDiff(nil, diff, opts.ValueTransform)
+}
+
+// formatMarkdownDiffBlock renders the changes of the given diff as a fenced diff block, consulting the given renderer,
+// if any, for the display text of primitive values. Changes past the remaining diff budget are omitted and counted
+// instead.
+func formatMarkdownDiffBlock(diff *resource.ObjectDiff, replacementPaths []diffPathPattern,
+	render engine.PropertyRenderer, budget *diffBudget) string {
+
+	limit := budget.remaining()
+
+	var lines []string
+	changes := 0
+	walkObjectDiff(diff, func(path []interface{}, op deploy.StepOp, old, new resource.PropertyValue) {
+		if op == deploy.OpSame {
+			return
+		}
+		if changes++; limit >= 0 && changes > limit {
+			return
+		}
+
+		suffix := ""
+		if isReplacementPath(path, replacementPaths) {
+			suffix = " [forces replacement]"
+		}
+		key := engine.FormatPropertyPath(path)
+		if op != deploy.OpCreate {
+			lines = append(lines, fmt.Sprintf("- %s: %s%s", key, formatMarkdownValue(path, old, render), suffix))
+		}
+		if op != deploy.OpDelete {
+			lines = append(lines, fmt.Sprintf("+ %s: %s%s", key, formatMarkdownValue(path, new, render), suffix))
+		}
+	})
+	if limit >= 0 && changes > limit {
+		budget.spend(limit)
+		omitted := changes - limit
+		lines = append(lines, fmt.Sprintf("… and %s %s", humanize.Comma(int64(omitted)),
+			english.PluralWord(omitted, "more change", "more changes")))
+	} else {
+		budget.spend(changes)
+	}
+
+	text := strings.Join(lines, "\n")
+	fence := markdownFence(text)
+	return fmt.Sprintf("%sdiff\n%s\n%s\n", fence, text, fence)
+}
+
+// formatMarkdownValue formats the value at the given path on a single line. Arrays and objects are formatted in full,
+// in the manner of JSON; secrets are masked.
+func formatMarkdownValue(path []interface{}, v resource.PropertyValue, render engine.PropertyRenderer) string {
+	switch {
+	case v.IsArray():
+		elements := make([]string, len(v.ArrayValue()))
+		for i, element := range v.ArrayValue() {
+			elements[i] = formatMarkdownValue(appendPath(path, i), element, render)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case v.IsObject():
+		var properties []string
+		for _, k := range v.ObjectValue().StableKeys() {
+			properties = append(properties,
+				fmt.Sprintf("%q: %s", k, formatMarkdownValue(appendPath(path, string(k)), v.ObjectValue()[k], render)))
+		}
+		return "{" + strings.Join(properties, ", ") + "}"
+	default:
+		if render != nil {
+			if text, ok := render(path, v); ok {
+				return text
+			}
+		}
+		return engine.FormatPropertyValue(v, true /*planning*/)
+	}
+}
+
+// markdownFence returns a code fence that is longer than any run of backticks in the given text, so that the text
+// cannot close the fence early.
+func markdownFence(text string) string {
+	longest, run := 0, 0
+	for _, c := range text {
+		if c == '`' {
+			if run++; run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
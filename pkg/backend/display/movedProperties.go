@@ -0,0 +1,145 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// movedProperty is a property that was deleted at one path and added with an identical value at another, e.g.
+// because a provider's schema moved the property to a different parent.
+type movedProperty struct {
+	from, to []interface{}          // the paths from and to which the property moved.
+	value    resource.PropertyValue // the property's value.
+}
+
+// detectMovedProperties finds the properties of the given diff that were deleted at one path and added with an
+// identical value at another, and returns a copy of the diff from which those deletes and adds have been removed along
+// with the moves that they describe. Detection is deliberately conservative: only object properties are considered
+// (array elements shift too easily to be matched reliably), values must be known and not null, and a delete and an add
+// are only paired if each is the other's sole match, as decided by the given equality. Unless anyKey is true, the two
+// properties must also share a name.
+// Top-level properties that are not in the include set are ignored; a nil include set includes all properties.
+func detectMovedProperties(diff *resource.ObjectDiff, include []resource.PropertyKey,
+	anyKey bool, equals ValueEquality) (*resource.ObjectDiff, []movedProperty) {
+
+	if diff == nil {
+		return nil, nil
+	}
+
+	type change struct {
+		path  []interface{}
+		value resource.PropertyValue
+	}
+	includeSet := makeIncludeSet(include)
+	var deletes, adds []change
+	walkObjectDiff(diff, func(path []interface{}, op deploy.StepOp, old, new resource.PropertyValue) {
+		if includeSet != nil && !includeSet[resource.PropertyKey(path[0].(string))] || !isMovablePath(path) {
+			return
+		}
+		switch {
+		case op == deploy.OpDelete && isMovableValue(old):
+			deletes = append(deletes, change{path: path, value: old})
+		case op == deploy.OpCreate && isMovableValue(new):
+			adds = append(adds, change{path: path, value: new})
+		}
+	})
+
+	matches := func(delete, add change) bool {
+		return (anyKey || delete.path[len(delete.path)-1] == add.path[len(add.path)-1]) &&
+			equals(delete.value, add.value)
+	}
+	var moves []movedProperty
+	var moved []diffPathPattern
+	for _, delete := range deletes {
+		var candidates []change
+		for _, add := range adds {
+			if matches(delete, add) {
+				candidates = append(candidates, add)
+			}
+		}
+		if len(candidates) != 1 {
+			continue
+		}
+
+		add, rivals := candidates[0], 0
+		for _, other := range deletes {
+			if matches(other, add) {
+				rivals++
+			}
+		}
+		if rivals == 1 {
+			moves = append(moves, movedProperty{from: delete.path, to: add.path, value: add.value})
+			moved = append(moved, diffPathPattern(delete.path), diffPathPattern(add.path))
+		}
+	}
+	if len(moves) == 0 {
+		return diff, nil
+	}
+	return filterIgnoredDiffs(diff, moved), moves
+}
+
+// isMovablePath returns true if the property at the given path may be detected as moved: it must be an object
+// property, and, as the paths of moved properties are removed from diffs as patterns, none of the path's elements may
+// be a wildcard.
+func isMovablePath(path []interface{}) bool {
+	if _, ok := path[len(path)-1].(string); !ok {
+		return false
+	}
+	for _, element := range path {
+		if element == "*" || element == "**" {
+			return false
+		}
+	}
+	return true
+}
+
+// isMovableValue returns true if a property with the given value may be detected as moved.
+func isMovableValue(v resource.PropertyValue) bool {
+	return !v.IsNull() && !isUnknown(v) && !v.ContainsUnknowns()
+}
+
+// renderMovedProperties renders the given moved properties beneath the diff of the resource that contains them, e.g.
+//
+//	--moved:--
+//	~ spec.template.labels => metadata.labels: <object>
+//
+// The moved values are transformed by the options' value transform, if any.
+func renderMovedProperties(moves []movedProperty, planning bool, indent int, opts Options) string {
+	if len(moves) == 0 {
+		return ""
+	}
+
+	indentation := engine.GetIndentationString(indent)
+	prefix := indentation[:len(indentation)-2] + deploy.OpUpdate.RawPrefix()
+
+	var buf bytes.Buffer
+	fprintfIgnoreError(&buf, "%v%v--moved:--%v\n", deploy.OpUpdate.Color(), indentation, colors.Reset)
+	for _, move := range moves {
+		value := move.value
+		if opts.ValueTransform != nil {
+			value = transformValue(move.to, value, opts.ValueTransform)
+		}
+		fprintfIgnoreError(&buf, "%v%v%v => %v: %v%v\n", deploy.OpUpdate.Color(), prefix,
+			engine.FormatPropertyPath(move.from), engine.FormatPropertyPath(move.to),
+			engine.FormatPropertyValue(value, planning), colors.Reset)
+	}
+	return buf.String()
+}
@@ -0,0 +1,76 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// ParseDiffNormalization parses the names of the rules of a diff normalization policy: numbers, under which numbers
+// and numeric strings that denote the same number are equivalent; whitespace, under which strings that differ only in
+// trailing whitespace are equivalent; nulls, under which null properties are equivalent to absent ones; and
+// empty-strings, under which empty strings are equivalent to nulls.
+func ParseDiffNormalization(names []string) (resource.DiffNormalization, error) {
+	var norm resource.DiffNormalization
+	for _, name := range names {
+		switch name {
+		case "numbers":
+			norm.NumericEquivalence = true
+		case "whitespace":
+			norm.TrailingWhitespace = true
+		case "nulls":
+			norm.NullEqualsAbsent = true
+		case "empty-strings":
+			norm.EmptyStringEqualsNull = true
+		default:
+			return resource.DiffNormalization{}, errors.Errorf(
+				"unknown diff normalization %q (expected one of numbers, whitespace, nulls, or empty-strings)", name)
+		}
+	}
+	return norm, nil
+}
+
+// isCosmeticUpdate returns true if the given step is an update whose diff has no changes that are meaningful under
+// the options' diff normalization policy, e.g. because the provider reports only numbers that changed into equivalent
+// strings, whose only changes are to JSON strings that were merely re-serialized, or whose only changes are between
+// values that the options' value equality considers the same. Such updates are displayed as sames; they are still
+// performed.
+func isCosmeticUpdate(step engine.StepEventMetadata, opts Options) bool {
+	if step.Op != deploy.OpUpdate || step.Old == nil || step.New == nil {
+		return false
+	}
+	if !opts.NormalizeDiffs.Any() {
+		if len(opts.JSONStringPaths) == 0 && opts.Equals == nil {
+			return false
+		}
+		// Updates that have no changes to begin with are not cosmetic, and are displayed as usual.
+		raw := opts
+		raw.JSONStringPaths, raw.Equals = nil, nil
+		return getStepDiff(step, raw) != nil && getStepDiff(step, opts) == nil
+	}
+	return !getStepDiff(step, opts).AnyChangesUnder(opts.NormalizeDiffs)
+}
+
+// normalizeStepOp returns the given step, displayed as a same if it is a cosmetic update.
+func normalizeStepOp(step engine.StepEventMetadata, opts Options) engine.StepEventMetadata {
+	if isCosmeticUpdate(step, opts) {
+		step.Op = deploy.OpSame
+	}
+	return step
+}
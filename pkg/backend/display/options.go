@@ -14,7 +14,10 @@
 
 package display
 
-import "github.com/pulumi/pulumi/pkg/diag/colors"
+import (
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
 
 // Type of output to display.
 type Type int
@@ -40,4 +43,65 @@ type Options struct {
 	Type                 Type                // type of display (rich diff, progress, or query).
 	JSONDisplay          bool                // true if we should emit the entire diff as JSON.
 	Debug                bool                // true to enable debug output.
+	ChangelogPath        string              // if non-empty, the path to which to write a changelog of the update.
+	ImportPlanPath       string              // if non-empty, the path to which to write an import plan of additions.
+	DiffLogPath          string              // if non-empty, the path to which to log each property change.
+	DiffLogValues        bool                // true to include non-secret values in the property change log.
+	DiffFormat           DiffFormat          // if jsonlines, write a JSON record per resource diff instead of text.
+
+	DiffOptions // controls how the diffs of resources are rendered.
+
+	translator   *diffTranslator     // if non-nil, translates and caches the detailed diffs of the steps being displayed.
+	computed     *computedDiffLeaves // if non-nil, tracks the computed leaves of the diffs being displayed.
+	replacements *replacementSteps   // if non-nil, records the steps that replace resources as they are displayed.
+}
+
+// DiffOptions controls how the diffs of resources are rendered. The zero value renders diffs as the engine does; see
+// usesCustomDiff for the options that require diffs to be rendered by the display instead.
+type DiffOptions struct {
+	PlainDiff              bool                // true to render resource diffs without color, regardless of Color.
+	DiffBudget             int                 // if positive, the number of property changes after which to cut diffs.
+	GlobalDiffBudget       bool                // true if DiffBudget applies to all resources rather than to each one.
+	DiffBudgetHint         string              // if non-empty, how to see the changes that the diff budget omits.
+	PropertyFormatters     *PropertyFormatters // if non-nil, custom formatters for the property values in diffs.
+	IgnoreDiffPaths        []string            // property path patterns whose changes are omitted from diffs.
+	MaxStringDisplayLength int                 // if positive, the number of characters after which to cut strings.
+	ValueTransform         ValueTransform      // if non-nil, transforms the property values in diffs before display.
+	UnorderedArrayPaths    []string            // property path patterns of arrays whose order is insignificant.
+	AlignArrays            bool                // true to show elements inserted into or removed from arrays as such.
+	TupleArrayPaths        []string            // property path patterns of arrays whose elements are never aligned.
+	StrictDetailedDiff     bool                // true to report detailed diff kinds that conflict with the display.
+	ShowFullUpdates        ResourceFilter      // if non-nil, selects resources whose updated objects are shown in full.
+	GroupReplacements      bool                // true to show changes that force replacements apart from other changes.
+	JSONStringPaths        []string            // property path patterns of strings whose JSON contents are diffed.
+	ResolveComputedDiffs   bool                // true to patch computed values in diffs as they resolve (experimental).
+	GroupChangesByKind     bool                // true to list the property changes of all resources grouped by kind.
+	DiffIndentWidth        int                 // if positive, the number of columns per level of nesting in diffs.
+	DiffTreeStyle          DiffTreeStyle       // if non-empty, the lines that connect nested properties in diffs.
+	PropertyColors         []PropertyColor     // overrides of the colors of property values in diffs, by path.
+	DetectMovedProperties  bool                // true to show properties deleted and re-added elsewhere as moves.
+	MovedPropertiesAnyKey  bool                // true to also detect moves between properties with different names.
+	ShowSecretChanges      bool                // true to list the secret properties that changed, without their values.
+	DiffSummaryThreshold   int                 // if positive, the number of changes after which to summarize diffs.
+	KeyedArrays            []KeyedArray        // arrays of objects whose elements are diffed by key rather than index.
+	PropertyOrder          []string            // property names or path patterns listed first among their siblings.
+	ShowArrayMoves         bool                // true to label moved array elements by their old and new indices.
+	FlattenDiffDepth       int                 // if positive, the depth from which single-property objects are flattened.
+	ShowDiffLegend         bool                // true to explain the markers that appear in diffs along with the summary.
+	MatchKeyCasing         bool                // true to match properties whose names differ only in casing convention.
+	DiffContext            int                 // if positive, the number of unchanged siblings shown around changes.
+	DiffWidth              int                 // if positive, the width at which long lines in diffs are wrapped.
+	TrustDetailedDiffKinds bool                // true to trust the kinds that providers report at every diff level.
+	DiffPathsOnly          bool                // true to render only the paths of changed properties, without values.
+	ShowReplacementReasons bool                // true to list the changes that force replacements apart from sames.
+	ArrayDiffWindow        int                 // if positive, the number of changes shown at either end of each array.
+	OrderByDependencies    bool                // true to show each resource's diff before those of its dependents.
+	SummarizeReplacements  bool                // true to list the changes that force each replacement after the diff.
+	Equals                 ValueEquality       // if non-nil, decides whether values are the same instead of deep equality.
+
+	// NormalizeDiffs is the policy under which updates whose changes are all cosmetic, e.g. numbers that became
+	// equivalent strings, are displayed as sames. Such updates are still performed. If the policy considers empty
+	// strings and nulls equivalent, so does the value equality, so changes between them are also displayed as sames
+	// within updates that have other changes.
+	NormalizeDiffs resource.DiffNormalization
 }
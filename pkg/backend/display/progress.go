@@ -20,6 +20,7 @@ import (
 	"io"
 	"os"
 	"regexp"
+	"runtime"
 	"sort"
 	"strings"
 	"time"
@@ -246,6 +247,10 @@ func ShowProgressEvents(op string, action apitype.UpdateKind, stack tokens.QName
 	// from to display to the console.
 	progressOutput := make(chan Progress)
 
+	// Translate the detailed diffs of resources ahead of their display, as this can be expensive for large resources.
+	opts.translator = newDiffTranslator(runtime.NumCPU(), opts.TrustDetailedDiffKinds, opts.MatchKeyCasing)
+	events = prefetchDiffs(events, opts.translator)
+
 	display := &ProgressDisplay{
 		action:                 action,
 		isPreview:              isPreview,
@@ -753,7 +758,12 @@ func (display *ProgressDisplay) processEndSteps() {
 			display.writeBlankLine()
 		}
 
-		msg := renderSummaryEvent(display.action, *display.summaryEventPayload, display.opts)
+		steps := make([]engine.StepEventMetadata, 0, len(display.eventUrnToResourceRow))
+		for _, row := range display.eventUrnToResourceRow {
+			steps = append(steps, row.Step())
+		}
+
+		msg := renderSummaryEvent(display.action, *display.summaryEventPayload, steps, display.opts)
 		display.writeSimpleMessage(msg)
 	}
 }
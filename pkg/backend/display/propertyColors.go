@@ -0,0 +1,76 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// PropertyColor overrides the color of the property values in diffs whose paths match a pattern, regardless of
+// whether the values were added, deleted, or updated. Patterns use the property path grammar, with "*" matching any
+// single path element and "**" matching any number of them; for example, "**.ingress.**" matches every value beneath
+// any property named ingress.
+type PropertyColor struct {
+	Path  string // the property path pattern of the values to color.
+	Color string // the color in which to display the values, e.g. colors.BrightCyan.
+}
+
+type propertyColor struct {
+	pattern diffPathPattern
+	color   string
+}
+
+// getPropertyColors parses the property color overrides of the given options. Overrides whose patterns are invalid
+// are skipped.
+func getPropertyColors(opts Options) []propertyColor {
+	var result []propertyColor
+	for _, c := range opts.PropertyColors {
+		if p, err := parseDiffPathPattern(c.Path); err == nil {
+			result = append(result, propertyColor{pattern: p, color: c.Color})
+		}
+	}
+	return result
+}
+
+// colorRenderer returns a renderer that displays the string, number, and bool values whose paths match one of the
+// given overrides in the override's color, and that otherwise defers to the given renderer, which may be nil. If
+// several overrides match a value, the first one applies. Other values, e.g. secrets and unknowns, keep the colors of
+// their changes.
+func colorRenderer(render engine.PropertyRenderer, overrides []propertyColor) engine.PropertyRenderer {
+	if len(overrides) == 0 {
+		return render
+	}
+
+	return func(path []interface{}, v resource.PropertyValue) (string, bool) {
+		text, ok := "", false
+		if render != nil {
+			text, ok = render(path, v)
+		}
+		if !ok && !v.IsString() && !v.IsNumber() && !v.IsBool() {
+			return "", false
+		}
+
+		for _, c := range overrides {
+			if c.pattern.matches(path) {
+				if !ok {
+					text = engine.FormatPropertyValue(v, false)
+				}
+				return c.color + text, true
+			}
+		}
+		return text, ok
+	}
+}
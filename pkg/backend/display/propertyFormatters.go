@@ -0,0 +1,120 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// PropertyFormatter renders a primitive property value (a string, number, or bool) for display in a resource diff. It
+// returns false if it declines to format the value, in which case the value is displayed as usual.
+type PropertyFormatter func(v resource.PropertyValue) (string, bool)
+
+// PropertyFormatters is a registry of custom formatters for the property values displayed in resource diffs.
+// Formatters may be registered for the values at particular property paths or for all values of a particular type.
+// Path formatters take precedence over type formatters, and are consulted in the order in which they were registered.
+type PropertyFormatters struct {
+	paths   []pathFormatter
+	types   map[string]PropertyFormatter
+	updates updateFormatter // if non-nil, formats both sides of an update to a primitive value together.
+}
+
+type pathFormatter struct {
+	pattern   diffPathPattern
+	formatter PropertyFormatter
+}
+
+// updateFormatter renders the old and new values of an update to a primitive value. It returns false if it declines to
+// format the values, in which case each is formatted separately.
+type updateFormatter func(old, new resource.PropertyValue) (string, string, bool)
+
+// NewPropertyFormatters creates an empty formatter registry.
+func NewPropertyFormatters() *PropertyFormatters {
+	return &PropertyFormatters{types: make(map[string]PropertyFormatter)}
+}
+
+// RegisterPath registers a formatter for the values whose property paths match the given pattern. Patterns use the
+// property path grammar, with "*" matching any single path element and "**" matching any number of them; for example,
+// "**.sizeBytes" matches any property named sizeBytes.
+func (f *PropertyFormatters) RegisterPath(pattern string, formatter PropertyFormatter) error {
+	p, err := parseDiffPathPattern(pattern)
+	if err != nil {
+		return err
+	}
+	f.paths = append(f.paths, pathFormatter{pattern: p, formatter: formatter})
+	return nil
+}
+
+// RegisterType registers a formatter for all values of the given type, as named by PropertyValue.TypeString (i.e.
+// "string", "number", or "bool").
+func (f *PropertyFormatters) RegisterType(typ string, formatter PropertyFormatter) {
+	f.types[typ] = formatter
+}
+
+// formatValue returns the custom formatting of the given primitive value at the given path. It returns false if no
+// formatter formats the value.
+func (f *PropertyFormatters) formatValue(path []interface{}, v resource.PropertyValue) (string, bool) {
+	if !v.IsString() && !v.IsNumber() && !v.IsBool() {
+		return "", false
+	}
+	for _, p := range f.paths {
+		if p.pattern.matches(path) {
+			if text, ok := p.formatter(v); ok {
+				return text, true
+			}
+		}
+	}
+	if formatter, has := f.types[v.TypeString()]; has {
+		return formatter(v)
+	}
+	return "", false
+}
+
+// formattedUpdate records the formatting of both sides of an update to a primitive value.
+type formattedUpdate struct {
+	old, new         resource.PropertyValue
+	oldText, newText string
+}
+
+// renderer returns a renderer that displays the values of the given diff in their custom formatting, if any. The
+// diff may be nil, in which case the sides of updates are never formatted together.
+func (f *PropertyFormatters) renderer(diff *resource.ObjectDiff) engine.PropertyRenderer {
+	updates := make(map[string]formattedUpdate)
+	if f.updates != nil && diff != nil {
+		walkObjectDiff(diff, func(path []interface{}, op deploy.StepOp, old, new resource.PropertyValue) {
+			if op != deploy.OpUpdate {
+				return
+			}
+			if oldText, newText, ok := f.updates(old, new); ok {
+				updates[engine.FormatPropertyPath(path)] = formattedUpdate{
+					old: old, new: new, oldText: oldText, newText: newText}
+			}
+		})
+	}
+
+	return func(path []interface{}, v resource.PropertyValue) (string, bool) {
+		if update, has := updates[engine.FormatPropertyPath(path)]; has {
+			switch {
+			case v.DeepEquals(update.old):
+				return update.oldText, true
+			case v.DeepEquals(update.new):
+				return update.newText, true
+			}
+		}
+		return f.formatValue(path, v)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dustin/go-humanize"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+func TestDiffPathPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    []interface{}
+		matches bool
+	}{
+		{"disk.sizeBytes", []interface{}{"disk", "sizeBytes"}, true},
+		{"disk.sizeBytes", []interface{}{"disk", "size"}, false},
+		{"*.sizeBytes", []interface{}{"disk", "sizeBytes"}, true},
+		{"*.sizeBytes", []interface{}{"sizeBytes"}, false},
+		{"*.sizeBytes", []interface{}{"spec", "disk", "sizeBytes"}, false},
+		{"**.sizeBytes", []interface{}{"sizeBytes"}, true},
+		{"**.sizeBytes", []interface{}{"spec", "volumes", 0, "sizeBytes"}, true},
+		{"volumes.*", []interface{}{"volumes", 3}, true},
+		{"volumes[1]", []interface{}{"volumes", 3}, false},
+		{`metadata["a.b"]`, []interface{}{"metadata", "a.b"}, true},
+		{"metadata.**", []interface{}{"metadata"}, true},
+		{"metadata.**", []interface{}{"spec"}, false},
+	}
+	for _, c := range cases {
+		pattern, err := parseDiffPathPattern(c.pattern)
+		assert.NoError(t, err)
+		assert.Equal(t, c.matches, pattern.matches(c.path), "%s %v", c.pattern, c.path)
+	}
+
+	_, err := parseDiffPathPattern("")
+	assert.Error(t, err)
+	_, err = parseDiffPathPattern(`metadata["unterminated`)
+	assert.Error(t, err)
+}
+
+func TestPropertyFormatters(t *testing.T) {
+	formatters := NewPropertyFormatters()
+	assert.NoError(t, formatters.RegisterPath("**.sizeBytes", func(v resource.PropertyValue) (string, bool) {
+		if !v.IsNumber() {
+			return "", false
+		}
+		return humanize.IBytes(uint64(v.NumberValue())), true
+	}))
+	formatters.RegisterType("string", func(v resource.PropertyValue) (string, bool) {
+		return strings.ToUpper(v.StringValue()), true
+	})
+
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name": "web",
+		"disks": []interface{}{
+			map[string]interface{}{"sizeBytes": 1 << 30},
+		},
+		"cache": map[string]interface{}{"sizeBytes": "unbounded"},
+	})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{
+		"name": "api",
+		"disks": []interface{}{
+			map[string]interface{}{"sizeBytes": 3 << 29},
+		},
+		"cache": map[string]interface{}{"sizeBytes": 1 << 20},
+	})
+	step := makeUpdateStep(olds, news, map[string]plugin.PropertyDiff{
+		"name":               {Kind: plugin.DiffUpdate},
+		"disks[0].sizeBytes": {Kind: plugin.DiffUpdate},
+		"cache.sizeBytes":    {Kind: plugin.DiffUpdate},
+	})
+
+	opts := Options{Color: colors.Never, Type: DisplayDiff, DiffOptions: DiffOptions{PropertyFormatters: formatters}}
+	assertGolden(t, "property_formatters.txt", renderStepDiff(step, opts))
+}
+
+func TestPropertyFormattersRenderer(t *testing.T) {
+	formatters := NewPropertyFormatters()
+	assert.NoError(t, formatters.RegisterPath("spec.zone", func(v resource.PropertyValue) (string, bool) {
+		return "zone " + v.StringValue(), true
+	}))
+	formatters.RegisterType("string", func(v resource.PropertyValue) (string, bool) {
+		return strings.ToUpper(v.StringValue()), true
+	})
+	formatters.updates = func(old, new resource.PropertyValue) (string, string, bool) {
+		if !old.IsNumber() || !new.IsNumber() {
+			return "", "", false
+		}
+		return "was", "now", true
+	}
+
+	str, num := resource.NewStringProperty, resource.NewNumberProperty
+	diff := &resource.ObjectDiff{
+		Sames: resource.PropertyMap{"name": str("web")},
+		Updates: map[resource.PropertyKey]resource.ValueDiff{
+			"spec": {Object: &resource.ObjectDiff{
+				Updates: map[resource.PropertyKey]resource.ValueDiff{
+					"zone":     {Old: str("a"), New: str("b")},
+					"replicas": {Old: num(3), New: num(5)},
+				},
+			}},
+		},
+	}
+	render := formatters.renderer(diff)
+
+	cases := []struct {
+		path     []interface{}
+		value    resource.PropertyValue
+		expected string
+		ok       bool
+	}{
+		{[]interface{}{"name"}, str("web"), "WEB", true},
+		{[]interface{}{"spec", "zone"}, str("b"), "zone b", true},
+		{[]interface{}{"spec", "replicas"}, num(3), "was", true},
+		{[]interface{}{"spec", "replicas"}, num(5), "now", true},
+		{[]interface{}{"spec", "replicas"}, num(4), "", false},
+		{[]interface{}{"ports"}, resource.NewArrayProperty(nil), "", false},
+	}
+	for _, c := range cases {
+		text, ok := render(c.path, c.value)
+		assert.Equal(t, c.ok, ok, "%v", c.path)
+		assert.Equal(t, c.expected, text, "%v", c.path)
+	}
+}
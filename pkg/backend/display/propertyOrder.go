@@ -0,0 +1,103 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"sort"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// getPropertyOrder parses the property order of the given options. A pattern that is a single property name, e.g.
+// "replicas", matches that property at any depth; other patterns match property paths as usual. Invalid patterns are
+// skipped.
+func getPropertyOrder(opts Options) []diffPathPattern {
+	var patterns []diffPathPattern
+	for _, pattern := range opts.PropertyOrder {
+		p, err := parseDiffPathPattern(pattern)
+		if err != nil {
+			continue
+		}
+		if name, ok := p[0].(string); ok && len(p) == 1 && name != "*" && name != "**" {
+			p = diffPathPattern{"**", name}
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// orderObjectDiff returns a copy of the given diff in which the properties of each object that match one of the given
+// patterns are listed before their siblings, ordered by the first pattern that each matches. Properties that match no
+// pattern follow in the usual order.
+func orderObjectDiff(path []interface{}, diff *resource.ObjectDiff, patterns []diffPathPattern) *resource.ObjectDiff {
+	if diff == nil || len(patterns) == 0 {
+		return diff
+	}
+
+	rank := make(map[resource.PropertyKey]int)
+	for _, k := range diff.Keys() {
+		for i, p := range patterns {
+			if p.matches(appendPath(path, string(k))) {
+				rank[k] = i
+				break
+			}
+		}
+	}
+	var leading []resource.PropertyKey
+	for k := range rank {
+		leading = append(leading, k)
+	}
+	sort.Slice(leading, func(i, j int) bool {
+		if rank[leading[i]] != rank[leading[j]] {
+			return rank[leading[i]] < rank[leading[j]]
+		}
+		return leading[i] < leading[j]
+	})
+
+	updates := make(map[resource.PropertyKey]resource.ValueDiff)
+	for k, update := range diff.Updates {
+		updates[k] = orderValueDiff(appendPath(path, string(k)), update, patterns)
+	}
+	return &resource.ObjectDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Renames: diff.Renames,
+		Sames:   diff.Sames,
+		Updates: updates,
+		Leading: leading,
+	}
+}
+
+func orderValueDiff(path []interface{}, diff resource.ValueDiff, patterns []diffPathPattern) resource.ValueDiff {
+	switch {
+	case diff.Array != nil:
+		updates := make(map[int]resource.ValueDiff)
+		for i, update := range diff.Array.Updates {
+			updates[i] = orderValueDiff(appendPath(path, diff.Array.Index(i)), update, patterns)
+		}
+		diff.Array = &resource.ArrayDiff{
+			Adds:    diff.Array.Adds,
+			Deletes: diff.Array.Deletes,
+			Schema:  diff.Array.Schema,
+			Sames:   diff.Array.Sames,
+			Updates: updates,
+			Moves:   diff.Array.Moves,
+		}
+	case diff.Object != nil:
+		diff.Object = orderObjectDiff(path, diff.Object, patterns)
+	}
+	return diff
+}
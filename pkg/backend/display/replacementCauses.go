@@ -0,0 +1,148 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/dustin/go-humanize/english"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// replacementCause is a change that forces a resource to be replaced. It deliberately carries no values. Properties
+// that the provider reports as forcing the replacement although their values are the same are recorded as sames.
+type replacementCause struct {
+	Path []interface{} // the path to the property.
+	Op   deploy.StepOp // the change: OpCreate for adds, OpDelete for deletes, OpUpdate for updates, or OpSame.
+}
+
+// replacementSteps records the steps that replace resources as they are displayed. The display otherwise only keeps
+// the last step of each resource, which for a replaced resource is usually the deletion of its old state.
+type replacementSteps struct {
+	steps map[resource.URN]engine.StepEventMetadata
+}
+
+func newReplacementSteps() *replacementSteps {
+	return &replacementSteps{steps: make(map[resource.URN]engine.StepEventMetadata)}
+}
+
+// record records the given step if it replaces its resource.
+func (r *replacementSteps) record(step engine.StepEventMetadata) {
+	if isReplacementStep(step) {
+		r.steps[step.URN] = step
+	}
+}
+
+func isReplacementStep(step engine.StepEventMetadata) bool {
+	return (step.Op == deploy.OpReplace || step.Op == deploy.OpCreateReplacement) && step.Old != nil && step.New != nil
+}
+
+// getReplacementCauses collects the changes that force each of the given steps that replace their resources and that
+// would be shown by the display to do so, keyed by URN. These are the changes that the diffs mark as replacing, in the
+// order in which diffs are walked, followed by any other paths that the provider reports as forcing the replacement
+// although their values are the same. If the options record the steps that replaced resources, those are used
+// instead of the given steps.
+func getReplacementCauses(steps []engine.StepEventMetadata, opts Options) map[resource.URN][]replacementCause {
+	if opts.replacements != nil {
+		steps = steps[:0:0]
+		for _, step := range opts.replacements.steps {
+			steps = append(steps, step)
+		}
+	}
+
+	causes := make(map[resource.URN][]replacementCause)
+	for _, step := range steps {
+		if !isReplacementStep(step) || !shouldShow(step, opts) {
+			continue
+		}
+
+		// Paths whose values are the same are recorded as such, whether or not the diff includes them.
+		unchanged := getReplacementReasons(step, opts).Unchanged
+		listed := make(map[string]bool)
+		for _, path := range unchanged {
+			listed[path] = false
+		}
+
+		var stepCauses []replacementCause
+		replacementPaths := getReplacementPaths(step)
+		walkObjectDiff(getStepDiff(step, opts), func(path []interface{}, op deploy.StepOp, _, _ resource.PropertyValue) {
+			if op == deploy.OpSame || !isReplacementPath(path, replacementPaths) {
+				return
+			}
+			key := engine.FormatPropertyPath(path)
+			if _, same := listed[key]; same {
+				op, listed[key] = deploy.OpSame, true
+			}
+			stepCauses = append(stepCauses, replacementCause{Path: path, Op: op})
+		})
+		for _, path := range unchanged {
+			if elements, err := parsedDiffPaths.parse(path); err == nil && !listed[path] {
+				stepCauses = append(stepCauses, replacementCause{Path: elements, Op: deploy.OpSame})
+			}
+		}
+		if len(stepCauses) > 0 {
+			causes[step.URN] = stepCauses
+		}
+	}
+	return causes
+}
+
+// renderReplacementCauses renders the changes that force the replacements among the given steps, resource by resource,
+// as a footnote to the diff, e.g.
+//
+//	Replacement causes:
+//	    +-urn:pulumi:dev::web::aws:ec2/instance:Instance::web
+//	        ami: updated
+//	        subnetId: unchanged, but reported as forcing replacement
+//	    1 resource replaced
+//
+// Nothing is rendered unless the options request it or if no resources are replaced.
+func renderReplacementCauses(steps []engine.StepEventMetadata, opts Options) string {
+	if !opts.SummarizeReplacements {
+		return ""
+	}
+	causes := getReplacementCauses(steps, opts)
+	if len(causes) == 0 {
+		return ""
+	}
+
+	urns := make([]resource.URN, 0, len(causes))
+	for urn := range causes {
+		urns = append(urns, urn)
+	}
+	sort.Slice(urns, func(i, j int) bool { return urns[i] < urns[j] })
+
+	var buf bytes.Buffer
+	fprintfIgnoreError(&buf, "%sReplacement causes:%s\n", colors.SpecHeadline, colors.Reset)
+	for _, urn := range urns {
+		fprintfIgnoreError(&buf, "    %s%s%s\n", deploy.OpReplace.Prefix(), urn, colors.Reset)
+		for _, cause := range causes[urn] {
+			if cause.Op == deploy.OpSame {
+				fprintfIgnoreError(&buf, "        %s%s: unchanged, but reported as forcing replacement%s\n",
+					colors.SpecWarning, engine.FormatPropertyPath(cause.Path), colors.Reset)
+				continue
+			}
+			fprintfIgnoreError(&buf, "        %s%s: %s%s\n", deploy.OpReplace.Color(),
+				engine.FormatPropertyPath(cause.Path), describeChange(cause.Op), colors.Reset)
+		}
+	}
+	fprintfIgnoreError(&buf, "    %s replaced\n\n", english.Plural(len(urns), "resource", "resources"))
+	return buf.String()
+}
@@ -0,0 +1,202 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// getReplacementPaths returns the property paths whose changes force the given step's resource to be replaced.
+func getReplacementPaths(step engine.StepEventMetadata) []diffPathPattern {
+	var paths []diffPathPattern
+	for _, path := range step.ReplaceReasons() {
+		if elements, err := parsedDiffPaths.parse(path); err == nil {
+			paths = append(paths, diffPathPattern(elements))
+		}
+	}
+	return paths
+}
+
+// isReplacementPath returns true if a change at the given property path forces a replacement, i.e. if the path, any
+// path that contains it, or any path that it contains is a replacement path. The last case arises when the ancestor of
+// a replacing change is added or deleted as a whole.
+func isReplacementPath(path []interface{}, paths []diffPathPattern) bool {
+	if isIgnoredDiffPath(path, paths) {
+		return true
+	}
+	for _, p := range paths {
+		if len(path) < len(p) && p[:len(path)].matches(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// partitionDiff splits the given diff into the changes that force a replacement, i.e. those at the given replacement
+// paths, and the changes that can be made in place. Sames are recorded with the in-place changes.
+func partitionDiff(path []interface{}, diff *resource.ObjectDiff,
+	paths []diffPathPattern) (*resource.ObjectDiff, *resource.ObjectDiff) {
+
+	newObjectDiff := func() *resource.ObjectDiff {
+		return &resource.ObjectDiff{
+			Adds:    make(resource.PropertyMap),
+			Deletes: make(resource.PropertyMap),
+			Sames:   make(resource.PropertyMap),
+			Updates: make(map[resource.PropertyKey]resource.ValueDiff),
+		}
+	}
+	replacing, inPlace := newObjectDiff(), newObjectDiff()
+	replacing.Schema, inPlace.Schema = diff.Schema, diff.Schema
+	replacing.Renames, inPlace.Renames = diff.Renames, diff.Renames
+	replacing.Paths, inPlace.Paths = diff.Paths, diff.Paths
+	inPlace.Elided = diff.Elided
+	for k, same := range diff.Sames {
+		inPlace.Sames[k] = same
+	}
+	for k, add := range diff.Adds {
+		if isReplacementPath(appendPath(path, diff.Path(k)...), paths) {
+			replacing.Adds[k] = add
+		} else {
+			inPlace.Adds[k] = add
+		}
+	}
+	for k, delete := range diff.Deletes {
+		if isReplacementPath(appendPath(path, diff.Path(k)...), paths) {
+			replacing.Deletes[k] = delete
+		} else {
+			inPlace.Deletes[k] = delete
+		}
+	}
+	for k, update := range diff.Updates {
+		elementPath := appendPath(path, diff.Path(k)...)
+		if update.Array == nil && update.Object == nil || isIgnoredDiffPath(elementPath, paths) {
+			if isReplacementPath(elementPath, paths) {
+				replacing.Updates[k] = update
+			} else {
+				inPlace.Updates[k] = update
+			}
+			continue
+		}
+
+		replacingUpdate, inPlaceUpdate := partitionValueDiff(elementPath, update, paths)
+		if replacingUpdate.AnyChanges() {
+			replacing.Updates[k] = replacingUpdate
+		}
+		if inPlaceUpdate.AnyChanges() {
+			inPlace.Updates[k] = inPlaceUpdate
+		}
+	}
+	return replacing, inPlace
+}
+
+func partitionArrayDiff(path []interface{}, diff *resource.ArrayDiff,
+	paths []diffPathPattern) (*resource.ArrayDiff, *resource.ArrayDiff) {
+
+	newArrayDiff := func() *resource.ArrayDiff {
+		return &resource.ArrayDiff{
+			Adds:    make(map[int]resource.PropertyValue),
+			Deletes: make(map[int]resource.PropertyValue),
+			Sames:   make(map[int]resource.PropertyValue),
+			Updates: make(map[int]resource.ValueDiff),
+		}
+	}
+	replacing, inPlace := newArrayDiff(), newArrayDiff()
+	replacing.Moves, inPlace.Moves = diff.Moves, diff.Moves
+	replacing.Schema, inPlace.Schema = diff.Schema, diff.Schema
+	inPlace.Elided, inPlace.Omitted = diff.Elided, diff.Omitted
+	for i, same := range diff.Sames {
+		inPlace.Sames[i] = same
+	}
+	for i, add := range diff.Adds {
+		if isReplacementPath(appendPath(path, diff.Index(i)), paths) {
+			replacing.Adds[i] = add
+		} else {
+			inPlace.Adds[i] = add
+		}
+	}
+	for i, delete := range diff.Deletes {
+		if isReplacementPath(appendPath(path, diff.Index(i)), paths) {
+			replacing.Deletes[i] = delete
+		} else {
+			inPlace.Deletes[i] = delete
+		}
+	}
+	for i, update := range diff.Updates {
+		elementPath := appendPath(path, diff.Index(i))
+		if update.Array == nil && update.Object == nil || isIgnoredDiffPath(elementPath, paths) {
+			if isReplacementPath(elementPath, paths) {
+				replacing.Updates[i] = update
+			} else {
+				inPlace.Updates[i] = update
+			}
+			continue
+		}
+
+		replacingUpdate, inPlaceUpdate := partitionValueDiff(elementPath, update, paths)
+		if replacingUpdate.AnyChanges() {
+			replacing.Updates[i] = replacingUpdate
+		}
+		if inPlaceUpdate.AnyChanges() {
+			inPlace.Updates[i] = inPlaceUpdate
+		}
+	}
+	return replacing, inPlace
+}
+
+func partitionValueDiff(path []interface{}, diff resource.ValueDiff,
+	paths []diffPathPattern) (resource.ValueDiff, resource.ValueDiff) {
+
+	replacing, inPlace := diff, diff
+	if diff.Array != nil {
+		replacing.Array, inPlace.Array = partitionArrayDiff(path, diff.Array, paths)
+	} else {
+		replacing.Object, inPlace.Object = partitionDiff(path, diff.Object, paths)
+	}
+	return replacing, inPlace
+}
+
+// renderReplacementGroups renders the given diff of the properties of the given event's step in two labeled sections:
+// first the changes that force the resource to be replaced, then the changes that can be made in place. The second
+// result is false if the step has no changes that force a replacement, in which case the diff should be rendered as
+// usual.
+func renderReplacementGroups(payload engine.ResourcePreEventPayload, diff *resource.ObjectDiff,
+	include []resource.PropertyKey, indent int, opts Options) (string, bool) {
+
+	paths := getReplacementPaths(payload.Metadata)
+	if len(paths) == 0 {
+		return "", false
+	}
+	replacing, inPlace := partitionDiff(nil, diff, paths)
+	if !replacing.AnyChanges() {
+		return "", false
+	}
+
+	var buf bytes.Buffer
+	fprintfIgnoreError(&buf, "%s%s%schanges that force replacement:%s\n", deploy.OpReplace.Color(), colors.Bold,
+		engine.GetIndentationString(indent), colors.Reset)
+	printObjectDiff(&buf, *replacing, include, payload.Planning, indent, payload.Debug, opts)
+
+	if inPlace.AnyChanges() {
+		fprintfIgnoreError(&buf, "%s%schanges made in place:%s\n", deploy.OpUpdate.Color(),
+			engine.GetIndentationString(indent), colors.Reset)
+		printObjectDiff(&buf, *inPlace, include, payload.Planning, indent, payload.Debug, opts)
+	}
+	return buf.String(), true
+}
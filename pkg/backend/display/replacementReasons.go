@@ -0,0 +1,113 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// replacementReasons describes why a step replaces its resource.
+type replacementReasons struct {
+	Changed   []string // the paths of the changed properties that force the replacement.
+	Unchanged []string // the paths that the provider reports as forcing the replacement, but whose values are the same.
+	Recreated []string // the top-level properties that the replacement recreates with the same values.
+}
+
+// getReplacementReasons compares the old and new values of the properties of the given replace step. The paths that
+// force the replacement are split into those whose values changed and those whose values did not, e.g. because the
+// provider considers a property that it computes to have changed. The top-level properties whose values are the same
+// are listed apart, so that they can be told apart from the changes that cause the replacement. Inputs are compared
+// under the options' value equality; a replacing property that is not an input is compared to its old output.
+func getReplacementReasons(step engine.StepEventMetadata, opts Options) replacementReasons {
+	equals := getValueEquality(opts)
+	olds, oldOutputs, news := step.Old.Inputs, step.Old.Outputs, step.New.Inputs
+
+	var reasons replacementReasons
+	for _, path := range step.ReplaceReasons() {
+		elements, err := parsedDiffPaths.parse(path)
+		if err != nil {
+			reasons.Changed = append(reasons.Changed, path)
+			continue
+		}
+
+		old, hasOld := lookupPropertyPath(elements, resource.NewObjectProperty(olds))
+		if !hasOld {
+			old, hasOld = lookupPropertyPath(elements, resource.NewObjectProperty(oldOutputs))
+		}
+		new, hasNew := lookupPropertyPath(elements, resource.NewObjectProperty(news))
+		if hasOld == hasNew && (!hasOld || equals(old, new)) {
+			reasons.Unchanged = append(reasons.Unchanged, path)
+		} else {
+			reasons.Changed = append(reasons.Changed, path)
+		}
+	}
+	for _, k := range news.StableKeys() {
+		if old, has := olds[k]; has && !engine.IsInternalPropertyKey(k) && equals(old, news[k]) {
+			reasons.Recreated = append(reasons.Recreated, engine.FormatPropertyPath([]interface{}{string(k)}))
+		}
+	}
+	return reasons
+}
+
+// lookupPropertyPath fetches the property at the given path from the given property value. Like lookupProperty, the
+// second result distinguishes a property that is absent, in which case it is false, from one that is explicitly null.
+func lookupPropertyPath(path []interface{}, v resource.PropertyValue) (resource.PropertyValue, bool) {
+	for _, element := range path {
+		var has bool
+		if v, has = lookupProperty(element, v); !has {
+			return resource.PropertyValue{}, false
+		}
+	}
+	return v, true
+}
+
+// renderReplacementReasons renders the reasons for which the given step replaces its resource, if the options request
+// them, e.g.
+//
+//	changes forcing replacement: zone
+//	reported as forcing replacement, but unchanged: name
+//	recreated unchanged: name, size, tags
+//
+// Nothing is rendered for steps that do not replace their resources.
+func renderReplacementReasons(step engine.StepEventMetadata, indent int, opts Options) string {
+	if !opts.ShowReplacementReasons || step.Old == nil || step.New == nil ||
+		step.Op != deploy.OpReplace && step.Op != deploy.OpCreateReplacement {
+		return ""
+	}
+
+	reasons := getReplacementReasons(step, opts)
+	indentation := engine.GetIndentationString(indent + 1)
+
+	var buf bytes.Buffer
+	if len(reasons.Changed) > 0 {
+		fprintfIgnoreError(&buf, "%s%schanges forcing replacement:%s %s\n", indentation, deploy.OpReplace.Color(),
+			colors.Reset, strings.Join(reasons.Changed, ", "))
+	}
+	if len(reasons.Unchanged) > 0 {
+		fprintfIgnoreError(&buf, "%s%sreported as forcing replacement, but unchanged:%s %s\n", indentation,
+			colors.SpecWarning, colors.Reset, strings.Join(reasons.Unchanged, ", "))
+	}
+	if len(reasons.Recreated) > 0 {
+		fprintfIgnoreError(&buf, "%s%srecreated unchanged: %s%s\n", indentation, deploy.OpSame.Color(),
+			strings.Join(reasons.Recreated, ", "), colors.Reset)
+	}
+	return buf.String()
+}
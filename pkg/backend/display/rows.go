@@ -135,6 +135,12 @@ type resourceRowData struct {
 	// If this row should be hidden by default.  We will hide unless we have any child nodes
 	// we need to show.
 	hideRowIfUnnecessary bool
+
+	// The translation of the detailed diff of the step identified by translationKey, if translated is true. Rows are
+	// rendered each time the display refreshes, so the translation is kept rather than repeated.
+	translated     bool
+	translationKey diffTranslationKey
+	translation    *resource.ObjectDiff
 }
 
 func (data *resourceRowData) DisplayOrderIndex() int {
@@ -423,12 +429,24 @@ func (data *resourceRowData) getInfoColumn() string {
 	return diagMsg
 }
 
+// translateDetailedDiff returns the translation of the given step's detailed diff by way of the display's translator,
+// which may already have translated it in the background.
+func (data *resourceRowData) translateDetailedDiff(step engine.StepEventMetadata) *resource.ObjectDiff {
+	key := getDiffTranslationKey(step)
+	if !data.translated || data.translationKey != key {
+		translator := data.display.opts.translator
+		data.translated, data.translationKey, data.translation = true, key, translator.translate(step)
+		translator.evict(step)
+	}
+	return data.translation
+}
+
 func (data *resourceRowData) getDiffInfo(step engine.StepEventMetadata) string {
 	changesBuf := &bytes.Buffer{}
 	if step.Old != nil && step.New != nil {
 		var diff *resource.ObjectDiff
 		if step.DetailedDiff != nil {
-			diff = translateDetailedDiff(step)
+			diff = data.translateDetailedDiff(step)
 		} else if data.diffOutputs {
 			if step.Old.Outputs != nil && step.New.Outputs != nil {
 				diff = step.Old.Outputs.Diff(step.New.Outputs)
@@ -436,6 +454,9 @@ func (data *resourceRowData) getDiffInfo(step engine.StepEventMetadata) string {
 		} else if step.Old.Inputs != nil && step.New.Inputs != nil {
 			diff = step.Old.Inputs.Diff(step.New.Inputs)
 		}
+		if diff = filterIgnoredDiffs(diff, getIgnoredDiffPaths(data.display.opts)); !diff.AnyChanges() {
+			diff = nil
+		}
 
 		// Show a diff if either `provider` or `protect` changed; they might not show a diff via inputs or outputs, but
 		// it is still useful to show that these changed in output.
@@ -0,0 +1,103 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+// isProviderUpgrade returns true if the old and new states of the given step are managed by different versions of
+// their provider.
+func isProviderUpgrade(step engine.StepEventMetadata) bool {
+	return step.Old != nil && step.New != nil &&
+		step.Old.ProviderVersion != "" && step.New.ProviderVersion != "" &&
+		step.Old.ProviderVersion != step.New.ProviderVersion
+}
+
+// markSchemaDiffs marks the changes in the given translation of the step's detailed diff that likely stem from an
+// upgrade of the step's provider rather than from a change to the resource itself. These are the properties that the
+// user did not set: properties that are deleted although they were only ever reported by the old provider as
+// outputs, and properties that are added although neither the old inputs nor the old outputs mention them. Elements
+// added to arrays are never marked, as they are part of a value that the resource already had. Marked adds and
+// deletes stay where they are, and are recorded in the schema set of the diff that holds them.
+func markSchemaDiffs(step engine.StepEventMetadata, diff *resource.ObjectDiff) {
+	oldInputs, oldOutputs := resource.NewObjectProperty(step.Old.Inputs), resource.NewObjectProperty(step.Old.Outputs)
+
+	parent := resource.ValueDiff{Object: diff}
+	for path, pdiff := range step.DetailedDiff {
+		elements, err := parsedDiffPaths.parse(path)
+		if err != nil {
+			continue
+		}
+
+		var isSchemaDiff bool
+		switch pdiff.Kind {
+		case plugin.DiffAdd, plugin.DiffAddReplace:
+			_, isProperty := elements[len(elements)-1].(string)
+			isSchemaDiff = isProperty &&
+				getPropertyPath(elements, oldInputs).IsNull() && getPropertyPath(elements, oldOutputs).IsNull()
+		case plugin.DiffDelete, plugin.DiffDeleteReplace:
+			isSchemaDiff = !pdiff.InputDiff && getPropertyPath(elements, oldInputs).IsNull()
+		}
+		if isSchemaDiff {
+			markSchemaDiff(&parent, elements)
+		}
+	}
+}
+
+// getPropertyPath fetches the property at the given path from the given property value. If the property does not
+// exist, it returns an empty `PropertyValue`.
+func getPropertyPath(path []interface{}, v resource.PropertyValue) resource.PropertyValue {
+	for _, element := range path {
+		v = getProperty(element, v)
+	}
+	return v
+}
+
+// markSchemaDiff marks the add or delete at the given path in the given diff as a schema diff. If the add or delete
+// was recorded for a property that contains the path, that property is marked instead.
+func markSchemaDiff(parent *resource.ValueDiff, path []interface{}) {
+	switch element := path[0].(type) {
+	case int:
+		if parent.Array == nil {
+			return
+		}
+		_, isadd := parent.Array.Adds[element]
+		_, isdelete := parent.Array.Deletes[element]
+		if isadd || isdelete {
+			if parent.Array.Schema == nil {
+				parent.Array.Schema = make(map[int]bool)
+			}
+			parent.Array.Schema[element] = true
+		} else if update, isupdate := parent.Array.Updates[element]; isupdate && len(path) > 1 {
+			markSchemaDiff(&update, path[1:])
+		}
+	case string:
+		if parent.Object == nil {
+			return
+		}
+		e := resource.PropertyKey(element)
+		if parent.Object.Added(e) || parent.Object.Deleted(e) {
+			if parent.Object.Schema == nil {
+				parent.Object.Schema = make(map[resource.PropertyKey]bool)
+			}
+			parent.Object.Schema[e] = true
+		} else if update, isupdate := parent.Object.Updates[e]; isupdate && len(path) > 1 {
+			markSchemaDiff(&update, path[1:])
+		}
+	}
+}
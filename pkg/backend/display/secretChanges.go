@@ -0,0 +1,148 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/dustin/go-humanize/english"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// secretChange is a changed property of a resource that is, or was, a secret. It deliberately carries no values.
+type secretChange struct {
+	URN  resource.URN  // the resource whose property changed.
+	Path []interface{} // the path to the property.
+	Op   deploy.StepOp // the change: OpCreate for adds, OpDelete for deletes, and OpUpdate for updates.
+}
+
+// getSecretChanges collects the changed properties of all of the given steps that would be shown by the display and
+// that are secrets in the old or new state of their resource, or that are contained by such secrets. Secrets within
+// objects and arrays that are added, deleted, or replaced as a whole are listed individually. Changes are sorted by URN
+// and then in the order in which diffs are walked.
+func getSecretChanges(steps []engine.StepEventMetadata, opts Options) []secretChange {
+	var changes []secretChange
+	for _, step := range steps {
+		if step.Op == deploy.OpSame || !shouldShow(step, opts) {
+			continue
+		}
+		diff := getStepDiff(step, opts)
+		if diff == nil {
+			continue
+		}
+
+		states := []resource.PropertyMap{step.Old.Inputs, step.Old.Outputs, step.New.Inputs, step.New.Outputs}
+		isSecret := func(path []interface{}) bool {
+			for _, state := range states {
+				if isSecretPath(path, state) {
+					return true
+				}
+			}
+			return false
+		}
+
+		seen := make(map[string]bool)
+		record := func(path []interface{}, op deploy.StepOp) {
+			if key := engine.FormatPropertyPath(path); !seen[key] {
+				seen[key] = true
+				changes = append(changes, secretChange{URN: step.URN, Path: path, Op: op})
+			}
+		}
+		walkObjectDiff(diff, func(path []interface{}, op deploy.StepOp, old, new resource.PropertyValue) {
+			switch {
+			case op == deploy.OpSame:
+				return
+			case isSecret(path):
+				record(path, op)
+			default:
+				for _, v := range []resource.PropertyValue{old, new} {
+					for _, secretPath := range findSecrets(path, v) {
+						record(secretPath, op)
+					}
+				}
+			}
+		})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].URN < changes[j].URN })
+	return changes
+}
+
+// findSecrets returns the paths of the secrets within the given value at the given path, including the value itself.
+func findSecrets(path []interface{}, v resource.PropertyValue) [][]interface{} {
+	switch {
+	case v.IsSecret():
+		return [][]interface{}{path}
+	case v.IsArray():
+		var paths [][]interface{}
+		for i, elem := range v.ArrayValue() {
+			paths = append(paths, findSecrets(appendPath(path, i), elem)...)
+		}
+		return paths
+	case v.IsObject():
+		var paths [][]interface{}
+		for _, k := range v.ObjectValue().StableKeys() {
+			paths = append(paths, findSecrets(appendPath(path, string(k)), v.ObjectValue()[k])...)
+		}
+		return paths
+	default:
+		return nil
+	}
+}
+
+// renderSecretChanges renders the changed secret properties of all of the given steps, e.g.
+//
+//	Secret changes:
+//	    ~ urn:pulumi:dev::web::aws:rds/instance:Instance::db password: updated
+//	    1 secret property changed
+//
+// Only the paths of the properties and the kinds of their changes are rendered; values are never included, whatever
+// the other options. Nothing is rendered unless the options request it or if no secret properties changed.
+func renderSecretChanges(steps []engine.StepEventMetadata, opts Options) string {
+	if !opts.ShowSecretChanges {
+		return ""
+	}
+	changes := getSecretChanges(steps, opts)
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	fprintfIgnoreError(&buf, "%sSecret changes:%s\n", colors.SpecHeadline, colors.Reset)
+	for _, change := range changes {
+		fprintfIgnoreError(&buf, "    %s%s %s: %s%s\n", change.Op.Prefix(), change.URN,
+			engine.FormatPropertyPath(change.Path), describeChange(change.Op), colors.Reset)
+	}
+	fprintfIgnoreError(&buf, "    %d %s changed\n\n", len(changes),
+		english.PluralWord(len(changes), "secret property", "secret properties"))
+	return buf.String()
+}
+
+// describeChange describes a property change with the given op: added, removed, or updated.
+func describeChange(op deploy.StepOp) string {
+	switch op {
+	case deploy.OpCreate:
+		return "added"
+	case deploy.OpDelete:
+		return "removed"
+	default:
+		return "updated"
+	}
+}
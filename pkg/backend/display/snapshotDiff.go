@@ -0,0 +1,211 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/apitype"
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+)
+
+// ShowSnapshotDiff writes the differences between the resources in two snapshots of a stack to the given writer.
+// Resources are matched by URN and compared by their output properties. The differences are rendered using the diff
+// view, or as JSON Lines with one record per changed resource if JSONDisplay is set, in snapshot order or, if
+// OrderByDependencies is set, in the order of the dependencies among the resources. ShowSnapshotDiff returns true if
+// any differences were found.
+func ShowSnapshotDiff(w io.Writer, olds, news *deploy.Snapshot, opts Options) (bool, error) {
+	steps := getSnapshotDiffSteps(olds, news, opts)
+	if opts.OrderByDependencies {
+		steps = orderStepsByDependencies(steps)
+	}
+
+	changed := false
+	for _, step := range steps {
+		if step.Op != deploy.OpSame {
+			changed = true
+		}
+	}
+
+	if opts.JSONDisplay {
+		return changed, writeSnapshotDiffJSON(w, steps, opts)
+	}
+
+	opts.DiffWidth = getDiffWidth(w, opts)
+	seen := make(map[resource.URN]engine.StepEventMetadata)
+	budget := newDiffBudget(opts)
+	changes := make(engine.ResourceChanges)
+	for _, step := range steps {
+		changes[step.Op]++
+		fprintIgnoreError(w, renderDiffResourcePreEvent(engine.ResourcePreEventPayload{
+			Metadata: step,
+			Debug:    opts.Debug,
+		}, seen, budget, opts))
+	}
+
+	summary := engine.SummaryEventPayload{IsPreview: true, ResourceChanges: changes}
+	fprintIgnoreError(w, "\n"+renderSummaryEvent(apitype.PreviewUpdate, summary, steps, opts))
+	return changed, nil
+}
+
+// getSnapshotDiffSteps returns a logical step for each resource in either of the given snapshots. Resources in the new
+// snapshot are returned first, in snapshot order, followed by the resources that only exist in the old snapshot.
+// Resources that were renamed are matched with their old states by way of their aliases.
+func getSnapshotDiffSteps(olds, news *deploy.Snapshot, opts Options) []engine.StepEventMetadata {
+	oldResources := matchSnapshotResources(getSnapshotResources(olds), getSnapshotResources(news))
+
+	var steps []engine.StepEventMetadata
+	matched := make(map[resource.URN]bool)
+	for _, res := range getSnapshotResources(news) {
+		old := oldResources[res.URN]
+		if old != nil {
+			matched[old.URN] = true
+		}
+		steps = append(steps, makeSnapshotDiffStep(old, res, opts))
+	}
+	for _, res := range getSnapshotResources(olds) {
+		if !matched[res.URN] {
+			steps = append(steps, makeSnapshotDiffStep(res, nil, opts))
+		}
+	}
+	return steps
+}
+
+// matchSnapshotResources returns the old resource that matches each of the given new resources, if any, by the new
+// resource's URN. A new resource is matched with an old resource that has the same URN or, failing that, with the old
+// resource whose URN is one of its aliases. Aliases are chained through the aliases of the resources in either
+// snapshot, so that a resource that was renamed more than once is matched with its old state even if its aliases
+// only name one of its earlier URNs, e.g. a resource renamed from a to b and then to c whose old state as b records
+// its alias a. Each old resource is matched at most once, and resources whose URNs did not change are matched before
+// any aliases are followed.
+func matchSnapshotResources(olds, news []*resource.State) map[resource.URN]*resource.State {
+	oldResources := make(map[resource.URN]*resource.State)
+	aliases := make(map[resource.URN][]resource.URN)
+	for _, res := range olds {
+		oldResources[res.URN] = res
+		aliases[res.URN] = append(aliases[res.URN], res.Aliases...)
+
+		// An old resource is also reachable from the URNs that it was once known by.
+		for _, alias := range res.Aliases {
+			aliases[alias] = append(aliases[alias], res.URN)
+		}
+	}
+	for _, res := range news {
+		aliases[res.URN] = append(aliases[res.URN], res.Aliases...)
+	}
+
+	matches := make(map[resource.URN]*resource.State)
+	matched := make(map[resource.URN]bool)
+	for _, res := range news {
+		if old, has := oldResources[res.URN]; has {
+			matches[res.URN], matched[old.URN] = old, true
+		}
+	}
+	for _, res := range news {
+		if _, has := matches[res.URN]; has {
+			continue
+		}
+
+		// Search the aliases breadth first, so that the most recent names are preferred.
+		seen := map[resource.URN]bool{res.URN: true}
+		for queue := append([]resource.URN(nil), aliases[res.URN]...); len(queue) > 0; queue = queue[1:] {
+			alias := queue[0]
+			if seen[alias] {
+				continue
+			}
+			seen[alias] = true
+			if old, has := oldResources[alias]; has && !matched[alias] {
+				matches[res.URN], matched[alias] = old, true
+				break
+			}
+			queue = append(queue, aliases[alias]...)
+		}
+	}
+	return matches
+}
+
+// getSnapshotResources returns the live resources in the given snapshot. Resources that are pending deletion are
+// omitted, as they have been superseded by a replacement with the same URN.
+func getSnapshotResources(snap *deploy.Snapshot) []*resource.State {
+	if snap == nil {
+		return nil
+	}
+
+	var resources []*resource.State
+	for _, res := range snap.Resources {
+		if !res.Delete {
+			resources = append(resources, res)
+		}
+	}
+	return resources
+}
+
+// makeSnapshotDiffStep returns a logical step that describes the difference between the old and new states of a
+// resource. A create is returned if there is no old state, a delete if there is no new state, and otherwise either an
+// update or a same, depending on whether the resource was renamed or its properties differ.
+func makeSnapshotDiffStep(old, new *resource.State, opts Options) engine.StepEventMetadata {
+	step := engine.StepEventMetadata{
+		Old:     engine.NewStepEventStateMetadata(old, opts.Debug),
+		New:     engine.NewStepEventStateMetadata(new, opts.Debug),
+		Logical: true,
+	}
+
+	switch {
+	case old == nil:
+		step.Op, step.Res = deploy.OpCreate, step.New
+	case new == nil:
+		step.Op, step.Res = deploy.OpDelete, step.Old
+	default:
+		step.Op, step.Res = deploy.OpSame, step.New
+		if diff, _, _ := getRenderedDiff(step, 0, opts); diff.AnyChanges() || old.URN != new.URN {
+			step.Op = deploy.OpUpdate
+		}
+	}
+
+	step.URN, step.Type, step.Provider = step.Res.URN, step.Res.Type, step.Res.Provider
+	return step
+}
+
+// writeSnapshotDiffJSON writes a record for each changed resource to the given writer as JSON Lines.
+func writeSnapshotDiffJSON(w io.Writer, steps []engine.StepEventMetadata, opts Options) error {
+	enc := json.NewEncoder(w)
+	for _, step := range steps {
+		if step.Op == deploy.OpSame {
+			continue
+		}
+
+		var diff *resource.ObjectDiff
+		switch step.Op {
+		case deploy.OpCreate:
+			diff = resource.PropertyMap{}.Diff(step.New.Outputs, engine.IsInternalPropertyKey)
+		case deploy.OpDelete:
+			diff = step.Old.Outputs.Diff(resource.PropertyMap{}, engine.IsInternalPropertyKey)
+		default:
+			diff, _, _ = getRenderedDiff(step, 0, opts)
+		}
+
+		entry := changelogEntry{URN: step.URN, OldURN: getRenamedURN(step), Op: step.Op,
+			Diff: serializeObjectDiff(diff, false)}
+		if err := enc.Encode(&entry); err != nil {
+			return errors.Wrap(err, "could not write diff")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/diag/colors"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+func makeSnapshot(resources ...*resource.State) *deploy.Snapshot {
+	return deploy.NewSnapshot(deploy.Manifest{}, nil, resources, nil)
+}
+
+func makeSnapshotResource(name string, parent resource.URN, outputs map[string]interface{}) *resource.State {
+	t := tokens.Type("pkg:index:Service")
+	if parent == "" {
+		t = resource.RootStackType
+	}
+	urn := resource.NewURN("stack", "project", "", t, tokens.QName(name))
+	props := resource.NewPropertyMapFromMap(outputs)
+	return resource.NewState(t, urn, true, false, resource.ID(name), props, props, parent, false, false, nil, nil,
+		"", nil, false, nil, nil)
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	stack := makeSnapshotResource("project-stack", "", nil)
+	olds := makeSnapshot(
+		stack,
+		makeSnapshotResource("web", stack.URN, map[string]interface{}{"replicas": 3, "name": "web"}),
+		makeSnapshotResource("db", stack.URN, map[string]interface{}{"size": 10}),
+		makeSnapshotResource("cache", stack.URN, map[string]interface{}{"size": 1}),
+	)
+	news := makeSnapshot(
+		stack,
+		makeSnapshotResource("web", stack.URN, map[string]interface{}{"replicas": 5, "name": "web"}),
+		makeSnapshotResource("db", stack.URN, map[string]interface{}{"size": 10}),
+		makeSnapshotResource("queue", stack.URN, map[string]interface{}{"fifo": true}),
+	)
+
+	var buf bytes.Buffer
+	changed, err := ShowSnapshotDiff(&buf, olds, news, Options{Color: colors.Never, Type: DisplayDiff})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assertGolden(t, "snapshot_diff.txt", buf.String())
+
+	buf.Reset()
+	changed, err = ShowSnapshotDiff(&buf, olds, news, Options{Color: colors.Never, Type: DisplayDiff, JSONDisplay: true})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assertGolden(t, "snapshot_diff.json", buf.String())
+
+	changed, err = ShowSnapshotDiff(&bytes.Buffer{}, olds, olds, Options{Color: colors.Never, Type: DisplayDiff})
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestSnapshotDiffAliases(t *testing.T) {
+	stack := makeSnapshotResource("project-stack", "", nil)
+	web := makeSnapshotResource("web", stack.URN, map[string]interface{}{"replicas": 3})
+	db := makeSnapshotResource("db", stack.URN, nil)
+	datastore := makeSnapshotResource("datastore", stack.URN, map[string]interface{}{"size": 10})
+	datastore.Aliases = []resource.URN{db.URN}
+	queue := makeSnapshotResource("queue", stack.URN, map[string]interface{}{"fifo": true})
+	olds := makeSnapshot(stack, web, datastore, queue)
+
+	// The web server was renamed once. The database was renamed twice, and only names its first URN as an alias, which
+	// its old state records, too. The new queue claims the old queue's URN as an alias, but the old queue still exists.
+	frontend := makeSnapshotResource("frontend", stack.URN, map[string]interface{}{"replicas": 5})
+	frontend.Aliases = []resource.URN{web.URN}
+	database := makeSnapshotResource("database", stack.URN, map[string]interface{}{"size": 10})
+	database.Aliases = []resource.URN{db.URN}
+	queue2 := makeSnapshotResource("queue-v2", stack.URN, map[string]interface{}{"fifo": false})
+	queue2.Aliases = []resource.URN{queue.URN}
+	news := makeSnapshot(stack, frontend, database, queue, queue2)
+
+	steps := getSnapshotDiffSteps(olds, news, Options{})
+	assert.Len(t, steps, 5)
+	assert.Equal(t, deploy.OpUpdate, steps[1].Op)
+	assert.Equal(t, web.URN, steps[1].Old.URN)
+	assert.Equal(t, deploy.OpUpdate, steps[2].Op)
+	assert.Equal(t, datastore.URN, steps[2].Old.URN)
+	assert.Equal(t, deploy.OpSame, steps[3].Op)
+	assert.Equal(t, deploy.OpCreate, steps[4].Op)
+
+	var buf bytes.Buffer
+	changed, err := ShowSnapshotDiff(&buf, olds, news, Options{Color: colors.Never, Type: DisplayDiff})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assertGolden(t, "snapshot_diff_aliases.txt", buf.String())
+}
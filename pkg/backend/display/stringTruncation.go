@@ -0,0 +1,132 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// truncateDisplayString returns the display text for a string that is longer than the given number of characters:
+// the quoted first max characters of the string, followed by an ellipsis and the string's full length. The second
+// result is false if the string is no longer than max characters, in which case it should be displayed as usual.
+func truncateDisplayString(s string, max int) (string, bool) {
+	return truncateDisplayWindow(s, 0, max)
+}
+
+// truncateDisplayStrings returns the display text for the old and new sides of an update to a string, either of which
+// may be longer than the given number of characters. Both sides are truncated to the same window of characters, so
+// that they remain aligned; if the strings share a prefix of at least max characters, the window is moved so that it
+// shows the first character at which they differ. The third result is false if neither string is truncated.
+func truncateDisplayStrings(old, new string, max int) (string, string, bool) {
+	if max <= 0 {
+		return "", "", false
+	}
+
+	common := 0
+	for o, n := old, new; o != "" && n != ""; common++ {
+		or, osize := utf8.DecodeRuneInString(o)
+		nr, nsize := utf8.DecodeRuneInString(n)
+		if or != nr || osize != nsize {
+			break
+		}
+		o, n = o[osize:], n[nsize:]
+	}
+
+	// If the window must move, center it on the first differing character, but keep it within the shorter string so
+	// that it is filled on both sides.
+	start := 0
+	if common >= max {
+		shortest := utf8.RuneCountInString(old)
+		if n := utf8.RuneCountInString(new); n < shortest {
+			shortest = n
+		}
+		if start = common - max/2; start > shortest-max {
+			start = shortest - max
+		}
+	}
+
+	oldText, oldTruncated := truncateDisplayWindow(old, start, max)
+	newText, newTruncated := truncateDisplayWindow(new, start, max)
+	if !oldTruncated && !newTruncated {
+		return "", "", false
+	}
+	if !oldTruncated {
+		oldText = fmt.Sprintf("%q", old)
+	}
+	if !newTruncated {
+		newText = fmt.Sprintf("%q", new)
+	}
+	return oldText, newText, true
+}
+
+// truncateDisplayWindow returns the display text for the max characters of a string that begin at the given character
+// offset, quoted, with ellipses marking any characters omitted before or after them, and followed by the string's
+// full length. The string is only ever cut between characters, never within the encoding of a single character. The
+// second result is false if the window covers the entire string, in which case it should be displayed as usual.
+func truncateDisplayWindow(s string, start, max int) (string, bool) {
+	length := utf8.RuneCountInString(s)
+	if max <= 0 || (start == 0 && length <= max) {
+		return "", false
+	}
+
+	end := start + max
+	if end > length {
+		end = length
+	}
+
+	text := fmt.Sprintf("%q", s[runeOffset(s, start):runeOffset(s, end)])
+	if start > 0 {
+		text = "…" + text
+	}
+	if end < length {
+		text += "…"
+	}
+	return fmt.Sprintf("%s (%s characters)", text, humanize.Comma(int64(length))), true
+}
+
+// runeOffset returns the byte offset of the character at the given index in the given string.
+func runeOffset(s string, index int) int {
+	offset := 0
+	for i := 0; i < index && offset < len(s); i++ {
+		_, size := utf8.DecodeRuneInString(s[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// getStringTruncation returns a formatter registry that truncates the strings that are longer than the maximum
+// string display length in the given options, or nil if that length is unlimited.
+func getStringTruncation(opts Options) *PropertyFormatters {
+	if opts.MaxStringDisplayLength <= 0 {
+		return nil
+	}
+
+	f := NewPropertyFormatters()
+	f.RegisterType("string", func(v resource.PropertyValue) (string, bool) {
+		return truncateDisplayString(v.StringValue(), opts.MaxStringDisplayLength)
+	})
+	f.updates = func(old, new resource.PropertyValue) (string, string, bool) {
+		if !old.IsString() || !new.IsString() {
+			return "", "", false
+		}
+		return truncateDisplayStrings(old.StringValue(), new.StringValue(), opts.MaxStringDisplayLength)
+	}
+	return f
+}
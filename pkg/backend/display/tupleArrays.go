@@ -0,0 +1,39 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+// ValidateTupleArrayPaths returns an error if any of the given property path patterns is malformed.
+func ValidateTupleArrayPaths(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := parseDiffPathPattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getTupleArrayPaths returns the parsed forms of the tuple array path patterns in the given options. Each position in
+// a tuple has a meaning of its own, so the elements of tuples are compared by position rather than aligned with similar
+// elements elsewhere in the array. Malformed patterns are skipped; they are expected to have been rejected by
+// ValidateTupleArrayPaths.
+func getTupleArrayPaths(opts Options) []diffPathPattern {
+	var patterns []diffPathPattern
+	for _, pattern := range opts.TupleArrayPaths {
+		if p, err := parseDiffPathPattern(pattern); err == nil {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,91 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// recordUnchangedElements returns a copy of the given translation of a detailed diff between the given old and new
+// properties in which the elements of arrays that the detailed diff does not mention are recorded as sames. A detailed
+// diff only mentions the elements that changed, so that the others would otherwise be displayed as empty positions.
+// The values of unchanged elements are taken from the new arrays where possible. Otherwise, they are taken from the
+// old outputs, or from the old inputs if the provider reported an input diff for the array.
+func recordUnchangedElements(diff *resource.ObjectDiff, oldOutputs, oldInputs,
+	news resource.PropertyMap) *resource.ObjectDiff {
+
+	if diff == nil {
+		return nil
+	}
+	return recordUnchangedObjectElements(diff, resource.NewObjectProperty(oldOutputs),
+		resource.NewObjectProperty(oldInputs), resource.NewObjectProperty(news))
+}
+
+func recordUnchangedObjectElements(diff *resource.ObjectDiff, old, oldInputs,
+	new resource.PropertyValue) *resource.ObjectDiff {
+
+	result := *diff
+	result.Updates = make(map[resource.PropertyKey]resource.ValueDiff)
+	for k, update := range diff.Updates {
+		elementOld, elementNew := getUpdatedValues(string(k), update, old, new)
+		elementOldInputs, _ := getUpdatedValues(string(k), update, oldInputs, new)
+		result.Updates[k] = recordUnchangedValueElements(update, elementOld, elementOldInputs, elementNew)
+	}
+	return &result
+}
+
+func recordUnchangedArrayElements(diff *resource.ArrayDiff, old, oldInputs,
+	new resource.PropertyValue) *resource.ArrayDiff {
+
+	result := *diff
+	result.Sames = make(map[int]resource.PropertyValue)
+	result.Updates = make(map[int]resource.ValueDiff)
+	for i, same := range diff.Sames {
+		result.Sames[i] = same
+	}
+	for i := 0; i < diff.Positions(); i++ {
+		_, isadd := diff.Adds[i]
+		_, isdelete := diff.Deletes[i]
+		_, issame := diff.Sames[i]
+		if update, isupdate := diff.Updates[i]; isupdate {
+			elementOld, elementNew := getUpdatedValues(i, update, old, new)
+			elementOldInputs, _ := getUpdatedValues(i, update, oldInputs, new)
+			result.Updates[i] = recordUnchangedValueElements(update, elementOld, elementOldInputs, elementNew)
+		} else if !isadd && !isdelete && !issame {
+			if same, has := lookupProperty(i, new); has {
+				result.Sames[i] = same
+			} else if same, has := lookupProperty(i, old); has {
+				result.Sames[i] = same
+			}
+		}
+	}
+	return &result
+}
+
+func recordUnchangedValueElements(diff resource.ValueDiff, old, oldInputs,
+	new resource.PropertyValue) resource.ValueDiff {
+
+	// An input diff compares the new inputs with the old inputs rather than with the old outputs.
+	if diff.InputDiff {
+		old = oldInputs
+	}
+	switch {
+	case diff.Array != nil:
+		diff.Array = recordUnchangedArrayElements(diff.Array, old, oldInputs, new)
+	case diff.Object != nil:
+		diff.Object = recordUnchangedObjectElements(diff.Object, old, oldInputs, new)
+	}
+	return diff
+}
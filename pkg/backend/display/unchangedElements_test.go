@@ -0,0 +1,95 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+func TestRecordUnchangedElements(t *testing.T) {
+	num := resource.NewNumberProperty
+	olds := resource.NewPropertyMapFromMap(map[string]interface{}{"ports": []interface{}{80, 443, 8443}})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{"ports": []interface{}{80, 444, 8443, 8080}})
+
+	cases := []struct {
+		name     string
+		ports    *resource.ArrayDiff
+		expected map[int]resource.PropertyValue
+	}{
+		{
+			name:     "add",
+			ports:    &resource.ArrayDiff{Adds: map[int]resource.PropertyValue{3: num(8080)}},
+			expected: map[int]resource.PropertyValue{0: num(80), 1: num(444), 2: num(8443)},
+		},
+		{
+			name: "update",
+			ports: &resource.ArrayDiff{
+				Updates: map[int]resource.ValueDiff{1: {Old: num(443), New: num(444)}},
+			},
+			expected: map[int]resource.PropertyValue{0: num(80)},
+		},
+		{
+			name: "recorded sames are kept",
+			ports: &resource.ArrayDiff{
+				Sames:   map[int]resource.PropertyValue{0: num(8)},
+				Deletes: map[int]resource.PropertyValue{2: num(8443)},
+			},
+			expected: map[int]resource.PropertyValue{0: num(8), 1: num(444)},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			diff := &resource.ObjectDiff{
+				Updates: map[resource.PropertyKey]resource.ValueDiff{"ports": {Array: c.ports}},
+			}
+			actual := recordUnchangedElements(diff, olds, olds, news)
+			assert.Equal(t, c.expected, actual.Updates["ports"].Array.Sames)
+
+			// The given diff is left as it was.
+			assert.NotEqual(t, c.expected, diff.Updates["ports"].Array.Sames)
+		})
+	}
+
+	assert.Nil(t, recordUnchangedElements(nil, olds, olds, news))
+}
+
+func TestRecordUnchangedElementsOfInputDiffs(t *testing.T) {
+	num := resource.NewNumberProperty
+	oldOutputs := resource.NewPropertyMapFromMap(map[string]interface{}{"ports": []interface{}{80, 443, 8443}})
+	oldInputs := resource.NewPropertyMapFromMap(map[string]interface{}{"ports": []interface{}{80, 444, 9443}})
+	news := resource.NewPropertyMapFromMap(map[string]interface{}{"ports": []interface{}{80}})
+
+	// Elements missing from the new array are taken from the old inputs if the provider reported an input diff.
+	for _, inputDiff := range []bool{false, true} {
+		diff := &resource.ObjectDiff{
+			Updates: map[resource.PropertyKey]resource.ValueDiff{
+				"ports": {
+					Array:     &resource.ArrayDiff{Deletes: map[int]resource.PropertyValue{2: num(8443)}},
+					InputDiff: inputDiff,
+				},
+			},
+		}
+		expected := map[int]resource.PropertyValue{0: num(80), 1: num(443)}
+		if inputDiff {
+			expected[1] = num(444)
+		}
+		actual := recordUnchangedElements(diff, oldOutputs, oldInputs, news)
+		assert.Equal(t, expected, actual.Updates["ports"].Array.Sames, "input diff: %v", inputDiff)
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// ValidateUnorderedArrayPaths returns an error if any of the given property path patterns is malformed.
+func ValidateUnorderedArrayPaths(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := parseDiffPathPattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getUnorderedArrayPaths returns the parsed forms of the unordered array path patterns in the given options.
+// Malformed patterns are skipped; they are expected to have been rejected by ValidateUnorderedArrayPaths.
+func getUnorderedArrayPaths(opts Options) []diffPathPattern {
+	var patterns []diffPathPattern
+	for _, pattern := range opts.UnorderedArrayPaths {
+		if p, err := parseDiffPathPattern(pattern); err == nil {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// diffUnorderedArrays returns a copy of the given diff between the given old and new properties in which each updated
+// array whose property path matches one of the given patterns has been diffed as a multiset: reordering the array's
+// elements is not a change, and its diff records only the elements that were added or removed. Arrays that are only
+// reordered are recorded as sames.
+func diffUnorderedArrays(diff *resource.ObjectDiff, olds, news resource.PropertyMap,
+	patterns []diffPathPattern, equals ValueEquality) *resource.ObjectDiff {
+
+	if diff == nil || len(patterns) == 0 {
+		return diff
+	}
+	return diffUnorderedObjectArrays(nil, diff, resource.NewObjectProperty(olds), resource.NewObjectProperty(news),
+		patterns, equals)
+}
+
+func diffUnorderedObjectArrays(path []interface{}, diff *resource.ObjectDiff, old, new resource.PropertyValue,
+	patterns []diffPathPattern, equals ValueEquality) *resource.ObjectDiff {
+
+	result := &resource.ObjectDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Renames: diff.Renames,
+		Sames:   make(resource.PropertyMap),
+		Updates: make(map[resource.PropertyKey]resource.ValueDiff),
+	}
+	for k, same := range diff.Sames {
+		result.Sames[k] = same
+	}
+	for k, update := range diff.Updates {
+		elementOld, elementNew := getUpdatedValues(string(k), update, old, new)
+		update = diffUnorderedValueArrays(appendPath(path, string(k)), update, elementOld, elementNew, patterns, equals)
+		if update.Array != nil && !update.Array.AnyChanges() {
+			result.Sames[k] = elementOld
+		} else {
+			result.Updates[k] = update
+		}
+	}
+	return result
+}
+
+func diffUnorderedArrayArrays(path []interface{}, diff *resource.ArrayDiff, old, new resource.PropertyValue,
+	patterns []diffPathPattern, equals ValueEquality) *resource.ArrayDiff {
+
+	result := &resource.ArrayDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Sames:   make(map[int]resource.PropertyValue),
+		Updates: make(map[int]resource.ValueDiff),
+		Moves:   diff.Moves,
+	}
+	for i, same := range diff.Sames {
+		result.Sames[i] = same
+	}
+	for i, update := range diff.Updates {
+		elementOld, elementNew := getUpdatedValues(diff.Index(i), update, old, new)
+		update = diffUnorderedValueArrays(appendPath(path, diff.Index(i)), update, elementOld, elementNew, patterns, equals)
+		if update.Array != nil && !update.Array.AnyChanges() {
+			result.Sames[i] = elementOld
+		} else {
+			result.Updates[i] = update
+		}
+	}
+	return result
+}
+
+func diffUnorderedValueArrays(path []interface{}, diff resource.ValueDiff, old, new resource.PropertyValue,
+	patterns []diffPathPattern, equals ValueEquality) resource.ValueDiff {
+
+	if old.IsArray() && new.IsArray() {
+		for _, pattern := range patterns {
+			if pattern.matches(path) {
+				return resource.ValueDiff{Old: old, New: new, Array: diffMultisets(old.ArrayValue(), new.ArrayValue(), equals)}
+			}
+		}
+	}
+
+	switch {
+	case diff.Array != nil:
+		diff.Array = diffUnorderedArrayArrays(path, diff.Array, old, new, patterns, equals)
+	case diff.Object != nil:
+		diff.Object = diffUnorderedObjectArrays(path, diff.Object, old, new, patterns, equals)
+	}
+	return diff
+}
+
+// getUpdatedValues returns the old and new values of the given update to the element with the given key in the given
+// old and new values. The values recorded by the update itself are preferred, as the elements of an array diff are not
+// necessarily keyed by their indices. Updates translated from detailed diffs may not record their values, in which
+// case the values are fetched from the old and new parents.
+func getUpdatedValues(key interface{}, update resource.ValueDiff,
+	old, new resource.PropertyValue) (resource.PropertyValue, resource.PropertyValue) {
+
+	if update.Old.V != nil || update.New.V != nil {
+		return update.Old, update.New
+	}
+	return getProperty(key, old), getProperty(key, new)
+}
+
+// diffMultisets diffs two arrays of property values without regard to the order of their elements. Each new element
+// is matched with an equal old element, if one remains; unmatched old elements are deletes, and unmatched new elements
+// are adds. Sames and adds are keyed by their indices in the new array, and deletes follow them in the order in which
+// they appear in the old array. Elements are compared with the given equality.
+func diffMultisets(old, new []resource.PropertyValue, equals ValueEquality) *resource.ArrayDiff {
+	diff := &resource.ArrayDiff{
+		Adds:    make(map[int]resource.PropertyValue),
+		Deletes: make(map[int]resource.PropertyValue),
+		Sames:   make(map[int]resource.PropertyValue),
+		Updates: make(map[int]resource.ValueDiff),
+	}
+
+	matched := make([]bool, len(old))
+	for j, v := range new {
+		diff.Adds[j] = v
+		for i := range old {
+			if !matched[i] && equals(old[i], v) {
+				matched[i] = true
+				delete(diff.Adds, j)
+				diff.Sames[j] = v
+				break
+			}
+		}
+	}
+
+	next := len(new)
+	for i, v := range old {
+		if !matched[i] {
+			diff.Deletes[next] = v
+			next++
+		}
+	}
+	return diff
+}
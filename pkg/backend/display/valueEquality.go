@@ -0,0 +1,150 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// ValueEquality decides whether two property values are the same, e.g. to compare strings without regard to their
+// casing, arrays as sets, or numbers within a tolerance. It is consulted wherever the display decides whether a value
+// changed: updated properties and array elements whose old and new values are equal are displayed as unchanged, and it
+// also matches the elements of unordered arrays, the unchanged elements of updates that are shown in full, and moved
+// properties. Values may be of any type, including objects, arrays, secrets, and unknowns; an equality that does not
+// apply to a pair of values should fall back to a.DeepEquals(b).
+type ValueEquality func(a, b resource.PropertyValue) bool
+
+// EmptyStringEqualsNull returns a value equality under which empty strings and nulls are the same, e.g. for providers
+// that represent the lack of a value inconsistently, and which otherwise defers to the given equality, or to deep
+// equality if it is nil. Note that this may hide real intent, as some providers do tell an empty string apart from
+// null.
+func EmptyStringEqualsNull(equals ValueEquality) ValueEquality {
+	if equals == nil {
+		equals = resource.PropertyValue.DeepEquals
+	}
+	return func(a, b resource.PropertyValue) bool {
+		return isNullOrEmptyString(a) && isNullOrEmptyString(b) || equals(a, b)
+	}
+}
+
+func isNullOrEmptyString(v resource.PropertyValue) bool {
+	return v.IsNull() || v.IsString() && v.StringValue() == ""
+}
+
+// getValueEquality returns the options' value equality, or deep equality if the options do not specify one.
+func getValueEquality(opts Options) ValueEquality {
+	if equals := getCustomValueEquality(opts); equals != nil {
+		return equals
+	}
+	return resource.PropertyValue.DeepEquals
+}
+
+// getCustomValueEquality returns the options' value equality, extended to consider empty strings and nulls the same
+// if the options' diff normalization does, or nil if the options use deep equality.
+func getCustomValueEquality(opts Options) ValueEquality {
+	equals := opts.Equals
+	if opts.NormalizeDiffs.EmptyStringEqualsNull {
+		equals = EmptyStringEqualsNull(equals)
+	}
+	return equals
+}
+
+// diffEqualValues returns a copy of the given diff between the given old and new properties in which each update whose
+// old and new values are the same under the given equality is recorded as a same. Updates of objects and arrays are
+// compared as a whole first, and then element by element; an object or array none of whose elements changed is also
+// recorded as a same. Adds and deletes are not affected. A nil equality leaves the diff as is.
+func diffEqualValues(diff *resource.ObjectDiff, olds, news resource.PropertyMap,
+	equals ValueEquality) *resource.ObjectDiff {
+
+	if diff == nil || equals == nil {
+		return diff
+	}
+	return diffEqualObjectValues(diff, resource.NewObjectProperty(olds), resource.NewObjectProperty(news), equals)
+}
+
+func diffEqualObjectValues(diff *resource.ObjectDiff, old, new resource.PropertyValue,
+	equals ValueEquality) *resource.ObjectDiff {
+
+	result := &resource.ObjectDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Renames: diff.Renames,
+		Sames:   make(resource.PropertyMap),
+		Updates: make(map[resource.PropertyKey]resource.ValueDiff),
+	}
+	for k, same := range diff.Sames {
+		result.Sames[k] = same
+	}
+	for k, update := range diff.Updates {
+		elementOld, elementNew := getUpdatedValues(string(k), update, old, new)
+		if update, changed := diffEqualValue(update, elementOld, elementNew, equals); changed {
+			result.Updates[k] = update
+		} else {
+			result.Sames[k] = elementNew
+		}
+	}
+	return result
+}
+
+func diffEqualArrayValues(diff *resource.ArrayDiff, old, new resource.PropertyValue,
+	equals ValueEquality) *resource.ArrayDiff {
+
+	result := &resource.ArrayDiff{
+		Adds:    diff.Adds,
+		Deletes: diff.Deletes,
+		Schema:  diff.Schema,
+		Sames:   make(map[int]resource.PropertyValue),
+		Updates: make(map[int]resource.ValueDiff),
+		Moves:   diff.Moves,
+	}
+	for i, same := range diff.Sames {
+		result.Sames[i] = same
+	}
+	for i, update := range diff.Updates {
+		elementOld, elementNew := getUpdatedValues(i, update, old, new)
+		if update, changed := diffEqualValue(update, elementOld, elementNew, equals); changed {
+			result.Updates[i] = update
+		} else {
+			result.Sames[i] = elementNew
+		}
+	}
+	return result
+}
+
+// diffEqualValue returns the given update of the given old value to the given new value with the updates of its
+// elements that are the same under the given equality removed, and false if nothing is left of the update.
+func diffEqualValue(diff resource.ValueDiff, old, new resource.PropertyValue,
+	equals ValueEquality) (resource.ValueDiff, bool) {
+
+	// Updates whose values cannot be found in the old and new properties are always considered changed.
+	if old.V == nil && new.V == nil {
+		return diff, true
+	}
+	if equals(old, new) {
+		return diff, false
+	}
+
+	switch {
+	case diff.Array != nil:
+		diff.Array = diffEqualArrayValues(diff.Array, old, new, equals)
+		return diff, diff.Array.AnyChanges()
+	case diff.Object != nil:
+		diff.Object = diffEqualObjectValues(diff.Object, old, new, equals)
+		return diff, diff.Object.AnyChanges()
+	default:
+		return diff, true
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package display
+
+import (
+	"github.com/pulumi/pulumi/pkg/engine"
+	"github.com/pulumi/pulumi/pkg/resource"
+)
+
+// ValueTransform transforms a leaf property value (i.e. any value other than an array or object) before it is
+// displayed, e.g. to mask a sensitive value that is not marked as a secret. The path is the sequence of property keys
+// (strings) and array indices (ints) that leads to the value. A transform must return the value unchanged if it does
+// not apply to it.
+type ValueTransform func(path []interface{}, v resource.PropertyValue) resource.PropertyValue
+
+// transformValue returns the given value with the given transform applied to each of its leaves.
+func transformValue(path []interface{}, v resource.PropertyValue, transform ValueTransform) resource.PropertyValue {
+	switch {
+	case v.IsArray():
+		arr := make([]resource.PropertyValue, len(v.ArrayValue()))
+		for i, elem := range v.ArrayValue() {
+			arr[i] = transformValue(appendPath(path, i), elem, transform)
+		}
+		return resource.NewArrayProperty(arr)
+	case v.IsObject():
+		return resource.NewObjectProperty(transformMap(path, v.ObjectValue(), transform))
+	default:
+		return transform(path, v)
+	}
+}
+
+func transformMap(path []interface{}, m resource.PropertyMap, transform ValueTransform) resource.PropertyMap {
+	if m == nil {
+		return nil
+	}
+	result := make(resource.PropertyMap)
+	for k, v := range m {
+		result[k] = transformValue(appendPath(path, string(k)), v, transform)
+	}
+	return result
+}
+
+// transformObjectDiff returns a copy of the given diff with the given transform applied to each of its leaves. The
+// transform applies to the old and new sides of each update alike, and does not affect the diff's structure or
+// annotations: an update whose old and new values are transformed into the same value is still displayed as an update.
+func transformObjectDiff(path []interface{}, diff *resource.ObjectDiff, transform ValueTransform) *resource.ObjectDiff {
+	if diff == nil || transform == nil {
+		return diff
+	}
+
+	result := *diff
+	result.Adds = transformMap(path, diff.Adds, transform)
+	result.Deletes = transformMap(path, diff.Deletes, transform)
+	result.Sames = transformMap(path, diff.Sames, transform)
+	result.Updates = make(map[resource.PropertyKey]resource.ValueDiff)
+	for k, update := range diff.Updates {
+		result.Updates[k] = transformValueDiff(appendPath(path, string(k)), update, transform)
+	}
+	return &result
+}
+
+func transformArrayDiff(path []interface{}, diff *resource.ArrayDiff, transform ValueTransform) *resource.ArrayDiff {
+	transformElements := func(elements map[int]resource.PropertyValue) map[int]resource.PropertyValue {
+		result := make(map[int]resource.PropertyValue)
+		for i, v := range elements {
+			result[i] = transformValue(appendPath(path, diff.Index(i)), v, transform)
+		}
+		return result
+	}
+
+	result := *diff
+	result.Adds = transformElements(diff.Adds)
+	result.Deletes = transformElements(diff.Deletes)
+	result.Sames = transformElements(diff.Sames)
+	result.Updates = make(map[int]resource.ValueDiff)
+	for i, update := range diff.Updates {
+		result.Updates[i] = transformValueDiff(appendPath(path, diff.Index(i)), update, transform)
+	}
+	return &result
+}
+
+func transformValueDiff(path []interface{}, diff resource.ValueDiff, transform ValueTransform) resource.ValueDiff {
+	switch {
+	case diff.Array != nil:
+		diff.Array = transformArrayDiff(path, diff.Array, transform)
+	case diff.Object != nil:
+		diff.Object = transformObjectDiff(path, diff.Object, transform)
+	}
+	diff.Old, diff.New = transformValue(path, diff.Old, transform), transformValue(path, diff.New, transform)
+	return diff
+}
+
+// transformStepValues returns a copy of the given step in which the given options' value transform has been applied
+// to the leaves of the properties of each of the step's states.
+func transformStepValues(step engine.StepEventMetadata, opts Options) engine.StepEventMetadata {
+	if opts.ValueTransform == nil {
+		return step
+	}
+
+	transformState := func(state *engine.StepEventStateMetadata) *engine.StepEventStateMetadata {
+		if state == nil {
+			return nil
+		}
+		transformed := *state
+		transformed.Inputs = transformMap(nil, state.Inputs, opts.ValueTransform)
+		transformed.Outputs = transformMap(nil, state.Outputs, opts.ValueTransform)
+		return &transformed
+	}
+
+	step.Old, step.New, step.Res = transformState(step.Old), transformState(step.New), transformState(step.Res)
+	return step
+}
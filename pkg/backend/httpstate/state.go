@@ -368,12 +368,13 @@ func convertStepEventStateMetadata(md *engine.StepEventStateMetadata) *apitype.S
 		return nil
 	}
 
+	// Secrets are sent as the string "[secret]" rather than as secret values.
 	inputs := make(map[string]interface{})
-	for k, v := range md.Inputs {
+	for k, v := range display.MassageSecrets(md.Inputs, false) {
 		inputs[string(k)] = v
 	}
 	outputs := make(map[string]interface{})
-	for k, v := range md.Outputs {
+	for k, v := range display.MassageSecrets(md.Outputs, false) {
 		outputs[string(k)] = v
 	}
 
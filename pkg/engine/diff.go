@@ -22,7 +22,9 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/dustin/go-humanize/english"
 	"github.com/sergi/go-diff/diffmatchpatch"
 
 	"github.com/pulumi/pulumi/pkg/diag/colors"
@@ -148,7 +150,14 @@ func GetResourcePropertiesSummary(step StepEventMetadata, indent int) string {
 	if id != "" {
 		writeWithIndentNoPrefix(&b, indent+1, simplePropOp, "[id=%s]\n", string(id))
 	}
-	if urn != "" {
+	if old != nil && old.URN != "" && old.URN != urn {
+		// The resource was renamed, e.g. by way of an alias, so show its URN change as prominently as its diff.
+		writeWithIndentNoPrefix(&b, indent+1, deploy.OpUpdate, "[urn: ")
+		write(&b, deploy.OpDelete, "%s", old.URN)
+		writeVerbatim(&b, deploy.OpUpdate, " => ")
+		write(&b, deploy.OpCreate, "%s", urn)
+		writeVerbatim(&b, deploy.OpUpdate, "]\n")
+	} else if urn != "" {
 		writeWithIndentNoPrefix(&b, indent+1, simplePropOp, "[urn=%s]\n", urn)
 	}
 
@@ -182,7 +191,7 @@ func GetResourcePropertiesSummary(step StepEventMetadata, indent int) string {
 }
 
 func GetResourcePropertiesDetails(
-	step StepEventMetadata, indent int, planning bool, summary bool, debug bool) string {
+	step StepEventMetadata, indent int, planning bool, summary bool, debug bool, render PropertyRenderer) string {
 	var b bytes.Buffer
 
 	// indent everything an additional level, like other properties.
@@ -191,31 +200,33 @@ func GetResourcePropertiesDetails(
 	old, new := step.Old, step.New
 	if old == nil && new != nil {
 		if len(new.Outputs) > 0 {
-			PrintObject(&b, new.Outputs, planning, indent, step.Op, false, debug)
+			PrintObject(&b, new.Outputs, planning, indent, step.Op, false, debug, render)
 		} else {
-			PrintObject(&b, new.Inputs, planning, indent, step.Op, false, debug)
+			PrintObject(&b, new.Inputs, planning, indent, step.Op, false, debug, render)
 		}
 	} else if new == nil && old != nil {
 		// in summary view, we don't have to print out the entire object that is getting deleted.
 		// note, the caller will have already printed out the type/name/id/urn of the resource,
 		// and that's sufficient for a summarized deletion view.
 		if !summary {
-			PrintObject(&b, old.Inputs, planning, indent, step.Op, false, debug)
+			PrintObject(&b, old.Inputs, planning, indent, step.Op, false, debug, render)
 		}
 	} else if len(new.Outputs) > 0 {
-		printOldNewDiffs(&b, old.Outputs, new.Outputs, nil, planning, indent, step.Op, summary, debug)
+		printOldNewDiffs(&b, old.Outputs, new.Outputs, nil, planning, indent, step.Op, summary, debug, render)
 	} else {
-		printOldNewDiffs(&b, old.Inputs, new.Inputs, step.Diffs, planning, indent, step.Op, summary, debug)
+		printOldNewDiffs(&b, old.Inputs, new.Inputs, step.Diffs, planning, indent, step.Op, summary, debug, render)
 	}
 
 	return b.String()
 }
 
+// maxKey returns the width of the longest of the given keys. Keys are padded by character rather than by byte, so
+// widths are measured in characters, too.
 func maxKey(keys []resource.PropertyKey) int {
 	maxkey := 0
 	for _, k := range keys {
-		if len(k) > maxkey {
-			maxkey = len(k)
+		if n := utf8.RuneCountInString(string(k)); n > maxkey {
+			maxkey = n
 		}
 	}
 	return maxkey
@@ -223,7 +234,14 @@ func maxKey(keys []resource.PropertyKey) int {
 
 func PrintObject(
 	b *bytes.Buffer, props resource.PropertyMap, planning bool,
-	indent int, op deploy.StepOp, prefix bool, debug bool) {
+	indent int, op deploy.StepOp, prefix bool, debug bool, render PropertyRenderer) {
+
+	printObject(b, props, planning, indent, op, prefix, debug, nil, render)
+}
+
+func printObject(
+	b *bytes.Buffer, props resource.PropertyMap, planning bool,
+	indent int, op deploy.StepOp, prefix bool, debug bool, path []interface{}, render PropertyRenderer) {
 
 	// Compute the maximum width of property keys so we can justify everything.
 	keys := props.StableKeys()
@@ -233,7 +251,7 @@ func PrintObject(
 	for _, k := range keys {
 		if v := props[k]; !IsInternalPropertyKey(k) && shouldPrintPropertyValue(v, planning) {
 			printPropertyTitle(b, string(k), maxkey, indent, op, prefix)
-			printPropertyValue(b, v, planning, indent, op, prefix, debug)
+			printPropertyValue(b, v, planning, indent, op, prefix, debug, appendPropertyPath(path, string(k)), render)
 		}
 	}
 }
@@ -307,10 +325,10 @@ func GetResourceOutputsPropertiesString(
 
 			if print {
 				if outputDiff != nil {
-					printObjectPropertyDiff(b, k, maxkey, *outputDiff, planning, indent, false, debug)
+					printObjectPropertyDiff(b, k, maxkey, *outputDiff, planning, indent, false, debug, nil, nil)
 				} else {
 					printPropertyTitle(b, string(k), maxkey, indent, op, false)
-					printPropertyValue(b, out, planning, indent, op, false, debug)
+					printPropertyValue(b, out, planning, indent, op, false, debug, nil, nil)
 				}
 			}
 		}
@@ -357,10 +375,10 @@ func printPropertyTitle(b io.StringWriter, name string, align int, indent int, o
 
 func printPropertyValue(
 	b *bytes.Buffer, v resource.PropertyValue, planning bool,
-	indent int, op deploy.StepOp, prefix bool, debug bool) {
+	indent int, op deploy.StepOp, prefix bool, debug bool, path []interface{}, render PropertyRenderer) {
 
 	if isPrimitive(v) {
-		printPrimitivePropertyValue(b, v, planning, op)
+		printPrimitivePropertyValue(b, v, planning, op, path, render)
 	} else if v.IsArray() {
 		arr := v.ArrayValue()
 		if len(arr) == 0 {
@@ -369,7 +387,7 @@ func printPropertyValue(
 			writeVerbatim(b, op, "[\n")
 			for i, elem := range arr {
 				writeWithIndent(b, indent, op, prefix, "    [%d]: ", i)
-				printPropertyValue(b, elem, planning, indent+1, op, prefix, debug)
+				printPropertyValue(b, elem, planning, indent+1, op, prefix, debug, appendPropertyPath(path, i), render)
 			}
 			writeWithIndentNoPrefix(b, indent, op, "]")
 		}
@@ -420,7 +438,7 @@ func printPropertyValue(
 			writeVerbatim(b, op, "{}")
 		} else {
 			writeVerbatim(b, op, "{\n")
-			PrintObject(b, obj, planning, indent+1, op, prefix, debug)
+			printObject(b, obj, planning, indent+1, op, prefix, debug, path, render)
 			writeWithIndentNoPrefix(b, indent, op, "}")
 		}
 	}
@@ -431,7 +449,7 @@ func printAssetOrArchive(
 	b *bytes.Buffer, v interface{}, name string, planning bool,
 	indent int, op deploy.StepOp, prefix bool, debug bool) {
 	writeWithIndent(b, indent, op, prefix, "    \"%v\": ", name)
-	printPropertyValue(b, assetOrArchiveToPropertyValue(v), planning, indent+1, op, prefix, debug)
+	printPropertyValue(b, assetOrArchiveToPropertyValue(v), planning, indent+1, op, prefix, debug, nil, nil)
 }
 
 func assetOrArchiveToPropertyValue(v interface{}) resource.PropertyValue {
@@ -455,20 +473,28 @@ func shortHash(hash string) string {
 
 func printOldNewDiffs(
 	b *bytes.Buffer, olds resource.PropertyMap, news resource.PropertyMap, include []resource.PropertyKey,
-	planning bool, indent int, op deploy.StepOp, summary bool, debug bool) {
+	planning bool, indent int, op deploy.StepOp, summary bool, debug bool, render PropertyRenderer) {
 
 	// Get the full diff structure between the two, and print it (recursively).
 	if diff := olds.Diff(news, IsInternalPropertyKey); diff != nil {
-		PrintObjectDiff(b, *diff, include, planning, indent, summary, debug)
+		PrintObjectDiff(b, *diff, include, planning, indent, summary, debug, render)
 	} else {
 		// If there's no diff, report the op as Same - there's no diff to render
 		// so it should be rendered as if nothing changed.
-		PrintObject(b, news, planning, indent, deploy.OpSame, true, debug)
+		PrintObject(b, news, planning, indent, deploy.OpSame, true, debug, render)
 	}
 }
 
+// PrintObjectDiff prints the given diff. If the given renderer is non-nil, it is consulted for the display text of each
+// primitive value in the diff.
 func PrintObjectDiff(b *bytes.Buffer, diff resource.ObjectDiff, include []resource.PropertyKey,
-	planning bool, indent int, summary bool, debug bool) {
+	planning bool, indent int, summary bool, debug bool, render PropertyRenderer) {
+
+	printObjectDiff(b, diff, include, planning, indent, summary, debug, nil, render)
+}
+
+func printObjectDiff(b *bytes.Buffer, diff resource.ObjectDiff, include []resource.PropertyKey,
+	planning bool, indent int, summary bool, debug bool, path []interface{}, render PropertyRenderer) {
 
 	contract.Assert(indent > 0)
 
@@ -488,37 +514,84 @@ func PrintObjectDiff(b *bytes.Buffer, diff resource.ObjectDiff, include []resour
 		}
 		keys = filteredKeys
 	}
-	maxkey := maxKey(keys)
+	var shown []resource.PropertyKey
+	for _, k := range keys {
+		if !diff.Elided[k] || diff.Changed(k) {
+			shown = append(shown, resource.PropertyKey(diff.Label(k)))
+		}
+	}
+	maxkey := maxKey(shown)
 
-	// To print an object diff, enumerate the keys in stable order, and print each property independently.
+	// To print an object diff, enumerate the keys in stable order, and print each property independently. Each run of
+	// consecutive elided properties is printed as a single line that counts them.
+	elided := 0
 	for _, k := range keys {
-		printObjectPropertyDiff(b, k, maxkey, diff, planning, indent, summary, debug)
+		if diff.Elided[k] && diff.Same(k) {
+			elided++
+			continue
+		}
+		printElidedSames(b, elided, "property", "properties", indent, summary)
+		printObjectPropertyDiff(b, k, maxkey, diff, planning, indent, summary, debug, path, render)
+		elided = 0
+	}
+	printElidedSames(b, elided, "property", "properties", indent, summary)
+}
+
+// printElidedSames prints the line that stands in for the given number of consecutive unchanged properties or elements
+// that were elided from a diff, e.g. "... 3 unchanged properties". Nothing is printed if no sames were elided or if
+// the diff is summarized, as summaries omit sames altogether.
+func printElidedSames(b io.StringWriter, count int, singular, plural string, indent int, summary bool) {
+	if count > 0 && !summary {
+		writeWithIndent(b, indent, deploy.OpSame, false, "... %s\n",
+			english.Plural(count, "unchanged "+singular, "unchanged "+plural))
+	}
+}
+
+// printOmittedChanges prints the line that stands in for a run of omitted array elements among which the given number
+// of elements changed, e.g. "… (12 more)". Unlike elided sames, omitted changes are counted even in summaries.
+func printOmittedChanges(b io.StringWriter, count int, indent int) {
+	if count > 0 {
+		writeWithIndent(b, indent, deploy.OpSame, false, "… (%d more)\n", count)
 	}
 }
 
 func printObjectPropertyDiff(b *bytes.Buffer, key resource.PropertyKey, maxkey int, diff resource.ObjectDiff,
-	planning bool, indent int, summary bool, debug bool) {
+	planning bool, indent int, summary bool, debug bool, path []interface{}, render PropertyRenderer) {
 
+	path = appendPropertyPath(path, diff.Path(key)...)
 	titleFunc := func(top deploy.StepOp, prefix bool) {
-		printPropertyTitle(b, string(key), maxkey, indent, top, prefix)
+		printPropertyTitle(b, diff.Label(key), maxkey, indent, top, prefix)
+	}
+	if diff.Schema[key] {
+		titleFunc = schemaDiffTitleFunc(b, titleFunc)
 	}
 	if add, isadd := diff.Adds[key]; isadd {
-		printAdd(b, add, titleFunc, planning, indent, debug)
+		printAdd(b, add, titleFunc, planning, indent, debug, path, render)
 	} else if delete, isdelete := diff.Deletes[key]; isdelete {
-		printDelete(b, delete, titleFunc, planning, indent, debug)
+		printDelete(b, delete, titleFunc, planning, indent, debug, path, render)
 	} else if update, isupdate := diff.Updates[key]; isupdate {
 		printPropertyValueDiff(
-			b, titleFunc, update, planning, indent, summary, debug)
+			b, titleFunc, update, planning, indent, summary, debug, path, render)
 	} else if same := diff.Sames[key]; !summary && shouldPrintPropertyValue(same, planning) {
 		titleFunc(deploy.OpSame, false)
-		printPropertyValue(b, diff.Sames[key], planning, indent, deploy.OpSame, false, debug)
+		printPropertyValue(b, diff.Sames[key], planning, indent, deploy.OpSame, false, debug, path, render)
+	}
+}
+
+// schemaDiffTitleFunc wraps the given title function so that it notes that the change likely stems from an upgrade of
+// the resource's provider rather than from a change to the resource itself, e.g. because the new provider no longer
+// reports a property.
+func schemaDiffTitleFunc(b *bytes.Buffer, titleFunc func(deploy.StepOp, bool)) func(deploy.StepOp, bool) {
+	return func(top deploy.StepOp, prefix bool) {
+		titleFunc(top, prefix)
+		write(b, top, "[provider upgrade] ")
 	}
 }
 
 func printPropertyValueDiff(
 	b *bytes.Buffer, titleFunc func(deploy.StepOp, bool),
 	diff resource.ValueDiff, planning bool,
-	indent int, summary bool, debug bool) {
+	indent int, summary bool, debug bool, path []interface{}, render PropertyRenderer) {
 
 	op := deploy.OpUpdate
 	contract.Assert(indent > 0)
@@ -527,34 +600,125 @@ func printPropertyValueDiff(
 		titleFunc(op, true)
 		writeVerbatim(b, op, "[\n")
 
+		// Runs of omitted elements are printed as a single line that counts the changes among them, and runs of
+		// elided sames as a single line that counts them.
 		a := diff.Array
-		for i := 0; i < a.Len(); i++ {
+		elided, omitted := 0, 0
+		for i := 0; i < a.Positions(); i++ {
 			elemTitleFunc := func(eop deploy.StepOp, eprefix bool) {
-				writeWithIndent(b, indent+1, eop, eprefix, "[%d]: ", i)
+				writeWithIndent(b, indent+1, eop, eprefix, "%s: ", a.Label(i))
+			}
+			if a.Schema[i] {
+				elemTitleFunc = schemaDiffTitleFunc(b, elemTitleFunc)
 			}
+			if a.Omitted[i] {
+				printElidedSames(b, elided, "element", "elements", indent+1, summary)
+				elided = 0
+				if _, same := a.Sames[i]; !same {
+					omitted++
+				}
+				continue
+			}
+			printOmittedChanges(b, omitted, indent+1)
+			omitted = 0
+			if _, same := a.Sames[i]; same && a.Elided[i] {
+				elided++
+				continue
+			}
+			printElidedSames(b, elided, "element", "elements", indent+1, summary)
+			elided = 0
+
+			elemPath := appendPropertyPath(path, a.Index(i))
 			if add, isadd := a.Adds[i]; isadd {
-				printAdd(b, add, elemTitleFunc, planning, indent+2, debug)
+				printAdd(b, add, elemTitleFunc, planning, indent+2, debug, elemPath, render)
 			} else if delete, isdelete := a.Deletes[i]; isdelete {
-				printDelete(b, delete, elemTitleFunc, planning, indent+2, debug)
+				printDelete(b, delete, elemTitleFunc, planning, indent+2, debug, elemPath, render)
 			} else if update, isupdate := a.Updates[i]; isupdate {
 				printPropertyValueDiff(
 					b, elemTitleFunc, update, planning,
-					indent+2, summary, debug)
+					indent+2, summary, debug, elemPath, render)
 			} else if !summary {
 				elemTitleFunc(deploy.OpSame, false)
-				printPropertyValue(b, a.Sames[i], planning, indent+2, deploy.OpSame, false, debug)
+				printPropertyValue(b, a.Sames[i], planning, indent+2, deploy.OpSame, false, debug, elemPath, render)
 			}
 		}
+		printOmittedChanges(b, omitted, indent+1)
+		printElidedSames(b, elided, "element", "elements", indent+1, summary)
 		writeWithIndentNoPrefix(b, indent, op, "]\n")
 	} else if diff.Object != nil {
 		titleFunc(op, true)
 		writeVerbatim(b, op, "{\n")
-		PrintObjectDiff(b, *diff.Object, nil, planning, indent+1, summary, debug)
+		printObjectDiff(b, *diff.Object, nil, planning, indent+1, summary, debug, path, render)
 		writeWithIndentNoPrefix(b, indent, op, "}\n")
 	} else {
 		shouldPrintOld := shouldPrintPropertyValue(diff.Old, false)
 		shouldPrintNew := shouldPrintPropertyValue(diff.New, false)
 
+		// When debugging, note which diffs were computed against the resource's old inputs rather than its old outputs,
+		// as this can explain otherwise surprising changes.
+		if debug && diff.InputDiff {
+			printTitle := titleFunc
+			titleFunc = func(top deploy.StepOp, prefix bool) {
+				printTitle(top, prefix)
+				write(b, top, "[input diff] ")
+			}
+		}
+
+		// If the value became a secret (or stopped being one), say so explicitly, masking both sides.
+		if shouldPrintOld && shouldPrintNew {
+			if text, ok := FormatSecretTransition(diff.Old, diff.New); ok {
+				titleFunc(deploy.OpUpdate, true)
+				write(b, deploy.OpUpdate, "%s\n", text)
+				return
+			}
+		}
+
+		// If a structured value is being replaced by an unknown value (or vice versa), show the structure of the known
+		// side in its entirety, annotated to indicate that the value is (or was) unknown.
+		if isUnknown(diff.New) && !isUnknown(diff.Old) && shouldPrintOld && !isPrimitive(diff.Old) {
+			titleFunc(deploy.OpUpdate, true)
+			write(b, deploy.OpUpdate, "[will be computed] ")
+			printPropertyValue(b, diff.Old, planning, indent, deploy.OpDelete, true, debug, path, render)
+			return
+		}
+		if isUnknown(diff.Old) && !isUnknown(diff.New) && shouldPrintNew && !isPrimitive(diff.New) {
+			titleFunc(deploy.OpUpdate, true)
+			write(b, deploy.OpUpdate, "[was computed] ")
+			printPropertyValue(b, diff.New, planning, indent, deploy.OpCreate, true, debug, path, render)
+			return
+		}
+
+		// An update to or from an explicit null is distinct from an add or a delete, so the null side is shown rather
+		// than omitted. Structured values are shown in their entirety, annotated to indicate the null side.
+		if diff.NullDiff && (diff.Old.IsNull() && shouldPrintNew || diff.New.IsNull() && shouldPrintOld) {
+			titleFunc(deploy.OpUpdate, true)
+			switch {
+			case isPrimitive(diff.Old) && isPrimitive(diff.New):
+				printPrimitivePropertyValue(b, diff.Old, planning, deploy.OpDelete, path, render)
+				writeVerbatim(b, deploy.OpUpdate, " => ")
+				printPrimitivePropertyValue(b, diff.New, planning, deploy.OpCreate, path, render)
+				writeVerbatim(b, deploy.OpUpdate, "\n")
+			case diff.Old.IsNull():
+				write(b, deploy.OpUpdate, "[was null] ")
+				printPropertyValue(b, diff.New, planning, indent, deploy.OpCreate, true, debug, path, render)
+			default:
+				write(b, deploy.OpUpdate, "[now null] ")
+				printPropertyValue(b, diff.Old, planning, indent, deploy.OpDelete, true, debug, path, render)
+			}
+			return
+		}
+
+		// If the value changed kinds (e.g. from a string to an object), annotate the change as such so that it is
+		// distinguishable from an edit within a single kind. The old and new values are then printed as a delete and
+		// an add, respectively. Note that transitions to or from null never reach here, as they are handled above.
+		if shouldPrintOld && shouldPrintNew && isKindChange(diff.Old, diff.New) {
+			titleFunc(deploy.OpUpdate, true)
+			write(b, deploy.OpUpdate, "[type changed: %s => %s]\n", kindString(diff.Old), kindString(diff.New))
+			printDelete(b, diff.Old, titleFunc, planning, indent, debug, path, render)
+			printAdd(b, diff.New, titleFunc, planning, indent, debug, path, render)
+			return
+		}
+
 		if shouldPrintOld && shouldPrintNew {
 			if diff.Old.IsArchive() &&
 				diff.New.IsArchive() {
@@ -565,11 +729,32 @@ func printPropertyValueDiff(
 				return
 			}
 
+			// Assets whose contents are known to differ are rendered as a content diff. If the hashes are the same
+			// (e.g. because neither has been computed yet), we fall back to the delete/add rendering below.
+			if diff.Old.IsAsset() && diff.New.IsAsset() &&
+				diff.Old.AssetValue().Hash != diff.New.AssetValue().Hash {
+
+				printAssetDiff(
+					b, titleFunc, diff.Old.AssetValue(), diff.New.AssetValue(),
+					planning, indent, summary, debug)
+				return
+			}
+
+			// Multi-line strings, e.g. scripts, are rendered as a line-by-line diff nested under the property rather
+			// than as two blobs, so that edits within them can be reviewed. Strings that the renderer displays are left
+			// to it.
+			_, oldRendered := renderPropertyValue(render, path, diff.Old)
+			_, newRendered := renderPropertyValue(render, path, diff.New)
+			if isMultilineString(diff.Old) && isMultilineString(diff.New) && !oldRendered && !newRendered {
+				printMultilineStringDiff(b, titleFunc, diff.Old.StringValue(), diff.New.StringValue(), indent)
+				return
+			}
+
 			if isPrimitive(diff.Old) && isPrimitive(diff.New) {
 				titleFunc(deploy.OpUpdate, true /*indent*/)
-				printPrimitivePropertyValue(b, diff.Old, planning, deploy.OpDelete)
+				printPrimitivePropertyValue(b, diff.Old, planning, deploy.OpDelete, path, render)
 				writeVerbatim(b, deploy.OpUpdate, " => ")
-				printPrimitivePropertyValue(b, diff.New, planning, deploy.OpCreate)
+				printPrimitivePropertyValue(b, diff.New, planning, deploy.OpCreate, path, render)
 				writeVerbatim(b, deploy.OpUpdate, "\n")
 				return
 			}
@@ -578,31 +763,92 @@ func printPropertyValueDiff(
 		// If we ended up here, the two values either differ by type, or they have different primitive values.  We will
 		// simply emit a deletion line followed by an addition line.
 		if shouldPrintOld {
-			printDelete(b, diff.Old, titleFunc, planning, indent, debug)
+			printDelete(b, diff.Old, titleFunc, planning, indent, debug, path, render)
 		}
 		if shouldPrintNew {
-			printAdd(b, diff.New, titleFunc, planning, indent, debug)
+			printAdd(b, diff.New, titleFunc, planning, indent, debug, path, render)
+		}
+	}
+}
+
+// isKindChange returns true if the old and new values are known values of different kinds. Unknown values and
+// secrets are never considered to have changed kinds, as their underlying kind may not be apparent.
+func isKindChange(old, new resource.PropertyValue) bool {
+	for _, v := range []resource.PropertyValue{old, new} {
+		if v.IsNull() || v.IsComputed() || v.IsOutput() || v.IsSecret() {
+			return false
 		}
 	}
+	return kindString(old) != kindString(new)
+}
+
+// FormatSecretTransition returns the text that is displayed for an update between the given values if exactly one of
+// them is a secret, e.g. "[now marked secret] [secret]". Both sides are masked, as the side that is not a secret may
+// reveal the side that is; if the underlying values differ, the text is instead e.g. "[no longer secret] [secret] =>
+// [secret]". The values of secrets in step events are masked, so their underlying values are never known to differ.
+// The second result is false if the update does not change whether the value is a secret.
+func FormatSecretTransition(old, new resource.PropertyValue) (string, bool) {
+	if old.IsSecret() == new.IsSecret() {
+		return "", false
+	}
+
+	annotation, plain, secret := "[now marked secret]", old, new
+	if old.IsSecret() {
+		annotation, plain, secret = "[no longer secret]", new, old
+	}
+	if plain.IsNull() || isUnknown(plain) {
+		return "", false
+	}
+	if element := secret.SecretValue().Element; element.DeepEquals(plain) || element.DeepEquals(maskedSecret) {
+		return annotation + " [secret]", true
+	}
+	return annotation + " [secret] => [secret]", true
+}
+
+// kindString returns a human-readable name for the kind of the given value.
+func kindString(v resource.PropertyValue) string {
+	if v.IsArray() {
+		return "array"
+	}
+	return v.TypeString()
+}
+
+// isUnknown returns true if the given value's contents are not yet known.
+func isUnknown(v resource.PropertyValue) bool {
+	return v.IsComputed() || v.IsOutput()
 }
 
 func isPrimitive(value resource.PropertyValue) bool {
 	return value.IsNull() || value.IsString() || value.IsNumber() ||
-		value.IsBool() || value.IsComputed() || value.IsOutput()
+		value.IsBool() || value.IsComputed() || value.IsOutput() || value.IsSecret()
 }
 
-func printPrimitivePropertyValue(b io.StringWriter, v resource.PropertyValue, planning bool, op deploy.StepOp) {
-	contract.Assert(isPrimitive(v))
+// PropertyRenderer renders the primitive property value at the given path for display in a diff. It returns false if
+// it declines to render the value, in which case the value is displayed as usual. Paths are relative to the object
+// whose diff is printed, and hold the indices of array elements.
+type PropertyRenderer func(path []interface{}, v resource.PropertyValue) (string, bool)
 
-	if v.IsNull() {
-		writeVerbatim(b, op, "<null>")
-	} else if v.IsBool() {
-		write(b, op, "%t", v.BoolValue())
-	} else if v.IsNumber() {
-		write(b, op, "%v", v.NumberValue())
-	} else if v.IsString() {
-		write(b, op, "%q", v.StringValue())
-	} else if v.IsComputed() || v.IsOutput() {
+// appendPropertyPath returns a copy of the given property path with the given elements appended.
+func appendPropertyPath(path []interface{}, elements ...interface{}) []interface{} {
+	result := make([]interface{}, len(path), len(path)+len(elements))
+	copy(result, path)
+	return append(result, elements...)
+}
+
+// FormatPropertyValue returns the text that is displayed for the given value, whether the value is shown as part of a
+// diff or as part of a resource's state. Arrays, objects, assets, and archives, which are displayed across several
+// lines, are summarized by their kind (e.g. "<object>"). Secrets are always masked.
+func FormatPropertyValue(v resource.PropertyValue, planning bool) string {
+	switch {
+	case v.IsNull():
+		return "<null>"
+	case v.IsBool():
+		return fmt.Sprintf("%t", v.BoolValue())
+	case v.IsNumber():
+		return fmt.Sprintf("%v", v.NumberValue())
+	case v.IsString():
+		return fmt.Sprintf("%q", v.StringValue())
+	case v.IsComputed() || v.IsOutput():
 		// We render computed and output values differently depending on whether or not we are
 		// planning or deploying: in the former case, we display `computed<type>` or `output<type>`;
 		// in the former we display `undefined`. This is because we currently cannot distinguish
@@ -611,29 +857,56 @@ func printPrimitivePropertyValue(b io.StringWriter, v resource.PropertyValue, pl
 		// have richer information about the dataflow between resources, we should be able to do a
 		// better job here (pulumi/pulumi#234).
 		if planning {
-			writeVerbatim(b, op, v.TypeString())
-		} else {
-			write(b, op, "undefined")
+			return v.TypeString()
 		}
-	} else {
-		contract.Failf("Unexpected property value kind")
+		return "undefined"
+	case v.IsSecret():
+		return "[secret]"
+	case v.IsArray():
+		return "<array>"
+	case v.IsAsset():
+		return "<asset>"
+	case v.IsArchive():
+		return "<archive>"
+	default:
+		contract.Assert(v.IsObject())
+		return "<object>"
+	}
+}
+
+func printPrimitivePropertyValue(b io.StringWriter, v resource.PropertyValue, planning bool, op deploy.StepOp,
+	path []interface{}, render PropertyRenderer) {
+
+	contract.Assert(isPrimitive(v))
+	if text, ok := renderPropertyValue(render, path, v); ok {
+		writeVerbatim(b, op, text)
+		return
 	}
+	writeVerbatim(b, op, FormatPropertyValue(v, planning))
+}
+
+// renderPropertyValue returns the text with which the given renderer, if any, displays the given value.
+func renderPropertyValue(render PropertyRenderer, path []interface{}, v resource.PropertyValue) (string, bool) {
+	if render == nil {
+		return "", false
+	}
+	return render(path, v)
 }
 
 func printDelete(
 	b *bytes.Buffer, v resource.PropertyValue, title func(deploy.StepOp, bool),
-	planning bool, indent int, debug bool) {
+	planning bool, indent int, debug bool, path []interface{}, render PropertyRenderer) {
 	op := deploy.OpDelete
 	title(op, true)
-	printPropertyValue(b, v, planning, indent, op, true, debug)
+	printPropertyValue(b, v, planning, indent, op, true, debug, path, render)
 }
 
 func printAdd(
 	b *bytes.Buffer, v resource.PropertyValue, title func(deploy.StepOp, bool),
-	planning bool, indent int, debug bool) {
+	planning bool, indent int, debug bool, path []interface{}, render PropertyRenderer) {
 	op := deploy.OpCreate
 	title(op, true)
-	printPropertyValue(b, v, planning, indent, op, true, debug)
+	printPropertyValue(b, v, planning, indent, op, true, debug, path, render)
 }
 
 func printArchiveDiff(
@@ -673,10 +946,10 @@ func printArchiveDiff(
 	// Type of archive changed, print this out as an remove and an add.
 	printDelete(
 		b, assetOrArchiveToPropertyValue(oldArchive),
-		titleFunc, planning, indent, debug)
+		titleFunc, planning, indent, debug, nil, nil)
 	printAdd(
 		b, assetOrArchiveToPropertyValue(newArchive),
-		titleFunc, planning, indent, debug)
+		titleFunc, planning, indent, debug, nil, nil)
 }
 
 func printAssetsDiff(
@@ -784,7 +1057,7 @@ func printAssetsDiff(
 			}
 			printDelete(
 				b, assetOrArchiveToPropertyValue(oldAssets[oldName]),
-				titleFunc, planning, newIndent, debug)
+				titleFunc, planning, newIndent, debug, nil, nil)
 			i++
 			continue
 		} else {
@@ -795,7 +1068,7 @@ func printAssetsDiff(
 			}
 			printAdd(
 				b, assetOrArchiveToPropertyValue(newAssets[newName]),
-				titleFunc, planning, newIndent, debug)
+				titleFunc, planning, newIndent, debug, nil, nil)
 			j++
 		}
 	}
@@ -853,16 +1126,47 @@ func printAssetDiff(
 	// Type of asset changed, print this out as an remove and an add.
 	printDelete(
 		b, assetOrArchiveToPropertyValue(oldAsset),
-		titleFunc, planning, indent, debug)
+		titleFunc, planning, indent, debug, nil, nil)
 	printAdd(
 		b, assetOrArchiveToPropertyValue(newAsset),
-		titleFunc, planning, indent, debug)
+		titleFunc, planning, indent, debug, nil, nil)
+}
+
+// isMultilineString returns true if the given value is a string that spans more than one line. A single trailing
+// newline does not make a string multi-line.
+func isMultilineString(v resource.PropertyValue) bool {
+	return v.IsString() && strings.Contains(strings.TrimSuffix(v.StringValue(), "\n"), "\n")
+}
+
+// printMultilineStringDiff prints an update of a multi-line string as a diff of its lines, e.g.
+//
+//	~ script: """
+//	      #!/bin/sh
+//	    - echo old
+//	    + echo new
+//	  """
+//
+// Added and removed lines are marked, and long runs of unchanged lines are elided as in the diffs of text assets.
+func printMultilineStringDiff(b *bytes.Buffer, titleFunc func(deploy.StepOp, bool), old, new string, indent int) {
+	op := deploy.OpUpdate
+	titleFunc(op, true)
+	writeVerbatim(b, op, "\"\"\"\n")
+
+	differ := diffmatchpatch.New()
+	differ.DiffTimeout = 0
+
+	hashed1, hashed2, lineArray := differ.DiffLinesToChars(old, new)
+	diffs1 := differ.DiffMain(hashed1, hashed2, false)
+	diffs2 := differ.DiffCharsToLines(diffs1, lineArray)
+
+	writeString(b, diffToPrettyString(diffs2, indent+1))
+	writeWithIndentNoPrefix(b, indent, op, "\"\"\"\n")
 }
 
 func printAssetArchiveDiff(b *bytes.Buffer, titleFunc func(deploy.StepOp, bool), old interface{}, new interface{},
 	planning bool, indent int, summary bool, debug bool) {
-	printDelete(b, assetOrArchiveToPropertyValue(old), titleFunc, planning, indent, debug)
-	printAdd(b, assetOrArchiveToPropertyValue(new), titleFunc, planning, indent, debug)
+	printDelete(b, assetOrArchiveToPropertyValue(old), titleFunc, planning, indent, debug, nil, nil)
+	printAdd(b, assetOrArchiveToPropertyValue(new), titleFunc, planning, indent, debug, nil, nil)
 }
 
 func getTextChangeString(old string, new string) string {
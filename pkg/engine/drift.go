@@ -0,0 +1,149 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// ReadResourceDiff reads the current state of the custom resource with the given URN from its provider and diffs the
+// resource's outputs in the given snapshot against the outputs that were read, without planning a refresh of the
+// stack. It returns the diff, which is nil if the outputs have not drifted, and the outputs that were read. If the
+// provider can no longer find the resource, the diff deletes all of the resource's outputs and the outputs are nil.
+//
+// Only the resource's provider is loaded, using the given plugin host; the snapshot is not modified.
+func ReadResourceDiff(host plugin.Host, snap *deploy.Snapshot,
+	urn resource.URN) (*resource.ObjectDiff, resource.PropertyMap, error) {
+
+	contract.Require(host != nil, "host")
+
+	// Find the resource and its provider. If the snapshot contains resources that are pending deletion as well as a
+	// live resource with the same URN, the live resource is the one that is read.
+	var res *resource.State
+	if snap != nil {
+		for _, r := range snap.Resources {
+			if r.URN == urn && (res == nil || res.Delete) {
+				res = r
+			}
+		}
+	}
+	if res == nil {
+		return nil, nil, errors.Errorf("resource '%v' not found in the stack's state", urn)
+	}
+	if !res.Custom || providers.IsProviderType(res.Type) {
+		return nil, nil, errors.Errorf("resource '%v' is not a custom resource and cannot be read", urn)
+	}
+
+	ref, err := providers.ParseReference(res.Provider)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "resource '%v' has an invalid provider reference", urn)
+	}
+	var providerState *resource.State
+	for _, r := range snap.Resources {
+		if r.URN == ref.URN() && r.ID == ref.ID() {
+			providerState = r
+		}
+	}
+	if providerState == nil {
+		return nil, nil, errors.Errorf("provider '%v' of resource '%v' not found in the stack's state", ref, urn)
+	}
+
+	registry, err := providers.NewRegistry(host, []*resource.State{providerState}, false, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	prov, ok := registry.GetProvider(ref)
+	contract.Assert(ok)
+
+	outputs, err := readResource(prov, res)
+	if err != nil {
+		return nil, nil, err
+	}
+	if outputs == nil {
+		return res.Outputs.Diff(resource.PropertyMap{}, IsInternalPropertyKey), nil, nil
+	}
+	return res.Outputs.Diff(outputs, IsInternalPropertyKey), outputs, nil
+}
+
+// ReadSnapshot reads the current state of each live custom resource in the given snapshot from its provider, without
+// planning a refresh of the stack, and returns a copy of the snapshot whose resources carry the outputs that were read.
+// Resources that their providers can no longer find are left out of the copy; component resources, providers and
+// resources that are pending deletion are copied as they are.
+//
+// The snapshot's providers are loaded using the given plugin host; the snapshot is not modified.
+func ReadSnapshot(host plugin.Host, snap *deploy.Snapshot) (*deploy.Snapshot, error) {
+	contract.Require(host != nil, "host")
+
+	if snap == nil {
+		return nil, nil
+	}
+
+	var providerStates []*resource.State
+	for _, r := range snap.Resources {
+		if providers.IsProviderType(r.Type) && !r.Delete {
+			providerStates = append(providerStates, r)
+		}
+	}
+	registry, err := providers.NewRegistry(host, providerStates, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*resource.State, 0, len(snap.Resources))
+	for _, res := range snap.Resources {
+		if !res.Custom || res.Delete || providers.IsProviderType(res.Type) {
+			resources = append(resources, res)
+			continue
+		}
+
+		ref, err := providers.ParseReference(res.Provider)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resource '%v' has an invalid provider reference", res.URN)
+		}
+		prov, ok := registry.GetProvider(ref)
+		if !ok {
+			return nil, errors.Errorf("provider '%v' of resource '%v' not found in the stack's state", ref, res.URN)
+		}
+
+		outputs, err := readResource(prov, res)
+		if err != nil {
+			return nil, err
+		}
+		if outputs == nil {
+			continue
+		}
+		live := *res
+		live.Outputs = outputs
+		resources = append(resources, &live)
+	}
+	return deploy.NewSnapshot(snap.Manifest, snap.SecretsManager, resources, snap.PendingOperations), nil
+}
+
+// readResource reads the current outputs of the given resource from its provider. The outputs are nil if the provider
+// can no longer find the resource.
+func readResource(prov plugin.Provider, res *resource.State) (resource.PropertyMap, error) {
+	// A partial failure still reports the resource's current state, so it is not an error here.
+	read, rst, err := prov.Read(res.URN, res.ID, res.Inputs, res.Outputs)
+	if err != nil && rst != resource.StatusPartialFailure {
+		return nil, errors.Wrapf(err, "reading resource '%v'", res.URN)
+	}
+	return read.Outputs, nil
+}
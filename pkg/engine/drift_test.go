@@ -0,0 +1,125 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+	"github.com/pulumi/pulumi/pkg/tokens"
+)
+
+// newDriftTest returns a snapshot of a provider and three of its resources, "drifted", "unchanged" and "gone", along
+// with a plugin host whose provider reports that "drifted" has grown, "unchanged" has not changed and "gone" is gone.
+func newDriftTest(t *testing.T) (*deploy.Snapshot, plugin.Host) {
+	provURN := resource.NewURN("test", "test", "", providers.MakeProviderType("pkgA"), "provA")
+	provRef, err := providers.NewReference(provURN, "0")
+	assert.NoError(t, err)
+	prov := resource.NewState(providers.MakeProviderType("pkgA"), provURN, true, false, "0",
+		resource.PropertyMap{}, resource.PropertyMap{}, "", false, false, nil, nil, "", nil, false, nil, nil)
+
+	newResource := func(name tokens.QName, outputs map[string]interface{}) *resource.State {
+		urn := resource.NewURN("test", "test", "", "pkgA:m:typA", name)
+		return resource.NewState("pkgA:m:typA", urn, true, false, resource.ID(name), resource.PropertyMap{},
+			resource.NewPropertyMapFromMap(outputs), "", false, false, nil, nil, provRef.String(), nil, false, nil, nil)
+	}
+	drifted := newResource("drifted", map[string]interface{}{"size": "small", "tags": map[string]interface{}{"a": "b"}})
+	unchanged := newResource("unchanged", map[string]interface{}{"size": "small"})
+	gone := newResource("gone", map[string]interface{}{"size": "small", "zone": "a"})
+	snap := deploy.NewSnapshot(deploy.Manifest{}, nil, []*resource.State{prov, drifted, unchanged, gone}, nil)
+
+	loaders := []*deploytest.ProviderLoader{
+		deploytest.NewProviderLoader("pkgA", semver.MustParse("1.0.0"), func() (plugin.Provider, error) {
+			return &deploytest.Provider{
+				ReadF: func(urn resource.URN, id resource.ID,
+					inputs, state resource.PropertyMap) (plugin.ReadResult, resource.Status, error) {
+
+					switch id {
+					case "drifted":
+						return plugin.ReadResult{Outputs: resource.NewPropertyMapFromMap(map[string]interface{}{
+							"size": "large",
+							"tags": map[string]interface{}{"a": "b"},
+						})}, resource.StatusOK, nil
+					case "unchanged":
+						return plugin.ReadResult{Outputs: state}, resource.StatusOK, nil
+					default:
+						return plugin.ReadResult{}, resource.StatusOK, nil
+					}
+				},
+			}, nil
+		}),
+	}
+	return snap, deploytest.NewPluginHost(nil, nil, nil, loaders...)
+}
+
+func TestReadResourceDiff(t *testing.T) {
+	t.Parallel()
+
+	snap, host := newDriftTest(t)
+	prov, drifted, unchanged, gone := snap.Resources[0], snap.Resources[1], snap.Resources[2], snap.Resources[3]
+
+	// A resource that drifted is diffed against the outputs that were read.
+	diff, outputs, err := ReadResourceDiff(host, snap, drifted.URN)
+	assert.NoError(t, err)
+	assert.Len(t, diff.Updates, 1)
+	assert.Equal(t, "small", diff.Updates["size"].Old.StringValue())
+	assert.Equal(t, "large", outputs["size"].StringValue())
+
+	// A resource that has not drifted has no diff.
+	diff, outputs, err = ReadResourceDiff(host, snap, unchanged.URN)
+	assert.NoError(t, err)
+	assert.Nil(t, diff)
+	assert.Equal(t, unchanged.Outputs, outputs)
+
+	// A resource that the provider can no longer find is deleted as a whole.
+	diff, outputs, err = ReadResourceDiff(host, snap, gone.URN)
+	assert.NoError(t, err)
+	assert.Equal(t, gone.Outputs, diff.Deletes)
+	assert.Nil(t, outputs)
+
+	// Resources that are not in the snapshot and provider resources cannot be read.
+	_, _, err = ReadResourceDiff(host, snap, resource.NewURN("test", "test", "", "pkgA:m:typA", "missing"))
+	assert.Error(t, err)
+	_, _, err = ReadResourceDiff(host, snap, prov.URN)
+	assert.Error(t, err)
+}
+
+func TestReadSnapshot(t *testing.T) {
+	t.Parallel()
+
+	snap, host := newDriftTest(t)
+	prov, drifted, unchanged := snap.Resources[0], snap.Resources[1], snap.Resources[2]
+
+	// Resources that the provider can no longer find are left out; the others carry the outputs that were read.
+	live, err := ReadSnapshot(host, snap)
+	assert.NoError(t, err)
+	if assert.Len(t, live.Resources, 3) {
+		assert.Equal(t, prov, live.Resources[0])
+		assert.Equal(t, drifted.URN, live.Resources[1].URN)
+		assert.Equal(t, "large", live.Resources[1].Outputs["size"].StringValue())
+		assert.Equal(t, unchanged.Outputs, live.Resources[2].Outputs)
+	}
+
+	// The snapshot itself is not modified.
+	assert.Len(t, snap.Resources, 4)
+	assert.Equal(t, "small", drifted.Outputs["size"].StringValue())
+}
@@ -17,6 +17,8 @@ package engine
 import (
 	"bytes"
 	"reflect"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/pulumi/pulumi/pkg/apitype"
@@ -25,6 +27,7 @@ import (
 	"github.com/pulumi/pulumi/pkg/resource"
 	"github.com/pulumi/pulumi/pkg/resource/config"
 	"github.com/pulumi/pulumi/pkg/resource/deploy"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
 	"github.com/pulumi/pulumi/pkg/tokens"
 	"github.com/pulumi/pulumi/pkg/util/contract"
@@ -130,6 +133,30 @@ type StepEventMetadata struct {
 	Provider     string                         // the provider that performed this step.
 }
 
+// ReplaceReasons returns the sorted paths of the properties whose changes force this step's resource to be replaced.
+// These are the paths of the replacing entries in the step's detailed diff, if it has one, or else its replace keys.
+// Paths are formatted using FormatPropertyPath; a path that cannot be parsed is returned as reported.
+func (s StepEventMetadata) ReplaceReasons() []string {
+	var paths []string
+	if s.DetailedDiff != nil {
+		for path, pdiff := range s.DetailedDiff {
+			if !pdiff.Kind.IsReplace() {
+				continue
+			}
+			if elements, err := ParsePropertyPath(path); err == nil {
+				path = FormatPropertyPath(elements)
+			}
+			paths = append(paths, path)
+		}
+	} else {
+		for _, k := range s.Keys {
+			paths = append(paths, FormatPropertyPath([]interface{}{string(k)}))
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
 // StepEventStateMetadata contains detailed metadata about a resource's state pertaining to a given step.
 type StepEventStateMetadata struct {
 	// State contains the raw, complete state, for this resource.
@@ -150,7 +177,7 @@ type StepEventStateMetadata struct {
 	Protect bool
 	// the resource's input properties (as specified by the program). Note: because this will cross
 	// over rpc boundaries it will be slightly different than the Inputs found in resource_state.
-	// Specifically, secrets will have been filtered out, and large values (like assets) will be
+	// Specifically, secrets will have had their values masked, and large values (like assets) will be
 	// have a simple hash-based representation.  This allows clients to display this information
 	// properly, without worrying about leaking sensitive data, and without having to transmit huge
 	// amounts of data.
@@ -160,6 +187,8 @@ type StepEventStateMetadata struct {
 	Outputs resource.PropertyMap
 	// the resource's provider reference
 	Provider string
+	// the version of the resource's provider plugin, if known.
+	ProviderVersion string
 	// InitErrors is the set of errors encountered in the process of initializing resource (i.e.,
 	// during create or update).
 	InitErrors []string
@@ -188,12 +217,17 @@ func makeEventEmitter(events chan<- Event, update UpdateInfo) (eventEmitter, err
 	logging.AddGlobalFilter(logging.CreateFilter(secrets, "[secret]"))
 
 	return eventEmitter{
-		Chan: events,
+		Chan:            events,
+		warnedDiffKinds: &sync.Map{},
 	}, nil
 }
 
 type eventEmitter struct {
 	Chan chan<- Event
+
+	// warnedDiffKinds records the unknown diff kinds that have been warned about, so that each is only warned about once
+	// per update.
+	warnedDiffKinds *sync.Map
 }
 
 func makeStepEventMetadata(op deploy.StepOp, step deploy.Step, debug bool) StepEventMetadata {
@@ -214,22 +248,46 @@ func makeStepEventMetadata(op deploy.StepOp, step deploy.Step, debug bool) StepE
 		detailedDiff = detailedDiffer.DetailedDiff()
 	}
 
-	return StepEventMetadata{
+	metadata := StepEventMetadata{
 		Op:           op,
 		URN:          step.URN(),
 		Type:         step.Type(),
 		Keys:         keys,
 		Diffs:        diffs,
 		DetailedDiff: detailedDiff,
-		Old:          makeStepEventStateMetadata(step.Old(), debug),
-		New:          makeStepEventStateMetadata(step.New(), debug),
-		Res:          makeStepEventStateMetadata(step.Res(), debug),
+		Old:          NewStepEventStateMetadata(step.Old(), debug),
+		New:          NewStepEventStateMetadata(step.New(), debug),
+		Res:          NewStepEventStateMetadata(step.Res(), debug),
 		Logical:      step.Logical(),
 		Provider:     step.Provider(),
 	}
+
+	// Record the versions of the providers that manage the old and new states so that changes that stem from a
+	// provider upgrade can be told apart from changes to the resource itself.
+	if plan := step.Plan(); plan != nil {
+		for _, state := range []*StepEventStateMetadata{metadata.Old, metadata.New, metadata.Res} {
+			setProviderVersion(plan, state)
+		}
+	}
+	return metadata
 }
 
-func makeStepEventStateMetadata(state *resource.State, debug bool) *StepEventStateMetadata {
+// setProviderVersion records the version of the provider plugin that manages the given state, if it is known.
+func setProviderVersion(plan *deploy.Plan, state *StepEventStateMetadata) {
+	if state == nil || state.Provider == "" {
+		return
+	}
+	ref, err := providers.ParseReference(state.Provider)
+	if err != nil {
+		return
+	}
+	if version := plan.GetProviderVersion(ref); version != nil {
+		state.ProviderVersion = version.String()
+	}
+}
+
+// NewStepEventStateMetadata returns the display metadata for the given resource state, or nil if the state is nil.
+func NewStepEventStateMetadata(state *resource.State, debug bool) *StepEventStateMetadata {
 	if state == nil {
 		return nil
 	}
@@ -250,6 +308,9 @@ func makeStepEventStateMetadata(state *resource.State, debug bool) *StepEventSta
 	}
 }
 
+// maskedSecret stands in for the value of each secret in the states of step events.
+var maskedSecret = resource.NewStringProperty("[secret]")
+
 func filterPropertyMap(propertyMap resource.PropertyMap, debug bool) resource.PropertyMap {
 	mappable := propertyMap.Mappable()
 
@@ -311,7 +372,8 @@ func filterPropertyMap(propertyMap resource.PropertyMap, debug bool) resource.Pr
 				Assets: filterValue(t.Assets).(map[string]interface{}),
 			}
 		case resource.Secret:
-			return "[secret]"
+			// Secrets stay secrets so that clients can tell which properties are secret, but their values are masked.
+			return resource.Secret{Element: maskedSecret}
 		case resource.Computed:
 			return resource.Computed{
 				Element: filterPropertyValue(t.Element),
@@ -394,16 +456,42 @@ func (e *eventEmitter) resourcePreEvent(
 
 	contract.Requiref(e != nil, "e", "!= nil")
 
+	metadata := makeStepEventMetadata(step.Op(), step, debug)
+	e.warnUnknownDiffKinds(metadata)
+
 	e.Chan <- Event{
 		Type: ResourcePreEvent,
 		Payload: ResourcePreEventPayload{
-			Metadata: makeStepEventMetadata(step.Op(), step, debug),
+			Metadata: metadata,
 			Planning: planning,
 			Debug:    debug,
 		},
 	}
 }
 
+// warnUnknownDiffKinds warns about the kinds of the entries in the given step's detailed diff that this version of the
+// engine does not know, e.g. because the step's provider is newer. Such entries are displayed as whatever change the
+// old and new values show. Each kind is only warned about once.
+func (e *eventEmitter) warnUnknownDiffKinds(step StepEventMetadata) {
+	paths := make([]string, 0, len(step.DetailedDiff))
+	for path := range step.DetailedDiff {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		kind := step.DetailedDiff[path].Kind
+		if kind.IsKnown() {
+			continue
+		}
+		if _, warned := e.warnedDiffKinds.LoadOrStore(kind, true); !warned {
+			newEventSink(*e, false).Warningf(diag.Message(step.URN, "the provider reported an unknown kind of diff "+
+				"(%d) for %s, which is displayed as the change that its values show; a newer version of the CLI may "+
+				"display it more accurately"), int(kind), path)
+		}
+	}
+}
+
 func (e *eventEmitter) preludeEvent(isPreview bool, cfg config.Map) {
 	contract.Requiref(e != nil, "e", "!= nil")
 
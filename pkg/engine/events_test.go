@@ -0,0 +1,95 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/pkg/diag"
+	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/plugin"
+)
+
+func TestReplaceReasons(t *testing.T) {
+	step := StepEventMetadata{
+		DetailedDiff: map[string]plugin.PropertyDiff{
+			`tags["Name"]`:                     {Kind: plugin.DiffAddReplace},
+			"subnetId":                         {Kind: plugin.DiffDeleteReplace},
+			`["spec"].ports[0]`:                {Kind: plugin.DiffUpdateReplace},
+			"spec.replicas":                    {Kind: plugin.DiffUpdate},
+			`tags["Environment"]`:              {Kind: plugin.DiffAdd},
+			`labels["app.kubernetes.io/name"]`: {Kind: plugin.DiffDelete},
+		},
+		Keys: []resource.PropertyKey{"ignored"},
+	}
+	assert.Equal(t, []string{"spec.ports[0]", "subnetId", "tags.Name"}, step.ReplaceReasons())
+
+	// Paths that cannot be parsed are returned as reported.
+	step = StepEventMetadata{
+		DetailedDiff: map[string]plugin.PropertyDiff{
+			`tags["unterminated`: {Kind: plugin.DiffUpdateReplace},
+		},
+	}
+	assert.Equal(t, []string{`tags["unterminated`}, step.ReplaceReasons())
+
+	// Without a detailed diff, the replace keys are used.
+	step = StepEventMetadata{
+		Keys: []resource.PropertyKey{"zone", "instance type"},
+	}
+	assert.Equal(t, []string{`["instance type"]`, "zone"}, step.ReplaceReasons())
+
+	// A detailed diff without replacing entries means no replacement, even if there are replace keys.
+	step = StepEventMetadata{
+		DetailedDiff: map[string]plugin.PropertyDiff{
+			"spec.replicas": {Kind: plugin.DiffUpdate},
+		},
+		Keys: []resource.PropertyKey{"zone"},
+	}
+	assert.Empty(t, step.ReplaceReasons())
+}
+
+func TestWarnUnknownDiffKinds(t *testing.T) {
+	events := make(chan Event, 10)
+	emitter := eventEmitter{Chan: events, warnedDiffKinds: &sync.Map{}}
+
+	urn := resource.NewURN("stack", "project", "", "pkg:index:Service", "web")
+	step := StepEventMetadata{
+		URN: urn,
+		DetailedDiff: map[string]plugin.PropertyDiff{
+			"size": {Kind: plugin.DiffKind(1000)},
+			"tags": {Kind: plugin.DiffKind(1000)},
+			"zone": {Kind: plugin.DiffUpdate},
+		},
+	}
+
+	// Each unknown kind is warned about once per update, naming the first path at which it was reported.
+	emitter.warnUnknownDiffKinds(step)
+	emitter.warnUnknownDiffKinds(step)
+	close(events)
+
+	var warnings []DiagEventPayload
+	for e := range events {
+		assert.Equal(t, DiagEvent, e.Type)
+		warnings = append(warnings, e.Payload.(DiagEventPayload))
+	}
+	if assert.Len(t, warnings, 1) {
+		assert.Equal(t, diag.Warning, warnings[0].Severity)
+		assert.Equal(t, urn, warnings[0].URN)
+		assert.Contains(t, warnings[0].Message, "unknown kind of diff (1000) for size")
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/util/contract"
+)
+
+// ParsePropertyPath parses the given property path, as reported in detailed diffs, into its elements: property keys
+// (strings) and array indices (ints).
+func ParsePropertyPath(path string) ([]interface{}, error) {
+	// Complete paths obey the following EBNF-ish grammar:
+	//
+	//   propertyName := [a-zA-Z_$] { [a-zA-Z0-9_$] }
+	//   escapeSequence := '\' ( '\' | '"' | 'n' | 'r' | 't' )
+	//   quotedPropertyName := '"' { ( escapeSequence | [^"] ) } '"'
+	//   arrayIndex := { [0-9] }
+	//
+	//   propertyIndex := '[' ( quotedPropertyName | arrayIndex ) ']'
+	//   rootProperty := ( propertyName | propertyIndex )
+	//   propertyAccessor := ( ( '.' propertyName ) |  propertyIndex )
+	//   path := rootProperty { propertyAccessor }
+	//
+	// We interpret this a little loosely in order to keep things simple. Specifically, we will accept something close
+	// to the following:
+	// pathElement := { '.' } ( '[' ( [0-9]+ | '"' { escapeSequence | [^"] } '"' ']' | [a-zA-Z_$][a-zA-Z0-9_$] )
+	// path := { pathElement }
+	//
+	// A backslash that does not begin one of the escape sequences above is taken literally.
+
+	var elements []interface{}
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+		case '[':
+			// If the character following the '[' is a '"', parse a string key.
+			var pathElement interface{}
+			if len(path) > 1 && path[1] == '"' {
+				var propertyKey []byte
+				var i int
+				for i = 2; ; {
+					if i == len(path) {
+						return nil, errors.New("missing closing quote in property name")
+					} else if path[i] == '"' {
+						i++
+						break
+					} else if c, ok := unescapePropertyPathChar(path, i); ok {
+						propertyKey = append(propertyKey, c)
+						i += 2
+					} else {
+						propertyKey = append(propertyKey, path[i])
+						i++
+					}
+				}
+				if i == len(path) || path[i] != ']' {
+					return nil, errors.New("missing closing bracket in property access")
+				}
+				pathElement, path = string(propertyKey), path[i:]
+			} else {
+				// Look for a closing ']'
+				rbracket := strings.IndexRune(path, ']')
+				if rbracket == -1 {
+					return nil, errors.New("missing closing bracket in array index")
+				}
+
+				index, err := strconv.ParseInt(path[1:rbracket], 10, 0)
+				if err != nil {
+					return nil, errors.Wrap(err, "invalid array index")
+				}
+				pathElement, path = int(index), path[rbracket:]
+			}
+			elements, path = append(elements, pathElement), path[1:]
+		default:
+			for i := 0; ; i++ {
+				if i == len(path) || path[i] == '.' || path[i] == '[' {
+					elements, path = append(elements, path[:i]), path[i:]
+					break
+				}
+			}
+		}
+	}
+	return elements, nil
+}
+
+// unescapePropertyPathChar returns the character denoted by the escape sequence at the given offset in a quoted
+// property name, if any.
+func unescapePropertyPathChar(path string, i int) (byte, bool) {
+	if path[i] != '\\' || i+1 == len(path) {
+		return 0, false
+	}
+	switch path[i+1] {
+	case '\\', '"':
+		return path[i+1], true
+	case 'n':
+		return '\n', true
+	case 'r':
+		return '\r', true
+	case 't':
+		return '\t', true
+	default:
+		return 0, false
+	}
+}
+
+// FormatPropertyPath formats the given path elements as a property path that ParsePropertyPath will parse back into the
+// same elements. Property names that are not simple identifiers are quoted, with backslashes, quotes, and newline,
+// carriage return, and tab characters escaped.
+func FormatPropertyPath(path []interface{}) string {
+	var b strings.Builder
+	for _, element := range path {
+		switch element := element.(type) {
+		case int:
+			b.WriteString("[")
+			b.WriteString(strconv.Itoa(element))
+			b.WriteString("]")
+		case string:
+			if isPropertyPathIdentifier(element) {
+				if b.Len() > 0 {
+					b.WriteString(".")
+				}
+				b.WriteString(element)
+				continue
+			}
+
+			b.WriteString(`["`)
+			for i := 0; i < len(element); i++ {
+				switch c := element[i]; c {
+				case '\\', '"':
+					b.WriteByte('\\')
+					b.WriteByte(c)
+				case '\n':
+					b.WriteString(`\n`)
+				case '\r':
+					b.WriteString(`\r`)
+				case '\t':
+					b.WriteString(`\t`)
+				default:
+					b.WriteByte(c)
+				}
+			}
+			b.WriteString(`"]`)
+		default:
+			contract.Failf("unexpected path element of type %T", element)
+		}
+	}
+	return b.String()
+}
+
+// isPropertyPathIdentifier returns true if the given property name may appear unquoted in a property path.
+func isPropertyPathIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		isLetter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$'
+		if !isLetter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePropertyPath(t *testing.T) {
+	cases := []struct {
+		path     string
+		elements []interface{}
+	}{
+		{
+			"root",
+			[]interface{}{"root"},
+		},
+		{
+			"root.nested",
+			[]interface{}{"root", "nested"},
+		},
+		{
+			`root["nested"]`,
+			[]interface{}{"root", "nested"},
+		},
+		{
+			"root.double.nest",
+			[]interface{}{"root", "double", "nest"},
+		},
+		{
+			`root["double"].nest`,
+			[]interface{}{"root", "double", "nest"},
+		},
+		{
+			`root["double"]["nest"]`,
+			[]interface{}{"root", "double", "nest"},
+		},
+		{
+			"root.array[0]",
+			[]interface{}{"root", "array", 0},
+		},
+		{
+			"root.array[100]",
+			[]interface{}{"root", "array", 100},
+		},
+		{
+			"root.array[0].nested",
+			[]interface{}{"root", "array", 0, "nested"},
+		},
+		{
+			"root.array[0][1].nested",
+			[]interface{}{"root", "array", 0, 1, "nested"},
+		},
+		{
+			"root.nested.array[0].double[1]",
+			[]interface{}{"root", "nested", "array", 0, "double", 1},
+		},
+		{
+			`root["key with \"escaped\" quotes"]`,
+			[]interface{}{"root", `key with "escaped" quotes`},
+		},
+		{
+			`root["key with a ."]`,
+			[]interface{}{"root", "key with a ."},
+		},
+		{
+			`["root key with \"escaped\" quotes"].nested`,
+			[]interface{}{`root key with "escaped" quotes`, "nested"},
+		},
+		{
+			`["root key with a ."][100]`,
+			[]interface{}{"root key with a .", 100},
+		},
+		{
+			`root["line\nbreak\ttab\rreturn"]`,
+			[]interface{}{"root", "line\nbreak\ttab\rreturn"},
+		},
+		{
+			`root["back\\slash"]["[bracketed]"]`,
+			[]interface{}{"root", `back\slash`, "[bracketed]"},
+		},
+		{
+			`root["unknown \escape"][""]`,
+			[]interface{}{"root", `unknown \escape`, ""},
+		},
+	}
+
+	for _, c := range cases {
+		elements, err := ParsePropertyPath(c.path)
+		assert.NoError(t, err)
+		assert.Equal(t, c.elements, elements)
+	}
+}
+
+func TestParsePropertyPathErrors(t *testing.T) {
+	for _, path := range []string{`[`, `root["unterminated`, `root["escaped quote\"]`, `root["key"`, `root[1`} {
+		_, err := ParsePropertyPath(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestFormatPropertyPath(t *testing.T) {
+	assert.Equal(t, `root.nested[0]["key with a ."]`,
+		FormatPropertyPath([]interface{}{"root", "nested", 0, "key with a ."}))
+	assert.Equal(t, `["0"]["a\"b\\c\nd"]`, FormatPropertyPath([]interface{}{"0", "a\"b\\c\nd"}))
+
+	// Generate random paths whose keys draw from a mix of identifier characters, punctuation that is significant to
+	// the path grammar, control characters, and non-ASCII characters, and check that each round-trips.
+	alphabet := []rune("aZ_$09 .[]\"\\\n\t\r\x00\x1béü日本😀")
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 1000; i++ {
+		var path []interface{}
+		for j := 0; j < 1+r.Intn(4); j++ {
+			if j > 0 && r.Intn(4) == 0 {
+				path = append(path, r.Intn(100))
+				continue
+			}
+
+			key := make([]rune, r.Intn(8))
+			for k := range key {
+				key[k] = alphabet[r.Intn(len(alphabet))]
+			}
+			path = append(path, string(key))
+		}
+
+		formatted := FormatPropertyPath(path)
+		parsed, err := ParsePropertyPath(formatted)
+		if assert.NoError(t, err, formatted) {
+			assert.Equal(t, path, parsed, formatted)
+		}
+	}
+}
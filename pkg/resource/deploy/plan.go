@@ -17,6 +17,7 @@ package deploy
 import (
 	"context"
 	"math"
+	"sync"
 
 	"github.com/blang/semver"
 	"github.com/pkg/errors"
@@ -112,6 +113,8 @@ type Plan struct {
 	preview   bool                             // true if this plan is to be previewed rather than applied.
 	depGraph  *graph.DependencyGraph           // the dependency graph of the old snapshot
 	providers *providers.Registry              // the provider registry for this plan.
+
+	providerVersions sync.Map // a cache of provider plugin versions, keyed by provider reference.
 }
 
 // addDefaultProviders adds any necessary default provider definitions and references to the given snapshot. Version
@@ -273,6 +276,25 @@ func (p *Plan) GetProvider(ref providers.Reference) (plugin.Provider, bool) {
 	return p.providers.GetProvider(ref)
 }
 
+// GetProviderVersion returns the version of the provider plugin loaded for the given provider reference, or nil if no
+// provider is loaded for the reference or if its version is unknown.
+func (p *Plan) GetProviderVersion(ref providers.Reference) *semver.Version {
+	if version, ok := p.providerVersions.Load(ref.String()); ok {
+		return version.(*semver.Version)
+	}
+
+	provider, ok := p.GetProvider(ref)
+	if !ok {
+		return nil
+	}
+	info, err := provider.GetPluginInfo()
+	if err != nil {
+		return nil
+	}
+	p.providerVersions.Store(ref.String(), info.Version)
+	return info.Version
+}
+
 // generateURN generates a resource's URN from its parent, type, and name under the scope of the plan's stack and
 // project.
 func (p *Plan) generateURN(parent resource.URN, ty tokens.Type, name tokens.QName) resource.URN {
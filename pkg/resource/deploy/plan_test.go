@@ -4,9 +4,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/blang/semver"
+
 	"github.com/pulumi/pulumi/pkg/secrets/b64"
 
 	"github.com/pulumi/pulumi/pkg/resource"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/deploytest"
+	"github.com/pulumi/pulumi/pkg/resource/deploy/providers"
 	"github.com/pulumi/pulumi/pkg/resource/plugin"
 	"github.com/pulumi/pulumi/pkg/tokens"
 	"github.com/pulumi/pulumi/pkg/version"
@@ -57,3 +61,34 @@ func TestPendingOperationsPlan(t *testing.T) {
 	assert.Equal(t, resourceB.URN, invalidErr.Operations[0].Resource.URN)
 	assert.Equal(t, resource.OperationTypeCreating, invalidErr.Operations[0].Type)
 }
+
+func TestGetProviderVersion(t *testing.T) {
+	version := semver.MustParse("1.2.3")
+	loader := deploytest.NewProviderLoader("pkgA", version, func() (plugin.Provider, error) {
+		return &deploytest.Provider{Package: "pkgA", Version: version}, nil
+	})
+	host := deploytest.NewPluginHost(nil, nil, nil, loader)
+
+	urn := resource.NewURN("test", "test", "", providers.MakeProviderType("pkgA"), "provA")
+	registry, err := providers.NewRegistry(host, []*resource.State{{
+		Type:   urn.Type(),
+		URN:    urn,
+		ID:     "id1",
+		Inputs: resource.PropertyMap{},
+	}}, false, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	plan := &Plan{providers: registry}
+
+	ref, err := providers.NewReference(urn, "id1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, plan.GetProviderVersion(ref)) {
+		assert.Equal(t, version, *plan.GetProviderVersion(ref))
+	}
+
+	// References to providers that are not loaded have no version.
+	unknown, err := providers.NewReference(urn, "id2")
+	assert.NoError(t, err)
+	assert.Nil(t, plan.GetProviderVersion(unknown))
+}
@@ -120,6 +120,17 @@ func (d DiffKind) String() string {
 	}
 }
 
+// IsKnown returns true if the diff kind is one of the kinds defined above. Providers that are newer than the engine may
+// report kinds that it does not know.
+func (d DiffKind) IsKnown() bool {
+	switch d {
+	case DiffAdd, DiffAddReplace, DiffDelete, DiffDeleteReplace, DiffUpdate, DiffUpdateReplace:
+		return true
+	default:
+		return false
+	}
+}
+
 func (d DiffKind) IsReplace() bool {
 	switch d {
 	case DiffAddReplace, DiffDeleteReplace, DiffUpdateReplace:
@@ -15,15 +15,23 @@
 package resource
 
 import (
+	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 )
 
 // ObjectDiff holds the results of diffing two object property maps.
 type ObjectDiff struct {
-	Adds    PropertyMap               // properties in this map are created in the new.
-	Deletes PropertyMap               // properties in this map are deleted from the new.
-	Sames   PropertyMap               // properties in this map are the same.
-	Updates map[PropertyKey]ValueDiff // properties in this map are changed in the new.
+	Adds    PropertyMap                   // properties in this map are created in the new.
+	Deletes PropertyMap                   // properties in this map are deleted from the new.
+	Sames   PropertyMap                   // properties in this map are the same.
+	Updates map[PropertyKey]ValueDiff     // properties in this map are changed in the new.
+	Leading []PropertyKey                 // properties in this list are ordered first, in this order, by Keys.
+	Elided  map[PropertyKey]bool          // unchanged properties in this map are elided from displays of the diff.
+	Schema  map[PropertyKey]bool          // adds and deletes in this map likely stem from a provider upgrade.
+	Renames map[PropertyKey]PropertyKey   // properties in this map were renamed from the mapped names.
+	Paths   map[PropertyKey][]interface{} // properties in this map stand for the nested properties at the mapped paths.
 }
 
 // Added returns true if the property 'k' has been added in the new property set.
@@ -55,7 +63,26 @@ func (diff *ObjectDiff) Same(k PropertyKey) bool {
 	return !diff.Changed(k)
 }
 
-// Keys returns a stable snapshot of all keys known to this object, across adds, deletes, sames, and updates.
+// Label returns the label with which to display the property with the given key: its old and new names, e.g.
+// max_size→maxSize, if it was renamed, or else its key.
+func (diff *ObjectDiff) Label(k PropertyKey) string {
+	if from, ok := diff.Renames[k]; ok {
+		return fmt.Sprintf("%s→%s", from, k)
+	}
+	return string(k)
+}
+
+// Path returns the path, relative to the object, of the property with the given key: the path of the nested property
+// that it stands for, e.g. ["spec", "template"] for a property that merges a chain of objects, or else its key.
+func (diff *ObjectDiff) Path(k PropertyKey) []interface{} {
+	if path, ok := diff.Paths[k]; ok {
+		return path
+	}
+	return []interface{}{string(k)}
+}
+
+// Keys returns a stable snapshot of all keys known to this object, across adds, deletes, sames, and updates. The keys
+// are sorted, except that any leading keys come first.
 func (diff *ObjectDiff) Keys() []PropertyKey {
 	var ks []PropertyKey
 	for k := range diff.Adds {
@@ -71,49 +98,448 @@ func (diff *ObjectDiff) Keys() []PropertyKey {
 		ks = append(ks, k)
 	}
 	sort.Slice(ks, func(i, j int) bool { return ks[i] < ks[j] })
+	if len(diff.Leading) == 0 {
+		return ks
+	}
+
+	// Move any leading properties to the front, keeping the others in order.
+	rank := make(map[PropertyKey]int)
+	for i, k := range diff.Leading {
+		if _, has := rank[k]; !has {
+			rank[k] = i
+		}
+	}
+	sort.SliceStable(ks, func(i, j int) bool {
+		ri, iok := rank[ks[i]]
+		rj, jok := rank[ks[j]]
+		return iok && (!jok || ri < rj)
+	})
 	return ks
 }
 
+// Len returns the number of properties tracked by this diff, across adds, deletes, sames, and updates.
+func (diff *ObjectDiff) Len() int {
+	return len(diff.Adds) + len(diff.Deletes) + len(diff.Sames) + len(diff.Updates)
+}
+
+// AnyChanges returns true if this diff contains any adds, deletes, or updates. A diff that records only sames (or
+// updates that themselves contain only sames) is considered to have no changes.
+func (diff *ObjectDiff) AnyChanges() bool {
+	if diff == nil {
+		return false
+	}
+	if len(diff.Adds) > 0 || len(diff.Deletes) > 0 {
+		return true
+	}
+	for _, update := range diff.Updates {
+		if update.AnyChanges() {
+			return true
+		}
+	}
+	return false
+}
+
 // ValueDiff holds the results of diffing two property values.
 type ValueDiff struct {
-	Old    PropertyValue // the old value.
-	New    PropertyValue // the new value.
-	Array  *ArrayDiff    // the array's detailed diffs (only for arrays).
-	Object *ObjectDiff   // the object's detailed diffs (only for objects).
+	Old       PropertyValue // the old value.
+	New       PropertyValue // the new value.
+	Array     *ArrayDiff    // the array's detailed diffs (only for arrays).
+	Object    *ObjectDiff   // the object's detailed diffs (only for objects).
+	InputDiff bool          // true if the old value is an old input rather than an old output (only for detailed diffs).
+	NullDiff  bool          // true if a null side is an explicit null rather than absent (only for detailed diffs).
+}
+
+// AnyChanges returns true if this value diff represents an actual change. Leaf diffs (those without array or object
+// detail) are always considered changes; otherwise, the nested diff is consulted.
+func (diff *ValueDiff) AnyChanges() bool {
+	switch {
+	case diff.Array != nil:
+		return diff.Array.AnyChanges()
+	case diff.Object != nil:
+		return diff.Object.AnyChanges()
+	default:
+		return true
+	}
 }
 
-// ArrayDiff holds the results of diffing two arrays of property values.
+// ArrayDiff holds the results of diffing two arrays of property values. Elements are keyed by their positions in the
+// diff. Diff compares arrays element by element, so positions are indices. A diff may instead align the old and new
+// arrays, e.g. for display, so that an inserted element is a single add: a same or update then pairs an old element
+// with a new one, while an add or delete occupies a position of its own, and the indices of elements whose positions
+// differ from their indices are recorded in Moves. Index maps positions to indices, and At addresses elements by index.
 type ArrayDiff struct {
 	Adds    map[int]PropertyValue // elements added in the new.
 	Deletes map[int]PropertyValue // elements deleted in the new.
 	Sames   map[int]PropertyValue // elements the same in both.
 	Updates map[int]ValueDiff     // elements that have changed in the new.
+	Moves   map[int]ArrayMove     // the indices of elements whose indices differ from their positions, if known.
+	Labels  map[int]string        // the labels with which to display elements in place of their positions, if any.
+	Elided  map[int]bool          // unchanged elements in this map are elided from displays of the diff.
+	Omitted map[int]bool          // elements in this map, changed or not, are omitted from displays of the diff.
+	Schema  map[int]bool          // adds and deletes in this map likely stem from a provider upgrade.
+}
+
+// ArrayMove records the indices in the old and new arrays of an element of an array diff. From is -1 for an added
+// element, and To is -1 for a deleted element.
+type ArrayMove struct {
+	From int // the element's index in the old array.
+	To   int // the element's index in the new array.
+}
+
+// Label returns the label with which to display the element at the given position: its label, if it has one, or else
+// its index in brackets, e.g. [2].
+func (diff *ArrayDiff) Label(i int) string {
+	if label, ok := diff.Labels[i]; ok {
+		return label
+	}
+	return fmt.Sprintf("[%d]", diff.Index(i))
 }
 
-// Len computes the length of this array, taking into account adds, deletes, sames, and updates.
+// Len computes the length of this array, taking into account adds, deletes, sames, and updates: the greater of the
+// lengths of the old and new arrays.
 func (diff *ArrayDiff) Len() int {
 	len := 0
+	for i, n := 0, diff.Positions(); i < n; i++ {
+		if move, ok := diff.move(i); ok {
+			if move.From+1 > len {
+				len = move.From + 1
+			}
+			if move.To+1 > len {
+				len = move.To + 1
+			}
+		}
+	}
+	return len
+}
+
+// Positions returns the number of positions in this diff, across adds, deletes, sames, and updates. Iterating over the
+// positions in order visits the diff's elements in the order in which they are displayed. The positions of a diff that
+// does not align its arrays are the elements' indices.
+func (diff *ArrayDiff) Positions() int {
+	n := 0
 	for i := range diff.Adds {
-		if i+1 > len {
-			len = i + 1
+		if i+1 > n {
+			n = i + 1
 		}
 	}
 	for i := range diff.Deletes {
-		if i+1 > len {
-			len = i + 1
+		if i+1 > n {
+			n = i + 1
 		}
 	}
 	for i := range diff.Sames {
-		if i+1 > len {
-			len = i + 1
+		if i+1 > n {
+			n = i + 1
 		}
 	}
 	for i := range diff.Updates {
-		if i+1 > len {
-			len = i + 1
+		if i+1 > n {
+			n = i + 1
 		}
 	}
-	return len
+	return n
+}
+
+// Index returns the index of the element at the given position: its index in the new array, or its index in the old
+// array if it was deleted.
+func (diff *ArrayDiff) Index(pos int) int {
+	if move, ok := diff.Moves[pos]; ok {
+		if move.To == -1 {
+			return move.From
+		}
+		return move.To
+	}
+	return pos
+}
+
+// move returns the indices in the old and new arrays of the element at the given position, and true, or false if there
+// is no element at the position.
+func (diff *ArrayDiff) move(pos int) (ArrayMove, bool) {
+	if move, ok := diff.Moves[pos]; ok {
+		return move, true
+	}
+	if _, isadd := diff.Adds[pos]; isadd {
+		return ArrayMove{From: -1, To: pos}, true
+	} else if _, isdelete := diff.Deletes[pos]; isdelete {
+		return ArrayMove{From: pos, To: -1}, true
+	} else if _, isupdate := diff.Updates[pos]; isupdate {
+		return ArrayMove{From: pos, To: pos}, true
+	} else if _, issame := diff.Sames[pos]; issame {
+		return ArrayMove{From: pos, To: pos}, true
+	}
+	return ArrayMove{}, false
+}
+
+// position returns the position of the element with the given index, and true, or false if there is none. Indices
+// address the new array, except that an index that is past its end addresses an element deleted from the old array.
+func (diff *ArrayDiff) position(i int) (int, bool) {
+	if len(diff.Moves) == 0 {
+		return i, true
+	}
+
+	deleted := -1
+	for pos, n := 0, diff.Positions(); pos < n; pos++ {
+		move, ok := diff.move(pos)
+		switch {
+		case !ok:
+			continue
+		case move.To == i:
+			return pos, true
+		case move.To == -1 && move.From == i && deleted == -1:
+			deleted = pos
+		}
+	}
+	return deleted, deleted != -1
+}
+
+// AnyChanges returns true if this diff contains any adds, deletes, or updates. A diff that records only sames (or
+// updates that themselves contain only sames) is considered to have no changes.
+func (diff *ArrayDiff) AnyChanges() bool {
+	if diff == nil {
+		return false
+	}
+	if len(diff.Adds) > 0 || len(diff.Deletes) > 0 {
+		return true
+	}
+	for _, update := range diff.Updates {
+		if update.AnyChanges() {
+			return true
+		}
+	}
+	return false
+}
+
+// At returns the change at the given property path within this diff, and true, or false if the value at the path is the
+// same on both sides or absent from both. Each element of the path is either a property key, as a string, or an array
+// index, as an int, as parsed from a path like "spec.containers[0].image". The path may end at a value that changed in
+// its own right or at one that contains changes, e.g. an object some of whose properties were updated, in which case
+// the change records the nested diff. The change at a path that leads into an added or deleted value has a null old or
+// new value, respectively. An empty path has no change.
+func (diff *ObjectDiff) At(path []interface{}) (*ValueDiff, bool) {
+	if diff == nil || len(path) == 0 {
+		return nil, false
+	}
+	k, ok := path[0].(string)
+	if !ok {
+		return nil, false
+	}
+
+	key := PropertyKey(k)
+	if add, isadd := diff.Adds[key]; isadd {
+		return changeAt(path[1:], NewNullProperty(), add)
+	} else if delete, isdelete := diff.Deletes[key]; isdelete {
+		return changeAt(path[1:], delete, NewNullProperty())
+	} else if update, isupdate := diff.Updates[key]; isupdate {
+		return update.at(path[1:])
+	}
+	return nil, false
+}
+
+// At returns the change at the given property path within this diff, and true, or false if the value at the path is the
+// same on both sides or absent from both. The path's first element is an index into the array, which addresses the new
+// array unless it is past the new array's end, in which case it addresses an element deleted from the old array. See
+// ObjectDiff.At.
+func (diff *ArrayDiff) At(path []interface{}) (*ValueDiff, bool) {
+	if diff == nil || len(path) == 0 {
+		return nil, false
+	}
+	index, ok := path[0].(int)
+	if !ok {
+		return nil, false
+	}
+	i, ok := diff.position(index)
+	if !ok {
+		return nil, false
+	}
+
+	if add, isadd := diff.Adds[i]; isadd {
+		return changeAt(path[1:], NewNullProperty(), add)
+	} else if delete, isdelete := diff.Deletes[i]; isdelete {
+		return changeAt(path[1:], delete, NewNullProperty())
+	} else if update, isupdate := diff.Updates[i]; isupdate {
+		return update.at(path[1:])
+	}
+	return nil, false
+}
+
+func (diff *ValueDiff) at(path []interface{}) (*ValueDiff, bool) {
+	switch {
+	case len(path) == 0:
+		if !diff.AnyChanges() {
+			return nil, false
+		}
+		result := *diff
+		return &result, true
+	case diff.Array != nil:
+		return diff.Array.At(path)
+	case diff.Object != nil:
+		return diff.Object.At(path)
+	default:
+		// Updates without nested diffs record only their old and new values, so the values at the path are compared.
+		return changeAt(path, diff.Old, diff.New)
+	}
+}
+
+// changeAt returns the change between the values at the given path within the given old and new values, and true, or
+// false if the values at the path are the same or absent from both. An empty path refers to the values themselves,
+// which are always considered changed.
+func changeAt(path []interface{}, old, new PropertyValue) (*ValueDiff, bool) {
+	if len(path) == 0 {
+		return &ValueDiff{Old: old, New: new}, true
+	}
+
+	oldAt, hasOld := valueAt(path, old)
+	newAt, hasNew := valueAt(path, new)
+	if hasOld == hasNew && (!hasOld || oldAt.DeepEquals(newAt)) {
+		return nil, false
+	}
+	return &ValueDiff{Old: oldAt, New: newAt}, true
+}
+
+// valueAt returns the value at the given path within the given value, and true, or false if there is no such value.
+func valueAt(path []interface{}, v PropertyValue) (PropertyValue, bool) {
+	for _, element := range path {
+		switch key := element.(type) {
+		case string:
+			if !v.IsObject() {
+				return PropertyValue{}, false
+			}
+			child, has := v.ObjectValue()[PropertyKey(key)]
+			if !has {
+				return PropertyValue{}, false
+			}
+			v = child
+		case int:
+			if !v.IsArray() || key < 0 || key >= len(v.ArrayValue()) {
+				return PropertyValue{}, false
+			}
+			v = v.ArrayValue()[key]
+		default:
+			return PropertyValue{}, false
+		}
+	}
+	return v, true
+}
+
+// DiffNormalization is a policy under which some changes recorded by a diff are considered cosmetic rather than
+// meaningful, e.g. because a provider reports a number that it was given as a string.
+type DiffNormalization struct {
+	NumericEquivalence bool // true if numbers and numeric strings that denote the same number are equivalent.
+	TrailingWhitespace bool // true if strings that differ only in whitespace at the ends of their lines are equivalent.
+	NullEqualsAbsent   bool // true if a property whose value is null is equivalent to an absent property.
+
+	// EmptyStringEqualsNull is true if an empty string is equivalent to null, for providers that represent the lack of
+	// a value inconsistently. This may hide real intent, as some providers do tell an empty string apart from null.
+	EmptyStringEqualsNull bool
+}
+
+// Any returns true if the policy normalizes anything.
+func (norm DiffNormalization) Any() bool {
+	return norm.NumericEquivalence || norm.TrailingWhitespace || norm.NullEqualsAbsent || norm.EmptyStringEqualsNull
+}
+
+// isNull returns true if the given value is null or equivalent to null under the policy.
+func (norm DiffNormalization) isNull(v PropertyValue) bool {
+	return v.IsNull() || norm.EmptyStringEqualsNull && v.IsString() && v.StringValue() == ""
+}
+
+// AnyChangesUnder returns true if this diff contains any changes that are meaningful under the given normalization
+// policy. Updates that do not record both their old and new values are always meaningful.
+func (diff *ObjectDiff) AnyChangesUnder(norm DiffNormalization) bool {
+	if diff == nil {
+		return false
+	}
+	for _, m := range []PropertyMap{diff.Adds, diff.Deletes} {
+		for _, v := range m {
+			if !norm.NullEqualsAbsent || !norm.isNull(v) {
+				return true
+			}
+		}
+	}
+	for _, update := range diff.Updates {
+		if update.AnyChangesUnder(norm) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnyChangesUnder returns true if this diff contains any changes that are meaningful under the given normalization
+// policy. Added and deleted elements are always meaningful, as they shift the positions of the elements that follow.
+func (diff *ArrayDiff) AnyChangesUnder(norm DiffNormalization) bool {
+	if diff == nil {
+		return false
+	}
+	if len(diff.Adds) > 0 || len(diff.Deletes) > 0 {
+		return true
+	}
+	for _, update := range diff.Updates {
+		if update.AnyChangesUnder(norm) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnyChangesUnder returns true if this value diff represents a change that is meaningful under the given
+// normalization policy.
+func (diff *ValueDiff) AnyChangesUnder(norm DiffNormalization) bool {
+	switch {
+	case diff.Array != nil:
+		return diff.Array.AnyChangesUnder(norm)
+	case diff.Object != nil:
+		return diff.Object.AnyChangesUnder(norm)
+	case diff.Old.V == nil && diff.New.V == nil:
+		return true
+	default:
+		return !normalizedEquals(diff.Old, diff.New, norm)
+	}
+}
+
+// normalizedEquals returns true if the given values are equivalent under the given normalization policy.
+func normalizedEquals(old, new PropertyValue, norm DiffNormalization) bool {
+	switch {
+	case old.IsSecret() && new.IsSecret():
+		return normalizedEquals(old.SecretValue().Element, new.SecretValue().Element, norm)
+	case norm.EmptyStringEqualsNull && norm.isNull(old) && norm.isNull(new):
+		return true
+	case (old.IsArray() && new.IsArray()) || (old.IsObject() && new.IsObject()):
+		diff := old.Diff(new)
+		return diff == nil || !diff.AnyChangesUnder(norm)
+	case old.IsString() && new.IsString() && norm.TrailingWhitespace &&
+		trimTrailingWhitespace(old.StringValue()) == trimTrailingWhitespace(new.StringValue()):
+		return true
+	case norm.NumericEquivalence:
+		o, ook := numericValue(old)
+		n, nok := numericValue(new)
+		if ook && nok && o == n {
+			return true
+		}
+	}
+	return old.DeepEquals(new)
+}
+
+// trimTrailingWhitespace removes the whitespace at the end of each line of the given string, and any trailing blank
+// lines.
+func trimTrailingWhitespace(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// numericValue returns the number denoted by the given value, which may be a number or a string that parses as one.
+func numericValue(v PropertyValue) (float64, bool) {
+	switch {
+	case v.IsNumber():
+		return v.NumberValue(), true
+	case v.IsString():
+		f, err := strconv.ParseFloat(strings.TrimSpace(v.StringValue()), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
 }
 
 // IgnoreKeyFunc is the callback type for Diff's ignore option.
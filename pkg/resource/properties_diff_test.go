@@ -351,3 +351,286 @@ func TestMismatchedPropertyValueDiff(t *testing.T) {
 	assert.True(t, s2.DeepEquals(s1))
 	assert.True(t, s1.DeepEquals(s2))
 }
+
+func TestDiffAnyChanges(t *testing.T) {
+	t.Parallel()
+
+	sames := &ObjectDiff{
+		Adds:    PropertyMap{},
+		Deletes: PropertyMap{},
+		Sames: PropertyMap{
+			"a": NewStringProperty("foo"),
+			"b": NewNumberProperty(42),
+		},
+		Updates: map[PropertyKey]ValueDiff{
+			"c": {
+				Array: &ArrayDiff{
+					Adds:    map[int]PropertyValue{},
+					Deletes: map[int]PropertyValue{},
+					Sames:   map[int]PropertyValue{0: NewStringProperty("bar")},
+					Updates: map[int]ValueDiff{},
+				},
+			},
+			"d": {
+				Object: &ObjectDiff{
+					Adds:    PropertyMap{},
+					Deletes: PropertyMap{},
+					Sames:   PropertyMap{"e": NewBoolProperty(true)},
+					Updates: map[PropertyKey]ValueDiff{},
+				},
+			},
+		},
+	}
+	assert.False(t, sames.AnyChanges())
+	assert.False(t, sames.Updates["c"].Array.AnyChanges())
+	assert.Equal(t, 4, sames.Len())
+	assert.Equal(t, 1, sames.Updates["c"].Array.Len())
+
+	var nilDiff *ObjectDiff
+	assert.False(t, nilDiff.AnyChanges())
+
+	d := NewObjectProperty(PropertyMap{"a": NewStringProperty("foo")}).Diff(
+		NewObjectProperty(PropertyMap{"a": NewStringProperty("bar")}))
+	assert.NotNil(t, d)
+	assert.True(t, d.AnyChanges())
+	assert.True(t, d.Object.AnyChanges())
+
+	a := NewPropertyValue([]string{"a", "b"}).Diff(NewPropertyValue([]string{"a", "b", "c"}))
+	assert.NotNil(t, a)
+	assert.True(t, a.Array.AnyChanges())
+	assert.Equal(t, 3, a.Array.Len())
+}
+
+func TestDiffAnyChangesUnder(t *testing.T) {
+	t.Parallel()
+
+	changesUnder := func(olds, news map[string]interface{}, norm DiffNormalization) bool {
+		return NewPropertyMapFromMap(olds).Diff(NewPropertyMapFromMap(news)).AnyChangesUnder(norm)
+	}
+	numbers := DiffNormalization{NumericEquivalence: true}
+	whitespace := DiffNormalization{TrailingWhitespace: true}
+	nulls := DiffNormalization{NullEqualsAbsent: true}
+
+	// Numeric equivalence.
+	olds := map[string]interface{}{"port": 80, "ratio": "0.50", "nested": map[string]interface{}{"n": []interface{}{1}}}
+	news := map[string]interface{}{"port": "80", "ratio": 0.5, "nested": map[string]interface{}{"n": []interface{}{"1"}}}
+	assert.True(t, changesUnder(olds, news, DiffNormalization{}))
+	assert.False(t, changesUnder(olds, news, numbers))
+	assert.True(t, changesUnder(olds, map[string]interface{}{"port": "81", "ratio": 0.5}, numbers))
+	assert.True(t, changesUnder(map[string]interface{}{"port": 80}, map[string]interface{}{"port": "eighty"}, numbers))
+
+	// Trailing whitespace.
+	olds = map[string]interface{}{"script": "echo hi\necho bye\n"}
+	news = map[string]interface{}{"script": "echo hi  \r\necho bye\t\n\n"}
+	assert.True(t, changesUnder(olds, news, numbers))
+	assert.False(t, changesUnder(olds, news, whitespace))
+	assert.True(t, changesUnder(olds, map[string]interface{}{"script": "  echo hi\necho bye\n"}, whitespace))
+
+	// Null vs. absent. Diffs of property maps already treat nulls as absent, but translated detailed diffs may not.
+	d := &ObjectDiff{Adds: PropertyMap{"c": NewNullProperty()}, Deletes: PropertyMap{"b": NewNullProperty()}}
+	assert.True(t, d.AnyChangesUnder(whitespace))
+	assert.False(t, d.AnyChangesUnder(nulls))
+	d.Deletes["a"] = NewStringProperty("x")
+	assert.True(t, d.AnyChangesUnder(nulls))
+
+	// Empty strings vs. nulls, including empty strings that are added or deleted where nulls are equivalent to absent
+	// properties. Changes between empty and non-empty strings are still meaningful.
+	emptyStrings := DiffNormalization{EmptyStringEqualsNull: true}
+	d = &ObjectDiff{Updates: map[PropertyKey]ValueDiff{
+		"a": {Old: NewStringProperty(""), New: NewNullProperty()},
+		"b": {Old: NewNullProperty(), New: NewStringProperty("")},
+	}}
+	assert.True(t, d.AnyChangesUnder(nulls))
+	assert.False(t, d.AnyChangesUnder(emptyStrings))
+	d.Updates["c"] = ValueDiff{Old: NewStringProperty(""), New: NewStringProperty("x")}
+	assert.True(t, d.AnyChangesUnder(emptyStrings))
+	d = &ObjectDiff{Adds: PropertyMap{"c": NewStringProperty("")}}
+	assert.True(t, d.AnyChangesUnder(emptyStrings))
+	assert.False(t, d.AnyChangesUnder(DiffNormalization{NullEqualsAbsent: true, EmptyStringEqualsNull: true}))
+
+	// Secrets are compared by their elements, but a change to whether a value is secret is always meaningful.
+	secret := func(v interface{}) PropertyValue { return MakeSecret(NewPropertyValue(v)) }
+	d = PropertyMap{"s": secret(80)}.Diff(PropertyMap{"s": secret("80")})
+	assert.False(t, d.AnyChangesUnder(numbers))
+	d = PropertyMap{"s": NewNumberProperty(80)}.Diff(PropertyMap{"s": secret("80")})
+	assert.True(t, d.AnyChangesUnder(numbers))
+
+	// Updates that do not record their values and added array elements are always meaningful.
+	d = &ObjectDiff{Updates: map[PropertyKey]ValueDiff{"a": {}}}
+	assert.True(t, d.AnyChangesUnder(DiffNormalization{true, true, true, true}))
+	a := NewPropertyValue([]interface{}{nil}).Diff(NewPropertyValue([]interface{}{nil, nil}))
+	assert.True(t, a.AnyChangesUnder(nulls))
+
+	var nilDiff *ObjectDiff
+	assert.False(t, nilDiff.AnyChangesUnder(numbers))
+}
+
+func TestObjectDiffLeadingKeys(t *testing.T) {
+	t.Parallel()
+
+	diff := PropertyMap{"a": NewNumberProperty(1), "b": NewNumberProperty(2), "c": NewNumberProperty(3)}.Diff(
+		PropertyMap{"b": NewNumberProperty(2), "c": NewNumberProperty(4), "d": NewNumberProperty(5)})
+	assert.Equal(t, []PropertyKey{"a", "b", "c", "d"}, diff.Keys())
+
+	// Leading keys come first in the given order, and keys that are not in the diff are ignored.
+	diff.Leading = []PropertyKey{"d", "missing", "b"}
+	assert.Equal(t, []PropertyKey{"d", "b", "a", "c"}, diff.Keys())
+}
+
+func TestObjectDiffAt(t *testing.T) {
+	t.Parallel()
+
+	olds := NewPropertyMapFromMap(map[string]interface{}{
+		"name": "web",
+		"spec": map[string]interface{}{
+			"image": "nginx:1.16",
+			"ports": []interface{}{80, 443},
+		},
+		"labels": map[string]interface{}{"tier": "frontend"},
+	})
+	news := NewPropertyMapFromMap(map[string]interface{}{
+		"name": "web",
+		"spec": map[string]interface{}{
+			"image": "nginx:1.17",
+			"ports": []interface{}{80, 443, 8080},
+		},
+		"volumes": []interface{}{map[string]interface{}{"name": "data"}},
+	})
+	diff := olds.Diff(news)
+
+	// Changed leaves are reported with their old and new values.
+	change, ok := diff.At([]interface{}{"spec", "image"})
+	assert.True(t, ok)
+	assert.Equal(t, NewStringProperty("nginx:1.16"), change.Old)
+	assert.Equal(t, NewStringProperty("nginx:1.17"), change.New)
+	change, ok = diff.At([]interface{}{"spec", "ports", 2})
+	assert.True(t, ok)
+	assert.True(t, change.Old.IsNull())
+	assert.Equal(t, NewNumberProperty(8080), change.New)
+
+	// Intermediate paths report the nested diff.
+	change, ok = diff.At([]interface{}{"spec"})
+	assert.True(t, ok)
+	assert.NotNil(t, change.Object)
+	assert.True(t, change.Object.Updated("image"))
+	change, ok = diff.At([]interface{}{"spec", "ports"})
+	assert.True(t, ok)
+	assert.NotNil(t, change.Array)
+
+	// Paths into added and deleted values report the corresponding parts of those values.
+	change, ok = diff.At([]interface{}{"volumes", 0, "name"})
+	assert.True(t, ok)
+	assert.True(t, change.Old.IsNull())
+	assert.Equal(t, NewStringProperty("data"), change.New)
+	change, ok = diff.At([]interface{}{"labels", "tier"})
+	assert.True(t, ok)
+	assert.Equal(t, NewStringProperty("frontend"), change.Old)
+	assert.True(t, change.New.IsNull())
+
+	// Unchanged and absent paths, and paths whose elements are of the wrong kind, report no change.
+	for _, path := range [][]interface{}{
+		{"name"},
+		{"spec", "ports", 0},
+		{"missing"},
+		{"spec", "missing"},
+		{"spec", "ports", 3},
+		{"spec", 0},
+		{"spec", "ports", "0"},
+		{"volumes", 0, "missing"},
+		{},
+	} {
+		_, ok := diff.At(path)
+		assert.False(t, ok, "%v", path)
+	}
+	var nilDiff *ObjectDiff
+	_, ok = nilDiff.At([]interface{}{"name"})
+	assert.False(t, ok)
+
+	// Updates without nested diffs, as built from detailed diffs, are navigated through their old and new values.
+	leaf := &ObjectDiff{Updates: map[PropertyKey]ValueDiff{
+		"spec": {Old: olds["spec"], New: news["spec"]},
+	}}
+	change, ok = leaf.At([]interface{}{"spec", "image"})
+	assert.True(t, ok)
+	assert.Equal(t, NewStringProperty("nginx:1.17"), change.New)
+	_, ok = leaf.At([]interface{}{"spec", "ports", 1})
+	assert.False(t, ok)
+}
+
+func TestArrayDiffAtAligned(t *testing.T) {
+	t.Parallel()
+
+	str := NewStringProperty
+
+	// ["a", "b"] => ["x", "a", "B"]: "x" was inserted at the front, which moved the other elements along by one.
+	inserted := &ArrayDiff{
+		Adds:    map[int]PropertyValue{0: str("x")},
+		Deletes: map[int]PropertyValue{},
+		Sames:   map[int]PropertyValue{1: str("a")},
+		Updates: map[int]ValueDiff{2: {Old: str("b"), New: str("B")}},
+		Moves:   map[int]ArrayMove{1: {From: 0, To: 1}, 2: {From: 1, To: 2}},
+	}
+	assert.Equal(t, 3, inserted.Len())
+	assert.Equal(t, 3, inserted.Positions())
+	change, ok := inserted.At([]interface{}{0})
+	assert.True(t, ok)
+	assert.Equal(t, str("x"), change.New)
+	_, ok = inserted.At([]interface{}{1})
+	assert.False(t, ok)
+	change, ok = inserted.At([]interface{}{2})
+	assert.True(t, ok)
+	assert.Equal(t, str("b"), change.Old)
+	assert.Equal(t, str("B"), change.New)
+
+	// ["a", "b", "c"] => ["b", "c", "a"]: "a" was moved from the front to the back, which is recorded as a delete at
+	// the first position and an add at the last. Indices address the new array, so only index 2 changed.
+	rotated := &ArrayDiff{
+		Adds:    map[int]PropertyValue{3: str("a")},
+		Deletes: map[int]PropertyValue{0: str("a")},
+		Sames:   map[int]PropertyValue{1: str("b"), 2: str("c")},
+		Updates: map[int]ValueDiff{},
+		Moves: map[int]ArrayMove{
+			0: {From: 0, To: -1},
+			1: {From: 1, To: 0},
+			2: {From: 2, To: 1},
+			3: {From: -1, To: 2},
+		},
+	}
+	assert.Equal(t, 3, rotated.Len())
+	assert.Equal(t, 4, rotated.Positions())
+	assert.Equal(t, []int{0, 0, 1, 2}, []int{rotated.Index(0), rotated.Index(1), rotated.Index(2), rotated.Index(3)})
+	for _, i := range []int{0, 1, 3} {
+		_, ok := rotated.At([]interface{}{i})
+		assert.False(t, ok, "%d", i)
+	}
+	change, ok = rotated.At([]interface{}{2})
+	assert.True(t, ok)
+	assert.True(t, change.Old.IsNull())
+	assert.Equal(t, str("a"), change.New)
+
+	// An index past the end of the new array addresses an element deleted from the old array.
+	shortened := &ArrayDiff{
+		Adds:    map[int]PropertyValue{},
+		Deletes: map[int]PropertyValue{0: str("a"), 2: str("c")},
+		Sames:   map[int]PropertyValue{1: str("b")},
+		Updates: map[int]ValueDiff{},
+		Moves:   map[int]ArrayMove{0: {From: 0, To: -1}, 1: {From: 1, To: 0}, 2: {From: 2, To: -1}},
+	}
+	assert.Equal(t, 3, shortened.Len())
+	for _, i := range []int{0, 1} {
+		_, ok := shortened.At([]interface{}{i})
+		assert.False(t, ok, "%d", i)
+	}
+	change, ok = shortened.At([]interface{}{2})
+	assert.True(t, ok)
+	assert.Equal(t, str("c"), change.Old)
+	assert.True(t, change.New.IsNull())
+
+	// Object diffs address the elements of their arrays by index, too.
+	diff := &ObjectDiff{Updates: map[PropertyKey]ValueDiff{"hosts": {Array: rotated}}}
+	change, ok = diff.At([]interface{}{"hosts", 2})
+	assert.True(t, ok)
+	assert.Equal(t, str("a"), change.New)
+	_, ok = diff.At([]interface{}{"hosts", 0})
+	assert.False(t, ok)
+}
@@ -0,0 +1,147 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// JSONPatchOperation is a single operation of a JSON Patch, as described by RFC 6902.
+type JSONPatchOperation struct {
+	Op    string      // the operation: add, remove, or replace.
+	Path  string      // a JSON Pointer, as described by RFC 6901, to the value that the operation applies to.
+	Value interface{} // the value to add or to replace with; unused by removes.
+}
+
+// MarshalJSON marshals the operation as a JSON Patch operation object. Values are marshaled even if they are null, as
+// adding or replacing with null differs from removing, and are omitted from removes.
+func (op JSONPatchOperation) MarshalJSON() ([]byte, error) {
+	if op.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{op.Op, op.Path})
+	}
+	return json.Marshal(struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{op.Op, op.Path, op.Value})
+}
+
+// JSONPatch returns the JSON Patch operations that, applied in order to the JSON representation of the old properties
+// of this diff, produce the JSON representation of the given new properties. Properties that were deleted are removed
+// unless they are still present in the new properties with null values, in which case they are replaced with null.
+//
+// Values are converted to JSON as by Mappable, except that empty arrays remain arrays, secrets are represented by
+// their plaintext values, and unknown values are represented by null. Callers that must not reveal secrets should
+// mask them before computing the diff.
+func (diff *ObjectDiff) JSONPatch(news PropertyMap) []JSONPatchOperation {
+	if diff == nil {
+		return nil
+	}
+	return diff.jsonPatch("", news)
+}
+
+// JSONPatch returns the JSON Patch operations that, applied in order to the JSON representation of this diff's old
+// value, produce the JSON representation of its new value. See ObjectDiff.JSONPatch for how values are converted.
+func (diff ValueDiff) JSONPatch() []JSONPatchOperation {
+	return diff.jsonPatch("")
+}
+
+func (diff *ObjectDiff) jsonPatch(path string, news PropertyMap) []JSONPatchOperation {
+	var ops []JSONPatchOperation
+	for _, k := range diff.Keys() {
+		elementPath := path + "/" + escapeJSONPointer(string(k))
+		if add, isadd := diff.Adds[k]; isadd {
+			ops = append(ops, JSONPatchOperation{Op: "add", Path: elementPath, Value: jsonPatchValue(add)})
+		} else if _, isdelete := diff.Deletes[k]; isdelete {
+			if new, has := news[k]; has && new.IsNull() {
+				ops = append(ops, JSONPatchOperation{Op: "replace", Path: elementPath, Value: nil})
+			} else {
+				ops = append(ops, JSONPatchOperation{Op: "remove", Path: elementPath})
+			}
+		} else if update, isupdate := diff.Updates[k]; isupdate {
+			ops = append(ops, update.jsonPatch(elementPath)...)
+		}
+	}
+	return ops
+}
+
+// jsonPatch returns the operations for an array diff. The diff's positions are patched in order, so that each
+// operation applies to the array as the operations before it left it: elements that are deleted are removed from the
+// current index, and elements that are added are inserted at it.
+func (diff *ArrayDiff) jsonPatch(path string) []JSONPatchOperation {
+	var ops []JSONPatchOperation
+	index := 0
+	for i := 0; i < diff.Positions(); i++ {
+		elementPath := path + "/" + strconv.Itoa(index)
+		if add, isadd := diff.Adds[i]; isadd {
+			ops = append(ops, JSONPatchOperation{Op: "add", Path: elementPath, Value: jsonPatchValue(add)})
+			index++
+		} else if _, isdelete := diff.Deletes[i]; isdelete {
+			ops = append(ops, JSONPatchOperation{Op: "remove", Path: elementPath})
+		} else if update, isupdate := diff.Updates[i]; isupdate {
+			ops = append(ops, update.jsonPatch(elementPath)...)
+			index++
+		} else if _, issame := diff.Sames[i]; issame {
+			index++
+		}
+	}
+	return ops
+}
+
+func (diff ValueDiff) jsonPatch(path string) []JSONPatchOperation {
+	switch {
+	case diff.Array != nil:
+		return diff.Array.jsonPatch(path)
+	case diff.Object != nil:
+		var news PropertyMap
+		if diff.New.IsObject() {
+			news = diff.New.ObjectValue()
+		}
+		return diff.Object.jsonPatch(path, news)
+	default:
+		return []JSONPatchOperation{{Op: "replace", Path: path, Value: jsonPatchValue(diff.New)}}
+	}
+}
+
+// escapeJSONPointer escapes a reference token of a JSON Pointer, as described by RFC 6901.
+func escapeJSONPointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// jsonPatchValue converts a property value to the value of a JSON Patch operation.
+func jsonPatchValue(v PropertyValue) interface{} {
+	return v.MapRepl(nil, func(v PropertyValue) (interface{}, bool) {
+		switch {
+		case v.IsSecret():
+			return jsonPatchValue(v.SecretValue().Element), true
+		case v.IsComputed() || v.IsOutput():
+			return nil, true
+		case v.IsArray():
+			// Empty arrays are converted to empty arrays rather than to null.
+			elements := make([]interface{}, len(v.ArrayValue()))
+			for i, e := range v.ArrayValue() {
+				elements[i] = jsonPatchValue(e)
+			}
+			return elements, true
+		default:
+			return nil, false
+		}
+	})
+}
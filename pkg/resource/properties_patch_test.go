@@ -0,0 +1,152 @@
+// Copyright 2016-2019, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// applyJSONPatch applies the given JSON-encoded JSON Patch to the given JSON document. Only the add, remove, and
+// replace operations are supported.
+func applyJSONPatch(t *testing.T, doc interface{}, patch []byte) interface{} {
+	var ops []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	}
+	assert.NoError(t, json.Unmarshal(patch, &ops))
+
+	var apply func(doc interface{}, tokens []string, op string, value interface{}) interface{}
+	apply = func(doc interface{}, tokens []string, op string, value interface{}) interface{} {
+		if len(tokens) == 0 {
+			return value
+		}
+		token := strings.NewReplacer("~1", "/", "~0", "~").Replace(tokens[0])
+		switch doc := doc.(type) {
+		case map[string]interface{}:
+			switch {
+			case len(tokens) > 1:
+				doc[token] = apply(doc[token], tokens[1:], op, value)
+			case op == "remove":
+				delete(doc, token)
+			default:
+				doc[token] = value
+			}
+			return doc
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			assert.NoError(t, err)
+			switch {
+			case len(tokens) > 1:
+				doc[i] = apply(doc[i], tokens[1:], op, value)
+			case op == "remove":
+				doc = append(doc[:i], doc[i+1:]...)
+			case op == "add":
+				doc = append(doc[:i], append([]interface{}{value}, doc[i:]...)...)
+			default:
+				doc[i] = value
+			}
+			return doc
+		default:
+			assert.Failf(t, "cannot apply patch", "%v is not a container", doc)
+			return doc
+		}
+	}
+
+	for _, op := range ops {
+		// Values must be present, even if null, for all operations but removes.
+		assert.Equal(t, op.Op != "remove", op.Value != nil, op.Path)
+		var value interface{}
+		if op.Value != nil {
+			assert.NoError(t, json.Unmarshal(op.Value, &value))
+		}
+		doc = apply(doc, strings.Split(op.Path, "/")[1:], op.Op, value)
+	}
+	return doc
+}
+
+// toJSON returns the JSON document that represents the given value.
+func toJSON(t *testing.T, v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	assert.NoError(t, err)
+	var doc interface{}
+	assert.NoError(t, json.Unmarshal(b, &doc))
+	return doc
+}
+
+func TestJSONPatch(t *testing.T) {
+	olds := NewPropertyMapFromMap(map[string]interface{}{
+		"name":    "web",
+		"a/b":     1,
+		"c~d":     "x",
+		"cleared": "value",
+		"removed": true,
+		"ports":   []interface{}{80, 443, 8080},
+		"hosts":   []interface{}{"a", "b", "c", "d"},
+		"spec": map[string]interface{}{
+			"replicas": 3,
+			"labels":   map[string]interface{}{"app": "web"},
+			"args":     []interface{}{},
+		},
+	})
+	news := NewPropertyMapFromMap(map[string]interface{}{
+		"name":  "web",
+		"a/b":   2,
+		"c~d":   "y",
+		"ports": []interface{}{80},
+		"hosts": []interface{}{"a", "x", "c", "e", "d"},
+		"spec": map[string]interface{}{
+			"replicas": 5,
+			"labels":   map[string]interface{}{"app": "web", "tier": "frontend"},
+			"args":     []interface{}{"--verbose"},
+			"env":      []interface{}{},
+		},
+		"owner": "ops",
+	})
+	news["cleared"] = NewNullProperty()
+	news["token"] = MakeSecret(NewStringProperty("s3cr3t"))
+
+	diff := olds.Diff(news)
+	patch, err := json.Marshal(diff.JSONPatch(news))
+	assert.NoError(t, err)
+
+	// Applying the patch to the old document must produce the new document, including the property that was cleared.
+	patched := applyJSONPatch(t, toJSON(t, jsonPatchValue(NewObjectProperty(olds))), patch)
+	assert.Equal(t, toJSON(t, jsonPatchValue(NewObjectProperty(news))), patched)
+	assert.Contains(t, patched, "cleared")
+	assert.Nil(t, patched.(map[string]interface{})["cleared"])
+
+	// Keys are escaped, cleared properties are replaced with null, and deleted properties are removed.
+	ops := diff.JSONPatch(news)
+	assert.Contains(t, ops, JSONPatchOperation{Op: "replace", Path: "/a~1b", Value: float64(2)})
+	assert.Contains(t, ops, JSONPatchOperation{Op: "replace", Path: "/c~0d", Value: "y"})
+	assert.Contains(t, ops, JSONPatchOperation{Op: "replace", Path: "/cleared", Value: nil})
+	assert.Contains(t, ops, JSONPatchOperation{Op: "remove", Path: "/removed"})
+	assert.Contains(t, ops, JSONPatchOperation{Op: "add", Path: "/spec/env", Value: []interface{}{}})
+
+	// Value diffs are patched from their root.
+	update := *NewStringProperty("a").Diff(NewNullProperty())
+	assert.Equal(t, []JSONPatchOperation{{Op: "replace", Path: "", Value: nil}}, update.JSONPatch())
+	b, err := json.Marshal(update.JSONPatch())
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"op":"replace","path":"","value":null}]`, string(b))
+
+	assert.Nil(t, (*ObjectDiff)(nil).JSONPatch(news))
+}
@@ -138,6 +138,8 @@ type ProjectStack struct {
 	EncryptionSalt string `json:"encryptionsalt,omitempty" yaml:"encryptionsalt,omitempty"`
 	// Config is an optional config bag.
 	Config config.Map `json:"config,omitempty" yaml:"config,omitempty"`
+	// IgnoreDiffPaths is an optional list of property path patterns whose changes are omitted from displayed diffs.
+	IgnoreDiffPaths []string `json:"ignoreDiffPaths,omitempty" yaml:"ignoreDiffPaths,omitempty"`
 }
 
 // Save writes a project definition to a file.